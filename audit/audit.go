@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/logging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// EventType names the kind of security relevant action
+type EventType string
+
+const (
+	EventLogin         EventType = "login"
+	EventSignup        EventType = "signup"
+	EventTokenCheck    EventType = "token_check"
+	EventSuspendUser   EventType = "admin.suspend_user"
+	EventUnsuspendUser EventType = "admin.unsuspend_user"
+	EventForceLogout   EventType = "admin.force_logout"
+	EventResetPassword EventType = "admin.reset_password"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+const writeTimeout = 5 * time.Second
+
+// Event is a single entry of the audit log.
+// Entries are only ever inserted, a MongoStore drops them through its retention index.
+type Event struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Type      EventType          `bson:"type"`
+	UserID    string             `bson:"user_id,omitempty"`
+	TargetID  string             `bson:"target_id,omitempty"`
+	IP        string             `bson:"ip,omitempty"`
+	UserAgent string             `bson:"user_agent,omitempty"`
+	Outcome   string             `bson:"outcome"`
+	Reason    string             `bson:"reason,omitempty"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// Query selects events from the audit log, zero values match everything
+type Query struct {
+	Type    EventType
+	UserID  string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	Limit   int64
+}
+
+// Store persists audit events
+type Store interface {
+	Insert(ctx context.Context, e Event) error
+	// Find returns matching events, newest first
+	Find(ctx context.Context, q Query) ([]Event, error)
+}
+
+// Log records security events to an append-only store
+type Log struct {
+	store Store
+}
+
+// NewLog returns an audit log writing to store
+func NewLog(store Store) *Log {
+	return &Log{store: store}
+}
+
+// Record stores e, enriched with the caller's IP and user agent from ctx.
+// Failures are logged and never fail the calling RPC. A nil Log discards events.
+func (l *Log) Record(ctx context.Context, e Event) {
+	if l == nil {
+		return
+	}
+	e.ID = primitive.NewObjectID()
+	e.CreatedAt = time.Now().UTC()
+	if e.IP == "" && e.UserAgent == "" {
+		e.IP, e.UserAgent = ClientInfo(ctx)
+	}
+
+	// insert should not take more that 5 seconds
+	dbCtx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	if err := l.store.Insert(dbCtx, e); err != nil {
+		logging.FromContext(ctx).Error("Error returned while inserting audit event", zap.Error(err))
+	}
+}
+
+// Query returns matching events, newest first
+func (l *Log) Query(ctx context.Context, q Query) ([]Event, error) {
+	return l.store.Find(ctx, q)
+}
+
+// gatewayNetwork is the network of the in-process gateway's bufconn listener
+const gatewayNetwork = "bufconn"
+
+// ClientInfo returns the caller's IP and user agent.
+// The IP is the peer address, x-forwarded-for is only trusted when the in-process
+// gateway is the peer, in which case the right-most hop is the gateway's client.
+func ClientInfo(ctx context.Context) (ip, userAgent string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if p.Addr.Network() == gatewayNetwork {
+			if forwarded := md.Get("x-forwarded-for"); len(forwarded) > 0 {
+				hops := strings.Split(forwarded[len(forwarded)-1], ",")
+				ip = strings.TrimSpace(hops[len(hops)-1])
+			}
+		} else {
+			ip = p.Addr.String()
+			if host, _, err := net.SplitHostPort(ip); err == nil {
+				ip = host
+			}
+		}
+	}
+	for _, key := range []string{"x-user-agent", "user-agent"} {
+		if values := md.Get(key); len(values) > 0 {
+			userAgent = values[0]
+			break
+		}
+	}
+	return ip, userAgent
+}
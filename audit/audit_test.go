@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func Test_ClientInfo(t *testing.T) {
+
+	peerCtx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 51234}})
+	gatewayCtx := peer.NewContext(context.Background(), &peer.Peer{Addr: bufconn.Listen(1).Addr()})
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"ctx":       context.Background(),
+			"ip":        "",
+			"userAgent": "",
+		},
+		map[string]interface{}{
+			"ctx":       peerCtx,
+			"ip":        "10.0.0.7",
+			"userAgent": "",
+		},
+		map[string]interface{}{
+			"ctx":       metadata.NewIncomingContext(peerCtx, metadata.Pairs("x-forwarded-for", "203.0.113.9, 10.0.0.1", "user-agent", "grpc-go/1.37.0")),
+			"ip":        "10.0.0.7",
+			"userAgent": "grpc-go/1.37.0",
+		},
+		map[string]interface{}{
+			"ctx":       metadata.NewIncomingContext(gatewayCtx, metadata.Pairs("x-forwarded-for", "203.0.113.9")),
+			"ip":        "203.0.113.9",
+			"userAgent": "",
+		},
+		map[string]interface{}{
+			"ctx":       metadata.NewIncomingContext(gatewayCtx, metadata.Pairs("x-forwarded-for", "1.2.3.4, 203.0.113.9")),
+			"ip":        "203.0.113.9",
+			"userAgent": "",
+		},
+		map[string]interface{}{
+			"ctx":       metadata.NewIncomingContext(peerCtx, metadata.Pairs("x-user-agent", "grpc-web-javascript/0.1", "user-agent", "Mozilla/5.0")),
+			"ip":        "10.0.0.7",
+			"userAgent": "grpc-web-javascript/0.1",
+		},
+	}
+
+	for _, tcase := range testCases {
+		ip, userAgent := ClientInfo(tcase["ctx"].(context.Context))
+		assert.Equalf(t, tcase["ip"], ip, "case: %v", tcase)
+		assert.Equalf(t, tcase["userAgent"], userAgent, "case: %v", tcase)
+	}
+}
+
+func Test_Log_nil(t *testing.T) {
+
+	var l *Log
+	assert.NotPanics(t, func() { l.Record(context.Background(), Event{Type: EventLogin}) })
+
+	// events reach the store with an id and timestamp
+	store := NewMemoryStore()
+	NewLog(store).Record(context.Background(), Event{Type: EventLogin, Outcome: OutcomeSuccess})
+	events, err := NewLog(store).Query(context.Background(), Query{})
+	if assert.NoError(t, err) && assert.Len(t, events, 1) {
+		assert.False(t, events[0].ID.IsZero())
+		assert.False(t, events[0].CreatedAt.IsZero())
+	}
+}
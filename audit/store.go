@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultRetention = 90 * 24 * time.Hour
+
+// MongoStore keeps events in a collection
+type MongoStore struct {
+	collection *mongo.Collection
+	retention  time.Duration
+}
+
+// NewMongoStore returns a store backed by collection.
+// Events older than retention are removed by mongo, a zero retention keeps them for 90 days.
+func NewMongoStore(collection *mongo.Collection, retention time.Duration) *MongoStore {
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+	return &MongoStore{collection: collection, retention: retention}
+}
+
+// EnsureIndexes creates the retention and lookup indexes
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(s.retention.Seconds())),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	})
+	return err
+}
+
+func (s *MongoStore) Insert(ctx context.Context, e Event) error {
+	_, err := s.collection.InsertOne(ctx, e)
+	return err
+}
+
+func (s *MongoStore) Find(ctx context.Context, q Query) ([]Event, error) {
+	cursor, err := s.collection.Find(ctx, q.filter(), options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(q.Limit))
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (q Query) filter() bson.M {
+	filter := bson.M{}
+	if q.Type != "" {
+		filter["type"] = q.Type
+	}
+	if q.UserID != "" {
+		filter["user_id"] = q.UserID
+	}
+	if q.Outcome != "" {
+		filter["outcome"] = q.Outcome
+	}
+	createdAt := bson.M{}
+	if !q.Since.IsZero() {
+		createdAt["$gte"] = q.Since
+	}
+	if !q.Until.IsZero() {
+		createdAt["$lt"] = q.Until
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+	return filter
+}
+
+// MemoryStore keeps events in memory, for tests and local runs
+type MemoryStore struct {
+	mu     sync.RWMutex
+	events []Event
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Insert(ctx context.Context, e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *MemoryStore) Find(ctx context.Context, q Query) ([]Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []Event
+	// events are appended as they happen, so walking backwards is newest first
+	for i := len(s.events) - 1; i >= 0; i-- {
+		if q.Limit > 0 && int64(len(events)) == q.Limit {
+			break
+		}
+		if q.matches(s.events[i]) {
+			events = append(events, s.events[i])
+		}
+	}
+	return events, nil
+}
+
+// matches is the in-memory equivalent of filter
+func (q Query) matches(e Event) bool {
+	if q.Type != "" && e.Type != q.Type {
+		return false
+	}
+	if q.UserID != "" && e.UserID != q.UserID {
+		return false
+	}
+	if q.Outcome != "" && e.Outcome != q.Outcome {
+		return false
+	}
+	if !q.Since.IsZero() && e.CreatedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.CreatedAt.Before(q.Until) {
+		return false
+	}
+	return true
+}
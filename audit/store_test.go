@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func Test_Query_filter(t *testing.T) {
+
+	since := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, bson.M{}, Query{}.filter())
+	assert.Equal(t, bson.M{
+		"type":       EventLogin,
+		"outcome":    OutcomeFailure,
+		"created_at": bson.M{"$gte": since},
+	}, Query{Type: EventLogin, Outcome: OutcomeFailure, Since: since}.filter())
+}
+
+func Test_NewMongoStore(t *testing.T) {
+
+	assert.Equal(t, 90*24*time.Hour, NewMongoStore(nil, 0).retention)
+	assert.Equal(t, time.Hour, NewMongoStore(nil, time.Hour).retention)
+}
+
+func Test_MemoryStore_Find(t *testing.T) {
+
+	since := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	store := NewMemoryStore()
+	for _, e := range []Event{
+		Event{Type: EventLogin, UserID: "a", Outcome: OutcomeFailure, CreatedAt: since.Add(-time.Hour)},
+		Event{Type: EventLogin, UserID: "a", Outcome: OutcomeSuccess, CreatedAt: since},
+		Event{Type: EventSignup, UserID: "b", Outcome: OutcomeSuccess, CreatedAt: since.Add(time.Hour)},
+	} {
+		store.Insert(context.Background(), e)
+	}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"query": Query{},
+			"users": []string{"b", "a", "a"},
+		},
+		map[string]interface{}{
+			"query": Query{Type: EventLogin},
+			"users": []string{"a", "a"},
+		},
+		map[string]interface{}{
+			"query": Query{Outcome: OutcomeSuccess, Limit: 1},
+			"users": []string{"b"},
+		},
+		map[string]interface{}{
+			"query": Query{UserID: "a", Since: since},
+			"users": []string{"a"},
+		},
+		map[string]interface{}{
+			"query": Query{Until: since},
+			"users": []string{"a"},
+		},
+	}
+
+	for _, tcase := range testCases {
+		events, err := store.Find(context.Background(), tcase["query"].(Query))
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		var users []string
+		for _, e := range events {
+			users = append(users, e.UserID)
+		}
+		assert.Equalf(t, tcase["users"], users, "case: %v", tcase)
+	}
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/audit"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	roleGranted = "grant"
+	roleRevoked = "revoke"
+
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+type adminServer struct {
+	users     store.Users
+	roleAudit store.RoleAudit
+	auditLog  *audit.Log
+	pages     *paging.Codec
+}
+
+func (a *adminServer) GrantRole(ctx context.Context, in *proto.RoleRequest) (*proto.RolesResponse, error) {
+	return a.changeRole(ctx, in, roleGranted)
+}
+
+func (a *adminServer) RevokeRole(ctx context.Context, in *proto.RoleRequest) (*proto.RolesResponse, error) {
+	return a.changeRole(ctx, in, roleRevoked)
+}
+
+func (a *adminServer) changeRole(ctx context.Context, in *proto.RoleRequest, action string) (*proto.RolesResponse, error) {
+	role, err := policy.ParseRole(in.GetRole())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	targetID, err := primitive.ObjectIDFromHex(in.GetUserID())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid user id")
+	}
+
+	actor := global.UserFromContext(ctx)
+	if action == roleRevoked && role == policy.RoleAdmin && actor.ID == targetID {
+		return nil, status.Error(codes.FailedPrecondition, "Admins cannot revoke their own admin role")
+	}
+
+	// update should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	var updated global.User
+	if action == roleRevoked {
+		updated, err = a.users.RemoveRole(dbCtx, targetID, role)
+	} else {
+		updated, err = a.users.AddRole(dbCtx, targetID, role)
+	}
+	if err == store.ErrNotFound {
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while updating roles", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	err = a.roleAudit.Insert(dbCtx, store.RoleChange{
+		ID:        primitive.NewObjectID(),
+		ActorID:   actor.ID,
+		TargetID:  targetID,
+		Role:      role,
+		Action:    action,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while inserting role audit entry", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	return &proto.RolesResponse{UserID: targetID.Hex(), Roles: policy.RoleNames(updated.GetRoles())}, nil
+}
+
+func (a *adminServer) ListRoleAudit(ctx context.Context, in *proto.RoleAuditRequest) (*proto.RoleAuditResponse, error) {
+	var targetID primitive.ObjectID
+	if in.GetUserID() != "" {
+		var err error
+		targetID, err = primitive.ObjectIDFromHex(in.GetUserID())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "Invalid user id")
+		}
+	}
+
+	limit := in.GetLimit()
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	changes, err := a.roleAudit.List(dbCtx, targetID, limit)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while reading role audit", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	entries := make([]*proto.RoleAuditEntry, 0, len(changes))
+	for _, c := range changes {
+		entries = append(entries, &proto.RoleAuditEntry{
+			ID:        c.ID.Hex(),
+			ActorID:   c.ActorID.Hex(),
+			TargetID:  c.TargetID.Hex(),
+			Role:      string(c.Role),
+			Action:    c.Action,
+			CreatedAt: c.CreatedAt.Unix(),
+		})
+	}
+	return &proto.RoleAuditResponse{Entries: entries}, nil
+}
+
+func (a *adminServer) QueryAuditLog(ctx context.Context, in *proto.QueryAuditLogRequest) (*proto.QueryAuditLogResponse, error) {
+	q := audit.Query{
+		Type:    audit.EventType(in.GetType()),
+		UserID:  in.GetUserID(),
+		Outcome: in.GetOutcome(),
+		Limit:   in.GetLimit(),
+	}
+	if in.GetSince() > 0 {
+		q.Since = time.Unix(in.GetSince(), 0)
+	}
+	if in.GetUntil() > 0 {
+		q.Until = time.Unix(in.GetUntil(), 0)
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultAuditLimit
+	}
+	if q.Limit > maxAuditLimit {
+		q.Limit = maxAuditLimit
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	events, err := a.auditLog.Query(dbCtx, q)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while reading audit log", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	res := &proto.QueryAuditLogResponse{}
+	for _, e := range events {
+		res.Events = append(res.Events, &proto.AuditEvent{
+			ID:        e.ID.Hex(),
+			Type:      string(e.Type),
+			UserID:    e.UserID,
+			TargetID:  e.TargetID,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			Outcome:   e.Outcome,
+			Reason:    e.Reason,
+			CreatedAt: e.CreatedAt.Unix(),
+		})
+	}
+	return res, nil
+}
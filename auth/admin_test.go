@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_adminServer_GrantRevokeRole(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+	ctx := h.as(fixtureAdmin)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			// the implicit default role is kept
+			"action": "grant",
+			"userID": fixtureLegacy.ID.Hex(),
+			"role":   "editor",
+			"roles":  []string{"author", "editor"},
+		},
+		map[string]interface{}{
+			"action": "grant",
+			"userID": fixtureLegacy.ID.Hex(),
+			"role":   "editor",
+			"roles":  []string{"author", "editor"},
+		},
+		map[string]interface{}{
+			"action": "revoke",
+			"userID": fixtureLegacy.ID.Hex(),
+			"role":   "author",
+			"roles":  []string{"editor"},
+		},
+		map[string]interface{}{
+			"action": "revoke",
+			"userID": fixtureEditor.ID.Hex(),
+			"role":   "editor",
+			"roles":  []string{"author"},
+		},
+		map[string]interface{}{
+			"action": "grant",
+			"userID": fixtureLegacy.ID.Hex(),
+			"role":   "superuser",
+			"error":  "Unknown role",
+		},
+		map[string]interface{}{
+			"action": "grant",
+			"userID": "not-an-id",
+			"role":   "editor",
+			"error":  "UserID should be a valid id",
+		},
+		map[string]interface{}{
+			"action": "grant",
+			"userID": primitive.NewObjectID().Hex(),
+			"role":   "editor",
+			"error":  "User not found",
+		},
+		map[string]interface{}{
+			"action": "revoke",
+			"userID": fixtureAdmin.ID.Hex(),
+			"role":   "admin",
+			"error":  "Admins cannot revoke their own admin role",
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		req := &proto.RoleRequest{UserID: tcase["userID"].(string), Role: tcase["role"].(string)}
+		var res *proto.RolesResponse
+		var err error
+		if tcase["action"] == "grant" {
+			res, err = h.admin.GrantRole(ctx, req)
+		} else {
+			res, err = h.admin.RevokeRole(ctx, req)
+		}
+
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			assert.ElementsMatchf(t, tcase["roles"], res.GetRoles(), "case: %v", tcase)
+		}
+	}
+}
+
+func Test_adminServer_ListRoleAudit(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+	ctx := h.as(fixtureAdmin)
+
+	for _, req := range []*proto.RoleRequest{
+		&proto.RoleRequest{UserID: fixtureAuthor.ID.Hex(), Role: "editor"},
+		&proto.RoleRequest{UserID: fixtureLegacy.ID.Hex(), Role: "editor"},
+		&proto.RoleRequest{UserID: fixtureAuthor.ID.Hex(), Role: "admin"},
+	} {
+		if _, err := h.admin.GrantRole(ctx, req); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"request": &proto.RoleAuditRequest{},
+			"roles":   []string{"admin", "editor", "editor"},
+		},
+		map[string]interface{}{
+			// newest first
+			"request": &proto.RoleAuditRequest{UserID: fixtureAuthor.ID.Hex()},
+			"roles":   []string{"admin", "editor"},
+		},
+		map[string]interface{}{
+			"request": &proto.RoleAuditRequest{Limit: 1},
+			"roles":   []string{"admin"},
+		},
+		map[string]interface{}{
+			"request": &proto.RoleAuditRequest{UserID: fixtureEditor.ID.Hex()},
+			"roles":   []string(nil),
+		},
+		map[string]interface{}{
+			"request": &proto.RoleAuditRequest{UserID: "not-an-id"},
+			"error":   "UserID should be a valid id",
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		res, err := h.admin.ListRoleAudit(ctx, tcase["request"].(*proto.RoleAuditRequest))
+
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			var roles []string
+			for _, e := range res.GetEntries() {
+				assert.Equalf(t, fixtureAdmin.ID.Hex(), e.GetActorID(), "case: %v", tcase)
+				assert.Equalf(t, "grant", e.GetAction(), "case: %v", tcase)
+				roles = append(roles, e.GetRole())
+			}
+			assert.Equalf(t, tcase["roles"], roles, "case: %v", tcase)
+		}
+	}
+}
+
+func Test_adminServer_QueryAuditLog(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+	ctx := h.as(fixtureAdmin)
+
+	_, err := h.auth.Login(context.Background(), &proto.LoginRequest{Login: "audit-unknown-user", Password: "audit-password"})
+	assert.Error(t, err)
+	_, err = h.auth.Login(context.Background(), &proto.LoginRequest{Login: "fixture-author", Password: fixturePassword})
+	assert.NoError(t, err)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"request": &proto.QueryAuditLogRequest{Type: "login", Outcome: "failure"},
+			"reasons": []string{"unknown login"},
+		},
+		map[string]interface{}{
+			"request": &proto.QueryAuditLogRequest{Type: "login", UserID: fixtureAuthor.ID.Hex()},
+			"reasons": []string{""},
+		},
+		map[string]interface{}{
+			"request": &proto.QueryAuditLogRequest{Type: "login", Since: time.Now().Add(time.Hour).Unix()},
+			"reasons": []string(nil),
+		},
+		map[string]interface{}{
+			"request": &proto.QueryAuditLogRequest{Type: "login", Until: time.Now().Add(-time.Hour).Unix()},
+			"reasons": []string(nil),
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		res, err := h.admin.QueryAuditLog(ctx, tcase["request"].(*proto.QueryAuditLogRequest))
+		if !assert.NoErrorf(t, err, "case: %v", tcase) {
+			continue
+		}
+		var reasons []string
+		for _, e := range res.GetEvents() {
+			reasons = append(reasons, e.GetReason())
+		}
+		assert.Equalf(t, tcase["reasons"], reasons, "case: %v", tcase)
+	}
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/audit"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	userStatusActive    = "active"
+	userStatusSuspended = "suspended"
+)
+
+func (a *adminServer) ListUsers(ctx context.Context, in *proto.ListUsersRequest) (*proto.ListUsersResponse, error) {
+	filter, page, err := a.userListing(in)
+	if err != nil {
+		return nil, err
+	}
+	users, total, err := a.listUsers(ctx, filter, page)
+	if err != nil {
+		return nil, err
+	}
+
+	var last interface{}
+	if len(users) > 0 {
+		last = users[len(users)-1].ID
+	}
+	next, err := a.pages.Next(page, len(users), total, last)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while issuing page token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	res := &proto.ListUsersResponse{
+		Total:         total,
+		PageSize:      page.Size,
+		NextPageToken: next,
+		Query:         in.GetQuery(),
+		Role:          in.GetRole(),
+		Status:        in.GetStatus(),
+	}
+	for _, u := range users {
+		res.Users = append(res.Users, toUserInfo(u))
+	}
+	return res, nil
+}
+
+func (a *adminServer) StreamUsers(in *proto.ListUsersRequest, stream proto.AdminService_StreamUsersServer) error {
+	filter, page, err := a.userListing(in)
+	if err != nil {
+		return err
+	}
+	return paging.Walk(page, func(page paging.Page) (int, interface{}, error) {
+		users, _, err := a.listUsers(stream.Context(), filter, page)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, u := range users {
+			if err := stream.Send(toUserInfo(u)); err != nil {
+				return 0, nil, err
+			}
+		}
+		if len(users) == 0 {
+			return 0, nil, nil
+		}
+		return len(users), users[len(users)-1].ID, nil
+	})
+}
+
+// userListing returns the store filter and the first page of a request
+func (a *adminServer) userListing(in *proto.ListUsersRequest) (store.UserFilter, paging.Page, error) {
+	filter := store.UserFilter{Query: in.GetQuery()}
+	if in.GetRole() != "" {
+		role, err := policy.ParseRole(in.GetRole())
+		if err != nil {
+			return filter, paging.Page{}, status.Error(codes.InvalidArgument, err.Error())
+		}
+		filter.Role = role
+	}
+	switch in.GetStatus() {
+	case "":
+	case userStatusActive:
+		suspended := false
+		filter.Suspended = &suspended
+	case userStatusSuspended:
+		suspended := true
+		filter.Suspended = &suspended
+	default:
+		return filter, paging.Page{}, status.Error(codes.InvalidArgument, "Status should be one of active, suspended")
+	}
+
+	page, err := a.pages.Start(in.GetPageToken(), in.GetPageSize(), filter)
+	return filter, page, err
+}
+
+// listUsers returns the users of page among those matching filter, and the
+// number of all of them
+func (a *adminServer) listUsers(ctx context.Context, filter store.UserFilter, page paging.Page) ([]global.User, int64, error) {
+	if _, err := page.Key(&filter.After); err != nil {
+		return nil, 0, err
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	users, total, err := a.users.List(dbCtx, filter, 0, page.Size)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while listing users", zap.Error(err))
+		return nil, 0, status.Error(codes.Internal, "Internal Error")
+	}
+	return users, total, nil
+}
+
+func (a *adminServer) GetUser(ctx context.Context, in *proto.UserRequest) (*proto.UserInfo, error) {
+	id, err := primitive.ObjectIDFromHex(in.GetUserID())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid user id")
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := a.users.FindByID(dbCtx, id)
+	if err == store.ErrNotFound {
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while fetching user", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	return toUserInfo(user), nil
+}
+
+func (a *adminServer) SuspendUser(ctx context.Context, in *proto.SuspendUserRequest) (*proto.UserInfo, error) {
+	actor := global.UserFromContext(ctx)
+	if actor.ID.Hex() == in.GetUserID() {
+		return nil, status.Error(codes.FailedPrecondition, "Admins cannot suspend themselves")
+	}
+
+	user, err := a.updateUser(ctx, in.GetUserID(), func(ctx context.Context, id primitive.ObjectID) (global.User, error) {
+		return a.users.Suspend(ctx, id, in.GetReason())
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.auditLog.Record(ctx, audit.Event{Type: audit.EventSuspendUser, UserID: actor.ID.Hex(), TargetID: user.ID.Hex(), Outcome: audit.OutcomeSuccess, Reason: in.GetReason()})
+	return toUserInfo(user), nil
+}
+
+func (a *adminServer) UnsuspendUser(ctx context.Context, in *proto.UserRequest) (*proto.UserInfo, error) {
+	user, err := a.updateUser(ctx, in.GetUserID(), a.users.Unsuspend)
+	if err != nil {
+		return nil, err
+	}
+	a.auditLog.Record(ctx, audit.Event{Type: audit.EventUnsuspendUser, UserID: global.UserFromContext(ctx).ID.Hex(), TargetID: user.ID.Hex(), Outcome: audit.OutcomeSuccess})
+	return toUserInfo(user), nil
+}
+
+func (a *adminServer) ForceLogout(ctx context.Context, in *proto.UserRequest) (*proto.UserInfo, error) {
+	user, err := a.updateUser(ctx, in.GetUserID(), a.users.RevokeTokens)
+	if err != nil {
+		return nil, err
+	}
+	a.auditLog.Record(ctx, audit.Event{Type: audit.EventForceLogout, UserID: global.UserFromContext(ctx).ID.Hex(), TargetID: user.ID.Hex(), Outcome: audit.OutcomeSuccess})
+	return toUserInfo(user), nil
+}
+
+func (a *adminServer) ResetUserPassword(ctx context.Context, in *proto.ResetUserPasswordRequest) (*proto.ResetUserPasswordResponse, error) {
+	password := in.GetNewPassword()
+	if password == "" {
+		password = temporaryPassword()
+	}
+
+	pw, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while hashing password", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	// a new password also ends every existing session
+	user, err := a.updateUser(ctx, in.GetUserID(), func(ctx context.Context, id primitive.ObjectID) (global.User, error) {
+		return a.users.SetPassword(ctx, id, string(pw))
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.auditLog.Record(ctx, audit.Event{Type: audit.EventResetPassword, UserID: global.UserFromContext(ctx).ID.Hex(), TargetID: user.ID.Hex(), Outcome: audit.OutcomeSuccess})
+	return &proto.ResetUserPasswordResponse{Password: password}, nil
+}
+
+// updateUser runs update against the user with the given hex id and returns the updated record
+func (a *adminServer) updateUser(ctx context.Context, userID string, update func(context.Context, primitive.ObjectID) (global.User, error)) (global.User, error) {
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return global.NilUser, status.Error(codes.InvalidArgument, "Invalid user id")
+	}
+
+	// update should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := update(dbCtx, id)
+	if err == store.ErrNotFound {
+		return global.NilUser, status.Error(codes.NotFound, "User not found")
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while updating user", zap.Error(err))
+		return global.NilUser, status.Error(codes.Internal, "Internal Error")
+	}
+	return user, nil
+}
+
+func toUserInfo(u global.User) *proto.UserInfo {
+	return &proto.UserInfo{
+		ID:            u.ID.Hex(),
+		Username:      u.Username,
+		Email:         u.Email,
+		Roles:         policy.RoleNames(u.GetRoles()),
+		Suspended:     u.Suspended,
+		SuspendReason: u.SuspendReason,
+		CreatedAt:     u.CreatedAt().Unix(),
+	}
+}
+
+// temporaryPassword returns a random 16 character password
+func temporaryPassword() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,453 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_adminServer_ListUsers(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+	ctx := h.as(fixtureAdmin)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"request": &proto.ListUsersRequest{},
+			"users":   []string{"test-user", "fixture-admin", "fixture-editor", "fixture-author", "fixture-suspended"},
+			"total":   int64(5),
+		},
+		map[string]interface{}{
+			"request": &proto.ListUsersRequest{Query: "FIXTURE-"},
+			"users":   []string{"fixture-admin", "fixture-editor", "fixture-author", "fixture-suspended"},
+			"total":   int64(4),
+		},
+		map[string]interface{}{
+			// matches the email too
+			"request": &proto.ListUsersRequest{Query: "test-user@"},
+			"users":   []string{"test-user"},
+			"total":   int64(1),
+		},
+		map[string]interface{}{
+			"request": &proto.ListUsersRequest{Query: "fixture-", Role: "editor"},
+			"users":   []string{"fixture-editor"},
+			"total":   int64(1),
+		},
+		map[string]interface{}{
+			"request": &proto.ListUsersRequest{Status: "suspended"},
+			"users":   []string{"fixture-suspended"},
+			"total":   int64(1),
+		},
+		map[string]interface{}{
+			"request": &proto.ListUsersRequest{Query: "fixture-", Status: "active"},
+			"users":   []string{"fixture-admin", "fixture-editor", "fixture-author"},
+			"total":   int64(3),
+		},
+		map[string]interface{}{
+			"request": &proto.ListUsersRequest{Query: "fixture-", PageSize: 3},
+			"users":   []string{"fixture-admin", "fixture-editor", "fixture-author"},
+			"total":   int64(4),
+		},
+		map[string]interface{}{
+			"request": &proto.ListUsersRequest{Query: "nobody"},
+			"users":   []string(nil),
+			"total":   int64(0),
+		},
+		map[string]interface{}{
+			// regex characters are matched literally
+			"request": &proto.ListUsersRequest{Query: "fixture-.*"},
+			"users":   []string(nil),
+			"total":   int64(0),
+		},
+		map[string]interface{}{
+			"request": &proto.ListUsersRequest{Role: "superuser"},
+			"error":   "Unknown role",
+		},
+		map[string]interface{}{
+			"request": &proto.ListUsersRequest{Status: "deleted"},
+			"error":   "Status should be one of active, suspended",
+		},
+		map[string]interface{}{
+			"request": &proto.ListUsersRequest{PageToken: "forged"},
+			"error":   "Invalid page token",
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		res, err := h.admin.ListUsers(ctx, tcase["request"].(*proto.ListUsersRequest))
+
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			var usernames []string
+			for _, u := range res.GetUsers() {
+				usernames = append(usernames, u.GetUsername())
+			}
+			assert.Equalf(t, tcase["users"], usernames, "case: %v", tcase)
+			assert.Equalf(t, tcase["total"], res.GetTotal(), "case: %v", tcase)
+		}
+	}
+}
+
+func Test_adminServer_ListUsers_pages(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+	ctx := h.as(fixtureAdmin)
+
+	var usernames []string
+	in := &proto.ListUsersRequest{Query: "fixture-", PageSize: 3}
+	for {
+		res, err := h.admin.ListUsers(ctx, in)
+		if !assert.NoError(t, err) {
+			break
+		}
+		assert.Equal(t, "fixture-", res.GetQuery())
+		for _, u := range res.GetUsers() {
+			usernames = append(usernames, u.GetUsername())
+		}
+		if res.GetNextPageToken() == "" {
+			break
+		}
+		// the size is kept in the token
+		in = &proto.ListUsersRequest{Query: "fixture-", PageToken: res.GetNextPageToken()}
+	}
+	assert.Equal(t, []string{"fixture-admin", "fixture-editor", "fixture-author", "fixture-suspended"}, usernames)
+
+	// tokens only continue the listing they come from
+	res, err := h.admin.ListUsers(ctx, &proto.ListUsersRequest{Query: "fixture-", PageSize: 1})
+	assert.NoError(t, err)
+	_, err = h.admin.ListUsers(ctx, &proto.ListUsersRequest{Query: "fixture-", Status: "active", PageToken: res.GetNextPageToken()})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Page token does not match the filter")
+	}
+}
+
+func Test_adminServer_StreamUsers(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"ctx":     h.as(fixtureAdmin),
+			"request": &proto.ListUsersRequest{Query: "fixture-", PageSize: 2},
+			"users":   []string{"fixture-admin", "fixture-editor", "fixture-author", "fixture-suspended"},
+		},
+		map[string]interface{}{
+			"ctx":     h.as(fixtureAdmin),
+			"request": &proto.ListUsersRequest{Status: "suspended"},
+			"users":   []string{"fixture-suspended"},
+		},
+		map[string]interface{}{
+			"ctx":     h.as(fixtureEditor),
+			"request": &proto.ListUsersRequest{},
+			"code":    codes.PermissionDenied,
+		},
+		map[string]interface{}{
+			"ctx":     h.as(fixtureAdmin),
+			"request": &proto.ListUsersRequest{Status: "deleted"},
+			"code":    codes.InvalidArgument,
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		stream, err := h.admin.StreamUsers(tcase["ctx"].(context.Context), tcase["request"].(*proto.ListUsersRequest))
+		if !assert.NoErrorf(t, err, "case: %v", tcase) {
+			continue
+		}
+		var usernames []string
+		for {
+			u, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if code, ok := tcase["code"]; ok {
+				assert.Equalf(t, code, status.Code(err), "case: %v", tcase)
+				break
+			}
+			if !assert.NoErrorf(t, err, "case: %v", tcase) {
+				break
+			}
+			usernames = append(usernames, u.GetUsername())
+		}
+		if users, ok := tcase["users"]; ok {
+			assert.Equalf(t, users, usernames, "case: %v", tcase)
+		}
+	}
+}
+
+func Test_adminServer_userListing(t *testing.T) {
+
+	// the validator rejects these first, in-process callers skip it
+	a := &adminServer{pages: paging.NewCodec(nil)}
+	_, _, err := a.userListing(&proto.ListUsersRequest{Status: "banned"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, _, err = a.userListing(&proto.ListUsersRequest{Role: "superuser"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_adminServer_GetUser(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+	ctx := h.as(fixtureAdmin)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"userID": fixtureSuspended.ID.Hex(),
+			"info": &proto.UserInfo{
+				ID:            fixtureSuspended.ID.Hex(),
+				Username:      "fixture-suspended",
+				Email:         "fixture-suspended@gmail.com",
+				Roles:         []string{"author"},
+				Suspended:     true,
+				SuspendReason: "spam",
+				CreatedAt:     fixtureSuspended.CreatedAt().Unix(),
+			},
+		},
+		map[string]interface{}{
+			"userID": fixtureLegacy.ID.Hex(),
+			"info": &proto.UserInfo{
+				ID:        fixtureLegacy.ID.Hex(),
+				Username:  "test-user",
+				Email:     "test-user@gmail.com",
+				Roles:     []string{"author"},
+				CreatedAt: fixtureLegacy.CreatedAt().Unix(),
+			},
+		},
+		map[string]interface{}{
+			"userID": "not-an-id",
+			"error":  "UserID should be a valid id",
+		},
+		map[string]interface{}{
+			"userID": primitive.NewObjectID().Hex(),
+			"error":  "User not found",
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		info, err := h.admin.GetUser(ctx, &proto.UserRequest{UserID: tcase["userID"].(string)})
+
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+				assert.NotEqualf(t, codes.Unknown, status.Code(err), "case: %v", tcase)
+			}
+		} else {
+			want := tcase["info"].(*proto.UserInfo)
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			assert.Equalf(t, want.String(), info.String(), "case: %v", tcase)
+		}
+	}
+}
+
+func Test_adminServer_SuspendUser(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+	ctx := h.as(fixtureAdmin)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"userID": fixtureAdmin.ID.Hex(),
+			"reason": "testing",
+			"error":  "Admins cannot suspend themselves",
+			"code":   codes.FailedPrecondition,
+		},
+		map[string]interface{}{
+			"userID": fixtureAuthor.ID.Hex(),
+			"reason": "",
+			"error":  "Reason is required",
+			"code":   codes.InvalidArgument,
+		},
+		map[string]interface{}{
+			"userID": primitive.NewObjectID().Hex(),
+			"reason": "testing",
+			"error":  "User not found",
+			"code":   codes.NotFound,
+		},
+		map[string]interface{}{
+			"userID": fixtureAuthor.ID.Hex(),
+			"reason": "testing",
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		info, err := h.admin.SuspendUser(ctx, &proto.SuspendUserRequest{UserID: tcase["userID"].(string), Reason: tcase["reason"].(string)})
+
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+				assert.Equalf(t, tcase["code"], status.Code(err), "case: %v", tcase)
+			}
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			assert.Truef(t, info.GetSuspended(), "case: %v", tcase)
+			assert.Equalf(t, tcase["reason"], info.GetSuspendReason(), "case: %v", tcase)
+		}
+	}
+
+	// suspended users can neither login nor use their tokens
+	_, err := h.auth.Login(context.Background(), &proto.LoginRequest{Login: "fixture-author", Password: fixturePassword})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Account suspended")
+	}
+	_, err = h.auth.AuthUser(context.Background(), &proto.AuthUserRequest{Token: fixtureAuthor.GetToken()})
+	assert.Error(t, err)
+}
+
+func Test_adminServer_UnsuspendUser(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+	ctx := h.as(fixtureAdmin)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"userID": fixtureSuspended.ID.Hex(),
+		},
+		map[string]interface{}{
+			// unsuspending an active user is a no-op
+			"userID": fixtureAuthor.ID.Hex(),
+		},
+		map[string]interface{}{
+			"userID": primitive.NewObjectID().Hex(),
+			"error":  "User not found",
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		info, err := h.admin.UnsuspendUser(ctx, &proto.UserRequest{UserID: tcase["userID"].(string)})
+
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			assert.Falsef(t, info.GetSuspended(), "case: %v", tcase)
+			assert.Emptyf(t, info.GetSuspendReason(), "case: %v", tcase)
+		}
+	}
+
+	_, err := h.auth.Login(context.Background(), &proto.LoginRequest{Login: "fixture-suspended", Password: fixturePassword})
+	assert.NoError(t, err)
+}
+
+func Test_adminServer_ForceLogout(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+	ctx := h.as(fixtureAdmin)
+
+	login, err := h.auth.Login(context.Background(), &proto.LoginRequest{Login: "fixture-author", Password: fixturePassword})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"userID": fixtureAuthor.ID.Hex(),
+		},
+		map[string]interface{}{
+			"userID": "not-an-id",
+			"error":  "UserID should be a valid id",
+		},
+		map[string]interface{}{
+			"userID": primitive.NewObjectID().Hex(),
+			"error":  "User not found",
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		_, err := h.admin.ForceLogout(ctx, &proto.UserRequest{UserID: tcase["userID"].(string)})
+
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+		}
+	}
+
+	// the token issued above is invalidated, a new login works
+	_, err = h.auth.AuthUser(context.Background(), &proto.AuthUserRequest{Token: login.GetToken()})
+	assert.Error(t, err)
+	login, err = h.auth.Login(context.Background(), &proto.LoginRequest{Login: "fixture-author", Password: fixturePassword})
+	if assert.NoError(t, err) {
+		_, err = h.auth.AuthUser(context.Background(), &proto.AuthUserRequest{Token: login.GetToken()})
+		assert.NoError(t, err)
+	}
+}
+
+func Test_adminServer_ResetUserPassword(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+	ctx := h.as(fixtureAdmin)
+
+	unknown := global.User{ID: primitive.NewObjectID()}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"user":        fixtureAuthor,
+			"newPassword": "",
+		},
+		map[string]interface{}{
+			"user":        fixtureEditor,
+			"newPassword": "chosen-password",
+		},
+		map[string]interface{}{
+			"user":        fixtureLegacy,
+			"newPassword": "short",
+			"error":       "NewPassword should be between 8 and 120 characters",
+		},
+		map[string]interface{}{
+			"user":        unknown,
+			"newPassword": "",
+			"error":       "User not found",
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		user, newPassword := tcase["user"].(global.User), tcase["newPassword"].(string)
+		res, err := h.admin.ResetUserPassword(ctx, &proto.ResetUserPasswordRequest{UserID: user.ID.Hex(), NewPassword: newPassword})
+
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+			continue
+		}
+		if !assert.NoErrorf(t, err, "case: %v", tcase) {
+			continue
+		}
+
+		if newPassword == "" {
+			assert.Lenf(t, res.GetPassword(), 16, "case: %v", tcase)
+		} else {
+			assert.Equalf(t, newPassword, res.GetPassword(), "case: %v", tcase)
+		}
+
+		// the new password works, the old one and tokens issued before do not
+		_, err = h.auth.Login(context.Background(), &proto.LoginRequest{Login: user.Username, Password: res.GetPassword()})
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		_, err = h.auth.Login(context.Background(), &proto.LoginRequest{Login: user.Username, Password: fixturePassword})
+		assert.Errorf(t, err, "case: %v", tcase)
+		_, err = h.auth.AuthUser(context.Background(), &proto.AuthUserRequest{Token: user.GetToken()})
+		assert.Errorf(t, err, "case: %v", tcase)
+	}
+}
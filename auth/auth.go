@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/audit"
+	"github.com/HiteshRepo/blog-application/gateway"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/metrics"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Service serves the AuthService, login and signup, and the AdminService,
+// user and role management
+type Service struct {
+	auth  *authServer
+	admin *adminServer
+}
+
+// New returns the auth service on the given stores, issuing page tokens with pages
+func New(users store.Users, roleAudit store.RoleAudit, auditLog *audit.Log, pages *paging.Codec) *Service {
+	return &Service{
+		auth:  &authServer{users: users, auditLog: auditLog},
+		admin: &adminServer{users: users, roleAudit: roleAudit, auditLog: auditLog, pages: pages},
+	}
+}
+
+// Register adds the AuthService and the AdminService to server
+func (s *Service) Register(server *grpc.Server) {
+	proto.RegisterAuthServiceServer(server, s.auth)
+	proto.RegisterAdminServiceServer(server, s.admin)
+}
+
+type authServer struct {
+	users    store.Users
+	auditLog *audit.Log
+}
+
+func (a *authServer) Login(ctx context.Context, in *proto.LoginRequest) (*proto.AuthResponse, error) {
+
+	// fetch login and password from request
+	login, password := in.GetLogin(), in.GetPassword()
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	// look for user by creds entered
+	user, err := a.users.FindByLogin(dbCtx, login)
+	if err != nil && err != store.ErrNotFound {
+		logging.FromContext(ctx).Error("Error returned while looking up user", zap.Error(err))
+		return &proto.AuthResponse{}, status.Error(codes.Internal, "Internal Error")
+	}
+
+	// check for empty user record
+	if err == store.ErrNotFound {
+		a.auditLog.Record(ctx, audit.Event{Type: audit.EventLogin, Outcome: audit.OutcomeFailure, Reason: "unknown login"})
+		metrics.FailedLogins.WithLabelValues("unknown_login").Inc()
+		return &proto.AuthResponse{}, status.Error(codes.Unauthenticated, "Invalid login credentials provided")
+	}
+
+	// validate password
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		a.auditLog.Record(ctx, audit.Event{Type: audit.EventLogin, UserID: user.ID.Hex(), Outcome: audit.OutcomeFailure, Reason: "wrong password"})
+		metrics.FailedLogins.WithLabelValues("wrong_password").Inc()
+		return &proto.AuthResponse{}, status.Error(codes.Unauthenticated, "Invalid login credentials provided")
+	}
+
+	if user.Suspended {
+		a.auditLog.Record(ctx, audit.Event{Type: audit.EventLogin, UserID: user.ID.Hex(), Outcome: audit.OutcomeFailure, Reason: "account suspended"})
+		metrics.FailedLogins.WithLabelValues("suspended").Inc()
+		return &proto.AuthResponse{}, status.Error(codes.PermissionDenied, "Account suspended")
+	}
+
+	a.auditLog.Record(ctx, audit.Event{Type: audit.EventLogin, UserID: user.ID.Hex(), Outcome: audit.OutcomeSuccess})
+	metrics.Logins.Inc()
+
+	// send token
+	return &proto.AuthResponse{Token: user.GetToken()}, nil
+}
+
+func (a *authServer) Signup(ctx context.Context, in *proto.SignupRequest) (*proto.AuthResponse, error) {
+
+	res, err := a.UsernameUsed(ctx, &proto.UsernameUsedRequest{Username: in.GetUsername()})
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned from UsernameUsed", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	if res.GetUsed() {
+		a.auditLog.Record(ctx, audit.Event{Type: audit.EventSignup, Outcome: audit.OutcomeFailure, Reason: "username taken"})
+		return nil, status.Error(codes.AlreadyExists, "Username already taken.")
+	}
+
+	res, err = a.EmailUsed(ctx, &proto.EmailUsedRequest{Email: in.GetEmail()})
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned from EmailUsed", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	if res.GetUsed() {
+		a.auditLog.Record(ctx, audit.Event{Type: audit.EventSignup, Outcome: audit.OutcomeFailure, Reason: "email used"})
+		return nil, status.Error(codes.AlreadyExists, "Email already used.")
+	}
+
+	pw, _ := bcrypt.GenerateFromPassword([]byte(in.GetPassword()), bcrypt.DefaultCost)
+
+	newUser := global.User{
+		ID:       primitive.NewObjectID(),
+		Email:    in.GetEmail(),
+		Username: in.GetUsername(),
+		Password: string(pw),
+		Roles:    []policy.Role{policy.DefaultRole},
+	}
+
+	// insert user to db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	err = a.users.Insert(dbCtx, newUser)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while inserting user to DB", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal error while inserting user to DB.")
+	}
+
+	a.auditLog.Record(ctx, audit.Event{Type: audit.EventSignup, UserID: newUser.ID.Hex(), Outcome: audit.OutcomeSuccess})
+	metrics.Signups.Inc()
+
+	// send token
+	return &proto.AuthResponse{Token: newUser.GetToken()}, nil
+}
+
+func (a *authServer) UsernameUsed(ctx context.Context, in *proto.UsernameUsedRequest) (*proto.UsedResponse, error) {
+	// fetch username from request
+	username := in.GetUsername()
+
+	// fetch from db should not take more that 5 seconds
+	ctx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := a.users.FindByUsername(ctx, username)
+	if err != nil && err != store.ErrNotFound {
+		return nil, err
+	}
+
+	return &proto.UsedResponse{Used: err == nil}, nil
+}
+
+func (a *authServer) EmailUsed(ctx context.Context, in *proto.EmailUsedRequest) (*proto.UsedResponse, error) {
+	// fetch email from request
+	email := in.GetEmail()
+
+	// fetch from db should not take more that 5 seconds
+	ctx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := a.users.FindByEmail(ctx, email)
+	if err != nil && err != store.ErrNotFound {
+		return nil, err
+	}
+
+	return &proto.UsedResponse{Used: err == nil}, nil
+}
+
+func (a *authServer) AuthUser(ctx context.Context, in *proto.AuthUserRequest) (*proto.AuthUserResponse, error) {
+	// JSON API clients send their token as a bearer header instead
+	token := in.GetToken()
+	if token == "" {
+		token = policy.TokenFromContext(ctx)
+	}
+
+	user, err := a.userFromToken(ctx, token)
+	if err != nil {
+		a.auditLog.Record(ctx, audit.Event{Type: audit.EventTokenCheck, Outcome: audit.OutcomeFailure, Reason: err.Error()})
+		return &proto.AuthUserResponse{}, err
+	}
+	a.auditLog.Record(ctx, audit.Event{Type: audit.EventTokenCheck, UserID: user.ID.Hex(), Outcome: audit.OutcomeSuccess})
+	return &proto.AuthUserResponse{ID: user.ID.Hex(), Username: user.Username, Email: user.Email, Roles: policy.RoleNames(user.GetRoles())}, nil
+}
+
+// userFromToken returns the current db record of the token's user.
+// Reading the record makes role changes, suspensions and forced logouts
+// apply to tokens already issued.
+func (a *authServer) userFromToken(ctx context.Context, token string) (global.User, error) {
+	claimed := global.UserFromToken(token)
+	if claimed.IsNil() {
+		return global.NilUser, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	// fetch from db should not take more that 5 seconds
+	ctx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := a.users.FindByID(ctx, claimed.ID)
+	if err != nil && err != store.ErrNotFound {
+		logging.FromContext(ctx).Error("Error returned while looking up token user", zap.Error(err))
+		return global.NilUser, status.Error(codes.Internal, "Internal Error")
+	}
+	if err == store.ErrNotFound || user.TokenVersion != claimed.TokenVersion {
+		return global.NilUser, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	if user.Suspended {
+		return global.NilUser, status.Error(codes.PermissionDenied, "Account suspended")
+	}
+	return user, nil
+}
+
+// Authenticate resolves the caller from the bearer token sent as metadata
+func (s *Service) Authenticate(ctx context.Context) (context.Context, []policy.Role, error) {
+	user, err := s.auth.userFromToken(ctx, policy.TokenFromContext(ctx))
+	if err != nil {
+		return ctx, nil, err
+	}
+	return global.ContextWithUser(ctx, user), user.GetRoles(), nil
+}
+
+// Routes maps the AuthService to the JSON API
+func (s *Service) Routes(g *gateway.Gateway) {
+	g.Handle(http.MethodPost, "/v1/auth/login", "/proto.AuthService/Login").
+		Handle(http.MethodPost, "/v1/auth/signup", "/proto.AuthService/Signup").
+		Handle(http.MethodGet, "/v1/auth/usernames/{username}", "/proto.AuthService/UsernameUsed").
+		Handle(http.MethodGet, "/v1/auth/emails/{email}", "/proto.AuthService/EmailUsed").
+		Handle(http.MethodGet, "/v1/auth/me", "/proto.AuthService/AuthUser")
+}
+
+// Require lists the permissions needed by every guarded RPC
+func (s *Service) Require(p *policy.Policy) {
+	p.Require("/proto.AdminService/GrantRole", policy.PermManageRoles).
+		Require("/proto.AdminService/RevokeRole", policy.PermManageRoles).
+		Require("/proto.AdminService/ListRoleAudit", policy.PermManageRoles).
+		Require("/proto.AdminService/ListUsers", policy.PermManageUsers).
+		Require("/proto.AdminService/StreamUsers", policy.PermManageUsers).
+		Require("/proto.AdminService/GetUser", policy.PermManageUsers).
+		Require("/proto.AdminService/SuspendUser", policy.PermManageUsers).
+		Require("/proto.AdminService/UnsuspendUser", policy.PermManageUsers).
+		Require("/proto.AdminService/ForceLogout", policy.PermManageUsers).
+		Require("/proto.AdminService/ResetUserPassword", policy.PermManageUsers).
+		Require("/proto.AdminService/QueryAuditLog", policy.PermReadAudit)
+}
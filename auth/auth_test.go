@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_authServer_Login(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+
+	testcases := []map[string]interface{}{
+		map[string]interface{}{
+			"login":    "fixture-author@gmail.com",
+			"password": fixturePassword,
+		},
+		map[string]interface{}{
+			"login":    "fixture-author",
+			"password": fixturePassword,
+		},
+		map[string]interface{}{
+			"login":    "incorrect-email@gmail.com",
+			"password": "incorrect-password",
+			"error":    "Invalid login credentials provided",
+			"code":     codes.Unauthenticated,
+		},
+		map[string]interface{}{
+			"login":    "fixture-author",
+			"password": "incorrect-password",
+			"error":    "Invalid login credentials provided",
+			"code":     codes.Unauthenticated,
+		},
+		map[string]interface{}{
+			// rejected before the database is queried
+			"login":    "",
+			"password": fixturePassword,
+			"error":    "Login is required",
+			"code":     codes.InvalidArgument,
+		},
+		map[string]interface{}{
+			"login":    "fixture-suspended",
+			"password": fixturePassword,
+			"error":    "Account suspended",
+			"code":     codes.PermissionDenied,
+		},
+	}
+
+	for _, tcase := range testcases {
+
+		resp, err := h.auth.Login(context.Background(), &proto.LoginRequest{Login: tcase["login"].(string), Password: tcase["password"].(string)})
+
+		if errMsg, ok := tcase["error"]; ok {
+			// invalid creds
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+				assert.Equalf(t, tcase["code"], status.Code(err), "case: %v", tcase)
+			}
+		} else {
+			// valid creds - email/username & password
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			assert.Equalf(t, fixtureAuthor.ID, global.UserFromToken(resp.GetToken()).ID, "case: %v", tcase)
+		}
+	}
+}
+
+func Test_authServer_Signup(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"username": "fixture-author",
+			"email":    "signup-user@gmail.com",
+			"password": "signup-password",
+			"error":    "Username already taken.",
+		},
+		map[string]interface{}{
+			"username": "signup-user",
+			"email":    "fixture-author@gmail.com",
+			"password": "signup-password",
+			"error":    "Email already used.",
+		},
+		map[string]interface{}{
+			"username": "usr",
+			"email":    "signup-user@gmail.com",
+			"password": "signup-password",
+			"error":    "Validation failed",
+		},
+		map[string]interface{}{
+			"username": "signup-user",
+			"email":    "signup-user@gmail.com",
+			"password": "signup-password",
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		resp, err := h.auth.Signup(context.Background(), &proto.SignupRequest{
+			Username: tcase["username"].(string),
+			Email:    tcase["email"].(string),
+			Password: tcase["password"].(string),
+		})
+
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			assert.Truef(t, len(resp.GetToken()) > 0, "case: %v", tcase)
+		}
+	}
+
+	// the new account can login and holds the default role
+	login, err := h.auth.Login(context.Background(), &proto.LoginRequest{Login: "signup-user", Password: "signup-password"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	me, err := h.auth.AuthUser(context.Background(), &proto.AuthUserRequest{Token: login.GetToken()})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"author"}, me.GetRoles())
+	}
+}
+
+func Test_authServer_UsernameUsed(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"username": "fixture-author",
+			"used":     true,
+		},
+		map[string]interface{}{
+			"username": "fixture-unused",
+			"used":     false,
+		},
+		map[string]interface{}{
+			// usernames are case sensitive
+			"username": "FIXTURE-AUTHOR",
+			"used":     false,
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		res, err := h.auth.UsernameUsed(context.Background(), &proto.UsernameUsedRequest{Username: tcase["username"].(string)})
+		if !assert.NoErrorf(t, err, "case: %v", tcase) {
+			t.FailNow()
+		}
+		assert.Equalf(t, tcase["used"], res.GetUsed(), "case: %v", tcase)
+	}
+}
+
+func Test_authServer_EmailUsed(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"email": "fixture-author@gmail.com",
+			"used":  true,
+		},
+		map[string]interface{}{
+			"email": "fixture-unused@gmail.com",
+			"used":  false,
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		res, err := h.auth.EmailUsed(context.Background(), &proto.EmailUsedRequest{Email: tcase["email"].(string)})
+		if !assert.NoErrorf(t, err, "case: %v", tcase) {
+			t.FailNow()
+		}
+		assert.Equalf(t, tcase["used"], res.GetUsed(), "case: %v", tcase)
+	}
+}
+
+func Test_authServer_AuthUser(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+
+	loggedOut := fixtureEditor
+	staleToken := loggedOut.GetToken()
+	if _, err := h.users.RevokeTokens(context.Background(), loggedOut.ID); !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	unknown := global.User{ID: primitive.NewObjectID(), Username: "fixture-unknown"}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"token": legacyToken,
+			"user":  fixtureLegacy,
+			"roles": []string{"author"},
+		},
+		map[string]interface{}{
+			"token": fixtureAdmin.GetToken(),
+			"user":  fixtureAdmin,
+			"roles": []string{"admin"},
+		},
+		map[string]interface{}{
+			"token": "incorrect-auth-token",
+			"error": "Invalid token",
+		},
+		map[string]interface{}{
+			"token": unknown.GetToken(),
+			"error": "Invalid token",
+		},
+		map[string]interface{}{
+			"token": fixtureSuspended.GetToken(),
+			"error": "Account suspended",
+		},
+		map[string]interface{}{
+			"token": staleToken,
+			"error": "Invalid token",
+		},
+		map[string]interface{}{
+			// the bearer token is used when the request has none
+			"token":  "",
+			"bearer": fixtureAdmin,
+			"user":   fixtureAdmin,
+			"roles":  []string{"admin"},
+		},
+		map[string]interface{}{
+			"token": "",
+			"error": "Invalid token",
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		ctx := context.Background()
+		if bearer, ok := tcase["bearer"]; ok {
+			ctx = h.as(bearer.(global.User))
+		}
+		resp, err := h.auth.AuthUser(ctx, &proto.AuthUserRequest{Token: tcase["token"].(string)})
+
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+		} else {
+			user := tcase["user"].(global.User)
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			assert.Equalf(t, user.ID.Hex(), resp.GetID(), "case: %v", tcase)
+			assert.Equalf(t, user.Email, resp.GetEmail(), "case: %v", tcase)
+			assert.Equalf(t, user.Username, resp.GetUsername(), "case: %v", tcase)
+			assert.Equalf(t, tcase["roles"], resp.GetRoles(), "case: %v", tcase)
+		}
+	}
+}
+
+func Test_rolePolicy(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"ctx":  context.Background(),
+			"code": codes.Unauthenticated,
+		},
+		map[string]interface{}{
+			"ctx":  h.as(fixtureSuspended),
+			"code": codes.Unauthenticated,
+		},
+		map[string]interface{}{
+			"ctx":  h.as(fixtureAuthor),
+			"code": codes.PermissionDenied,
+		},
+		map[string]interface{}{
+			"ctx":  h.as(fixtureEditor),
+			"code": codes.PermissionDenied,
+		},
+		map[string]interface{}{
+			"ctx":  h.as(fixtureAdmin),
+			"code": codes.OK,
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		_, err := h.admin.ListUsers(tcase["ctx"].(context.Context), &proto.ListUsersRequest{})
+		assert.Equalf(t, tcase["code"], status.Code(err), "case: %v", tcase)
+	}
+
+	// the auth service itself stays public
+	_, err := h.auth.UsernameUsed(context.Background(), &proto.UsernameUsedRequest{Username: "fixture-admin"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/audit"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/server"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1024 * 1024
+
+// harness runs the services in-process over bufconn, on the production
+// server bootstrap, with stores seeded with the fixture users
+type harness struct {
+	users     store.Users
+	roleAudit store.RoleAudit
+	auditLog  *audit.Log
+
+	auth  proto.AuthServiceClient
+	admin proto.AdminServiceClient
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+
+	users, roleAudit, auditStore := newTestStores(t)
+	h := &harness{users: users, roleAudit: roleAudit, auditLog: audit.NewLog(auditStore)}
+
+	ctx, cancel := global.NewDBContext(10 * time.Second)
+	defer cancel()
+	for _, u := range fixtureUsers() {
+		if err := users.Insert(ctx, u); err != nil {
+			t.Fatalf("Error inserting fixture %s : %v", u.Username, err)
+		}
+	}
+
+	service := New(users, roleAudit, h.auditLog, paging.NewCodec(nil))
+	grpcServer := server.New(server.Config{Logger: zap.NewNop(), Authenticate: service.Authenticate}, service).GRPCServer()
+	listener := bufconn.Listen(bufSize)
+	go grpcServer.Serve(listener)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error dialing bufconn : %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+	})
+
+	h.auth = proto.NewAuthServiceClient(conn)
+	h.admin = proto.NewAdminServiceClient(conn)
+	return h
+}
+
+// as returns a context that calls the services with user's token
+func (h *harness) as(user global.User) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+user.GetToken())
+}
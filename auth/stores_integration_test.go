@@ -0,0 +1,51 @@
+//go:build integration
+// +build integration
+
+package auth
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/audit"
+	"github.com/HiteshRepo/blog-application/database"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestStores returns stores backed by a fresh database on the mongod at
+// MONGO_TEST_URI (default mongodb://localhost:27017), dropped after the test
+func newTestStores(t *testing.T) (store.Users, store.RoleAudit, audit.Store) {
+	t.Helper()
+
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// a database per test keeps parallel tests apart
+	db, err := database.Connect(ctx, database.Config{
+		URI:     uri,
+		Name:    global.DefaultDBName + "_test_" + primitive.NewObjectID().Hex(),
+		Retries: -1,
+	})
+	if err != nil {
+		t.Fatalf("Error connecting to db : %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		db.Database().Drop(ctx)
+		db.Close(ctx)
+	})
+
+	return store.NewMongoUsers(db.Collection("user")),
+		store.NewMongoRoleAudit(db.Collection("role_audit")),
+		audit.NewMongoStore(db.Collection("audit_log"), 0)
+}
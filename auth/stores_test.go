@@ -0,0 +1,17 @@
+//go:build !integration
+// +build !integration
+
+package auth
+
+import (
+	"testing"
+
+	"github.com/HiteshRepo/blog-application/audit"
+	"github.com/HiteshRepo/blog-application/store"
+)
+
+// newTestStores returns empty in-memory stores.
+// Run the tests with -tags integration to use mongo instead.
+func newTestStores(t *testing.T) (store.Users, store.RoleAudit, audit.Store) {
+	return store.NewMemoryUsers(), store.NewMemoryRoleAudit(), audit.NewMemoryStore()
+}
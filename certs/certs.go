@@ -0,0 +1,170 @@
+// Package certs loads the TLS certificates of the servers and their clients,
+// reloading them when the files change, and generates self-signed ones for
+// development.
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Config names the PEM files of a server
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile holds the CAs client certificates are verified against,
+	// leave it empty to not ask clients for certificates
+	ClientCAFile string
+}
+
+// Reloader serves the certificate and client CAs of a Config, picking up
+// changes to the files on Reload
+type Reloader struct {
+	cfg Config
+
+	mu        sync.RWMutex
+	pem       [][]byte
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// NewReloader loads the files of cfg
+func NewReloader(cfg Config) (*Reloader, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("Certificate and key files are required")
+	}
+	r := &Reloader{cfg: cfg}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again and reports whether they changed. Invalid
+// files are an error and the previous certificates are kept.
+func (r *Reloader) Reload() (bool, error) {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.ClientCAFile}
+	contents := make([][]byte, len(files))
+	for i, file := range files {
+		if file == "" {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return false, err
+		}
+		contents[i] = content
+	}
+
+	r.mu.RLock()
+	unchanged := r.pem != nil && equal(r.pem, contents)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(contents[0], contents[1])
+	if err != nil {
+		return false, err
+	}
+	var clientCAs *x509.CertPool
+	if contents[2] != nil {
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(contents[2]) {
+			return false, errors.New("No certificate found in " + r.cfg.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.pem, r.cert, r.clientCAs = contents, &cert, clientCAs
+	r.mu.Unlock()
+	return true, nil
+}
+
+// Watch reloads the files every interval until ctx is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.Reload()
+			if err != nil {
+				logger.Error("Error reloading certificates, keeping the previous ones", zap.Error(err))
+			} else if changed {
+				logger.Info("Reloaded certificates", zap.String("cert", r.cfg.CertFile))
+			}
+		}
+	}
+}
+
+// ServerConfig returns a tls config presenting the current certificate.
+// With requireClientCert, clients must present a certificate signed by one
+// of the client CAs, otherwise the certificate is only verified when sent.
+func (r *Reloader) ServerConfig(requireClientCert bool) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// the config is rebuilt per connection so that reloads apply to new ones
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				NextProtos:   []string{"h2", "http/1.1"},
+			}
+			if r.clientCAs != nil {
+				cfg.ClientCAs = r.clientCAs
+				cfg.ClientAuth = tls.VerifyClientCertIfGiven
+				if requireClientCert {
+					cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// ClientConfig returns a tls config trusting the CAs in caFile, the system
+// ones when empty, and presenting the certificate in certFile and keyFile
+// when set
+func ClientConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.New("No certificate found in " + caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func equal(a, b [][]byte) bool {
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,208 @@
+package certs
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFiles writes name -> content into dir and returns the paths by name
+func writeFiles(t *testing.T, dir string, files map[string][]byte) map[string]string {
+	paths := map[string]string{}
+	for name, content := range files {
+		paths[name] = filepath.Join(dir, name)
+		if err := os.WriteFile(paths[name], content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func newAuthority(t *testing.T, name string) *Authority {
+	ca, err := NewAuthority(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+func issue(t *testing.T, ca *Authority, name string, hosts ...string) ([]byte, []byte) {
+	cert, key, err := ca.Issue(name, hosts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// handshake connects a client using clientCfg to a server using serverCfg
+// and returns the common name of the server certificate the client saw
+func handshake(t *testing.T, serverCfg, clientCfg *tls.Config) (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverConn, err := listener.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer serverConn.Close()
+		serverErr <- tls.Server(serverConn, serverCfg).Handshake()
+	}()
+
+	clientConn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clientConn.Close()
+	client := tls.Client(clientConn, clientCfg)
+	if err := client.Handshake(); err != nil {
+		return "", err
+	}
+	// with TLS 1.3 the server verifies the client certificate after the client is done
+	if err := <-serverErr; err != nil {
+		return "", err
+	}
+	return client.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+}
+
+func Test_Reloader_ServerConfig(t *testing.T) {
+
+	ca := newAuthority(t, "test CA")
+	otherCA := newAuthority(t, "other CA")
+	serverCert, serverKey := issue(t, ca, "server", "localhost")
+	clientCert, clientKey := issue(t, ca, "client")
+	strangerCert, strangerKey := issue(t, otherCA, "stranger")
+
+	dir := t.TempDir()
+	files := writeFiles(t, dir, map[string][]byte{
+		"ca.pem": ca.PEM, "server.pem": serverCert, "server-key.pem": serverKey,
+		"client.pem": clientCert, "client-key.pem": clientKey,
+		"stranger.pem": strangerCert, "stranger-key.pem": strangerKey,
+	})
+
+	reloader, err := NewReloader(Config{CertFile: files["server.pem"], KeyFile: files["server-key.pem"], ClientCAFile: files["ca.pem"]})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"require": true,
+			"cert":    "client",
+			"valid":   true,
+		},
+		map[string]interface{}{
+			"require": true,
+			"cert":    "",
+			"valid":   false,
+		},
+		map[string]interface{}{
+			"require": true,
+			"cert":    "stranger",
+			"valid":   false,
+		},
+		map[string]interface{}{
+			// certificates are optional
+			"require": false,
+			"cert":    "",
+			"valid":   true,
+		},
+	}
+
+	for _, tcase := range testCases {
+		name := tcase["cert"].(string)
+		certFile, keyFile := "", ""
+		if name != "" {
+			certFile, keyFile = files[name+".pem"], files[name+"-key.pem"]
+		}
+		clientCfg, err := ClientConfig(files["ca.pem"], certFile, keyFile)
+		if !assert.NoErrorf(t, err, "case: %v", tcase) {
+			continue
+		}
+		clientCfg.ServerName = "localhost"
+
+		cn, err := handshake(t, reloader.ServerConfig(tcase["require"].(bool)), clientCfg)
+		if tcase["valid"].(bool) {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			assert.Equalf(t, "server", cn, "case: %v", tcase)
+		} else {
+			assert.Errorf(t, err, "case: %v", tcase)
+		}
+	}
+}
+
+func Test_Reloader_Reload(t *testing.T) {
+
+	ca := newAuthority(t, "test CA")
+	cert, key := issue(t, ca, "first", "localhost")
+	dir := t.TempDir()
+	files := writeFiles(t, dir, map[string][]byte{"ca.pem": ca.PEM, "cert.pem": cert, "key.pem": key})
+
+	reloader, err := NewReloader(Config{CertFile: files["cert.pem"], KeyFile: files["key.pem"]})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	serverCfg := reloader.ServerConfig(false)
+	clientCfg, _ := ClientConfig(files["ca.pem"], "", "")
+	clientCfg.ServerName = "localhost"
+
+	changed, err := reloader.Reload()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// a renewed certificate is presented to new connections
+	cert, key = issue(t, ca, "second", "localhost")
+	writeFiles(t, dir, map[string][]byte{"cert.pem": cert, "key.pem": key})
+	changed, err = reloader.Reload()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	cn, err := handshake(t, serverCfg, clientCfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", cn)
+
+	// a half written renewal keeps the previous certificate
+	cert, _ = issue(t, ca, "third", "localhost")
+	writeFiles(t, dir, map[string][]byte{"cert.pem": cert})
+	_, err = reloader.Reload()
+	assert.Error(t, err)
+	cn, err = handshake(t, serverCfg, clientCfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", cn)
+}
+
+func Test_NewReloader_errors(t *testing.T) {
+
+	ca := newAuthority(t, "test CA")
+	cert, key := issue(t, ca, "server", "localhost")
+	dir := t.TempDir()
+	files := writeFiles(t, dir, map[string][]byte{"cert.pem": cert, "key.pem": key, "empty.pem": []byte("not a certificate")})
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"cfg": Config{},
+		},
+		map[string]interface{}{
+			"cfg": Config{CertFile: files["cert.pem"], KeyFile: filepath.Join(dir, "missing.pem")},
+		},
+		map[string]interface{}{
+			"cfg": Config{CertFile: files["key.pem"], KeyFile: files["cert.pem"]},
+		},
+		map[string]interface{}{
+			"cfg": Config{CertFile: files["cert.pem"], KeyFile: files["key.pem"], ClientCAFile: files["empty.pem"]},
+		},
+	}
+
+	for _, tcase := range testCases {
+		_, err := NewReloader(tcase["cfg"].(Config))
+		assert.Errorf(t, err, "case: %v", tcase)
+	}
+}
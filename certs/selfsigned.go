@@ -0,0 +1,129 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// generated certificates are valid for a year
+const validity = 365 * 24 * time.Hour
+
+// Authority is a certificate authority kept in memory, for development and tests
+type Authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	// PEM is the encoded CA certificate, to be trusted by peers
+	PEM []byte
+}
+
+// NewAuthority generates a CA named name
+func NewAuthority(name string) (*Authority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template, err := newTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &Authority{
+		cert: cert,
+		key:  key,
+		PEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}, nil
+}
+
+// Issue signs a certificate for name, usable by servers on hosts (dns
+// names or ips) and by clients. It returns the PEM certificate and key.
+func (a *Authority) Issue(name string, hosts ...string) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template, err := newTemplate(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// WriteSelfSigned generates a CA and a server certificate for hosts into
+// dir, as ca.pem, cert.pem and key.pem, and returns the server's Config.
+// Clients trust ca.pem.
+func WriteSelfSigned(dir string, hosts []string) (Config, error) {
+	ca, err := NewAuthority("blog-application development CA")
+	if err != nil {
+		return Config{}, err
+	}
+	cert, key, err := ca.Issue("blog-application", hosts...)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return Config{}, err
+	}
+	cfg := Config{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+	files := map[string][]byte{filepath.Join(dir, "ca.pem"): ca.PEM, cfg.CertFile: cert, cfg.KeyFile: key}
+	for file, content := range files {
+		if err := os.WriteFile(file, content, 0o600); err != nil {
+			return Config{}, err
+		}
+	}
+	return cfg, nil
+}
+
+func newTemplate(name string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		// tolerate clocks running slightly behind
+		NotBefore: now.Add(-time.Hour),
+		NotAfter:  now.Add(validity),
+	}, nil
+}
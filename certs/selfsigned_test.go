@@ -0,0 +1,45 @@
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WriteSelfSigned(t *testing.T) {
+
+	dir := filepath.Join(t.TempDir(), "certs")
+	cfg, err := WriteSelfSigned(dir, []string{"localhost", "127.0.0.1"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// the written files load, and the certificate chains to ca.pem for every host
+	_, err = NewReloader(cfg)
+	assert.NoError(t, err)
+
+	ca, _ := os.ReadFile(filepath.Join(dir, "ca.pem"))
+	roots := x509.NewCertPool()
+	assert.True(t, roots.AppendCertsFromPEM(ca))
+
+	certPEM, _ := os.ReadFile(cfg.CertFile)
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	for _, host := range []string{"localhost", "127.0.0.1"} {
+		_, err := cert.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+		assert.NoErrorf(t, err, "host: %v", host)
+	}
+	_, err = cert.Verify(x509.VerifyOptions{DNSName: "example.com", Roots: roots})
+	assert.Error(t, err)
+
+	info, _ := os.Stat(cfg.KeyFile)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
@@ -0,0 +1,258 @@
+// blog-backend serves every blog service from one process
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/audit"
+	"github.com/HiteshRepo/blog-application/auth"
+	"github.com/HiteshRepo/blog-application/certs"
+	"github.com/HiteshRepo/blog-application/database"
+	"github.com/HiteshRepo/blog-application/events"
+	"github.com/HiteshRepo/blog-application/feed"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/health"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/mail"
+	"github.com/HiteshRepo/blog-application/metrics"
+	"github.com/HiteshRepo/blog-application/notifications"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/posts"
+	"github.com/HiteshRepo/blog-application/profiles"
+	"github.com/HiteshRepo/blog-application/render"
+	"github.com/HiteshRepo/blog-application/search"
+	"github.com/HiteshRepo/blog-application/server"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/HiteshRepo/blog-application/tracing"
+	"go.uber.org/zap"
+)
+
+const (
+	// security events are kept for 90 days
+	auditRetention = 90 * 24 * time.Hour
+	// scheduled posts go out at most this late
+	scheduleInterval = 30 * time.Second
+	// the history of a post keeps its last 50 versions
+	revisionsKept = 50
+	// events beyond this many waiting for their handlers are dropped
+	eventQueue = 1000
+)
+
+func main() {
+
+	logger, err := logging.New(envOr("LOG_LEVEL", "info"))
+	if err != nil {
+		log.Fatal("Error creating logger : ", err.Error())
+	}
+	defer logger.Sync()
+	zap.ReplaceGlobals(logger)
+
+	logger.Info("Starting......")
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "blog-application", os.Getenv("TRACES_EXPORTER"))
+	if err != nil {
+		logger.Fatal("Error setting up tracing", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
+
+	// stop on SIGTERM (docker stop, kubernetes) or ctrl+c
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// there is no default database, credentials stay out of the code
+	mongoURI := os.Getenv("MONGO_URI")
+	if mongoURI == "" {
+		logger.Fatal("MONGO_URI must be set")
+	}
+	db, err := database.Connect(ctx, database.Config{
+		URI:     mongoURI,
+		Name:    envOr("MONGO_DB", global.DefaultDBName),
+		Monitor: database.CombineMonitors(metrics.MongoMonitor(), tracing.MongoMonitor()),
+	})
+	if err != nil {
+		logger.Fatal("Error connecting to db", zap.Error(err))
+	}
+
+	auditStore := audit.NewMongoStore(db.Collection("audit_log"), auditRetention)
+	indexCtx, cancel := global.NewDBContext(10 * time.Second)
+	if err := auditStore.EnsureIndexes(indexCtx); err != nil {
+		logger.Error("Error creating audit log indexes", zap.Error(err))
+	}
+	searchIndex := search.NewMongoIndex(db.Collection("search"))
+	if err := searchIndex.EnsureIndexes(indexCtx); err != nil {
+		logger.Error("Error creating search indexes", zap.Error(err))
+	}
+	// follows, timelines and reactions are only stored once thanks to unique
+	// indexes, without them counts go wrong
+	if err := store.EnsureFollowIndexes(indexCtx, db.Collection("follows")); err != nil {
+		logger.Fatal("Error creating follow indexes", zap.Error(err))
+	}
+	if err := store.EnsureTimelineIndexes(indexCtx, db.Collection("timelines")); err != nil {
+		logger.Fatal("Error creating timeline indexes", zap.Error(err))
+	}
+	if err := store.EnsureReactionIndexes(indexCtx, db.Collection("reactions")); err != nil {
+		logger.Fatal("Error creating reaction indexes", zap.Error(err))
+	}
+	cancel()
+
+	pageKey := os.Getenv("PAGE_TOKEN_KEY")
+	if pageKey == "" {
+		logger.Warn("PAGE_TOKEN_KEY is not set, page tokens only work on this instance until it restarts")
+	}
+	pages := paging.NewCodec([]byte(pageKey))
+
+	users := store.NewMongoUsers(db.Collection("user"))
+	authService := auth.New(
+		users,
+		store.NewMongoRoleAudit(db.Collection("role_audit")),
+		audit.NewLog(auditStore),
+		pages,
+	)
+	// services publish what users do, notifications tell the users concerned
+	bus := events.NewBus(eventQueue)
+	notificationStore := store.NewMongoNotifications(db.Collection("notifications"))
+	notificationService := notifications.New(notificationStore, pages)
+	bus.Subscribe(notificationService.Handle)
+	go func() {
+		<-ctx.Done()
+		notificationService.Shutdown()
+	}()
+	// digests go out every DIGEST_INTERVAL on the one instance it is set on
+	if interval := os.Getenv("DIGEST_INTERVAL"); interval != "" {
+		every, err := time.ParseDuration(interval)
+		if err != nil || every <= 0 {
+			logger.Fatal("DIGEST_INTERVAL must be a positive duration", zap.String("interval", interval))
+		}
+		go notifications.NewDigester(notificationStore, users, newMailer(logger)).Run(ctx, every, logger)
+	}
+
+	postStore := store.NewMongoPosts(db.Collection("posts"))
+	follows := store.NewMongoFollows(db.Collection("follows"))
+	// feeds are merged when read unless FEED_FANOUT is write, see the readme
+	var fanout feed.Fanout
+	switch strategy := envOr("FEED_FANOUT", "read"); strategy {
+	case "read":
+		fanout = feed.OnRead(follows, postStore)
+	case "write":
+		fanout = feed.OnWrite(follows, postStore, store.NewMongoTimelines(db.Collection("timelines")))
+	default:
+		logger.Fatal("FEED_FANOUT must be read or write", zap.String("fanout", strategy))
+	}
+	postStores := posts.Stores{
+		Posts:      postStore,
+		Revisions:  store.NewMongoRevisions(db.Collection("post_revisions"), revisionsKept),
+		Tags:       store.NewMongoTags(db.Collection("tags")),
+		Categories: store.NewMongoCategories(db.Collection("categories")),
+		Index:      searchIndex,
+		Feed:       fanout,
+		Reactions:  store.NewMongoReactions(db.Collection("reactions")),
+		Events:     bus,
+	}
+	postService := posts.New(postStores, render.New(), pages)
+	// every instance runs one, each due post is published once
+	go posts.NewScheduler(postStores).Run(ctx, scheduleInterval, logger)
+
+	tlsConfig, grpcTLSConfig, err := setupTLS(ctx, logger)
+	if err != nil {
+		logger.Fatal("Error loading certificates", zap.Error(err))
+	}
+
+	cors := server.CORSConfig{
+		AllowedOrigins:   strings.Split(envOr("CORS_ALLOWED_ORIGINS", "*"), ","),
+		AllowCredentials: os.Getenv("CORS_ALLOW_CREDENTIALS") == "true",
+		Websockets:       os.Getenv("GRPC_WEB_WEBSOCKETS") == "true",
+	}
+	if err := cors.Validate(); err != nil {
+		logger.Fatal("Invalid CORS configuration", zap.Error(err))
+	}
+
+	srv := server.New(server.Config{
+		GRPCAddr:     "0.0.0.0:5000", //+os.Getenv("GRPCPORT"))
+		HTTPAddr:     "0.0.0.0:9001", //+ os.Getenv("PORT"),
+		Logger:       logger,
+		Authenticate: authService.Authenticate,
+		Checks:       map[string]health.Check{"mongo": db.Ping},
+		CORS:         cors,
+		TLS:          tlsConfig,
+		GRPCTLS:      grpcTLSConfig,
+	}, authService, postService, search.New(searchIndex, pages), profiles.New(users, postStore), feed.New(users, follows, fanout, bus, pages), notificationService)
+
+	runErr := srv.Run(ctx)
+
+	// pending db work was part of the drained requests, or is queued on the bus
+	bus.Close()
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer closeCancel()
+	if err := db.Close(closeCtx); err != nil {
+		logger.Warn("Error disconnecting from db", zap.Error(err))
+	}
+
+	if runErr != nil {
+		logger.Fatal("Server stopped with error", zap.Error(runErr))
+	}
+}
+
+// setupTLS returns the tls configs of the http and grpc listeners, both nil
+// when neither TLS_CERT_FILE nor TLS_SELF_SIGNED are set. The certificate
+// files are reloaded every minute until ctx is done.
+func setupTLS(ctx context.Context, logger *zap.Logger) (*tls.Config, *tls.Config, error) {
+	cfg := certs.Config{
+		CertFile:     os.Getenv("TLS_CERT_FILE"),
+		KeyFile:      os.Getenv("TLS_KEY_FILE"),
+		ClientCAFile: os.Getenv("TLS_CLIENT_CA_FILE"),
+	}
+	if os.Getenv("TLS_SELF_SIGNED") == "true" {
+		// development only, clients trust ca.pem in the same directory
+		dir := envOr("TLS_DIR", filepath.Join(os.TempDir(), "blog-application-certs"))
+		selfSigned, err := certs.WriteSelfSigned(dir, []string{"localhost", "127.0.0.1"})
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.CertFile, cfg.KeyFile = selfSigned.CertFile, selfSigned.KeyFile
+		logger.Warn("Serving a self-signed certificate", zap.String("dir", dir))
+	}
+	if cfg.CertFile == "" {
+		return nil, nil, nil
+	}
+
+	reloader, err := certs.NewReloader(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	go reloader.Watch(ctx, time.Minute, logger)
+
+	// browsers have no client certificates, only other services are asked for one
+	return reloader.ServerConfig(false), reloader.ServerConfig(os.Getenv("TLS_REQUIRE_CLIENT_CERT") == "true"), nil
+}
+
+// newMailer sends through the SMTP server at SMTP_ADDR, or to the log when it
+// is unset
+func newMailer(logger *zap.Logger) mail.Mailer {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		logger.Warn("SMTP_ADDR is not set, digest emails are logged instead of sent")
+		return mail.NewLog(logger)
+	}
+	return mail.NewSMTP(mail.SMTPConfig{
+		Addr:     addr,
+		From:     envOr("SMTP_FROM", "blog@localhost"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+	})
+}
+
+// envOr returns the environment variable key, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/HiteshRepo/blog-application/audit"
+	"github.com/HiteshRepo/blog-application/database"
+	"github.com/HiteshRepo/blog-application/feed"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/posts"
+	"github.com/HiteshRepo/blog-application/search"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrations lists every schema change, in the order they were introduced.
+// Never edit or reorder applied entries, append new ones.
+var migrations = []database.Migration{
+	{
+		Name: "audit_log_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return audit.NewMongoStore(db.Collection("audit_log"), 0).EnsureIndexes(ctx)
+		},
+	},
+	{
+		// accounts created before roles existed get the default role stored
+		Name: "user_default_roles",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("user").UpdateMany(ctx,
+				bson.M{"roles": bson.M{"$in": bson.A{nil, bson.A{}}}},
+				bson.M{"$set": bson.M{"roles": []policy.Role{policy.DefaultRole}}},
+			)
+			return err
+		},
+	},
+	{
+		Name: "user_token_version",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("user").UpdateMany(ctx,
+				bson.M{"token_version": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"token_version": 0}},
+			)
+			return err
+		},
+	},
+	{
+		// posts saved before the lifecycle existed were public
+		Name: "post_states",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("posts").UpdateMany(ctx,
+				bson.M{"state": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"state": store.PostPublished}},
+			)
+			return err
+		},
+	},
+	{
+		// the scheduler looks up due posts
+		Name: "post_schedule_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("posts").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys: bson.D{{Key: "state", Value: 1}, {Key: "publish_at", Value: 1}},
+			})
+			return err
+		},
+	},
+	{
+		// a post has one revision per number, listed newest first
+		Name: "post_revision_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("post_revisions").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "post_id", Value: 1}, {Key: "number", Value: -1}},
+				Options: options.Index().SetUnique(true),
+			})
+			return err
+		},
+	},
+	{
+		// posts saved before revisions existed start their history as they are
+		Name: "post_first_revisions",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			cursor, err := db.Collection("posts").Find(ctx, bson.M{"revision": bson.M{"$exists": false}})
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+			for cursor.Next(ctx) {
+				var post store.Post
+				if err := cursor.Decode(&post); err != nil {
+					return err
+				}
+				_, err := db.Collection("post_revisions").InsertOne(ctx, store.Revision{
+					ID:        primitive.NewObjectID(),
+					PostID:    post.ID,
+					Number:    1,
+					AuthorID:  post.AuthorID,
+					Title:     post.Title,
+					Body:      post.Body,
+					CreatedAt: post.UpdatedAt,
+				})
+				if err != nil && !mongo.IsDuplicateKeyError(err) {
+					return err
+				}
+				_, err = db.Collection("posts").UpdateOne(ctx, bson.M{"_id": post.ID}, bson.M{"$set": bson.M{"revision": 1}})
+				if err != nil {
+					return err
+				}
+			}
+			return cursor.Err()
+		},
+	},
+	{
+		// tag and category pages list published posts, latest first
+		Name: "post_taxonomy_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("posts").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{Keys: bson.D{{Key: "tags", Value: 1}, {Key: "state", Value: 1}, {Key: "published_at", Value: -1}}},
+				{Keys: bson.D{{Key: "category", Value: 1}, {Key: "state", Value: 1}, {Key: "published_at", Value: -1}}},
+			})
+			return err
+		},
+	},
+	{
+		// search covers the posts published before it existed
+		Name: "search_index_posts",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			index := search.NewMongoIndex(db.Collection("search"))
+			if err := index.EnsureIndexes(ctx); err != nil {
+				return err
+			}
+			cursor, err := db.Collection("posts").Find(ctx, bson.M{"state": store.PostPublished})
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+			for cursor.Next(ctx) {
+				var post store.Post
+				if err := cursor.Decode(&post); err != nil {
+					return err
+				}
+				if err := index.Index(ctx, posts.Document(post)); err != nil {
+					return err
+				}
+			}
+			return cursor.Err()
+		},
+	},
+	{
+		// a user follows another once, followers are listed latest first
+		Name: "follow_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return store.EnsureFollowIndexes(ctx, db.Collection("follows"))
+		},
+	},
+	{
+		// feeds built on read list the published posts of a set of authors
+		Name: "post_author_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("posts").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "state", Value: 1}, {Key: "published_at", Value: -1}},
+			})
+			return err
+		},
+	},
+	{
+		// feeds built on write are read from the timelines, latest first
+		Name: "timeline_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return store.EnsureTimelineIndexes(ctx, db.Collection("timelines"))
+		},
+	},
+	{
+		// a user reacts to a post once per kind, bookmarks are listed latest first
+		Name: "reaction_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return store.EnsureReactionIndexes(ctx, db.Collection("reactions"))
+		},
+	},
+	{
+		// inboxes are listed latest first, digests look for what is left unread
+		Name: "notification_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("notifications").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: -1}}},
+				{Keys: bson.D{{Key: "digested_at", Value: 1}, {Key: "read_at", Value: 1}}},
+			})
+			return err
+		},
+	},
+}
+
+func dbMigrate(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "db migrate")
+	flags := newDatabaseFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := flags.open(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+
+	applied, err := db.Migrate(ctx, migrations)
+	for _, name := range applied {
+		fmt.Fprintln(e.out, "applied", name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Fprintln(e.out, "database is up to date")
+	}
+	return nil
+}
+
+// dbRebuildTimelines refills the timelines from the follows, for switching
+// FEED_FANOUT to write. Feeds built on write are incomplete while it runs.
+func dbRebuildTimelines(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "db rebuild-timelines")
+	flags := newDatabaseFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := flags.open(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+
+	if _, err := db.Collection("timelines").DeleteMany(ctx, bson.M{}); err != nil {
+		return err
+	}
+	follows := store.NewMongoFollows(db.Collection("follows"))
+	fanout := feed.OnWrite(follows, store.NewMongoPosts(db.Collection("posts")), store.NewMongoTimelines(db.Collection("timelines")))
+	rebuilt, err := feed.Replay(ctx, follows, fanout)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out, "rebuilt timelines from %d follows\n", rebuilt)
+	return nil
+}
+
+// seedUsers are created by db seed, one per role that matters for development
+var seedUsers = []struct {
+	username string
+	role     policy.Role
+}{
+	{"admin", policy.RoleAdmin},
+	{"editor", policy.RoleEditor},
+	{"author", policy.RoleAuthor},
+}
+
+// dbSeed creates the seed users, skipping those that already exist
+func dbSeed(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "db seed")
+	flags := newDatabaseFlags(fs)
+	password := fs.String("password", "", "password of the seed users, 8 to 120 characters")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	users, close, err := flags.users(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	for _, s := range seedUsers {
+		user, err := createUser(ctx, users, s.username, s.username+"@example.com", *password, s.role)
+		if err == errUsernameTaken || err == errEmailUsed {
+			fmt.Fprintf(e.out, "skipped %s : %v\n", s.username, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(e.out, "created %s %s\n", user.Username, user.ID.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbSeed(t *testing.T) {
+
+	e, out, users := newTestEnv(t, &fakeAdmin{})
+
+	err := dbSeed(context.Background(), e, []string{"-password", "short"})
+	assert.Error(t, err)
+
+	if !assert.NoError(t, dbSeed(context.Background(), e, []string{"-password", "seed-password"})) {
+		t.FailNow()
+	}
+	for _, s := range seedUsers {
+		user, err := users.FindByUsername(context.Background(), s.username)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []policy.Role{s.role}, user.Roles)
+		}
+	}
+
+	// seeding again leaves existing users alone
+	out.Reset()
+	assert.NoError(t, dbSeed(context.Background(), e, []string{"-password", "seed-password"}))
+	assert.Contains(t, out.String(), "skipped admin : Username already taken.")
+	_, total, _ := users.List(context.Background(), store.UserFilter{}, 0, 0)
+	assert.Equal(t, int64(len(seedUsers)), total)
+}
+
+func Test_migrations(t *testing.T) {
+
+	// applied migrations are recorded by name, so names must stay unique
+	names := map[string]bool{}
+	for _, m := range migrations {
+		assert.False(t, names[m.Name], m.Name)
+		names[m.Name] = true
+	}
+}
@@ -0,0 +1,232 @@
+// blogctl administers the blog backend. Most commands call the running
+// service over grpc, bootstrap tasks act on the database directly.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/certs"
+	"github.com/HiteshRepo/blog-application/database"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// command is a leaf of the command tree, e.g. "user list"
+type command struct {
+	name  string
+	args  string
+	about string
+	run   func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = []command{
+	{"user create", "-username NAME -email EMAIL -password PASSWORD [-role ROLE]", "create a user directly in the database", userCreate},
+	{"user list", "[-query PREFIX] [-role ROLE] [-status active|suspended] [-page-size N] [-page-token TOKEN | -all]", "list users", userList},
+	{"user suspend", "-id ID -reason REASON", "suspend a user", userSuspend},
+	{"user set-role", "-id ID -role ROLE [-revoke]", "grant, or revoke, a role", userSetRole},
+	{"token issue", "-login USERNAME|EMAIL", "issue a token directly from the database", tokenIssue},
+	{"token inspect", "TOKEN", "decode and verify a token", tokenInspect},
+	{"token revoke", "-id ID", "revoke every token of a user", tokenRevoke},
+	{"post list", "[-query TEXT] [-tag TAG] [-author ID] [-page-size N] [-page-token TOKEN | -all]", "list published posts", postList},
+	{"post delete", "-id ID", "delete a post, its revisions and reactions", postDelete},
+	{"db migrate", "", "apply pending database migrations", dbMigrate},
+	{"db seed", "-password PASSWORD", "insert an admin, an editor and an author", dbSeed},
+	{"db rebuild-timelines", "", "refill the feed timelines from the follows", dbRebuildTimelines},
+}
+
+// env holds what commands talk to, so that tests can swap it out
+type env struct {
+	out io.Writer
+
+	// dial connects to the service at addr
+	dial func(ctx context.Context, addr string, creds credentials.TransportCredentials) (*grpc.ClientConn, error)
+	// connect opens the database
+	connect func(ctx context.Context, uri, name string) (*database.Client, error)
+	// openUsers opens the user store of the database, close releases it
+	openUsers func(ctx context.Context, uri, name string) (users store.Users, close func(), err error)
+}
+
+func defaultEnv() *env {
+	e := &env{
+		out: os.Stdout,
+		dial: func(ctx context.Context, addr string, creds credentials.TransportCredentials) (*grpc.ClientConn, error) {
+			return grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(creds))
+		},
+		connect: func(ctx context.Context, uri, name string) (*database.Client, error) {
+			if uri == "" {
+				return nil, errors.New("-mongo-uri or MONGO_URI is required")
+			}
+			return database.Connect(ctx, database.Config{URI: uri, Name: name, Retries: -1})
+		},
+	}
+	e.openUsers = func(ctx context.Context, uri, name string) (store.Users, func(), error) {
+		db, err := e.connect(ctx, uri, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store.NewMongoUsers(db.Collection("user")), func() { closeDB(db) }, nil
+	}
+	return e
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, defaultEnv(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "blogctl:", err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches args to the matching command
+func run(ctx context.Context, e *env, args []string) error {
+	if len(args) < 2 {
+		usage(e.out)
+		return errors.New("missing command")
+	}
+	name := args[0] + " " + args[1]
+	for _, c := range commands {
+		if c.name == name {
+			return c.run(ctx, e, args[2:])
+		}
+	}
+	usage(e.out)
+	return fmt.Errorf("unknown command %q", name)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: blogctl COMMAND [flags]")
+	fmt.Fprintln(w)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", c.name, c.about)
+		if c.args != "" {
+			fmt.Fprintf(w, "  %-14s   %s\n", "", c.args)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Service commands read -addr and -token, or BLOG_ADDR and BLOG_TOKEN.")
+	fmt.Fprintln(w, "They connect over tls with -tls, -ca, or -cert and -key (BLOG_TLS, BLOG_CA, BLOG_CERT, BLOG_KEY).")
+	fmt.Fprintln(w, "Database commands read -mongo-uri and -mongo-db, or MONGO_URI and MONGO_DB.")
+}
+
+// newFlagSet returns the flag set of command name, printing errors to e.out
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("blogctl "+name, flag.ContinueOnError)
+	fs.SetOutput(e.out)
+	return fs
+}
+
+// serviceFlags registers the flags of commands that call the service
+type serviceFlags struct {
+	addr  *string
+	token *string
+
+	tls  *bool
+	ca   *string
+	cert *string
+	key  *string
+}
+
+func newServiceFlags(fs *flag.FlagSet) serviceFlags {
+	return serviceFlags{
+		addr:  fs.String("addr", envOr("BLOG_ADDR", "localhost:5000"), "grpc address of the service"),
+		token: fs.String("token", os.Getenv("BLOG_TOKEN"), "token of an admin"),
+		tls:   fs.Bool("tls", os.Getenv("BLOG_TLS") == "true", "connect over tls, trusting the system CAs"),
+		ca:    fs.String("ca", os.Getenv("BLOG_CA"), "CA file to verify the service with, implies -tls"),
+		cert:  fs.String("cert", os.Getenv("BLOG_CERT"), "client certificate file, implies -tls"),
+		key:   fs.String("key", os.Getenv("BLOG_KEY"), "client key file"),
+	}
+}
+
+// creds returns the transport credentials selected by the tls flags
+func (f serviceFlags) creds() (credentials.TransportCredentials, error) {
+	if !*f.tls && *f.ca == "" && *f.cert == "" {
+		return insecure.NewCredentials(), nil
+	}
+	cfg, err := certs.ClientConfig(*f.ca, *f.cert, *f.key)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+// conn dials the service and returns a context that authenticates with the token
+func (f serviceFlags) conn(ctx context.Context, e *env) (context.Context, *grpc.ClientConn, error) {
+	if *f.token == "" {
+		return nil, nil, errors.New("a token is required, set -token or BLOG_TOKEN")
+	}
+	creds, err := f.creds()
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := e.dial(ctx, *f.addr, creds)
+	if err != nil {
+		return nil, nil, err
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+*f.token), conn, nil
+}
+
+// databaseFlags registers the flags of commands that act on the database
+type databaseFlags struct {
+	uri  *string
+	name *string
+}
+
+func newDatabaseFlags(fs *flag.FlagSet) databaseFlags {
+	return databaseFlags{
+		uri:  fs.String("mongo-uri", os.Getenv("MONGO_URI"), "mongo connection string"),
+		name: fs.String("mongo-db", envOr("MONGO_DB", global.DefaultDBName), "database name"),
+	}
+}
+
+// open connects to the database, callers close the returned client
+func (f databaseFlags) open(ctx context.Context, e *env) (*database.Client, error) {
+	connectCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return e.connect(connectCtx, *f.uri, *f.name)
+}
+
+// users opens the user store, callers call close when done
+func (f databaseFlags) users(ctx context.Context, e *env) (store.Users, func(), error) {
+	connectCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return e.openUsers(connectCtx, *f.uri, *f.name)
+}
+
+// closeDB disconnects, giving pending operations 5 seconds to finish
+func closeDB(db *database.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	db.Close(ctx)
+}
+
+// required reports the first empty flag among names
+func required(fs *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if strings.TrimSpace(fs.Lookup(name).Value.String()) == "" {
+			return fmt.Errorf("-%s is required", name)
+		}
+	}
+	return nil
+}
+
+// envOr returns the environment variable key, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
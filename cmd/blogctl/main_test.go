@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HiteshRepo/blog-application/certs"
+	"github.com/HiteshRepo/blog-application/database"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeAdmin answers the admin RPCs blogctl uses with canned replies and remembers the last call
+type fakeAdmin struct {
+	proto.UnimplementedAdminServiceServer
+
+	method        string
+	request       interface{}
+	authorization string
+}
+
+func (f *fakeAdmin) record(ctx context.Context, method string, req interface{}) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	f.method, f.request = method, req
+	if values := md.Get("authorization"); len(values) > 0 {
+		f.authorization = values[0]
+	}
+}
+
+// fakeUsers are the users the fake lists
+var fakeUsers = []*proto.UserInfo{
+	&proto.UserInfo{ID: "62a000000000000000000001", Username: "admin", Email: "admin@example.com", Roles: []string{"admin"}},
+	&proto.UserInfo{ID: "62a000000000000000000002", Username: "spammer", Email: "spammer@example.com", Roles: []string{"author"}, Suspended: true, SuspendReason: "spam"},
+}
+
+func (f *fakeAdmin) ListUsers(ctx context.Context, in *proto.ListUsersRequest) (*proto.ListUsersResponse, error) {
+	f.record(ctx, "ListUsers", in)
+	return &proto.ListUsersResponse{Users: fakeUsers, Total: 3, PageSize: 20, NextPageToken: "next-token"}, nil
+}
+
+func (f *fakeAdmin) StreamUsers(in *proto.ListUsersRequest, stream proto.AdminService_StreamUsersServer) error {
+	f.record(stream.Context(), "StreamUsers", in)
+	for _, u := range fakeUsers {
+		if err := stream.Send(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeAdmin) SuspendUser(ctx context.Context, in *proto.SuspendUserRequest) (*proto.UserInfo, error) {
+	f.record(ctx, "SuspendUser", in)
+	return &proto.UserInfo{ID: in.GetUserID(), Username: "spammer", Suspended: true, SuspendReason: in.GetReason()}, nil
+}
+
+func (f *fakeAdmin) GrantRole(ctx context.Context, in *proto.RoleRequest) (*proto.RolesResponse, error) {
+	f.record(ctx, "GrantRole", in)
+	return &proto.RolesResponse{UserID: in.GetUserID(), Roles: []string{"author", in.GetRole()}}, nil
+}
+
+func (f *fakeAdmin) RevokeRole(ctx context.Context, in *proto.RoleRequest) (*proto.RolesResponse, error) {
+	f.record(ctx, "RevokeRole", in)
+	return &proto.RolesResponse{UserID: in.GetUserID(), Roles: []string{"author"}}, nil
+}
+
+func (f *fakeAdmin) ForceLogout(ctx context.Context, in *proto.UserRequest) (*proto.UserInfo, error) {
+	f.record(ctx, "ForceLogout", in)
+	return &proto.UserInfo{ID: in.GetUserID(), Username: "spammer"}, nil
+}
+
+// fakePosts answers the post and search RPCs blogctl uses with canned replies and remembers the last call
+type fakePosts struct {
+	proto.UnimplementedPostServiceServer
+	proto.UnimplementedSearchServiceServer
+	// fakeAdmin remembers the calls
+	fakeAdmin
+}
+
+// fakeHits are the posts the fake lists
+var fakeHits = []*proto.SearchHit{
+	&proto.SearchHit{Kind: "post", ID: "62b000000000000000000001", AuthorID: "62a000000000000000000002", Title: "Hello gRPC", Tags: []string{"go", "grpc"}, PublishedAt: 1654084800},
+	&proto.SearchHit{Kind: "post", ID: "62b000000000000000000002", AuthorID: "62a000000000000000000002", Title: "Cheap pills", PublishedAt: 1654088400},
+}
+
+func (f *fakePosts) Search(ctx context.Context, in *proto.SearchRequest) (*proto.SearchResponse, error) {
+	f.record(ctx, "Search", in)
+	return &proto.SearchResponse{Hits: fakeHits, Total: 3, PageSize: 20, NextPageToken: "next-token"}, nil
+}
+
+func (f *fakePosts) StreamSearch(in *proto.SearchRequest, stream proto.SearchService_StreamSearchServer) error {
+	f.record(stream.Context(), "StreamSearch", in)
+	for _, hit := range fakeHits {
+		if err := stream.Send(hit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakePosts) DeletePost(ctx context.Context, in *proto.PostRequest) (*proto.Post, error) {
+	f.record(ctx, "DeletePost", in)
+	return &proto.Post{ID: in.GetPostID(), Title: "Cheap pills"}, nil
+}
+
+// newTestEnv returns an env whose service is admin, and posts when given,
+// served over bufconn, and whose database is an in-memory user store
+func newTestEnv(t *testing.T, admin proto.AdminServiceServer, posts ...*fakePosts) (*env, *bytes.Buffer, store.Users) {
+	t.Helper()
+
+	server := grpc.NewServer()
+	proto.RegisterAdminServiceServer(server, admin)
+	for _, p := range posts {
+		proto.RegisterPostServiceServer(server, p)
+		proto.RegisterSearchServiceServer(server, p)
+	}
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	out := &bytes.Buffer{}
+	users := store.NewMemoryUsers()
+	e := &env{
+		out: out,
+		// bufconn is in-process, the credentials chosen by the flags are not needed
+		dial: func(ctx context.Context, addr string, _ credentials.TransportCredentials) (*grpc.ClientConn, error) {
+			return grpc.DialContext(ctx, addr,
+				grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+					return listener.DialContext(ctx)
+				}),
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+			)
+		},
+		connect: func(ctx context.Context, uri, name string) (*database.Client, error) {
+			t.Fatal("unexpected database connection")
+			return nil, nil
+		},
+		openUsers: func(ctx context.Context, uri, name string) (store.Users, func(), error) {
+			return users, func() {}, nil
+		},
+	}
+	return e, out, users
+}
+
+func Test_run(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"args":  []string{},
+			"error": "missing command",
+		},
+		map[string]interface{}{
+			"args":  []string{"user"},
+			"error": "missing command",
+		},
+		map[string]interface{}{
+			"args":  []string{"user", "delete"},
+			"error": `unknown command "user delete"`,
+		},
+		map[string]interface{}{
+			"args":  []string{"user", "list", "-token", "admin-token", "-bogus"},
+			"error": "flag provided but not defined: -bogus",
+		},
+		map[string]interface{}{
+			"args":  []string{"user", "list"},
+			"error": "a token is required",
+		},
+		map[string]interface{}{
+			"args":  []string{"user", "suspend", "-token", "admin-token", "-id", "62a000000000000000000002"},
+			"error": "-reason is required",
+		},
+		map[string]interface{}{
+			"args":  []string{"user", "list", "-token", "admin-token", "-ca", "missing.pem"},
+			"error": "missing.pem",
+		},
+	}
+
+	t.Setenv("BLOG_TOKEN", "")
+
+	for _, tcase := range testCases {
+		e, _, _ := newTestEnv(t, &fakeAdmin{})
+
+		err := run(context.Background(), e, tcase["args"].([]string))
+		if assert.Errorf(t, err, "case: %v", tcase) {
+			assert.Containsf(t, err.Error(), tcase["error"].(string), "case: %v", tcase)
+		}
+	}
+}
+
+func Test_serviceFlags_creds(t *testing.T) {
+
+	dir := t.TempDir()
+	ca, err := certs.NewAuthority("test CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(dir, "ca.pem")
+	os.WriteFile(caFile, ca.PEM, 0o600)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"args":     []string{},
+			"protocol": "insecure",
+		},
+		map[string]interface{}{
+			"args":     []string{"-tls"},
+			"protocol": "tls",
+		},
+		map[string]interface{}{
+			"args":     []string{"-ca", caFile},
+			"protocol": "tls",
+		},
+		map[string]interface{}{
+			"args":  []string{"-cert", caFile},
+			"error": true,
+		},
+	}
+
+	for _, key := range []string{"BLOG_TLS", "BLOG_CA", "BLOG_CERT", "BLOG_KEY"} {
+		t.Setenv(key, "")
+	}
+
+	for _, tcase := range testCases {
+		e, _, _ := newTestEnv(t, &fakeAdmin{})
+		fs := newFlagSet(e, "test")
+		flags := newServiceFlags(fs)
+		if err := fs.Parse(tcase["args"].([]string)); err != nil {
+			t.Fatal(err)
+		}
+
+		creds, err := flags.creds()
+		if _, ok := tcase["error"]; ok {
+			assert.Errorf(t, err, "case: %v", tcase)
+			continue
+		}
+		if assert.NoErrorf(t, err, "case: %v", tcase) {
+			assert.Equalf(t, tcase["protocol"], creds.Info().SecurityProtocol, "case: %v", tcase)
+		}
+	}
+}
+
+func Test_usage(t *testing.T) {
+
+	out := &bytes.Buffer{}
+	usage(out)
+	for _, c := range commands {
+		assert.Contains(t, out.String(), c.name)
+	}
+}
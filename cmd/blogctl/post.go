@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/proto"
+)
+
+// postList goes through the search listing, which holds every published
+// post, latest first without a query
+func postList(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "post list")
+	service := newServiceFlags(fs)
+	req := &proto.SearchRequest{}
+	fs.StringVar(&req.Query, "query", "", "words or \"phrases\" the posts match")
+	fs.StringVar(&req.AuthorID, "author", "", "only posts by the user with this id")
+	tag := fs.String("tag", "", "only posts with this tag slug")
+	fs.StringVar(&req.PageToken, "page-token", "", "token printed after the previous page")
+	fs.Int64Var(&req.PageSize, "page-size", 20, "posts per page")
+	all := fs.Bool("all", false, "list every post, from the page token on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *tag != "" {
+		req.Tags = []string{*tag}
+	}
+
+	ctx, conn, err := service.conn(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := proto.NewSearchServiceClient(conn)
+
+	w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tAUTHOR\tPUBLISHED\tTAGS")
+	printPost := func(hit *proto.SearchHit) {
+		published := time.Unix(hit.GetPublishedAt(), 0).UTC().Format(time.RFC3339)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", hit.GetID(), hit.GetTitle(), hit.GetAuthorID(), published, strings.Join(hit.GetTags(), ","))
+	}
+
+	if *all {
+		stream, err := client.StreamSearch(ctx, req)
+		if err != nil {
+			return err
+		}
+		for {
+			hit, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			printPost(hit)
+		}
+		return w.Flush()
+	}
+
+	res, err := client.Search(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, hit := range res.GetHits() {
+		printPost(hit)
+	}
+	w.Flush()
+	fmt.Fprintf(e.out, "%d posts in total\n", res.GetTotal())
+	if res.GetNextPageToken() != "" {
+		fmt.Fprintf(e.out, "next page: -page-token %s\n", res.GetNextPageToken())
+	}
+	return nil
+}
+
+// postDelete removes a post for good, in any state
+func postDelete(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "post delete")
+	service := newServiceFlags(fs)
+	req := &proto.PostRequest{}
+	fs.StringVar(&req.PostID, "id", "", "post id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "id"); err != nil {
+		return err
+	}
+
+	ctx, conn, err := service.conn(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	post, err := proto.NewPostServiceClient(conn).DeletePost(ctx, req)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out, "%s %q deleted\n", post.GetID(), post.GetTitle())
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_postList(t *testing.T) {
+
+	posts := &fakePosts{}
+	e, out, _ := newTestEnv(t, &fakeAdmin{}, posts)
+
+	err := postList(context.Background(), e, []string{"-token", "admin-token", "-tag", "go", "-author", "62a000000000000000000002"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "Bearer admin-token", posts.authorization)
+	assert.Equal(t, "Search", posts.method)
+	assert.Equal(t, []string{"go"}, posts.request.(*proto.SearchRequest).GetTags())
+	assert.Equal(t, "62a000000000000000000002", posts.request.(*proto.SearchRequest).GetAuthorID())
+	assert.Contains(t, out.String(), "Hello gRPC")
+	assert.Contains(t, out.String(), "2022-06-01T12:00:00Z  go,grpc")
+	assert.Contains(t, out.String(), "3 posts in total\nnext page: -page-token next-token\n")
+
+	out.Reset()
+	err = postList(context.Background(), e, []string{"-token", "admin-token", "-page-token", "next-token", "-all"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "StreamSearch", posts.method)
+		assert.Equal(t, "next-token", posts.request.(*proto.SearchRequest).GetPageToken())
+		assert.Empty(t, posts.request.(*proto.SearchRequest).GetTags())
+		assert.Contains(t, out.String(), "Hello gRPC")
+		assert.Contains(t, out.String(), "Cheap pills")
+		assert.NotContains(t, out.String(), "in total")
+	}
+}
+
+func Test_postDelete(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"args": []string{"-token", "admin-token", "-id", "62b000000000000000000002"},
+			"out":  "62b000000000000000000002 \"Cheap pills\" deleted\n",
+		},
+		map[string]interface{}{
+			"args":  []string{"-token", "admin-token"},
+			"error": "-id is required",
+		},
+	}
+
+	for _, tcase := range testCases {
+		posts := &fakePosts{}
+		e, out, _ := newTestEnv(t, &fakeAdmin{}, posts)
+
+		err := postDelete(context.Background(), e, tcase["args"].([]string))
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			assert.Equalf(t, "DeletePost", posts.method, "case: %v", tcase)
+			assert.Equalf(t, tcase["out"], out.String(), "case: %v", tcase)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+)
+
+// tokenIssue signs a token for a user read from the database, e.g. to
+// bootstrap the admin token the other commands need
+func tokenIssue(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "token issue")
+	db := newDatabaseFlags(fs)
+	login := fs.String("login", "", "username or email")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "login"); err != nil {
+		return err
+	}
+
+	users, close, err := db.users(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	user, err := users.FindByLogin(ctx, *login)
+	if err == store.ErrNotFound {
+		return errors.New("User not found")
+	}
+	if err != nil {
+		return err
+	}
+	if user.Suspended {
+		return errors.New("Account suspended")
+	}
+	fmt.Fprintln(e.out, user.GetToken())
+	return nil
+}
+
+// tokenInspect prints the claims of a token, it needs neither the service nor the database
+func tokenInspect(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "token inspect")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one token")
+	}
+
+	user := global.UserFromToken(strings.TrimPrefix(fs.Arg(0), "Bearer "))
+	if user.IsNil() {
+		return errors.New("Invalid token")
+	}
+	fmt.Fprintf(e.out, "id:            %s\n", user.ID.Hex())
+	fmt.Fprintf(e.out, "username:      %s\n", user.Username)
+	fmt.Fprintf(e.out, "email:         %s\n", user.Email)
+	fmt.Fprintf(e.out, "roles:         %s\n", strings.Join(policy.RoleNames(user.GetRoles()), ","))
+	fmt.Fprintf(e.out, "token version: %d\n", user.TokenVersion)
+	return nil
+}
+
+func tokenRevoke(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "token revoke")
+	service := newServiceFlags(fs)
+	req := &proto.UserRequest{}
+	fs.StringVar(&req.UserID, "id", "", "user id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "id"); err != nil {
+		return err
+	}
+
+	ctx, conn, err := service.conn(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	info, err := proto.NewAdminServiceClient(conn).ForceLogout(ctx, req)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out, "revoked every token of %s\n", info.GetUsername())
+	return nil
+}
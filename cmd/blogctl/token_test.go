@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_tokenIssue(t *testing.T) {
+
+	e, out, users := newTestEnv(t, &fakeAdmin{})
+
+	admin := global.User{ID: primitive.NewObjectID(), Username: "admin", Email: "admin@example.com", Roles: []policy.Role{policy.RoleAdmin}}
+	suspended := global.User{ID: primitive.NewObjectID(), Username: "spammer", Email: "spammer@example.com", Suspended: true}
+	users.Insert(context.Background(), admin)
+	users.Insert(context.Background(), suspended)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"login": "admin",
+		},
+		map[string]interface{}{
+			"login": "admin@example.com",
+		},
+		map[string]interface{}{
+			"login": "spammer",
+			"error": "Account suspended",
+		},
+		map[string]interface{}{
+			"login": "nobody",
+			"error": "User not found",
+		},
+	}
+
+	for _, tcase := range testCases {
+		out.Reset()
+		err := tokenIssue(context.Background(), e, []string{"-login", tcase["login"].(string)})
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			assert.Equalf(t, admin.ID, global.UserFromToken(strings.TrimSpace(out.String())).ID, "case: %v", tcase)
+		}
+	}
+}
+
+func Test_tokenInspect(t *testing.T) {
+
+	e, out, _ := newTestEnv(t, &fakeAdmin{})
+
+	user := global.User{ID: primitive.NewObjectID(), Username: "editor", Email: "editor@example.com", Roles: []policy.Role{policy.RoleEditor}, TokenVersion: 3}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"args": []string{user.GetToken()},
+		},
+		map[string]interface{}{
+			"args": []string{"Bearer " + user.GetToken()},
+		},
+		map[string]interface{}{
+			"args":  []string{"not-a-token"},
+			"error": "Invalid token",
+		},
+		map[string]interface{}{
+			"args":  []string{},
+			"error": "expected exactly one token",
+		},
+	}
+
+	for _, tcase := range testCases {
+		out.Reset()
+		err := tokenInspect(context.Background(), e, tcase["args"].([]string))
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			assert.Containsf(t, out.String(), user.ID.Hex(), "case: %v", tcase)
+			assert.Containsf(t, out.String(), "roles:         editor", "case: %v", tcase)
+			assert.Containsf(t, out.String(), "token version: 3", "case: %v", tcase)
+		}
+	}
+}
+
+func Test_tokenRevoke(t *testing.T) {
+
+	admin := &fakeAdmin{}
+	e, out, _ := newTestEnv(t, admin)
+
+	err := tokenRevoke(context.Background(), e, []string{"-token", "admin-token", "-id", "62a000000000000000000002"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "ForceLogout", admin.method)
+		assert.Equal(t, "62a000000000000000000002", admin.request.(*proto.UserRequest).GetUserID())
+		assert.Equal(t, "revoked every token of spammer\n", out.String())
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/HiteshRepo/blog-application/validate"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errUsernameTaken = errors.New("Username already taken.")
+	errEmailUsed     = errors.New("Email already used.")
+)
+
+// userCreate inserts a user straight into the database, which is how the
+// first admin gets created
+func userCreate(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "user create")
+	db := newDatabaseFlags(fs)
+	username := fs.String("username", "", "username")
+	email := fs.String("email", "", "email")
+	password := fs.String("password", "", "password, 8 to 120 characters")
+	role := fs.String("role", string(policy.DefaultRole), "role of the user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "username", "email", "password"); err != nil {
+		return err
+	}
+	r, err := policy.ParseRole(*role)
+	if err != nil {
+		return err
+	}
+
+	users, close, err := db.users(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	user, err := createUser(ctx, users, *username, *email, *password, r)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(e.out, user.ID.Hex())
+	return nil
+}
+
+// createUser inserts a user holding role, validated like a signup request
+func createUser(ctx context.Context, users store.Users, username, email, password string, role policy.Role) (global.User, error) {
+	if violations := validate.Check(&proto.SignupRequest{Username: username, Email: email, Password: password}); len(violations) > 0 {
+		return global.NilUser, errors.New(violations[0].String())
+	}
+	if _, err := users.FindByUsername(ctx, username); err != store.ErrNotFound {
+		if err == nil {
+			err = errUsernameTaken
+		}
+		return global.NilUser, err
+	}
+	if _, err := users.FindByEmail(ctx, email); err != store.ErrNotFound {
+		if err == nil {
+			err = errEmailUsed
+		}
+		return global.NilUser, err
+	}
+
+	pw, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return global.NilUser, err
+	}
+	user := global.User{
+		ID:       primitive.NewObjectID(),
+		Username: username,
+		Email:    email,
+		Password: string(pw),
+		Roles:    []policy.Role{role},
+	}
+	return user, users.Insert(ctx, user)
+}
+
+func userList(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "user list")
+	service := newServiceFlags(fs)
+	req := &proto.ListUsersRequest{}
+	fs.StringVar(&req.Query, "query", "", "username or email prefix")
+	fs.StringVar(&req.Role, "role", "", "only users holding role")
+	fs.StringVar(&req.Status, "status", "", "active or suspended")
+	fs.StringVar(&req.PageToken, "page-token", "", "token printed after the previous page")
+	fs.Int64Var(&req.PageSize, "page-size", 20, "users per page")
+	all := fs.Bool("all", false, "list every user, from the page token on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, conn, err := service.conn(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := proto.NewAdminServiceClient(conn)
+
+	w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSERNAME\tEMAIL\tROLES\tSTATUS")
+	printUser := func(u *proto.UserInfo) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", u.GetID(), u.GetUsername(), u.GetEmail(), strings.Join(u.GetRoles(), ","), userStatus(u))
+	}
+
+	if *all {
+		stream, err := client.StreamUsers(ctx, req)
+		if err != nil {
+			return err
+		}
+		for {
+			u, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			printUser(u)
+		}
+		return w.Flush()
+	}
+
+	res, err := client.ListUsers(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, u := range res.GetUsers() {
+		printUser(u)
+	}
+	w.Flush()
+	fmt.Fprintf(e.out, "%d users in total\n", res.GetTotal())
+	if res.GetNextPageToken() != "" {
+		fmt.Fprintf(e.out, "next page: -page-token %s\n", res.GetNextPageToken())
+	}
+	return nil
+}
+
+func userSuspend(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "user suspend")
+	service := newServiceFlags(fs)
+	req := &proto.SuspendUserRequest{}
+	fs.StringVar(&req.UserID, "id", "", "user id")
+	fs.StringVar(&req.Reason, "reason", "", "why the user is suspended")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "id", "reason"); err != nil {
+		return err
+	}
+
+	ctx, conn, err := service.conn(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	info, err := proto.NewAdminServiceClient(conn).SuspendUser(ctx, req)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out, "%s %s\n", info.GetUsername(), userStatus(info))
+	return nil
+}
+
+func userSetRole(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "user set-role")
+	service := newServiceFlags(fs)
+	req := &proto.RoleRequest{}
+	fs.StringVar(&req.UserID, "id", "", "user id")
+	fs.StringVar(&req.Role, "role", "", "role to grant")
+	revoke := fs.Bool("revoke", false, "revoke the role instead")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "id", "role"); err != nil {
+		return err
+	}
+
+	ctx, conn, err := service.conn(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	admin := proto.NewAdminServiceClient(conn)
+	var res *proto.RolesResponse
+	if *revoke {
+		res, err = admin.RevokeRole(ctx, req)
+	} else {
+		res, err = admin.GrantRole(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out, "%s %s\n", res.GetUserID(), strings.Join(res.GetRoles(), ","))
+	return nil
+}
+
+func userStatus(u *proto.UserInfo) string {
+	if u.GetSuspended() {
+		return "suspended (" + u.GetSuspendReason() + ")"
+	}
+	return "active"
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func Test_userCreate(t *testing.T) {
+
+	e, out, users := newTestEnv(t, &fakeAdmin{})
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"args": []string{"-username", "first-admin", "-email", "first-admin@example.com", "-password", "admin-password", "-role", "admin"},
+		},
+		map[string]interface{}{
+			"args": []string{"-username", "first-author", "-email", "first-author@example.com", "-password", "author-password"},
+		},
+		map[string]interface{}{
+			"args":  []string{"-username", "first-admin", "-email", "other@example.com", "-password", "admin-password"},
+			"error": "Username already taken.",
+		},
+		map[string]interface{}{
+			"args":  []string{"-username", "other", "-email", "first-admin@example.com", "-password", "admin-password"},
+			"error": "Email already used.",
+		},
+		map[string]interface{}{
+			"args":  []string{"-username", "other", "-email", "other@example.com", "-password", "short"},
+			"error": "Password should be between 8 and 120 characters",
+		},
+		map[string]interface{}{
+			"args":  []string{"-username", "other", "-email", "other@example.com", "-password", "other-password", "-role", "superuser"},
+			"error": "Unknown role",
+		},
+		map[string]interface{}{
+			"args":  []string{"-username", "other", "-password", "other-password"},
+			"error": "-email is required",
+		},
+	}
+
+	for _, tcase := range testCases {
+		err := userCreate(context.Background(), e, tcase["args"].([]string))
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+		}
+	}
+
+	admin, err := users.FindByUsername(context.Background(), "first-admin")
+	if assert.NoError(t, err) {
+		assert.Contains(t, out.String(), admin.ID.Hex())
+		assert.Equal(t, []policy.Role{policy.RoleAdmin}, admin.Roles)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte("admin-password")))
+	}
+	author, err := users.FindByUsername(context.Background(), "first-author")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []policy.Role{policy.RoleAuthor}, author.Roles)
+	}
+}
+
+func Test_userList(t *testing.T) {
+
+	admin := &fakeAdmin{}
+	e, out, _ := newTestEnv(t, admin)
+
+	err := userList(context.Background(), e, []string{"-token", "admin-token", "-query", "sp", "-status", "suspended"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "Bearer admin-token", admin.authorization)
+	assert.Equal(t, "sp", admin.request.(*proto.ListUsersRequest).GetQuery())
+	assert.Equal(t, "suspended", admin.request.(*proto.ListUsersRequest).GetStatus())
+	assert.Contains(t, out.String(), "spammer@example.com")
+	assert.Contains(t, out.String(), "suspended (spam)")
+	assert.Contains(t, out.String(), "3 users in total\nnext page: -page-token next-token\n")
+
+	out.Reset()
+	err = userList(context.Background(), e, []string{"-token", "admin-token", "-page-token", "next-token", "-all"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "StreamUsers", admin.method)
+		assert.Equal(t, "next-token", admin.request.(*proto.ListUsersRequest).GetPageToken())
+		assert.Contains(t, out.String(), "admin@example.com")
+		assert.Contains(t, out.String(), "spammer@example.com")
+		assert.NotContains(t, out.String(), "in total")
+	}
+}
+
+func Test_userSuspend(t *testing.T) {
+
+	admin := &fakeAdmin{}
+	e, out, _ := newTestEnv(t, admin)
+
+	err := userSuspend(context.Background(), e, []string{"-token", "admin-token", "-id", "62a000000000000000000002", "-reason", "spam"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SuspendUser", admin.method)
+		assert.Equal(t, "62a000000000000000000002", admin.request.(*proto.SuspendUserRequest).GetUserID())
+		assert.Equal(t, "spammer suspended (spam)\n", out.String())
+	}
+}
+
+func Test_userSetRole(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"args":   []string{"-token", "admin-token", "-id", "62a000000000000000000002", "-role", "editor"},
+			"method": "GrantRole",
+			"out":    "62a000000000000000000002 author,editor\n",
+		},
+		map[string]interface{}{
+			"args":   []string{"-token", "admin-token", "-id", "62a000000000000000000002", "-role", "editor", "-revoke"},
+			"method": "RevokeRole",
+			"out":    "62a000000000000000000002 author\n",
+		},
+		map[string]interface{}{
+			"args":  []string{"-token", "admin-token", "-id", "62a000000000000000000002"},
+			"error": "-role is required",
+		},
+	}
+
+	for _, tcase := range testCases {
+		admin := &fakeAdmin{}
+		e, out, _ := newTestEnv(t, admin)
+
+		err := userSetRole(context.Background(), e, tcase["args"].([]string))
+		if errMsg, ok := tcase["error"]; ok {
+			if assert.Errorf(t, err, "case: %v", tcase) {
+				assert.Containsf(t, err.Error(), errMsg.(string), "case: %v", tcase)
+			}
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+			assert.Equalf(t, tcase["method"], admin.method, "case: %v", tcase)
+			assert.Equalf(t, tcase["out"], out.String(), "case: %v", tcase)
+		}
+	}
+}
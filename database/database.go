@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultRetries        = 5
+	defaultRetryBackoff   = time.Second
+)
+
+// Config describes how to reach the database
+type Config struct {
+	URI  string
+	Name string
+
+	// ConnectTimeout bounds each connection attempt, 10 seconds by default
+	ConnectTimeout time.Duration
+	// Retries is the number of extra attempts after a failed one,
+	// 5 when left at zero and none when negative
+	Retries int
+	// RetryBackoff is the wait before the first retry, doubled after every attempt
+	RetryBackoff time.Duration
+	// Monitor receives every command sent to the database, if set
+	Monitor *event.CommandMonitor
+}
+
+// Client is a connected database handle
+type Client struct {
+	client *mongo.Client
+	db     *mongo.Database
+}
+
+// Connect dials the database described by cfg and checks it answers,
+// retrying with exponential backoff until it does or ctx is done
+func Connect(ctx context.Context, cfg Config) (*Client, error) {
+	if cfg.URI == "" || cfg.Name == "" {
+		return nil, errors.New("Database uri and name are required")
+	}
+	if cfg.ConnectTimeout <= 0 {
+		cfg.ConnectTimeout = defaultConnectTimeout
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	} else if cfg.Retries == 0 {
+		cfg.Retries = defaultRetries
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = defaultRetryBackoff
+	}
+
+	opts := options.Client().ApplyURI(cfg.URI)
+	if cfg.Monitor != nil {
+		opts.SetMonitor(cfg.Monitor)
+	}
+
+	backoff := cfg.RetryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var client *mongo.Client
+		client, err = connect(ctx, opts, cfg.ConnectTimeout)
+		if err == nil {
+			return &Client{client: client, db: client.Database(cfg.Name)}, nil
+		}
+		if attempt == cfg.Retries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return nil, err
+}
+
+func connect(ctx context.Context, opts *options.ClientOptions, timeout time.Duration) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	return client, nil
+}
+
+// Database returns the application database
+func (c *Client) Database() *mongo.Database {
+	return c.db
+}
+
+// Collection returns a handle on the named collection
+func (c *Client) Collection(name string) *mongo.Collection {
+	return c.db.Collection(name)
+}
+
+// Ping checks the primary answers
+func (c *Client) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx, readpref.Primary())
+}
+
+// Close disconnects from the database, waiting for in-use connections up to ctx's deadline
+func (c *Client) Close(ctx context.Context) error {
+	return c.client.Disconnect(ctx)
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// nothing listens on port 1, so every attempt fails fast
+const unreachableURI = "mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=50"
+
+func Test_Connect(t *testing.T) {
+
+	_, err := Connect(context.Background(), Config{Name: "blog-application"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Database uri and name are required")
+	}
+
+	start := time.Now()
+	_, err = Connect(context.Background(), Config{
+		URI:            unreachableURI,
+		Name:           "blog-application",
+		ConnectTimeout: 100 * time.Millisecond,
+		Retries:        2,
+		RetryBackoff:   20 * time.Millisecond,
+	})
+	assert.Error(t, err)
+	// two backoffs of 20ms and 40ms were waited
+	assert.True(t, time.Since(start) >= 60*time.Millisecond)
+}
+
+func Test_Connect_cancelled(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := Connect(ctx, Config{
+		URI:            unreachableURI,
+		Name:           "blog-application",
+		ConnectTimeout: 20 * time.Millisecond,
+		Retries:        100,
+		RetryBackoff:   time.Second,
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
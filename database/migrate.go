@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// migrationsCollection records the migrations applied to a database
+const migrationsCollection = "migrations"
+
+// Migration is a named, one-off change to the database.
+// Up should be safe to re-run, in case it fails half way.
+type Migration struct {
+	Name string
+	Up   func(ctx context.Context, db *mongo.Database) error
+}
+
+type appliedMigration struct {
+	Name      string    `bson:"_id"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// Migrate applies, in order, the migrations not applied before and returns their names.
+// It stops at the first failing migration.
+func (c *Client) Migrate(ctx context.Context, migrations []Migration) ([]string, error) {
+	collection := c.Collection(migrationsCollection)
+
+	var applied []string
+	for _, m := range migrations {
+		err := collection.FindOne(ctx, bson.M{"_id": m.Name}).Err()
+		if err == nil {
+			continue
+		}
+		if err != mongo.ErrNoDocuments {
+			return applied, err
+		}
+
+		if err := m.Up(ctx, c.Database()); err != nil {
+			return applied, fmt.Errorf("migration %s : %w", m.Name, err)
+		}
+		if _, err := collection.InsertOne(ctx, appliedMigration{Name: m.Name, AppliedAt: time.Now().UTC()}); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m.Name)
+	}
+	return applied, nil
+}
@@ -0,0 +1,50 @@
+//go:build integration
+// +build integration
+
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func Test_Client_Migrate(t *testing.T) {
+
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	c, err := Connect(ctx, Config{URI: uri, Name: "blog-application_test_" + primitive.NewObjectID().Hex(), Retries: -1})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer c.Close(ctx)
+	defer c.Database().Drop(ctx)
+
+	runs := map[string]int{}
+	up := func(name string, err error) Migration {
+		return Migration{Name: name, Up: func(context.Context, *mongo.Database) error {
+			runs[name]++
+			return err
+		}}
+	}
+
+	applied, err := c.Migrate(ctx, []Migration{up("one", nil), up("two", errors.New("boom")), up("three", nil)})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"one"}, applied)
+
+	applied, err = c.Migrate(ctx, []Migration{up("one", nil), up("two", nil), up("three", nil)})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"two", "three"}, applied)
+	assert.Equal(t, map[string]int{"one": 1, "two": 2, "three": 1}, runs)
+}
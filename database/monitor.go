@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// CommandCollection reads the target collection of a command, e.g. {"find": "user", ...}.
+// getMore names the collection in a field of its own.
+func CommandCollection(command string, doc bson.Raw) string {
+	key := command
+	if command == "getMore" {
+		key = "collection"
+	}
+	value, err := doc.LookupErr(key)
+	if err != nil {
+		return ""
+	}
+	name, _ := value.StringValueOK()
+	return name
+}
+
+// CombineMonitors fans every command event out to each of monitors,
+// since a mongo client accepts a single monitor
+func CombineMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func Test_CommandCollection(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"command":    "find",
+			"doc":        bson.D{{Key: "find", Value: "user"}, {Key: "filter", Value: bson.D{}}},
+			"collection": "user",
+		},
+		map[string]interface{}{
+			"command":    "getMore",
+			"doc":        bson.D{{Key: "getMore", Value: int64(42)}, {Key: "collection", Value: "audit_log"}},
+			"collection": "audit_log",
+		},
+		map[string]interface{}{
+			"command":    "ping",
+			"doc":        bson.D{{Key: "ping", Value: 1}},
+			"collection": "",
+		},
+	}
+
+	for _, tcase := range testCases {
+		doc, _ := bson.Marshal(tcase["doc"])
+		assert.Equalf(t, tcase["collection"], CommandCollection(tcase["command"].(string), doc), "case: %v", tcase)
+	}
+}
+
+func Test_CombineMonitors(t *testing.T) {
+
+	var calls []string
+	first := &event.CommandMonitor{
+		Started:   func(context.Context, *event.CommandStartedEvent) { calls = append(calls, "first.started") },
+		Succeeded: func(context.Context, *event.CommandSucceededEvent) { calls = append(calls, "first.succeeded") },
+	}
+	second := &event.CommandMonitor{
+		Started: func(context.Context, *event.CommandStartedEvent) { calls = append(calls, "second.started") },
+		Failed:  func(context.Context, *event.CommandFailedEvent) { calls = append(calls, "second.failed") },
+	}
+
+	combined := CombineMonitors(first, second)
+	combined.Started(context.Background(), &event.CommandStartedEvent{})
+	combined.Succeeded(context.Background(), &event.CommandSucceededEvent{})
+	combined.Failed(context.Background(), &event.CommandFailedEvent{})
+
+	assert.Equal(t, []string{"first.started", "second.started", "first.succeeded", "second.failed"}, calls)
+}
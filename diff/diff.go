@@ -0,0 +1,116 @@
+// Package diff compares two texts line by line or word by word.
+package diff
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// Op tells what a chunk of text went through from the old to the new text
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Chunk is a run of text with the same Op
+type Chunk struct {
+	Op   Op
+	Text string
+}
+
+// Lines diffs a and b by whole lines, each keeping its line break
+func Lines(a, b string) []Chunk {
+	return tokens(splitLines(a), splitLines(b))
+}
+
+// Words diffs a and b by words, runs of whitespace count as words
+func Words(a, b string) []Chunk {
+	return tokens(splitWords(a), splitWords(b))
+}
+
+// tokens diffs two token lists, by mapping every distinct token to a rune
+// and diffing the runes
+func tokens(a, b []string) []Chunk {
+	ids := map[string]rune{}
+	var values []string
+	encode := func(tokens []string) []rune {
+		runes := make([]rune, len(tokens))
+		for i, token := range tokens {
+			id, ok := ids[token]
+			if !ok {
+				// skip the surrogate range, not valid runes on their own
+				id = rune(len(values))
+				if id >= 0xD800 {
+					id += 0x800
+				}
+				ids[token] = id
+				values = append(values, token)
+			}
+			runes[i] = id
+		}
+		return runes
+	}
+	decode := func(id rune) string {
+		if id >= 0xE000 {
+			id -= 0x800
+		}
+		return values[id]
+	}
+
+	dmp := diffmatchpatch.New()
+	// no deadline, the result must be a minimal diff
+	dmp.DiffTimeout = 0
+	diffs := dmp.DiffMainRunes(encode(a), encode(b), false)
+
+	chunks := []Chunk{}
+	for _, d := range diffs {
+		var text strings.Builder
+		for _, id := range d.Text {
+			text.WriteString(decode(id))
+		}
+		op := Equal
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			op = Insert
+		case diffmatchpatch.DiffDelete:
+			op = Delete
+		}
+		// merge neighbours with the same op, the runes split them at times
+		if n := len(chunks); n > 0 && chunks[n-1].Op == op {
+			chunks[n-1].Text += text.String()
+			continue
+		}
+		chunks = append(chunks, Chunk{Op: op, Text: text.String()})
+	}
+	return chunks
+}
+
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	// a final line break leaves an empty string behind
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func splitWords(s string) []string {
+	var words []string
+	start, space := 0, false
+	for i, r := range s {
+		if i > start && unicode.IsSpace(r) != space {
+			words = append(words, s[start:i])
+			start = i
+		}
+		space = unicode.IsSpace(r)
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
@@ -0,0 +1,85 @@
+package diff
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Lines(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"a":      "",
+			"b":      "",
+			"chunks": []Chunk{},
+		},
+		map[string]interface{}{
+			"a":      "one\ntwo\n",
+			"b":      "one\ntwo\n",
+			"chunks": []Chunk{{Equal, "one\ntwo\n"}},
+		},
+		map[string]interface{}{
+			"a":      "one\ntwo\nthree\n",
+			"b":      "one\n2\nthree\nfour\n",
+			"chunks": []Chunk{{Equal, "one\n"}, {Delete, "two\n"}, {Insert, "2\n"}, {Equal, "three\n"}, {Insert, "four\n"}},
+		},
+		map[string]interface{}{
+			// lines differing only by their break are different lines
+			"a":      "last",
+			"b":      "last\n",
+			"chunks": []Chunk{{Delete, "last"}, {Insert, "last\n"}},
+		},
+		map[string]interface{}{
+			"a":      "# Title\n",
+			"b":      "",
+			"chunks": []Chunk{{Delete, "# Title\n"}},
+		},
+	}
+
+	for _, tcase := range testCases {
+		assert.Equalf(t, tcase["chunks"], Lines(tcase["a"].(string), tcase["b"].(string)), "case: %v", tcase)
+	}
+}
+
+func Test_Words(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"a":      "the quick brown fox",
+			"b":      "the slow brown fox",
+			"chunks": []Chunk{{Equal, "the "}, {Delete, "quick"}, {Insert, "slow"}, {Equal, " brown fox"}},
+		},
+		map[string]interface{}{
+			// words are never split, even when they share letters
+			"a":      "café au lait",
+			"b":      "cafés au lait",
+			"chunks": []Chunk{{Delete, "café"}, {Insert, "cafés"}, {Equal, " au lait"}},
+		},
+		map[string]interface{}{
+			"a":      "one  two",
+			"b":      "one two\nthree",
+			"chunks": []Chunk{{Equal, "one"}, {Delete, "  "}, {Insert, " "}, {Equal, "two"}, {Insert, "\nthree"}},
+		},
+	}
+
+	for _, tcase := range testCases {
+		assert.Equalf(t, tcase["chunks"], Words(tcase["a"].(string), tcase["b"].(string)), "case: %v", tcase)
+	}
+}
+
+func Test_Words_distinct(t *testing.T) {
+
+	// more distinct words than runes below the surrogate range
+	var a, b strings.Builder
+	for i := 0; i < 60000; i++ {
+		a.WriteString("w" + strconv.Itoa(i) + " ")
+		b.WriteString("w" + strconv.Itoa(i) + " ")
+	}
+	b.WriteString("end")
+
+	chunks := Words(a.String(), b.String())
+	assert.Equal(t, []Chunk{{Equal, a.String()}, {Insert, "end"}}, chunks)
+}
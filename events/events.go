@@ -0,0 +1,128 @@
+// Package events carries what users do in one service to the services that
+// react to it, within the process. Publishers do not know who listens.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/logging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Kind names what happened
+type Kind string
+
+const (
+	// Follow is a user following another one
+	Follow Kind = "follow"
+	// Like is a user liking a post
+	Like Kind = "like"
+)
+
+// Event is something a user, the actor, did that concerns another user
+type Event struct {
+	Kind Kind
+	// UserID is the user concerned, the followee or the author of the liked post
+	UserID        primitive.ObjectID
+	ActorID       primitive.ObjectID
+	ActorUsername string
+	// PostID and PostTitle are set for events about a post
+	PostID    primitive.ObjectID
+	PostTitle string
+	At        time.Time
+}
+
+// Handler reacts to an event. Handlers log their failures, the action that
+// raised the event happened whatever they do.
+type Handler func(ctx context.Context, e Event)
+
+// Publisher is what services raise events on
+type Publisher interface {
+	Publish(ctx context.Context, e Event)
+}
+
+// Discard drops every event, for services nobody listens to
+var Discard Publisher = discard{}
+
+type discard struct{}
+
+func (discard) Publish(ctx context.Context, e Event) {}
+
+// Bus hands every published event to every subscribed handler, in the
+// background. Publishing never waits on the handlers: events queue up for a
+// worker, which runs the handlers on a context of its own carrying the logger
+// of the request, so that they outlive it. Handlers bound their own work.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+	queue    chan published
+	closed   bool
+	done     chan struct{}
+}
+
+// published is a queued event with the context its handlers run on
+type published struct {
+	ctx context.Context
+	e   Event
+}
+
+// NewBus returns a bus with no handlers, queueing up to size events
+func NewBus(size int) *Bus {
+	b := &Bus{queue: make(chan published, size), done: make(chan struct{})}
+	go b.work()
+	return b
+}
+
+// Subscribe adds h to the handlers of every event published from now on
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish queues e for the handlers and returns. Events published while the
+// queue is full, or once the bus is closed, are dropped and logged.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	logger := logging.FromContext(ctx)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		logger.Warn("Event dropped, the bus is closed", zap.String("kind", string(e.Kind)))
+		return
+	}
+	select {
+	case b.queue <- published{ctx: logging.ContextWithLogger(context.Background(), logger), e: e}:
+	default:
+		logger.Warn("Event dropped, the bus is full", zap.String("kind", string(e.Kind)))
+	}
+}
+
+// Close stops taking events and returns once the queued ones are handled
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.mu.Unlock()
+	<-b.done
+}
+
+// work runs the handlers on every queued event, one event after the other
+// and the handlers in the order they subscribed
+func (b *Bus) work() {
+	defer close(b.done)
+	for p := range b.queue {
+		b.mu.RLock()
+		handlers := b.handlers
+		b.mu.RUnlock()
+
+		for _, h := range handlers {
+			h(p.ctx, p.e)
+		}
+	}
+}
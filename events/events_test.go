@@ -0,0 +1,58 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_Bus(t *testing.T) {
+
+	bus := NewBus(16)
+	e := Event{Kind: Follow, UserID: primitive.NewObjectID(), ActorID: primitive.NewObjectID(), ActorUsername: "ada"}
+
+	var got []string
+	bus.Subscribe(func(ctx context.Context, e Event) { got = append(got, "first:"+string(e.Kind)) })
+	bus.Subscribe(func(ctx context.Context, e Event) { got = append(got, "second:"+e.ActorUsername) })
+
+	// the handlers outlive the request that published the event
+	ctx, cancel := context.WithCancel(context.Background())
+	var errs []error
+	bus.Subscribe(func(ctx context.Context, e Event) { errs = append(errs, ctx.Err()) })
+	bus.Publish(ctx, e)
+	cancel()
+
+	// closing waits for the queued events, later ones are dropped
+	bus.Close()
+	bus.Publish(context.Background(), e)
+	bus.Close()
+	assert.Equal(t, []string{"first:follow", "second:ada"}, got)
+	assert.Equal(t, []error{nil}, errs)
+
+	Discard.Publish(context.Background(), e)
+}
+
+func Test_Bus_full(t *testing.T) {
+
+	bus := NewBus(1)
+	block, handled := make(chan struct{}), make(chan struct{})
+	var got []string
+	bus.Subscribe(func(ctx context.Context, e Event) {
+		if e.ActorUsername == "first" {
+			close(handled)
+			<-block
+		}
+		got = append(got, e.ActorUsername)
+	})
+
+	// publishing never waits on the handlers, what does not fit is dropped
+	bus.Publish(context.Background(), Event{Kind: Follow, ActorUsername: "first"})
+	<-handled
+	bus.Publish(context.Background(), Event{Kind: Follow, ActorUsername: "queued"})
+	bus.Publish(context.Background(), Event{Kind: Follow, ActorUsername: "dropped"})
+	close(block)
+	bus.Close()
+	assert.Equal(t, []string{"first", "queued"}, got)
+}
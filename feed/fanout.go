@@ -0,0 +1,209 @@
+package feed
+
+import (
+	"context"
+
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// batchSize bounds the follows and posts read at once while fanning out
+const batchSize = 500
+
+// Fanout builds the home feeds of users: the published posts of the users
+// they follow, latest published first. Posts and follows are handed to it as
+// they change.
+type Fanout interface {
+	// Published is called when a post is published, or edited while published
+	Published(ctx context.Context, post store.Post) error
+	// Withdrawn is called when a published post is unpublished, unlisted,
+	// archived or deleted
+	Withdrawn(ctx context.Context, post store.Post) error
+	Followed(ctx context.Context, follow store.Follow) error
+	Unfollowed(ctx context.Context, follow store.Follow) error
+	// Feed returns a page of the feed of a user. after, when set, lists the
+	// posts after it.
+	Feed(ctx context.Context, userID primitive.ObjectID, after *store.PostKey, limit int64) (Page, error)
+}
+
+// Page is a page of a feed. Feeds may read posts they leave out, Read and
+// Last are what the next page follows on from, not Posts.
+type Page struct {
+	Posts []store.Post
+	// Read is the number of posts the page went through, those left out
+	// included, and Last the key of the last of them
+	Read int
+	Last *store.PostKey
+	// Total is the length of the feed
+	Total int64
+}
+
+type onRead struct {
+	follows store.Follows
+	posts   store.Posts
+}
+
+// OnRead returns a fanout that merges the posts of the followed users when a
+// feed is read. Writes cost nothing, reads query the posts of every followed
+// user.
+func OnRead(follows store.Follows, posts store.Posts) Fanout {
+	return &onRead{follows: follows, posts: posts}
+}
+
+func (r *onRead) Published(ctx context.Context, post store.Post) error { return nil }
+
+func (r *onRead) Withdrawn(ctx context.Context, post store.Post) error { return nil }
+
+func (r *onRead) Followed(ctx context.Context, follow store.Follow) error { return nil }
+
+func (r *onRead) Unfollowed(ctx context.Context, follow store.Follow) error { return nil }
+
+func (r *onRead) Feed(ctx context.Context, userID primitive.ObjectID, after *store.PostKey, limit int64) (Page, error) {
+	follows, _, err := r.follows.List(ctx, store.FollowFilter{FollowerID: userID}, 0, 0)
+	if err != nil {
+		return Page{}, err
+	}
+	// an empty list matches no author
+	authors := make([]primitive.ObjectID, 0, len(follows))
+	for _, f := range follows {
+		authors = append(authors, f.FolloweeID)
+	}
+	posts, total, err := r.posts.List(ctx, store.PostFilter{Authors: authors, After: after}, 0, limit)
+	if err != nil {
+		return Page{}, err
+	}
+	page := Page{Posts: posts, Read: len(posts), Total: total}
+	if len(posts) > 0 {
+		last := posts[len(posts)-1].Key()
+		page.Last = &last
+	}
+	return page, nil
+}
+
+type onWrite struct {
+	follows   store.Follows
+	posts     store.Posts
+	timelines store.Timelines
+}
+
+// OnWrite returns a fanout that copies every post to the timelines of the
+// followers of its author when it is published, and the posts of a user to
+// the timeline of their new followers. Reads are a single timeline lookup,
+// publishing writes once per follower.
+func OnWrite(follows store.Follows, posts store.Posts, timelines store.Timelines) Fanout {
+	return &onWrite{follows: follows, posts: posts, timelines: timelines}
+}
+
+func (w *onWrite) Published(ctx context.Context, post store.Post) error {
+	filter := store.FollowFilter{FolloweeID: post.AuthorID}
+	for {
+		follows, _, err := w.follows.List(ctx, filter, 0, batchSize)
+		if err != nil {
+			return err
+		}
+		entries := make([]store.TimelineEntry, 0, len(follows))
+		for _, f := range follows {
+			entries = append(entries, entry(f.FollowerID, post))
+		}
+		if err := w.timelines.Add(ctx, entries); err != nil {
+			return err
+		}
+		if len(follows) < batchSize {
+			return nil
+		}
+		filter.After = follows[len(follows)-1].ID
+	}
+}
+
+func (w *onWrite) Withdrawn(ctx context.Context, post store.Post) error {
+	return w.timelines.RemovePost(ctx, post.ID)
+}
+
+// Followed copies the published posts of the followee to the timeline of the follower
+func (w *onWrite) Followed(ctx context.Context, follow store.Follow) error {
+	filter := store.PostFilter{Authors: []primitive.ObjectID{follow.FolloweeID}}
+	for {
+		posts, _, err := w.posts.List(ctx, filter, 0, batchSize)
+		if err != nil {
+			return err
+		}
+		entries := make([]store.TimelineEntry, 0, len(posts))
+		for _, p := range posts {
+			entries = append(entries, entry(follow.FollowerID, p))
+		}
+		if err := w.timelines.Add(ctx, entries); err != nil {
+			return err
+		}
+		if len(posts) < batchSize {
+			return nil
+		}
+		last := posts[len(posts)-1].Key()
+		filter.After = &last
+	}
+}
+
+func (w *onWrite) Unfollowed(ctx context.Context, follow store.Follow) error {
+	return w.timelines.RemoveAuthor(ctx, follow.FollowerID, follow.FolloweeID)
+}
+
+// Feed leaves out posts withdrawn since they were added, when taking them
+// off the timelines failed. The page then comes out short, or empty, and
+// the next one follows on from the last entry read.
+func (w *onWrite) Feed(ctx context.Context, userID primitive.ObjectID, after *store.PostKey, limit int64) (Page, error) {
+	entries, total, err := w.timelines.List(ctx, userID, after, limit)
+	if err != nil {
+		return Page{}, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.PostID)
+	}
+	found, err := w.posts.FindByIDs(ctx, ids)
+	if err != nil {
+		return Page{}, err
+	}
+
+	byID := make(map[primitive.ObjectID]store.Post, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+	page := Page{Posts: make([]store.Post, 0, len(entries)), Read: len(entries), Total: total}
+	for _, e := range entries {
+		if p, ok := byID[e.PostID]; ok && p.GetState() == store.PostPublished {
+			page.Posts = append(page.Posts, p)
+		}
+	}
+	if len(entries) > 0 {
+		last := entries[len(entries)-1].Key()
+		page.Last = &last
+	}
+	return page, nil
+}
+
+// Replay hands every follow to fanout and returns how many there were. It
+// fills the timelines of a fanout on write started on existing follows.
+func Replay(ctx context.Context, follows store.Follows, fanout Fanout) (int, error) {
+	var filter store.FollowFilter
+	replayed := 0
+	for {
+		page, _, err := follows.List(ctx, filter, 0, batchSize)
+		if err != nil {
+			return replayed, err
+		}
+		for _, follow := range page {
+			if err := fanout.Followed(ctx, follow); err != nil {
+				return replayed, err
+			}
+			replayed++
+		}
+		if len(page) < batchSize {
+			return replayed, nil
+		}
+		filter.After = page[len(page)-1].ID
+	}
+}
+
+// entry puts post on the timeline of userID
+func entry(userID primitive.ObjectID, post store.Post) store.TimelineEntry {
+	return store.TimelineEntry{UserID: userID, PostID: post.ID, AuthorID: post.AuthorID, PublishedAt: post.PublishedAt}
+}
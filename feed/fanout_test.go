@@ -0,0 +1,249 @@
+package feed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// strategies builds each fanout on the given stores
+var strategies = map[string]func(store.Follows, store.Posts, store.Timelines) Fanout{
+	"OnRead": func(follows store.Follows, posts store.Posts, _ store.Timelines) Fanout {
+		return OnRead(follows, posts)
+	},
+	"OnWrite": OnWrite,
+}
+
+// fanoutStores holds the stores behind a fanout, and drives it the way the
+// services do
+type fanoutStores struct {
+	follows store.Follows
+	posts   store.Posts
+	fanout  Fanout
+}
+
+func newFanoutStores(strategy func(store.Follows, store.Posts, store.Timelines) Fanout) *fanoutStores {
+	follows, posts := store.NewMemoryFollows(), store.NewMemoryPosts()
+	return &fanoutStores{follows: follows, posts: posts, fanout: strategy(follows, posts, store.NewMemoryTimelines())}
+}
+
+func (s *fanoutStores) publish(ctx context.Context, author primitive.ObjectID, at time.Time) (store.Post, error) {
+	post := store.Post{ID: primitive.NewObjectID(), AuthorID: author, State: store.PostPublished, PublishedAt: at}
+	if err := s.posts.Insert(ctx, post); err != nil {
+		return post, err
+	}
+	return post, s.fanout.Published(ctx, post)
+}
+
+func (s *fanoutStores) withdraw(ctx context.Context, post store.Post) error {
+	from := post.State
+	post.State = store.PostUnlisted
+	updated, err := s.posts.SetState(ctx, from, post)
+	if err != nil {
+		return err
+	}
+	return s.fanout.Withdrawn(ctx, updated)
+}
+
+func (s *fanoutStores) follow(ctx context.Context, follower, followee primitive.ObjectID) error {
+	follow := store.Follow{ID: primitive.NewObjectID(), FollowerID: follower, FolloweeID: followee, CreatedAt: time.Now()}
+	if err := s.follows.Insert(ctx, follow); err != nil {
+		return err
+	}
+	return s.fanout.Followed(ctx, follow)
+}
+
+func (s *fanoutStores) unfollow(ctx context.Context, follower, followee primitive.ObjectID) error {
+	follow, err := s.follows.Delete(ctx, follower, followee)
+	if err != nil {
+		return err
+	}
+	return s.fanout.Unfollowed(ctx, follow)
+}
+
+// ids returns the ids of posts, in order
+func ids(posts []store.Post) []primitive.ObjectID {
+	res := make([]primitive.ObjectID, 0, len(posts))
+	for _, p := range posts {
+		res = append(res, p.ID)
+	}
+	return res
+}
+
+func Test_Fanout(t *testing.T) {
+	for name, strategy := range strategies {
+		testFanout(t, name, newFanoutStores(strategy))
+	}
+}
+
+func testFanout(t *testing.T, name string, s *fanoutStores) {
+	ctx := context.Background()
+	ada, grace, reader := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	start := time.Now().UTC().Truncate(time.Millisecond)
+
+	// posts published before the follow are in the feed too, drafts never are
+	first, err := s.publish(ctx, ada, start)
+	assert.NoErrorf(t, err, "strategy: %v", name)
+	assert.NoError(t, s.posts.Insert(ctx, store.Post{ID: primitive.NewObjectID(), AuthorID: ada, State: store.PostDraft}))
+	second, err := s.publish(ctx, grace, start.Add(time.Minute))
+	assert.NoErrorf(t, err, "strategy: %v", name)
+	_, err = s.publish(ctx, primitive.NewObjectID(), start.Add(2*time.Minute))
+	assert.NoErrorf(t, err, "strategy: %v", name)
+	assert.NoError(t, s.follow(ctx, reader, ada))
+	assert.NoError(t, s.follow(ctx, reader, grace))
+	third, err := s.publish(ctx, ada, start.Add(3*time.Minute))
+	assert.NoErrorf(t, err, "strategy: %v", name)
+
+	page, err := s.fanout.Feed(ctx, reader, nil, 10)
+	assert.NoErrorf(t, err, "strategy: %v", name)
+	assert.Equalf(t, int64(3), page.Total, "strategy: %v", name)
+	assert.Equalf(t, []primitive.ObjectID{third.ID, second.ID, first.ID}, ids(page.Posts), "strategy: %v", name)
+
+	// pages follow on from the key of the last post
+	page, err = s.fanout.Feed(ctx, reader, nil, 2)
+	assert.NoErrorf(t, err, "strategy: %v", name)
+	assert.Equalf(t, []primitive.ObjectID{third.ID, second.ID}, ids(page.Posts), "strategy: %v", name)
+	assert.Equalf(t, 2, page.Read, "strategy: %v", name)
+	assert.Equalf(t, second.Key(), *page.Last, "strategy: %v", name)
+	page, err = s.fanout.Feed(ctx, reader, page.Last, 2)
+	assert.NoErrorf(t, err, "strategy: %v", name)
+	assert.Equalf(t, int64(3), page.Total, "strategy: %v", name)
+	assert.Equalf(t, []primitive.ObjectID{first.ID}, ids(page.Posts), "strategy: %v", name)
+
+	assert.NoError(t, s.unfollow(ctx, reader, grace))
+	assert.NoError(t, s.withdraw(ctx, first))
+	page, err = s.fanout.Feed(ctx, reader, nil, 10)
+	assert.NoErrorf(t, err, "strategy: %v", name)
+	assert.Equalf(t, int64(1), page.Total, "strategy: %v", name)
+	assert.Equalf(t, []primitive.ObjectID{third.ID}, ids(page.Posts), "strategy: %v", name)
+
+	// users following nobody have an empty feed
+	page, err = s.fanout.Feed(ctx, primitive.NewObjectID(), nil, 10)
+	assert.NoErrorf(t, err, "strategy: %v", name)
+	assert.Equalf(t, int64(0), page.Total, "strategy: %v", name)
+	assert.Emptyf(t, page.Posts, "strategy: %v", name)
+	assert.Nilf(t, page.Last, "strategy: %v", name)
+}
+
+func Test_onWrite_Feed_withdrawn(t *testing.T) {
+
+	ctx := context.Background()
+	s := newFanoutStores(strategies["OnWrite"])
+	author, reader := primitive.NewObjectID(), primitive.NewObjectID()
+	assert.NoError(t, s.follow(ctx, reader, author))
+	start := time.Now().UTC().Truncate(time.Millisecond)
+	older, err := s.publish(ctx, author, start)
+	assert.NoError(t, err)
+	// two posts withdrawn while taking them off the timelines failed
+	for i := 1; i <= 2; i++ {
+		post, err := s.publish(ctx, author, start.Add(time.Duration(i)*time.Minute))
+		assert.NoError(t, err)
+		post.State = store.PostArchived
+		_, err = s.posts.SetState(ctx, store.PostPublished, post)
+		assert.NoError(t, err)
+	}
+
+	// a page of withdrawn posts comes out empty, and still pages on
+	page, err := s.fanout.Feed(ctx, reader, nil, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, page.Posts)
+	assert.Equal(t, 2, page.Read)
+	if assert.NotNil(t, page.Last) {
+		page, err = s.fanout.Feed(ctx, reader, page.Last, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []primitive.ObjectID{older.ID}, ids(page.Posts))
+	}
+}
+
+func Test_Replay(t *testing.T) {
+
+	ctx := context.Background()
+	s := newFanoutStores(strategies["OnRead"])
+	reader := primitive.NewObjectID()
+	for i := 0; i < 3; i++ {
+		author := primitive.NewObjectID()
+		_, err := s.publish(ctx, author, time.Now().Add(time.Duration(i)*time.Minute))
+		assert.NoError(t, err)
+		assert.NoError(t, s.follow(ctx, reader, author))
+	}
+	want, err := s.fanout.Feed(ctx, reader, nil, 10)
+	assert.NoError(t, err)
+
+	// switching to fanning out on write fills the timelines from the follows
+	onWrite := OnWrite(s.follows, s.posts, store.NewMemoryTimelines())
+	replayed, err := Replay(ctx, s.follows, onWrite)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, replayed)
+	got, err := onWrite.Feed(ctx, reader, nil, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), got.Total)
+	assert.Equal(t, ids(want.Posts), ids(got.Posts))
+}
+
+// newBenchStores returns stores where every one of readers follows every one
+// of authors, who published postsPerAuthor posts each
+func newBenchStores(b *testing.B, strategy func(store.Follows, store.Posts, store.Timelines) Fanout, authors, readers, postsPerAuthor int) (*fanoutStores, []primitive.ObjectID, []primitive.ObjectID) {
+	b.Helper()
+
+	ctx := context.Background()
+	s := newFanoutStores(strategy)
+	authorIDs := make([]primitive.ObjectID, authors)
+	start := time.Now().Add(-time.Hour)
+	for i := range authorIDs {
+		authorIDs[i] = primitive.NewObjectID()
+		for j := 0; j < postsPerAuthor; j++ {
+			if _, err := s.publish(ctx, authorIDs[i], start.Add(time.Duration(i*postsPerAuthor+j)*time.Millisecond)); err != nil {
+				b.Fatalf("Error publishing post : %v", err)
+			}
+		}
+	}
+	readerIDs := make([]primitive.ObjectID, readers)
+	for i := range readerIDs {
+		readerIDs[i] = primitive.NewObjectID()
+		for _, author := range authorIDs {
+			if err := s.follow(ctx, readerIDs[i], author); err != nil {
+				b.Fatalf("Error following author : %v", err)
+			}
+		}
+	}
+	return s, authorIDs, readerIDs
+}
+
+// Benchmark_Fanout compares the cost of publishing a post to the followers of
+// its author with the cost of reading a page of a feed, for each strategy, on
+// the memory stores. Fanning out on write moves the work from every read to
+// every publish, which pays off as long as feeds are read more often than
+// posts are published times the followers of their authors.
+func Benchmark_Fanout(b *testing.B) {
+	const authors, readers, postsPerAuthor = 50, 200, 10
+
+	for _, name := range []string{"OnRead", "OnWrite"} {
+		strategy := strategies[name]
+
+		b.Run(name+"/Publish", func(b *testing.B) {
+			s, authorIDs, _ := newBenchStores(b, strategy, authors, readers, postsPerAuthor)
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.publish(ctx, authorIDs[i%len(authorIDs)], time.Now()); err != nil {
+					b.Fatalf("Error publishing post : %v", err)
+				}
+			}
+		})
+
+		b.Run(name+"/Feed", func(b *testing.B) {
+			s, _, readerIDs := newBenchStores(b, strategy, authors, readers, postsPerAuthor)
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.fanout.Feed(ctx, readerIDs[i%len(readerIDs)], nil, 20); err != nil {
+					b.Fatalf("Error reading feed : %v", err)
+				}
+			}
+		})
+	}
+}
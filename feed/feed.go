@@ -0,0 +1,300 @@
+// Package feed serves the FollowService and builds the home feeds of users
+// from the posts of the users they follow, either when the feeds are read or
+// when the posts are published.
+package feed
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/events"
+	"github.com/HiteshRepo/blog-application/gateway"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Service serves the FollowService
+type Service struct {
+	follows *followServer
+}
+
+// New returns the follow service on the given stores, telling fanout about
+// every follow and publishing new follows on publisher. Page tokens are
+// issued with pages.
+func New(users store.Users, follows store.Follows, fanout Fanout, publisher events.Publisher, pages *paging.Codec) *Service {
+	return &Service{follows: &followServer{users: users, follows: follows, fanout: fanout, events: publisher, pages: pages, now: time.Now}}
+}
+
+// Register adds the FollowService to server
+func (s *Service) Register(server *grpc.Server) {
+	proto.RegisterFollowServiceServer(server, s.follows)
+}
+
+// Require lists the permissions needed by every guarded RPC, any user follows others
+func (s *Service) Require(p *policy.Policy) {
+	p.Require("/proto.FollowService/Follow").
+		Require("/proto.FollowService/Unfollow")
+}
+
+// Routes maps the FollowService to the JSON API
+func (s *Service) Routes(g *gateway.Gateway) {
+	g.Handle(http.MethodPut, "/v1/users/{userID}/follow", "/proto.FollowService/Follow").
+		Handle(http.MethodDelete, "/v1/users/{userID}/follow", "/proto.FollowService/Unfollow").
+		Handle(http.MethodGet, "/v1/users/{userID}/followers", "/proto.FollowService/ListFollowers").
+		Handle(http.MethodGet, "/v1/users/{userID}/following", "/proto.FollowService/ListFollowing")
+}
+
+type followServer struct {
+	users   store.Users
+	follows store.Follows
+	fanout  Fanout
+	events  events.Publisher
+	pages   *paging.Codec
+	now     func() time.Time
+}
+
+// Follow does nothing when the caller follows the user already
+func (f *followServer) Follow(ctx context.Context, in *proto.FollowRequest) (*proto.FollowResponse, error) {
+	caller := global.UserFromContext(ctx)
+	followeeID, err := primitive.ObjectIDFromHex(in.GetUserID())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid user id")
+	}
+	if followeeID == caller.ID {
+		return nil, status.Error(codes.InvalidArgument, "Users cannot follow themselves")
+	}
+	if _, err := f.findUser(ctx, followeeID); err != nil {
+		return nil, err
+	}
+
+	// insert should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	follow := store.Follow{ID: primitive.NewObjectID(), FollowerID: caller.ID, FolloweeID: followeeID, CreatedAt: f.now().UTC()}
+	err = f.follows.Insert(dbCtx, follow)
+	if err == store.ErrConflict {
+		return &proto.FollowResponse{UserID: in.GetUserID(), Following: true}, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while inserting follow", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	f.fanOut(ctx, follow, f.fanout.Followed)
+	f.events.Publish(ctx, events.Event{Kind: events.Follow, UserID: followeeID, ActorID: caller.ID, ActorUsername: caller.Username, At: follow.CreatedAt})
+	return &proto.FollowResponse{UserID: in.GetUserID(), Following: true}, nil
+}
+
+// Unfollow does nothing when the caller does not follow the user
+func (f *followServer) Unfollow(ctx context.Context, in *proto.FollowRequest) (*proto.FollowResponse, error) {
+	followeeID, err := primitive.ObjectIDFromHex(in.GetUserID())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid user id")
+	}
+
+	// delete should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	follow, err := f.follows.Delete(dbCtx, global.UserFromContext(ctx).ID, followeeID)
+	if err == store.ErrNotFound {
+		return &proto.FollowResponse{UserID: in.GetUserID()}, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while deleting follow", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	f.fanOut(ctx, follow, f.fanout.Unfollowed)
+	return &proto.FollowResponse{UserID: in.GetUserID()}, nil
+}
+
+func (f *followServer) ListFollowers(ctx context.Context, in *proto.ListFollowsRequest) (*proto.ListFollowsResponse, error) {
+	return f.list(ctx, in, true)
+}
+
+func (f *followServer) ListFollowing(ctx context.Context, in *proto.ListFollowsRequest) (*proto.ListFollowsResponse, error) {
+	return f.list(ctx, in, false)
+}
+
+func (f *followServer) StreamFollowers(in *proto.ListFollowsRequest, stream proto.FollowService_StreamFollowersServer) error {
+	return f.stream(in, true, stream)
+}
+
+func (f *followServer) StreamFollowing(in *proto.ListFollowsRequest, stream proto.FollowService_StreamFollowingServer) error {
+	return f.stream(in, false, stream)
+}
+
+// fanOut hands follow to the fanout
+func (f *followServer) fanOut(ctx context.Context, follow store.Follow, hook func(context.Context, store.Follow) error) {
+	// fanning out should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	if err := hook(dbCtx, follow); err != nil {
+		// the follow is saved, only log
+		logging.FromContext(ctx).Error("Error returned while updating feeds", zap.Error(err), zap.String("follow_id", follow.ID.Hex()))
+	}
+}
+
+// findUser returns the user with id, hiding suspended users as if they did not exist
+func (f *followServer) findUser(ctx context.Context, id primitive.ObjectID) (global.User, error) {
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := f.users.FindByID(dbCtx, id)
+	if err != nil && err != store.ErrNotFound {
+		logging.FromContext(ctx).Error("Error returned while looking up user", zap.Error(err))
+		return global.User{}, status.Error(codes.Internal, "Internal Error")
+	}
+	if err == store.ErrNotFound || user.Suspended {
+		return global.User{}, status.Error(codes.NotFound, "User not found")
+	}
+	return user, nil
+}
+
+// followListing binds the page tokens of a list of follows to the user and
+// the direction
+type followListing struct {
+	UserID    string `json:"user_id"`
+	Followers bool   `json:"followers,omitempty"`
+}
+
+// list returns a page of the followers of the user, or of the users they
+// follow, latest follows first
+func (f *followServer) list(ctx context.Context, in *proto.ListFollowsRequest, followers bool) (*proto.ListFollowsResponse, error) {
+	userID, page, err := f.start(ctx, in, followers)
+	if err != nil {
+		return nil, err
+	}
+	read, err := f.page(ctx, userID, followers, page)
+	if err != nil {
+		return nil, err
+	}
+	next, err := f.pages.Next(page, read.read, read.total, read.last)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while issuing page token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	return &proto.ListFollowsResponse{
+		Users:         read.users,
+		Total:         read.total,
+		PageSize:      page.Size,
+		NextPageToken: next,
+	}, nil
+}
+
+// stream sends every follower of the user, or every user they follow, from
+// the page token on
+func (f *followServer) stream(in *proto.ListFollowsRequest, followers bool, stream followSender) error {
+	userID, page, err := f.start(stream.Context(), in, followers)
+	if err != nil {
+		return err
+	}
+	return paging.Walk(page, func(page paging.Page) (int, interface{}, error) {
+		read, err := f.page(stream.Context(), userID, followers, page)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, u := range read.users {
+			if err := stream.Send(u); err != nil {
+				return 0, nil, err
+			}
+		}
+		return read.read, read.last, nil
+	})
+}
+
+// followSender is a stream of users
+type followSender interface {
+	Context() context.Context
+	Send(*proto.FollowUser) error
+}
+
+// start returns the user a request lists the follows of and its first page
+func (f *followServer) start(ctx context.Context, in *proto.ListFollowsRequest, followers bool) (primitive.ObjectID, paging.Page, error) {
+	userID, err := primitive.ObjectIDFromHex(in.GetUserID())
+	if err != nil {
+		return userID, paging.Page{}, status.Error(codes.InvalidArgument, "Invalid user id")
+	}
+	if _, err := f.findUser(ctx, userID); err != nil {
+		return userID, paging.Page{}, err
+	}
+	page, err := f.pages.Start(in.GetPageToken(), in.GetPageSize(), followListing{UserID: in.GetUserID(), Followers: followers})
+	return userID, page, err
+}
+
+// followPage is a page of follows, as the users on their other end
+type followPage struct {
+	users []*proto.FollowUser
+	// read is the number of follows the page went through, those of
+	// suspended users included, and last the id of the last of them
+	read  int
+	last  interface{}
+	total int64
+}
+
+// page returns the users on the other end of the follows of page
+func (f *followServer) page(ctx context.Context, userID primitive.ObjectID, followers bool, page paging.Page) (followPage, error) {
+	filter := store.FollowFilter{FollowerID: userID}
+	if followers {
+		filter = store.FollowFilter{FolloweeID: userID}
+	}
+	if _, err := page.Key(&filter.After); err != nil {
+		return followPage{}, err
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	follows, total, err := f.follows.List(dbCtx, filter, 0, page.Size)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while listing follows", zap.Error(err))
+		return followPage{}, status.Error(codes.Internal, "Internal Error")
+	}
+	// the other end of every follow
+	ids := make([]primitive.ObjectID, 0, len(follows))
+	for _, follow := range follows {
+		if followers {
+			ids = append(ids, follow.FollowerID)
+		} else {
+			ids = append(ids, follow.FolloweeID)
+		}
+	}
+	users, err := f.users.FindByIDs(dbCtx, ids)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while looking up users", zap.Error(err))
+		return followPage{}, status.Error(codes.Internal, "Internal Error")
+	}
+	byID := make(map[primitive.ObjectID]global.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	res := followPage{users: make([]*proto.FollowUser, 0, len(follows)), read: len(follows), total: total}
+	if len(follows) > 0 {
+		res.last = follows[len(follows)-1].ID
+	}
+	for i, follow := range follows {
+		u, ok := byID[ids[i]]
+		if !ok || u.Suspended {
+			continue
+		}
+		listed := &proto.FollowUser{ID: u.ID.Hex(), Username: u.Username, FollowedAt: follow.CreatedAt.Unix()}
+		if !u.Profile.Privacy.HideDisplayName {
+			listed.DisplayName = u.Profile.DisplayName
+		}
+		res.users = append(res.users, listed)
+	}
+	return res, nil
+}
@@ -0,0 +1,254 @@
+package feed
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/HiteshRepo/blog-application/events"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/server"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// authenticate trusts the token, the auth service checks it against the db
+func authenticate(ctx context.Context) (context.Context, []policy.Role, error) {
+	user := global.UserFromToken(policy.TokenFromContext(ctx))
+	if user.IsNil() {
+		return ctx, nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	return global.ContextWithUser(ctx, user), user.GetRoles(), nil
+}
+
+// newTestClient serves the FollowService over bufconn on the production server bootstrap
+func newTestClient(t *testing.T, service *Service) proto.FollowServiceClient {
+	t.Helper()
+
+	grpcServer := server.New(server.Config{Logger: zap.NewNop(), Authenticate: authenticate}, service).GRPCServer()
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcServer.Serve(listener)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error dialing bufconn : %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+	})
+	return proto.NewFollowServiceClient(conn)
+}
+
+// as returns a context that calls the service with user's token
+func as(user global.User) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+user.GetToken())
+}
+
+// newTestUsers returns a store holding ada, grace hiding the display name,
+// linus and the suspended mallory
+func newTestUsers(t *testing.T) (store.Users, []global.User) {
+	t.Helper()
+
+	all := []global.User{
+		global.User{ID: primitive.NewObjectID(), Username: "ada", Email: "ada@example.com", Profile: global.Profile{DisplayName: "Ada L."}},
+		global.User{ID: primitive.NewObjectID(), Username: "grace", Email: "grace@example.com", Profile: global.Profile{
+			DisplayName: "Grace H.",
+			Privacy:     global.Privacy{HideDisplayName: true},
+		}},
+		global.User{ID: primitive.NewObjectID(), Username: "linus", Email: "linus@example.com"},
+		global.User{ID: primitive.NewObjectID(), Username: "mallory", Email: "mallory@example.com", Suspended: true},
+	}
+	users := store.NewMemoryUsers()
+	for _, u := range all {
+		if err := users.Insert(context.Background(), u); err != nil {
+			t.Fatalf("Error inserting user : %v", err)
+		}
+	}
+	return users, all
+}
+
+func Test_followServer_Follow(t *testing.T) {
+
+	users, all := newTestUsers(t)
+	follows, posts, timelines := store.NewMemoryFollows(), store.NewMemoryPosts(), store.NewMemoryTimelines()
+	bus := events.NewBus(16)
+	var published []events.Event
+	bus.Subscribe(func(ctx context.Context, e events.Event) { published = append(published, e) })
+	client := newTestClient(t, New(users, follows, OnWrite(follows, posts, timelines), bus, paging.NewCodec(nil)))
+	ada, grace, mallory := all[0], all[1], all[3]
+	post := store.Post{ID: primitive.NewObjectID(), AuthorID: grace.ID, State: store.PostPublished}
+	assert.NoError(t, posts.Insert(context.Background(), post))
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"user": grace.ID.Hex(),
+		},
+		map[string]interface{}{
+			// following again changes nothing
+			"user": grace.ID.Hex(),
+		},
+		map[string]interface{}{
+			"user": ada.ID.Hex(),
+			"code": codes.InvalidArgument,
+		},
+		map[string]interface{}{
+			"user": mallory.ID.Hex(),
+			"code": codes.NotFound,
+		},
+		map[string]interface{}{
+			"user": primitive.NewObjectID().Hex(),
+			"code": codes.NotFound,
+		},
+		map[string]interface{}{
+			"user": "not-an-id",
+			"code": codes.InvalidArgument,
+		},
+	}
+
+	for _, tcase := range testCases {
+		res, err := client.Follow(as(ada), &proto.FollowRequest{UserID: tcase["user"].(string)})
+		code, _ := tcase["code"].(codes.Code)
+		assert.Equalf(t, code, status.Code(err), "case: %v", tcase)
+		if code != codes.OK {
+			continue
+		}
+		assert.Equalf(t, tcase["user"], res.GetUserID(), "case: %v", tcase)
+		assert.Truef(t, res.GetFollowing(), "case: %v", tcase)
+	}
+
+	_, err := client.Follow(context.Background(), &proto.FollowRequest{UserID: grace.ID.Hex()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// grace is told once, once the bus handled what was published
+	bus.Close()
+	if assert.Len(t, published, 1) {
+		assert.Equal(t, events.Follow, published[0].Kind)
+		assert.Equal(t, grace.ID, published[0].UserID)
+		assert.Equal(t, ada.ID, published[0].ActorID)
+		assert.Equal(t, "ada", published[0].ActorUsername)
+	}
+
+	_, total, err := follows.List(context.Background(), store.FollowFilter{FollowerID: ada.ID}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	// the posts of grace were copied to the timeline of ada
+	entries, _, err := timelines.List(context.Background(), ada.ID, nil, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, post.ID, entries[0].PostID)
+	}
+
+	// unfollowing twice changes nothing either
+	for i := 0; i < 2; i++ {
+		res, err := client.Unfollow(as(ada), &proto.FollowRequest{UserID: grace.ID.Hex()})
+		assert.NoError(t, err)
+		assert.False(t, res.GetFollowing())
+	}
+	_, total, err = follows.List(context.Background(), store.FollowFilter{FollowerID: ada.ID}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+	_, total, err = timelines.List(context.Background(), ada.ID, nil, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+}
+
+func Test_followServer_invalidID(t *testing.T) {
+
+	// the handlers check ids themselves, without the validation interceptor
+	f := &followServer{}
+	_, err := f.Follow(context.Background(), &proto.FollowRequest{UserID: "not-an-id"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = f.Unfollow(context.Background(), &proto.FollowRequest{UserID: "not-an-id"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = f.ListFollowers(context.Background(), &proto.ListFollowsRequest{UserID: "not-an-id"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_followServer_List(t *testing.T) {
+
+	users, all := newTestUsers(t)
+	follows, posts := store.NewMemoryFollows(), store.NewMemoryPosts()
+	client := newTestClient(t, New(users, follows, OnRead(follows, posts), events.Discard, paging.NewCodec(nil)))
+	ada, grace, linus, mallory := all[0], all[1], all[2], all[3]
+
+	for _, follower := range []global.User{grace, linus} {
+		_, err := client.Follow(as(follower), &proto.FollowRequest{UserID: ada.ID.Hex()})
+		assert.NoError(t, err)
+	}
+	_, err := client.Follow(as(ada), &proto.FollowRequest{UserID: grace.ID.Hex()})
+	assert.NoError(t, err)
+
+	// latest follows first, hidden display names left out
+	res, err := client.ListFollowers(context.Background(), &proto.ListFollowsRequest{UserID: ada.ID.Hex(), PageSize: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), res.GetTotal())
+	if assert.Len(t, res.GetUsers(), 1) {
+		assert.Equal(t, "linus", res.GetUsers()[0].GetUsername())
+	}
+	next, err := client.ListFollowers(context.Background(), &proto.ListFollowsRequest{UserID: ada.ID.Hex(), PageSize: 1, PageToken: res.GetNextPageToken()})
+	assert.NoError(t, err)
+	if assert.Len(t, next.GetUsers(), 1) {
+		assert.Equal(t, grace.ID.Hex(), next.GetUsers()[0].GetID())
+		assert.Empty(t, next.GetUsers()[0].GetDisplayName())
+		assert.NotZero(t, next.GetUsers()[0].GetFollowedAt())
+	}
+	assert.Empty(t, next.GetNextPageToken())
+
+	res, err = client.ListFollowing(context.Background(), &proto.ListFollowsRequest{UserID: ada.ID.Hex()})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), res.GetTotal())
+	if assert.Len(t, res.GetUsers(), 1) {
+		assert.Equal(t, "grace", res.GetUsers()[0].GetUsername())
+	}
+	res, err = client.ListFollowing(context.Background(), &proto.ListFollowsRequest{UserID: grace.ID.Hex()})
+	assert.NoError(t, err)
+	if assert.Len(t, res.GetUsers(), 1) {
+		assert.Equal(t, "Ada L.", res.GetUsers()[0].GetDisplayName())
+	}
+
+	// tokens are bound to the user and the direction
+	first, err := client.ListFollowers(context.Background(), &proto.ListFollowsRequest{UserID: ada.ID.Hex(), PageSize: 1})
+	assert.NoError(t, err)
+	_, err = client.ListFollowing(context.Background(), &proto.ListFollowsRequest{UserID: ada.ID.Hex(), PageSize: 1, PageToken: first.GetNextPageToken()})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.ListFollowers(context.Background(), &proto.ListFollowsRequest{UserID: mallory.ID.Hex()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// streams send everything from the token on, a page at a time
+	stream, err := client.StreamFollowers(context.Background(), &proto.ListFollowsRequest{UserID: ada.ID.Hex(), PageSize: 1})
+	assert.NoError(t, err)
+	streamed := []string{}
+	for {
+		u, err := stream.Recv()
+		if err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+		streamed = append(streamed, u.GetUsername())
+	}
+	assert.Equal(t, []string{"linus", "grace"}, streamed)
+
+	stream, err = client.StreamFollowing(context.Background(), &proto.ListFollowsRequest{UserID: ada.ID.Hex(), PageToken: first.GetNextPageToken()})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
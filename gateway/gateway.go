@@ -0,0 +1,340 @@
+// Package gateway serves grpc methods as a JSON HTTP API and describes that
+// API with an OpenAPI document. Requests are forwarded to the grpc server
+// so that they go through the same interceptors and handlers.
+package gateway
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// request bodies are limited to 1MB
+const maxBodySize = 1 << 20
+
+// forwardedHeaders are passed on to the grpc method as metadata
+var forwardedHeaders = []string{"authorization", "x-request-id", "traceparent", "tracestate"}
+
+// Gateway maps HTTP routes to grpc methods
+type Gateway struct {
+	routes []*route
+}
+
+type route struct {
+	method   string
+	path     string
+	segments []string
+	rpc      string
+	desc     protoreflect.MethodDescriptor
+	in       protoreflect.MessageType
+	out      protoreflect.MessageType
+}
+
+// New returns a gateway without routes
+func New() *Gateway {
+	return &Gateway{}
+}
+
+// Handle maps method and path to the unary grpc method rpc, given by its
+// full name like "/proto.AuthService/Login". Path segments like {id} and
+// query parameters set the request fields of the same name, the JSON body
+// the others. It panics when rpc is not a registered unary method.
+func (g *Gateway) Handle(method, path, rpc string) *Gateway {
+	desc, err := findMethod(rpc)
+	if err != nil {
+		panic(err)
+	}
+	in, err := protoregistry.GlobalTypes.FindMessageByName(desc.Input().FullName())
+	if err != nil {
+		panic(err)
+	}
+	out, err := protoregistry.GlobalTypes.FindMessageByName(desc.Output().FullName())
+	if err != nil {
+		panic(err)
+	}
+
+	r := &route{method: method, path: path, segments: split(path), rpc: rpc, desc: desc, in: in, out: out}
+	for _, segment := range r.segments {
+		if name, ok := param(segment); ok && findField(in.Descriptor(), name) == nil {
+			panic(fmt.Sprintf("gateway: %s has no field %s", desc.Input().FullName(), name))
+		}
+	}
+	g.routes = append(g.routes, r)
+	return g
+}
+
+// findMethod looks up the descriptor of a unary method named like "/package.Service/Method"
+func findMethod(rpc string) (protoreflect.MethodDescriptor, error) {
+	parts := strings.Split(strings.TrimPrefix(rpc, "/"), "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("gateway: invalid method name %q", rpc)
+	}
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("gateway: unknown service %q", parts[0])
+	}
+	service, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("gateway: %q is not a service", parts[0])
+	}
+	method := service.Methods().ByName(protoreflect.Name(parts[1]))
+	if method == nil {
+		return nil, fmt.Errorf("gateway: unknown method %q", rpc)
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, fmt.Errorf("gateway: %q is a streaming method", rpc)
+	}
+	return method, nil
+}
+
+// Handler serves the routes by calling their methods on conn
+func (g *Gateway) Handler(conn grpc.ClientConnInterface) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		segments := split(r.URL.Path)
+		var allowed []string
+		for _, route := range g.routes {
+			params, ok := route.match(segments)
+			if !ok {
+				continue
+			}
+			if route.method != r.Method {
+				allowed = append(allowed, route.method)
+				continue
+			}
+			g.serve(w, r, conn, route, params)
+			return
+		}
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeError(w, http.StatusMethodNotAllowed, status.New(codes.Unimplemented, "Method not allowed"))
+			return
+		}
+		writeError(w, http.StatusNotFound, status.New(codes.NotFound, "Not found"))
+	})
+}
+
+func (g *Gateway) serve(w http.ResponseWriter, r *http.Request, conn grpc.ClientConnInterface, route *route, params map[string]string) {
+	in := route.in.New().Interface()
+	if err := decode(r, in.ProtoReflect(), params); err != nil {
+		writeStatus(w, status.New(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	md := metadata.MD{}
+	for _, key := range forwardedHeaders {
+		if value := r.Header.Get(key); value != "" {
+			md.Set(key, value)
+		}
+	}
+	if userAgent := r.Header.Get("User-Agent"); userAgent != "" {
+		md.Set("x-user-agent", userAgent)
+	}
+	// the grpc server sees the gateway as its peer, pass the client on
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		md.Set("x-forwarded-for", host)
+	}
+
+	out := route.out.New().Interface()
+	var header metadata.MD
+	err := conn.Invoke(metadata.NewOutgoingContext(r.Context(), md), route.rpc, in, out, grpc.Header(&header))
+	if values := header.Get("x-request-id"); len(values) > 0 {
+		w.Header().Set("X-Request-Id", values[0])
+	}
+	if err != nil {
+		writeStatus(w, status.Convert(err))
+		return
+	}
+
+	body, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(out)
+	if err != nil {
+		writeStatus(w, status.New(codes.Internal, "Internal Error"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// decode fills msg from the JSON body, then from the query and path parameters
+func decode(r *http.Request, msg protoreflect.Message, params map[string]string) error {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	if err != nil {
+		return fmt.Errorf("Error reading request body : %s", err.Error())
+	}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := protojson.Unmarshal(body, msg.Interface()); err != nil {
+			return fmt.Errorf("Invalid request body : %s", err.Error())
+		}
+	}
+
+	for name, values := range r.URL.Query() {
+		for _, value := range values {
+			if err := setField(msg, name, value); err != nil {
+				return err
+			}
+		}
+	}
+	for name, value := range params {
+		if err := setField(msg, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setField parses value into the scalar field of msg called name
+func setField(msg protoreflect.Message, name, value string) error {
+	field := findField(msg.Descriptor(), name)
+	if field == nil {
+		return fmt.Errorf("Unknown parameter %s", name)
+	}
+
+	var v protoreflect.Value
+	var err error
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(value)
+	case protoreflect.BoolKind:
+		var b bool
+		b, err = strconv.ParseBool(value)
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var i int64
+		i, err = strconv.ParseInt(value, 10, 32)
+		v = protoreflect.ValueOfInt32(int32(i))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var i int64
+		i, err = strconv.ParseInt(value, 10, 64)
+		v = protoreflect.ValueOfInt64(i)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var u uint64
+		u, err = strconv.ParseUint(value, 10, 32)
+		v = protoreflect.ValueOfUint32(uint32(u))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var u uint64
+		u, err = strconv.ParseUint(value, 10, 64)
+		v = protoreflect.ValueOfUint64(u)
+	case protoreflect.DoubleKind, protoreflect.FloatKind:
+		var f float64
+		f, err = strconv.ParseFloat(value, 64)
+		v = protoreflect.ValueOfFloat64(f)
+		if field.Kind() == protoreflect.FloatKind {
+			v = protoreflect.ValueOfFloat32(float32(f))
+		}
+	case protoreflect.EnumKind:
+		enum := field.Enum().Values().ByName(protoreflect.Name(value))
+		if enum == nil {
+			return fmt.Errorf("Invalid value for %s", name)
+		}
+		v = protoreflect.ValueOfEnum(enum.Number())
+	default:
+		return fmt.Errorf("Parameter %s cannot be set from the URL", name)
+	}
+	if err != nil {
+		return fmt.Errorf("Invalid value for %s", name)
+	}
+
+	if field.IsList() {
+		msg.Mutable(field).List().Append(v)
+	} else if field.IsMap() {
+		return fmt.Errorf("Parameter %s cannot be set from the URL", name)
+	} else {
+		msg.Set(field, v)
+	}
+	return nil
+}
+
+// findField returns the field of desc called name, ignoring case, so that
+// both ?username= and ?Username= set Username
+func findField(desc protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if strings.EqualFold(string(field.Name()), name) || strings.EqualFold(field.JSONName(), name) {
+			return field
+		}
+	}
+	return nil
+}
+
+// match reports whether segments match the route, with the path parameters
+func (r *route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, segment := range r.segments {
+		if name, ok := param(segment); ok {
+			params[name] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// param returns the name of a {name} path segment
+func param(segment string) (string, bool) {
+	if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+func split(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// writeStatus answers with the HTTP equivalent of the grpc status
+func writeStatus(w http.ResponseWriter, s *status.Status) {
+	writeError(w, HTTPStatus(s.Code()), s)
+}
+
+// writeError answers with s encoded like google.rpc.Status
+func writeError(w http.ResponseWriter, code int, s *status.Status) {
+	body, _ := protojson.Marshal(s.Proto())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+// HTTPStatus maps grpc codes to HTTP status codes
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		// nginx's "client closed request"
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,243 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeAuth echoes the requests and incoming metadata it gets back
+type fakeAuth struct {
+	proto.UnimplementedAuthServiceServer
+}
+
+func (f *fakeAuth) Login(ctx context.Context, in *proto.LoginRequest) (*proto.AuthResponse, error) {
+	if in.GetPassword() != "secret" {
+		return nil, status.Error(codes.Unauthenticated, "Invalid login credentials provided")
+	}
+	grpc.SetHeader(ctx, metadata.Pairs("x-request-id", "request-1"))
+	return &proto.AuthResponse{Token: "token-of-" + in.GetLogin()}, nil
+}
+
+func (f *fakeAuth) UsernameUsed(ctx context.Context, in *proto.UsernameUsedRequest) (*proto.UsedResponse, error) {
+	return &proto.UsedResponse{Used: in.GetUsername() == "taken"}, nil
+}
+
+func (f *fakeAuth) AuthUser(ctx context.Context, in *proto.AuthUserRequest) (*proto.AuthUserResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	get := func(key string) string { return strings.Join(md.Get(key), ";") }
+	return &proto.AuthUserResponse{
+		ID:       in.GetToken(),
+		Username: get("authorization"),
+		Email:    get("x-forwarded-for"),
+		Roles:    []string{get("x-user-agent"), get("x-request-id")},
+	}, nil
+}
+
+func newTestGateway(t *testing.T) http.Handler {
+	server := grpc.NewServer()
+	proto.RegisterAuthServiceServer(server, &fakeAuth{})
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	g := New().
+		Handle(http.MethodPost, "/v1/auth/login", "/proto.AuthService/Login").
+		Handle(http.MethodGet, "/v1/auth/usernames/{username}", "/proto.AuthService/UsernameUsed").
+		Handle(http.MethodGet, "/v1/auth/me", "/proto.AuthService/AuthUser")
+	return g.Handler(conn)
+}
+
+func Test_Gateway_Handler(t *testing.T) {
+
+	handler := newTestGateway(t)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"method": http.MethodPost,
+			"path":   "/v1/auth/login",
+			"body":   `{"Login": "someone", "Password": "secret"}`,
+			"code":   http.StatusOK,
+			"json":   map[string]interface{}{"Token": "token-of-someone"},
+		},
+		map[string]interface{}{
+			// trailing slashes are ignored
+			"method": http.MethodPost,
+			"path":   "/v1/auth/login/",
+			"body":   `{"Login": "someone", "Password": "wrong"}`,
+			"code":   http.StatusUnauthorized,
+			"json":   map[string]interface{}{"code": float64(codes.Unauthenticated), "message": "Invalid login credentials provided"},
+		},
+		map[string]interface{}{
+			"method": http.MethodPost,
+			"path":   "/v1/auth/login",
+			"body":   `{"Login": `,
+			"code":   http.StatusBadRequest,
+		},
+		map[string]interface{}{
+			"method": http.MethodPost,
+			"path":   "/v1/auth/login",
+			"body":   `{"Unknown": "field"}`,
+			"code":   http.StatusBadRequest,
+		},
+		map[string]interface{}{
+			"method": http.MethodGet,
+			"path":   "/v1/auth/usernames/taken",
+			"code":   http.StatusOK,
+			"json":   map[string]interface{}{"Used": true},
+		},
+		map[string]interface{}{
+			// unset fields are written too
+			"method": http.MethodGet,
+			"path":   "/v1/auth/usernames/free",
+			"code":   http.StatusOK,
+			"json":   map[string]interface{}{"Used": false},
+		},
+		map[string]interface{}{
+			"method": http.MethodGet,
+			"path":   "/v1/auth/me?token=abc",
+			"code":   http.StatusOK,
+			"json": map[string]interface{}{
+				"ID": "abc", "Username": "", "Email": "192.0.2.1",
+				"Roles": []interface{}{"", ""},
+			},
+		},
+		map[string]interface{}{
+			"method": http.MethodGet,
+			"path":   "/v1/auth/me",
+			"headers": map[string]string{
+				"Authorization":   "Bearer abc",
+				"X-Forwarded-For": "203.0.113.7",
+				"User-Agent":      "script/1.0",
+				"X-Request-Id":    "request-2",
+			},
+			"code": http.StatusOK,
+			"json": map[string]interface{}{
+				"ID": "", "Username": "Bearer abc", "Email": "192.0.2.1",
+				"Roles": []interface{}{"script/1.0", "request-2"},
+			},
+		},
+		map[string]interface{}{
+			"method": http.MethodGet,
+			"path":   "/v1/auth/me?unknown=1",
+			"code":   http.StatusBadRequest,
+		},
+		map[string]interface{}{
+			"method": http.MethodGet,
+			"path":   "/v1/auth/login",
+			"code":   http.StatusMethodNotAllowed,
+		},
+		map[string]interface{}{
+			"method": http.MethodGet,
+			"path":   "/v1/auth/unknown",
+			"code":   http.StatusNotFound,
+		},
+	}
+
+	for _, tcase := range testCases {
+		body, _ := tcase["body"].(string)
+		req := httptest.NewRequest(tcase["method"].(string), tcase["path"].(string), strings.NewReader(body))
+		if headers, ok := tcase["headers"]; ok {
+			for key, value := range headers.(map[string]string) {
+				req.Header.Set(key, value)
+			}
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equalf(t, tcase["code"], rec.Code, "case: %v", tcase)
+		assert.Equalf(t, "application/json", rec.Header().Get("Content-Type"), "case: %v", tcase)
+		if expected, ok := tcase["json"]; ok {
+			var got map[string]interface{}
+			json.Unmarshal(rec.Body.Bytes(), &got)
+			assert.Equalf(t, expected, got, "case: %v", tcase)
+		}
+	}
+}
+
+func Test_Gateway_Handler_headers(t *testing.T) {
+
+	handler := newTestGateway(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/login", strings.NewReader(`{"Login": "someone", "Password": "secret"}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "request-1", rec.Header().Get("X-Request-Id"))
+
+	req = httptest.NewRequest(http.MethodDelete, "/v1/auth/login", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+}
+
+func Test_Gateway_Handle_panics(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"path": "/v1/login",
+			"rpc":  "proto.AuthService.Login",
+		},
+		map[string]interface{}{
+			"path": "/v1/login",
+			"rpc":  "/proto.UnknownService/Login",
+		},
+		map[string]interface{}{
+			"path": "/v1/login",
+			"rpc":  "/proto.AuthService/Unknown",
+		},
+		map[string]interface{}{
+			"path": "/v1/login/{unknown}",
+			"rpc":  "/proto.AuthService/Login",
+		},
+	}
+
+	for _, tcase := range testCases {
+		assert.Panicsf(t, func() {
+			New().Handle(http.MethodPost, tcase["path"].(string), tcase["rpc"].(string))
+		}, "case: %v", tcase)
+	}
+}
+
+func Test_HTTPStatus(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"code": codes.OK, "status": http.StatusOK},
+		map[string]interface{}{"code": codes.InvalidArgument, "status": http.StatusBadRequest},
+		map[string]interface{}{"code": codes.Unauthenticated, "status": http.StatusUnauthorized},
+		map[string]interface{}{"code": codes.PermissionDenied, "status": http.StatusForbidden},
+		map[string]interface{}{"code": codes.NotFound, "status": http.StatusNotFound},
+		map[string]interface{}{"code": codes.AlreadyExists, "status": http.StatusConflict},
+		map[string]interface{}{"code": codes.ResourceExhausted, "status": http.StatusTooManyRequests},
+		map[string]interface{}{"code": codes.Unavailable, "status": http.StatusServiceUnavailable},
+		map[string]interface{}{"code": codes.Unknown, "status": http.StatusInternalServerError},
+		map[string]interface{}{"code": codes.Internal, "status": http.StatusInternalServerError},
+	}
+
+	for _, tcase := range testCases {
+		assert.Equalf(t, tcase["status"], HTTPStatus(tcase["code"].(codes.Code)), "case: %v", tcase)
+	}
+}
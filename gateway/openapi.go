@@ -0,0 +1,239 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/validate"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// statusSchema is the name of the error schema, google.rpc.Status
+const statusSchema = "Status"
+
+// OpenAPI returns the OpenAPI 3 document of the routes, generated from
+// the descriptors of their methods
+func (g *Gateway) OpenAPI(title, version string) map[string]interface{} {
+	schemas := map[string]interface{}{
+		statusSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"code":    map[string]interface{}{"type": "integer", "format": "int32", "description": "grpc status code"},
+				"message": map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+	paths := map[string]interface{}{}
+
+	for _, r := range g.routes {
+		in, out := r.desc.Input(), r.desc.Output()
+		addSchema(schemas, in)
+		addSchema(schemas, out)
+
+		operation := map[string]interface{}{
+			"operationId": string(r.desc.Parent().Name()) + "_" + string(r.desc.Name()),
+			"tags":        []string{string(r.desc.Parent().Name())},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "OK",
+					"content":     jsonContent(ref(out)),
+				},
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/" + statusSchema}),
+				},
+			},
+		}
+
+		parameters := []interface{}{}
+		inPath := map[protoreflect.Name]bool{}
+		for _, segment := range r.segments {
+			if name, ok := param(segment); ok {
+				field := findField(in, name)
+				inPath[field.Name()] = true
+				parameters = append(parameters, map[string]interface{}{
+					"name": name, "in": "path", "required": true, "schema": fieldSchema(schemas, field),
+				})
+			}
+		}
+		if r.method == http.MethodGet || r.method == http.MethodDelete {
+			// without a body every other scalar field is a query parameter
+			fields := in.Fields()
+			for i := 0; i < fields.Len(); i++ {
+				field := fields.Get(i)
+				if inPath[field.Name()] || field.Kind() == protoreflect.MessageKind || field.IsMap() {
+					continue
+				}
+				parameters = append(parameters, map[string]interface{}{
+					"name": field.JSONName(), "in": "query", "schema": fieldSchema(schemas, field),
+				})
+			}
+		} else {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(ref(in)),
+			}
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		item, ok := paths[r.path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[r.path] = item
+		}
+		item[strings.ToLower(r.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]interface{}{"title": title, "version": version},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		// public routes need no token, guarded ones reject requests without
+		"security": []interface{}{
+			map[string]interface{}{"bearer": []string{}},
+			map[string]interface{}{},
+		},
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document as JSON
+func (g *Gateway) OpenAPIHandler(title, version string) http.Handler {
+	doc, err := json.MarshalIndent(g.OpenAPI(title, version), "", "  ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	})
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+func ref(desc protoreflect.MessageDescriptor) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + schemaName(desc)}
+}
+
+func schemaName(desc protoreflect.MessageDescriptor) string {
+	return strings.ReplaceAll(string(desc.FullName()), ".", "_")
+}
+
+// addSchema adds the schema of desc and of the messages it refers to
+func addSchema(schemas map[string]interface{}, desc protoreflect.MessageDescriptor) {
+	name := schemaName(desc)
+	if _, ok := schemas[name]; ok {
+		return
+	}
+	properties := map[string]interface{}{}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	// set before the fields, messages may refer to themselves
+	schemas[name] = schema
+
+	var required []string
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		property := fieldSchema(schemas, field)
+		if rules := validate.Rules(field); rules != nil {
+			if rules.GetRequired() {
+				required = append(required, field.JSONName())
+			}
+			addRules(property, rules)
+		}
+		properties[field.JSONName()] = property
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+}
+
+// addRules documents the validation rules of a string field in its schema
+func addRules(schema map[string]interface{}, rules *proto.FieldRules) {
+	if schema["type"] == "array" {
+		schema = schema["items"].(map[string]interface{})
+	}
+	if rules.GetMinLen() > 0 {
+		schema["minLength"] = rules.GetMinLen()
+	}
+	if rules.GetMaxLen() > 0 {
+		schema["maxLength"] = rules.GetMaxLen()
+	}
+	switch rules.GetFormat() {
+	case "email":
+		schema["format"] = "email"
+	case "object_id":
+		schema["pattern"] = "^[0-9a-f]{24}$"
+	case "url":
+		schema["format"] = "uri"
+	}
+	if len(rules.GetIn()) > 0 {
+		schema["enum"] = rules.GetIn()
+	}
+}
+
+// fieldSchema returns the schema of field as encoded by protojson
+func fieldSchema(schemas map[string]interface{}, field protoreflect.FieldDescriptor) map[string]interface{} {
+	if field.IsMap() {
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": valueSchema(schemas, field.MapValue()),
+		}
+	}
+	if field.IsList() {
+		return map[string]interface{}{"type": "array", "items": valueSchema(schemas, field)}
+	}
+	return valueSchema(schemas, field)
+}
+
+func valueSchema(schemas map[string]interface{}, field protoreflect.FieldDescriptor) map[string]interface{} {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]interface{}{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]interface{}{"type": "integer", "format": "int64", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// protojson writes 64-bit integers as strings, and reads both
+		return map[string]interface{}{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case protoreflect.BytesKind:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		names := make([]string, values.Len())
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+		return map[string]interface{}{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		msg := field.Message()
+		switch msg.FullName() {
+		case "google.protobuf.Timestamp":
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		case "google.protobuf.Duration":
+			return map[string]interface{}{"type": "string", "example": "1.5s"}
+		}
+		addSchema(schemas, msg)
+		return ref(msg)
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
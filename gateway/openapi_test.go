@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Gateway_OpenAPI(t *testing.T) {
+
+	g := New().
+		Handle(http.MethodPost, "/v1/auth/login", "/proto.AuthService/Login").
+		Handle(http.MethodGet, "/v1/auth/usernames/{username}", "/proto.AuthService/UsernameUsed").
+		Handle(http.MethodGet, "/v1/admin/users", "/proto.AdminService/ListUsers")
+
+	rec := httptest.NewRecorder()
+	g.OpenAPIHandler("blog-application", "v1").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// compared as decoded JSON, the way clients read it
+	var doc map[string]interface{}
+	if !assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc)) {
+		t.FailNow()
+	}
+	get := func(path ...string) interface{} {
+		var v interface{} = doc
+		for _, key := range path {
+			v = v.(map[string]interface{})[key]
+		}
+		return v
+	}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"path":  []string{"openapi"},
+			"value": "3.0.3",
+		},
+		map[string]interface{}{
+			"path":  []string{"paths", "/v1/auth/login", "post", "operationId"},
+			"value": "AuthService_Login",
+		},
+		map[string]interface{}{
+			"path":  []string{"paths", "/v1/auth/login", "post", "requestBody", "content", "application/json", "schema", "$ref"},
+			"value": "#/components/schemas/proto_LoginRequest",
+		},
+		map[string]interface{}{
+			"path":  []string{"paths", "/v1/auth/login", "post", "responses", "200", "content", "application/json", "schema", "$ref"},
+			"value": "#/components/schemas/proto_AuthResponse",
+		},
+		map[string]interface{}{
+			"path":  []string{"paths", "/v1/auth/login", "post", "responses", "default", "content", "application/json", "schema", "$ref"},
+			"value": "#/components/schemas/Status",
+		},
+		map[string]interface{}{
+			"path": []string{"paths", "/v1/auth/usernames/{username}", "get", "parameters"},
+			"value": []interface{}{map[string]interface{}{
+				"name": "username", "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+			}},
+		},
+		map[string]interface{}{
+			"path":  []string{"components", "schemas", "proto_UsedResponse", "properties", "Used"},
+			"value": map[string]interface{}{"type": "boolean"},
+		},
+		map[string]interface{}{
+			// 64-bit integers are strings in protojson
+			"path":  []string{"components", "schemas", "proto_ListUsersResponse", "properties", "Total"},
+			"value": map[string]interface{}{"type": "string", "format": "int64"},
+		},
+		map[string]interface{}{
+			// nested messages get schemas of their own
+			"path":  []string{"components", "schemas", "proto_ListUsersResponse", "properties", "Users"},
+			"value": map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/components/schemas/proto_UserInfo"}},
+		},
+		map[string]interface{}{
+			"path":  []string{"components", "schemas", "proto_UserInfo", "properties", "Roles"},
+			"value": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		map[string]interface{}{
+			// validation rules are part of the schemas
+			"path":  []string{"components", "schemas", "proto_LoginRequest", "required"},
+			"value": []interface{}{"Login", "Password"},
+		},
+		map[string]interface{}{
+			"path":  []string{"components", "schemas", "proto_LoginRequest", "properties", "Login"},
+			"value": map[string]interface{}{"type": "string", "maxLength": float64(35)},
+		},
+		map[string]interface{}{
+			"path":  []string{"components", "schemas", "proto_ListUsersRequest", "properties", "Status"},
+			"value": map[string]interface{}{"type": "string", "enum": []interface{}{"active", "suspended"}},
+		},
+		map[string]interface{}{
+			"path":  []string{"components", "securitySchemes", "bearer", "scheme"},
+			"value": "bearer",
+		},
+	}
+
+	for _, tcase := range testCases {
+		assert.Equalf(t, tcase["value"], get(tcase["path"].([]string)...), "case: %v", tcase)
+	}
+
+	// GET requests take every field as a query parameter
+	params := get("paths", "/v1/admin/users", "get", "parameters").([]interface{})
+	names := []string{}
+	for _, p := range params {
+		assert.Equal(t, "query", p.(map[string]interface{})["in"])
+		names = append(names, p.(map[string]interface{})["name"].(string))
+	}
+	assert.Contains(t, names, "Query")
+	assert.Contains(t, names, "PageSize")
+}
@@ -2,50 +2,17 @@ package global
 
 import (
 	"context"
-	"log"
 	"time"
-
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// DB holds database connection
-var DB mongo.Database
-
-func connectToDatabase() {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(dburl))
-	if err != nil {
-		log.Fatal("Error connecting to db : ", err.Error())
-	}
-
-	DB = *client.Database(dbname)
-}
-
 // NewDBContext returns a new DB context according to app performance
 func NewDBContext(d time.Duration) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), d*performance/100)
+	return NewDBContextFrom(context.Background(), d)
 }
 
-// ConnectToTestDatabase overrides DB with test database
-func ConnectToTestDatabase() {
-	ctx, cancel := NewDBContext(10 * time.Second)
-	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(dburl))
-	if err != nil {
-		log.Fatal("Error connecting to db : ", err.Error())
-	}
-
-	// drop all previous test collections if exists
-	DB = *client.Database(dbname + "_test")
-	ctx, cancel = NewDBContext(30 * time.Second)
-	defer cancel()
-	collections, _ := DB.ListCollectionNames(ctx, bson.M{})
-	for _, collection := range collections {
-		ctx, cancel = NewDBContext(10 * time.Second)
-		defer cancel()
-		DB.Collection(collection).Drop(ctx)
-	}
+// NewDBContextFrom returns a new DB context according to app performance, derived
+// from the request context so that db calls are cancelled with the request and
+// traced as part of it
+func NewDBContextFrom(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, d*performance/100)
 }
@@ -1,5 +0,0 @@
-package global
-
-func init() {
-	connectToDatabase()
-}
@@ -1,8 +1,11 @@
 package global
 
 import (
+	"context"
 	"encoding/json"
+	"time"
 
+	"github.com/HiteshRepo/blog-application/policy"
 	"github.com/dgrijalva/jwt-go"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -16,13 +19,79 @@ type User struct {
 	Username string             `bson:"username"`
 	Email    string             `bson:"email"`
 	Password string             `bson:"password"`
+	Roles    []policy.Role      `bson:"roles"`
+
+	// suspended users can neither login nor use tokens issued earlier
+	Suspended     bool   `bson:"suspended"`
+	SuspendReason string `bson:"suspend_reason,omitempty"`
+
+	// TokenVersion is bumped to invalidate every token issued before
+	TokenVersion int `bson:"token_version"`
+
+	// Profile is shown on the user's public page, it stays out of tokens
+	Profile Profile `bson:"profile" json:"-"`
+}
+
+// Profile is what users tell others about themselves
+type Profile struct {
+	DisplayName string  `bson:"display_name,omitempty"`
+	Bio         string  `bson:"bio,omitempty"`
+	AvatarURL   string  `bson:"avatar_url,omitempty"`
+	Privacy     Privacy `bson:"privacy"`
+}
+
+// Privacy hides fields of the public profile, everything is shown by default
+type Privacy struct {
+	HideDisplayName bool `bson:"hide_display_name,omitempty"`
+	HideBio         bool `bson:"hide_bio,omitempty"`
+	HideAvatar      bool `bson:"hide_avatar,omitempty"`
+	HideJoined      bool `bson:"hide_joined,omitempty"`
+	HidePostCount   bool `bson:"hide_post_count,omitempty"`
+}
+
+type userKey struct{}
+
+// IsNil reports whether u is an empty user record
+func (u User) IsNil() bool {
+	return u.ID.IsZero()
+}
+
+// CreatedAt returns when the user signed up
+func (u User) CreatedAt() time.Time {
+	return u.ID.Timestamp()
+}
+
+// GetRoles returns the user's roles, falling back to the default role for
+// accounts created before roles existed
+func (u User) GetRoles() []policy.Role {
+	if len(u.Roles) == 0 {
+		return []policy.Role{policy.DefaultRole}
+	}
+	return u.Roles
+}
+
+// HasRole reports whether the user holds role
+func (u User) HasRole(role policy.Role) bool {
+	for _, r := range u.GetRoles() {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether any of the user's roles grants perm
+func (u User) Can(perm policy.Permission) bool {
+	return policy.Can(u.GetRoles(), perm)
 }
 
 // GetToken returns the user's JWT
 func (u User) GetToken() string {
+	u.Roles = u.GetRoles()
 	byteSlc, _ := json.Marshal(u)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"data": string(byteSlc),
+		"data":  string(byteSlc),
+		"roles": u.Roles,
 	})
 	tokenString, _ := token.SignedString(jwtSecret)
 	return tokenString
@@ -30,9 +99,13 @@ func (u User) GetToken() string {
 
 func UserFromToken(token string) User {
 	claims := jwt.MapClaims{}
-	jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+	parsed, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
 	})
+	// roles travel inside the token, so a bad signature must never yield a user
+	if err != nil || !parsed.Valid {
+		return NilUser
+	}
 	var result User
 	if claims["data"] != nil {
 		json.Unmarshal([]byte(claims["data"].(string)), &result)
@@ -41,3 +114,17 @@ func UserFromToken(token string) User {
 	}
 	return result
 }
+
+// ContextWithUser returns a copy of ctx carrying the authenticated user
+func ContextWithUser(ctx context.Context, u User) context.Context {
+	return context.WithValue(ctx, userKey{}, u)
+}
+
+// UserFromContext returns the authenticated user stored in ctx, or NilUser
+func UserFromContext(ctx context.Context) User {
+	u, ok := ctx.Value(userKey{}).(User)
+	if !ok {
+		return NilUser
+	}
+	return u
+}
@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check reports whether a dependency is usable
+type Check func(ctx context.Context) error
+
+// Checker runs readiness checks and mirrors their result into the grpc.health.v1 service
+type Checker struct {
+	checks  map[string]Check
+	timeout time.Duration
+	grpc    *health.Server
+
+	mu       sync.Mutex
+	draining bool
+}
+
+// NewChecker returns a checker running checks with the given timeout each
+func NewChecker(timeout time.Duration, checks map[string]Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout, grpc: health.NewServer()}
+}
+
+// GRPCServer returns the grpc.health.v1 implementation to register on a grpc server
+func (c *Checker) GRPCServer() *health.Server {
+	return c.grpc
+}
+
+// Check runs every check and returns the failures by name
+func (c *Checker) Check(ctx context.Context) map[string]string {
+	failures := map[string]string{}
+	if c.isDraining() {
+		failures["server"] = "shutting down"
+		return failures
+	}
+	for name, check := range c.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		if err := check(checkCtx); err != nil {
+			failures[name] = err.Error()
+		}
+		cancel()
+	}
+	return failures
+}
+
+// Watch runs the checks every interval until ctx is done and updates the grpc health status
+func (c *Checker) Watch(ctx context.Context, interval time.Duration) {
+	c.refresh(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.refresh(ctx)
+		}
+	}
+}
+
+func (c *Checker) refresh(ctx context.Context) {
+	status := healthpb.HealthCheckResponse_SERVING
+	if len(c.Check(ctx)) > 0 {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	// the empty service name stands for the whole server
+	c.grpc.SetServingStatus("", status)
+}
+
+// Drain marks the server as not ready, so that load balancers stop
+// routing to it while in-flight requests finish
+func (c *Checker) Drain() {
+	c.mu.Lock()
+	c.draining = true
+	c.mu.Unlock()
+	c.grpc.Shutdown()
+}
+
+func (c *Checker) isDraining() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.draining
+}
+
+// LivenessHandler answers 200 as long as the process can serve HTTP
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+	})
+}
+
+// ReadinessHandler answers 200 when every check passes and 503 with the failures otherwise
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failures := c.Check(r.Context())
+		if len(failures) > 0 {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"status": "unavailable", "failures": failures})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,93 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func Test_ReadinessHandler(t *testing.T) {
+
+	mongoErr := errors.New("server selection timeout")
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"checks": map[string]Check{"mongo": func(context.Context) error { return nil }},
+			"code":   http.StatusOK,
+		},
+		map[string]interface{}{
+			"checks":   map[string]Check{"mongo": func(context.Context) error { return mongoErr }},
+			"code":     http.StatusServiceUnavailable,
+			"failures": map[string]interface{}{"mongo": mongoErr.Error()},
+		},
+		map[string]interface{}{
+			"checks": map[string]Check{"slow": func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}},
+			"code":     http.StatusServiceUnavailable,
+			"failures": map[string]interface{}{"slow": context.DeadlineExceeded.Error()},
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		checker := NewChecker(10*time.Millisecond, tcase["checks"].(map[string]Check))
+		rec := httptest.NewRecorder()
+		checker.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+		assert.Equalf(t, tcase["code"], rec.Code, "case: %v", tcase)
+
+		var body map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if failures, ok := tcase["failures"]; ok {
+			assert.Equalf(t, failures, body["failures"], "case: %v", tcase)
+		}
+	}
+}
+
+func Test_LivenessHandler(t *testing.T) {
+
+	rec := httptest.NewRecorder()
+	LivenessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func Test_Checker_Watch(t *testing.T) {
+
+	healthy := true
+	checker := NewChecker(time.Second, map[string]Check{"mongo": func(context.Context) error {
+		if healthy {
+			return nil
+		}
+		return errors.New("down")
+	}})
+
+	status := func() healthpb.HealthCheckResponse_ServingStatus {
+		res, err := checker.GRPCServer().Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if err != nil {
+			return healthpb.HealthCheckResponse_UNKNOWN
+		}
+		return res.GetStatus()
+	}
+
+	checker.refresh(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status())
+
+	healthy = false
+	checker.refresh(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status())
+
+	// draining wins over healthy checks
+	healthy = true
+	checker.Drain()
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status())
+	assert.Equal(t, map[string]string{"server": "shutting down"}, checker.Check(context.Background()))
+}
@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDKey is the metadata key carrying the correlation id of a request
+const RequestIDKey = "x-request-id"
+
+type requestIDKey struct{}
+
+// UnaryServerInterceptor tags every call with a request id, taken from the
+// incoming metadata or generated, and logs its method, latency and status code.
+// Request and response payloads are never logged.
+func UnaryServerInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		requestID := requestIDFromMetadata(ctx)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, requestID))
+
+		reqLogger := logger.With(zap.String("request_id", requestID), zap.String("method", info.FullMethod))
+		ctx = context.WithValue(ContextWithLogger(ctx, reqLogger), requestIDKey{}, requestID)
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		fields := []zap.Field{
+			zap.String("code", code.String()),
+			zap.Duration("latency", time.Since(start)),
+		}
+		if err != nil {
+			fields = append(fields, zap.Error(err))
+		}
+		reqLogger.Check(levelFor(code), "finished call").Write(fields...)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor does for streams what UnaryServerInterceptor does
+// for unary calls, the call is logged when the stream ends
+func StreamServerInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+
+		requestID := requestIDFromMetadata(ss.Context())
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		ss.SetHeader(metadata.Pairs(RequestIDKey, requestID))
+
+		reqLogger := logger.With(zap.String("request_id", requestID), zap.String("method", info.FullMethod))
+		ctx := context.WithValue(ContextWithLogger(ss.Context(), reqLogger), requestIDKey{}, requestID)
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+
+		code := status.Code(err)
+		fields := []zap.Field{
+			zap.String("code", code.String()),
+			zap.Duration("latency", time.Since(start)),
+		}
+		if err != nil {
+			fields = append(fields, zap.Error(err))
+		}
+		reqLogger.Check(levelFor(code), "finished call").Write(fields...)
+
+		return err
+	}
+}
+
+// serverStream is a stream with the context of the request
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// RequestIDFromContext returns the correlation id of the current request
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func requestIDFromMetadata(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(RequestIDKey); len(values) > 0 && len(values[0]) <= 128 {
+		return values[0]
+	}
+	return ""
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// levelFor maps a status code to the level its call is logged at:
+// caller mistakes are warnings, server faults are errors.
+// Plain errors returned by handlers arrive as Unknown and are mostly caller
+// mistakes, handlers log their own internal failures.
+func levelFor(code codes.Code) zapcore.Level {
+	switch code {
+	case codes.OK:
+		return zapcore.InfoLevel
+	case codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented, codes.DeadlineExceeded:
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.WarnLevel
+	}
+}
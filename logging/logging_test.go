@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func Test_Redact(t *testing.T) {
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(Redact(core)).With(zap.String("Authorization", "Bearer abc"))
+
+	fields := []zap.Field{zap.String("password", "hunter22"), zap.String("username", "test-user"), zap.String("refresh_token", "xyz")}
+	logger.Info("login", fields...)
+
+	entry := logs.All()[0].ContextMap()
+	assert.Equal(t, redacted, entry["Authorization"])
+	assert.Equal(t, redacted, entry["password"])
+	assert.Equal(t, redacted, entry["refresh_token"])
+	assert.Equal(t, "test-user", entry["username"])
+
+	// the caller's fields are left untouched
+	assert.Equal(t, "hunter22", fields[0].String)
+}
+
+func Test_New(t *testing.T) {
+
+	_, err := New("debug")
+	assert.NoError(t, err)
+
+	_, err = New("verbose")
+	assert.Error(t, err)
+}
+
+func Test_UnaryServerInterceptor(t *testing.T) {
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	interceptor := UnaryServerInterceptor(zap.New(core))
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.AuthService/Login"}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"requestID": "client-supplied-id",
+			"err":       nil,
+			"level":     zapcore.InfoLevel,
+			"code":      "OK",
+		},
+		map[string]interface{}{
+			"requestID": "",
+			"err":       errors.New("Invalid login credentials provided"),
+			"level":     zapcore.WarnLevel,
+			"code":      "Unknown",
+		},
+		map[string]interface{}{
+			"requestID": "",
+			"err":       status.Error(codes.Internal, "boom"),
+			"level":     zapcore.ErrorLevel,
+			"code":      "Internal",
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		ctx := context.Background()
+		if id := tcase["requestID"].(string); id != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(RequestIDKey, id))
+		}
+
+		var seenID string
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			seenID = RequestIDFromContext(ctx)
+			FromContext(ctx).Debug("inside handler")
+			err, _ := tcase["err"].(error)
+			return nil, err
+		}
+		interceptor(ctx, nil, info, handler)
+
+		entries := logs.TakeAll()
+		if !assert.Lenf(t, entries, 2, "case: %v", tcase) {
+			continue
+		}
+		if id := tcase["requestID"].(string); id != "" {
+			assert.Equalf(t, id, seenID, "case: %v", tcase)
+		} else {
+			assert.Lenf(t, seenID, 32, "case: %v", tcase)
+		}
+
+		// both lines carry the same correlation fields
+		for _, e := range entries {
+			assert.Equalf(t, seenID, e.ContextMap()["request_id"], "case: %v", tcase)
+			assert.Equalf(t, info.FullMethod, e.ContextMap()["method"], "case: %v", tcase)
+		}
+		assert.Equalf(t, tcase["level"], entries[1].Level, "case: %v", tcase)
+		assert.Equalf(t, tcase["code"], entries[1].ContextMap()["code"], "case: %v", tcase)
+	}
+}
@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const redacted = "[REDACTED]"
+
+// field names that may never reach the logs with their value
+var secretKeys = []string{"password", "token", "authorization", "secret", "cookie"}
+
+type redactCore struct {
+	zapcore.Core
+}
+
+// Redact wraps core so that any field whose key looks like a secret
+// is written as [REDACTED], whatever its value
+func Redact(core zapcore.Core) zapcore.Core {
+	return redactCore{core}
+}
+
+func (c redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return redactCore{c.Core.With(redactFields(fields))}
+}
+
+func (c redactCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(e.Level) {
+		return ce.AddCore(e, c)
+	}
+	return ce
+}
+
+func (c redactCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(e, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	out, copied := fields, false
+	for i, f := range fields {
+		if !isSecret(f.Key) {
+			continue
+		}
+		// never modify the caller's slice
+		if !copied {
+			out, copied = append([]zapcore.Field(nil), fields...), true
+		}
+		out[i] = zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: redacted}
+	}
+	return out
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
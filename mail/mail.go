@@ -0,0 +1,129 @@
+// Package mail sends emails through a pluggable Mailer, an SMTP server in
+// production and the log on local runs.
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type logMailer struct {
+	logger *zap.Logger
+}
+
+// NewLog returns a mailer that writes messages to logger instead of sending
+// them, for local runs
+func NewLog(logger *zap.Logger) Mailer {
+	return &logMailer{logger: logger}
+}
+
+func (l *logMailer) Send(ctx context.Context, msg Message) error {
+	l.logger.Info("Email not sent, no SMTP server set", zap.String("to", msg.To), zap.String("subject", msg.Subject), zap.String("body", msg.Body))
+	return nil
+}
+
+// SMTPConfig sets up the SMTP mailer
+type SMTPConfig struct {
+	// Addr is the host:port of the server. Connections switch to TLS when
+	// it offers STARTTLS.
+	Addr string
+	// From is the sender of every message
+	From string
+	// Username and Password authenticate with PLAIN when Username is set
+	Username string
+	Password string
+}
+
+type smtpMailer struct {
+	cfg SMTPConfig
+}
+
+// NewSMTP returns a mailer sending through the server of cfg, one connection
+// per message
+func NewSMTP(cfg SMTPConfig) Mailer {
+	return &smtpMailer{cfg: cfg}
+}
+
+// Send gives up when ctx is done, net/smtp itself takes no context
+func (s *smtpMailer) Send(ctx context.Context, msg Message) error {
+	host, _, err := net.SplitHostPort(s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		// PLAIN refuses to send the password in clear to anything but localhost
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(format(s.cfg.From, msg, time.Now())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// format returns msg from from as sent on the wire
+func format(from string, msg Message, date time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,28 @@
+package mail
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_format(t *testing.T) {
+
+	date := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	got := format("blog@example.com", Message{To: "ada@example.com", Subject: "2 new notifications", Body: "Hi ada,\n\n- grace followed you\n"}, date)
+	assert.Equal(t, "From: blog@example.com\r\n"+
+		"To: ada@example.com\r\n"+
+		"Subject: 2 new notifications\r\n"+
+		"Date: Thu, 04 Mar 2021 05:06:07 +0000\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n"+
+		"\r\n"+
+		"Hi ada,\r\n\r\n- grace followed you\r\n", string(got))
+}
+
+func Test_NewLog(t *testing.T) {
+	assert.NoError(t, NewLog(zap.NewNop()).Send(context.Background(), Message{To: "ada@example.com"}))
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor counts every RPC and observes its latency
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		RequestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		RequestsTotal.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor counts every stream and observes how long it lasted
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+
+		RequestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		RequestsTotal.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		return err
+	}
+}
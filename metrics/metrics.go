@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// RequestsTotal counts finished RPCs by method and status code
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Total number of RPCs completed on the server, regardless of success or failure.",
+	}, []string{"method", "code"})
+
+	// RequestDuration observes RPC latency by method
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Histogram of response latency of RPCs handled by the server.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// MongoDuration observes mongo command latency by collection, command and outcome
+	MongoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mongo_command_duration_seconds",
+		Help:    "Histogram of mongo command latency.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"collection", "command", "outcome"})
+
+	// Signups counts accounts created
+	Signups = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "blog_signups_total",
+		Help: "Total number of successful signups.",
+	})
+
+	// Logins counts successful logins
+	Logins = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "blog_logins_total",
+		Help: "Total number of successful logins.",
+	})
+
+	// FailedLogins counts rejected logins by reason
+	FailedLogins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blog_failed_logins_total",
+		Help: "Total number of rejected logins.",
+	}, []string{"reason"})
+)
+
+func init() {
+	prometheus.MustRegister(RequestsTotal, RequestDuration, MongoDuration, Signups, Logins, FailedLogins)
+}
+
+// Handler serves every registered metric in the prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"google.golang.org/grpc"
+)
+
+func Test_UnaryServerInterceptor(t *testing.T) {
+
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.AuthService/Login"}
+
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+	fail := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.New("Invalid login credentials provided")
+	}
+
+	interceptor(context.Background(), nil, info, ok)
+	interceptor(context.Background(), nil, info, ok)
+	interceptor(context.Background(), nil, info, fail)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(RequestsTotal.WithLabelValues(info.FullMethod, "OK")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(RequestsTotal.WithLabelValues(info.FullMethod, "Unknown")))
+	assert.Equal(t, 1, testutil.CollectAndCount(RequestDuration))
+}
+
+func Test_MongoMonitor(t *testing.T) {
+
+	monitor := MongoMonitor()
+
+	find, _ := bson.Marshal(bson.D{{Key: "find", Value: "user"}, {Key: "filter", Value: bson.D{}}})
+	getMore, _ := bson.Marshal(bson.D{{Key: "getMore", Value: int64(42)}, {Key: "collection", Value: "audit_log"}})
+
+	monitor.Started(context.Background(), &event.CommandStartedEvent{Command: find, CommandName: "find", RequestID: 1})
+	monitor.Started(context.Background(), &event.CommandStartedEvent{Command: getMore, CommandName: "getMore", RequestID: 2})
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1, DurationNanos: 2e6}})
+	monitor.Failed(context.Background(), &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "getMore", RequestID: 2, DurationNanos: 1e6}})
+
+	assert.Equal(t, 2, testutil.CollectAndCount(MongoDuration))
+	assert.Equal(t, 1, testutil.CollectAndCount(MongoDuration.WithLabelValues("audit_log", "getMore", "failure").(prometheus.Histogram)))
+}
+
+func Test_Signups(t *testing.T) {
+
+	expected := `
+# HELP blog_signups_total Total number of successful signups.
+# TYPE blog_signups_total counter
+blog_signups_total 1
+`
+	Signups.Inc()
+	assert.NoError(t, testutil.CollectAndCompare(Signups, strings.NewReader(expected)))
+}
+
+func Test_Handler(t *testing.T) {
+
+	Logins.Inc()
+	FailedLogins.WithLabelValues("wrong_password").Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, 200, rec.Code)
+	assert.Contains(t, rec.Body.String(), "blog_logins_total 1")
+	assert.Contains(t, rec.Body.String(), "# TYPE blog_failed_logins_total counter")
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/database"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// MongoMonitor returns a command monitor that times every command sent by a mongo client
+func MongoMonitor() *event.CommandMonitor {
+	// collections are only known when a command starts
+	var collections sync.Map
+
+	observe := func(requestID int64, command string, duration time.Duration, outcome string) {
+		collection, _ := collections.LoadAndDelete(requestID)
+		name, _ := collection.(string)
+		MongoDuration.WithLabelValues(name, command, outcome).Observe(duration.Seconds())
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			collections.Store(e.RequestID, database.CommandCollection(e.CommandName, e.Command))
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			observe(e.RequestID, e.CommandName, time.Duration(e.DurationNanos), "success")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			observe(e.RequestID, e.CommandName, time.Duration(e.DurationNanos), "failure")
+		},
+	}
+}
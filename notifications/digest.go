@@ -0,0 +1,183 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/events"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/mail"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// digestLines is how many notifications a digest spells out, it counts the rest
+const digestLines = 20
+
+// digestBatch is how many pending notifications a run reads at once
+const digestBatch = 500
+
+// Digester emails users the notifications they left unread, each one once.
+// Runs on several instances at once may email some of them twice, only one
+// instance should run it.
+type Digester struct {
+	notifications store.Notifications
+	users         store.Users
+	mailer        mail.Mailer
+	// Now tells the time, tests replace it to move the clock by hand
+	Now func() time.Time
+}
+
+// NewDigester returns a digester sending through mailer, on the system clock
+func NewDigester(notifications store.Notifications, users store.Users, mailer mail.Mailer) *Digester {
+	return &Digester{notifications: notifications, users: users, mailer: mailer, Now: time.Now}
+}
+
+// Send emails every user one digest of their unread notifications no digest
+// told about yet, and returns how many emails went out. It goes through the
+// pending notifications batch by batch and digests each user the first time
+// they come up. A user whose email fails is left for the next run, the others
+// are sent whatever fails. Suspended users and users without an email are
+// never told about theirs, they stay in the inbox.
+func (d *Digester) Send(ctx context.Context) (int, error) {
+	sent := 0
+	var err error
+	seen := map[primitive.ObjectID]bool{}
+	filter := store.NotificationFilter{Unread: true, Undigested: true}
+	for {
+		// fetch from db should not take more that 5 seconds
+		dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+		pending, _, listErr := d.notifications.List(dbCtx, filter, 0, digestBatch)
+		cancel()
+		if listErr != nil {
+			return sent, listErr
+		}
+
+		userIDs := []primitive.ObjectID{}
+		for _, n := range pending {
+			if !seen[n.UserID] {
+				seen[n.UserID] = true
+				userIDs = append(userIDs, n.UserID)
+			}
+		}
+		batchSent, batchErr := d.sendTo(ctx, userIDs)
+		sent += batchSent
+		if batchErr != nil && err == nil {
+			err = batchErr
+		}
+
+		if len(pending) < digestBatch {
+			return sent, err
+		}
+		filter.After = pending[len(pending)-1].ID
+	}
+}
+
+// sendTo emails the users with ids their digest, users gone are skipped
+func (d *Digester) sendTo(ctx context.Context, ids []primitive.ObjectID) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	users, err := d.users.FindByIDs(dbCtx, ids)
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, user := range users {
+		ok, sendErr := d.sendOne(ctx, user)
+		if ok {
+			sent++
+		}
+		if sendErr != nil && err == nil {
+			err = sendErr
+		}
+	}
+	return sent, err
+}
+
+// sendOne emails user their digest and reports whether it went out
+func (d *Digester) sendOne(ctx context.Context, user global.User) (bool, error) {
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	pending, total, err := d.notifications.List(dbCtx, store.NotificationFilter{UserID: user.ID, Unread: true, Undigested: true}, 0, digestLines)
+	cancel()
+	if err != nil || len(pending) == 0 {
+		return false, err
+	}
+
+	emailed := false
+	if !user.Suspended && user.Email != "" {
+		if err := d.mailer.Send(ctx, digest(user, pending, total)); err != nil {
+			return false, err
+		}
+		emailed = true
+	}
+
+	// update should not take more that 5 seconds
+	dbCtx, cancel = global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	return emailed, d.notifications.MarkDigested(dbCtx, user.ID, pending[0].ID, d.Now().UTC())
+}
+
+// Run sends the digests every interval until ctx is done
+func (d *Digester) Run(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := d.Send(ctx)
+			if sent > 0 {
+				logger.Info("Sent notification digests", zap.Int("sent", sent))
+			}
+			if err != nil {
+				logger.Error("Error returned while sending notification digests", zap.Error(err))
+			}
+		}
+	}
+}
+
+// digest returns the email telling user about the total notifications
+// they have pending, spelling out the newest first
+func digest(user global.User, notifications []store.Notification, total int64) mail.Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\n", user.Username)
+	shown := len(notifications)
+	if shown > digestLines {
+		shown = digestLines
+	}
+	for _, n := range notifications[:shown] {
+		fmt.Fprintf(&b, "- %s\n", describe(n))
+	}
+	if more := total - int64(shown); more > 0 {
+		fmt.Fprintf(&b, "- and %d more\n", more)
+	}
+	b.WriteString("\nThey are waiting in your inbox.\n")
+
+	subject := "1 new notification"
+	if total > 1 {
+		subject = fmt.Sprintf("%d new notifications", total)
+	}
+	return mail.Message{To: user.Email, Subject: subject, Body: b.String()}
+}
+
+// describe puts n in words
+func describe(n store.Notification) string {
+	switch events.Kind(n.Kind) {
+	case events.Follow:
+		return fmt.Sprintf("%s followed you", n.ActorUsername)
+	case events.Like:
+		return fmt.Sprintf("%s liked your post %q", n.ActorUsername, n.PostTitle)
+	default:
+		return fmt.Sprintf("%s: %s", n.ActorUsername, n.Kind)
+	}
+}
@@ -0,0 +1,134 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/events"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/mail"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testMailer keeps the messages it is given, failing for the addresses in fail
+type testMailer struct {
+	sent []mail.Message
+	fail map[string]bool
+}
+
+func (m *testMailer) Send(ctx context.Context, msg mail.Message) error {
+	if m.fail[msg.To] {
+		return errors.New("mailbox unavailable")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func Test_Digester_Send(t *testing.T) {
+
+	ctx := context.Background()
+	linus := global.User{ID: primitive.NewObjectID(), Username: "linus", Email: "linus@example.com"}
+	mallory := global.User{ID: primitive.NewObjectID(), Username: "mallory", Email: "mallory@example.com", Suspended: true}
+	users := store.NewMemoryUsers()
+	for _, u := range []global.User{ada, grace, linus, mallory} {
+		assert.NoError(t, users.Insert(ctx, u))
+	}
+	notifications := store.NewMemoryNotifications()
+	service := New(notifications, nil)
+	mailer := &testMailer{fail: map[string]bool{linus.Email: true}}
+	digester := NewDigester(notifications, users, mailer)
+
+	service.Handle(ctx, follow(grace, ada))
+	service.Handle(ctx, events.Event{Kind: events.Like, UserID: ada.ID, ActorID: grace.ID, ActorUsername: grace.Username, PostID: primitive.NewObjectID(), PostTitle: "Hello"})
+	service.Handle(ctx, follow(grace, linus))
+	service.Handle(ctx, follow(grace, mallory))
+	// read notifications are left out
+	service.Handle(ctx, events.Event{Kind: events.Follow, UserID: grace.ID, ActorID: ada.ID, ActorUsername: ada.Username})
+	_, err := notifications.MarkRead(ctx, grace.ID, nil, time.Now())
+	assert.NoError(t, err)
+
+	sent, err := digester.Send(ctx)
+	assert.Error(t, err, "the email of linus fails")
+	assert.Equal(t, 1, sent)
+	if assert.Len(t, mailer.sent, 1) {
+		assert.Equal(t, "ada@example.com", mailer.sent[0].To)
+		assert.Equal(t, "2 new notifications", mailer.sent[0].Subject)
+		assert.Contains(t, mailer.sent[0].Body, "- grace liked your post \"Hello\"\n- grace followed you\n")
+	}
+
+	// each notification is told about once, failed emails are sent again
+	mailer.fail = nil
+	sent, err = digester.Send(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	if assert.Len(t, mailer.sent, 2) {
+		assert.Equal(t, "linus@example.com", mailer.sent[1].To)
+		assert.Equal(t, "1 new notification", mailer.sent[1].Subject)
+	}
+	sent, err = digester.Send(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	// suspended users are never told, their notifications stay in the inbox
+	undigested, _, err := notifications.List(ctx, store.NotificationFilter{UserID: mallory.ID, Undigested: true}, 0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, undigested)
+	_, unread, err := notifications.List(ctx, store.NotificationFilter{UserID: mallory.ID, Unread: true}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), unread)
+}
+
+func Test_Digester_Send_batches(t *testing.T) {
+
+	ctx := context.Background()
+	users := store.NewMemoryUsers()
+	for _, u := range []global.User{ada, grace} {
+		assert.NoError(t, users.Insert(ctx, u))
+	}
+	notifications := store.NewMemoryNotifications()
+	service := New(notifications, nil)
+	mailer := &testMailer{}
+	digester := NewDigester(notifications, users, mailer)
+
+	// the notification of grace only comes up in the second batch
+	service.Handle(ctx, follow(ada, grace))
+	for i := 0; i < digestBatch+5; i++ {
+		actor := global.User{ID: primitive.NewObjectID(), Username: fmt.Sprintf("user%d", i)}
+		service.Handle(ctx, follow(actor, ada))
+	}
+
+	sent, err := digester.Send(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	if assert.Len(t, mailer.sent, 2) {
+		assert.Equal(t, "ada@example.com", mailer.sent[0].To)
+		assert.Equal(t, fmt.Sprintf("%d new notifications", digestBatch+5), mailer.sent[0].Subject)
+		assert.Contains(t, mailer.sent[0].Body, fmt.Sprintf("- and %d more\n", digestBatch+5-digestLines))
+		assert.Equal(t, "grace@example.com", mailer.sent[1].To)
+	}
+
+	sent, err = digester.Send(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+}
+
+func Test_digest(t *testing.T) {
+
+	pending := make([]store.Notification, digestLines)
+	for i := range pending {
+		pending[i] = store.Notification{Kind: string(events.Follow), ActorUsername: fmt.Sprintf("user%d", i)}
+	}
+	msg := digest(ada, pending, digestLines+3)
+	assert.Contains(t, msg.Body, "Hi ada,")
+	assert.Contains(t, msg.Body, fmt.Sprintf("- user%d followed you\n- and 3 more\n", digestLines-1))
+	assert.Equal(t, fmt.Sprintf("%d new notifications", digestLines+3), msg.Subject)
+
+	msg = digest(ada, pending[:1], 1)
+	assert.Equal(t, "1 new notification", msg.Subject)
+	assert.NotContains(t, msg.Body, "more")
+}
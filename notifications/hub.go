@@ -0,0 +1,64 @@
+package notifications
+
+import (
+	"sync"
+
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// subscriptionBuffer is how many notifications wait for a slow subscriber
+// before the next ones skip it, they are in the inbox either way
+const subscriptionBuffer = 16
+
+// hub holds the live subscriptions on this instance
+type hub struct {
+	mu     sync.Mutex
+	subs   map[primitive.ObjectID]map[chan store.Notification]struct{}
+	closed chan struct{}
+	close  sync.Once
+}
+
+func newHub() *hub {
+	return &hub{subs: map[primitive.ObjectID]map[chan store.Notification]struct{}{}, closed: make(chan struct{})}
+}
+
+// subscribe returns the channel the notifications of user arrive on, and the
+// function that ends the subscription
+func (h *hub) subscribe(userID primitive.ObjectID) (<-chan store.Notification, func()) {
+	ch := make(chan store.Notification, subscriptionBuffer)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = map[chan store.Notification]struct{}{}
+	}
+	h.subs[userID][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[userID], ch)
+		if len(h.subs[userID]) == 0 {
+			delete(h.subs, userID)
+		}
+	}
+}
+
+// deliver hands notification to every subscription of its user without
+// waiting on any of them
+func (h *hub) deliver(notification store.Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[notification.UserID] {
+		select {
+		case ch <- notification:
+		default:
+		}
+	}
+}
+
+// shutdown ends every subscription, for the server to drain
+func (h *hub) shutdown() {
+	h.close.Do(func() { close(h.closed) })
+}
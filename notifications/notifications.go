@@ -0,0 +1,269 @@
+// Package notifications serves the NotificationService. It turns the events
+// other services publish into notifications, keeps them in the inbox of the
+// users they concern, pushes them to the users listening and emails digests
+// of those left unread.
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/events"
+	"github.com/HiteshRepo/blog-application/gateway"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Service serves the NotificationService
+type Service struct {
+	notifications *notificationServer
+}
+
+// New returns the notification service on the given store. Page tokens are
+// issued with pages.
+func New(notifications store.Notifications, pages *paging.Codec) *Service {
+	return &Service{notifications: &notificationServer{notifications: notifications, hub: newHub(), pages: pages, now: time.Now}}
+}
+
+// Register adds the NotificationService to server
+func (s *Service) Register(server *grpc.Server) {
+	proto.RegisterNotificationServiceServer(server, s.notifications)
+}
+
+// Require lists the permissions needed by every guarded RPC, any user reads their own inbox
+func (s *Service) Require(p *policy.Policy) {
+	p.Require("/proto.NotificationService/ListNotifications").
+		Require("/proto.NotificationService/StreamNotifications").
+		Require("/proto.NotificationService/MarkRead").
+		Require("/proto.NotificationService/Subscribe")
+}
+
+// Routes maps the NotificationService to the JSON API, Subscribe is only
+// served over grpc and grpc-web
+func (s *Service) Routes(g *gateway.Gateway) {
+	g.Handle(http.MethodGet, "/v1/notifications", "/proto.NotificationService/ListNotifications").
+		Handle(http.MethodPost, "/v1/notifications/read", "/proto.NotificationService/MarkRead")
+}
+
+// Handle keeps e as a notification in the inbox of the user it concerns and
+// pushes it to their subscriptions on this instance. Nobody is told about
+// what they did themselves.
+func (s *Service) Handle(ctx context.Context, e events.Event) {
+	if e.UserID.IsZero() || e.UserID == e.ActorID {
+		return
+	}
+	notification := store.Notification{
+		ID:            primitive.NewObjectID(),
+		UserID:        e.UserID,
+		Kind:          string(e.Kind),
+		ActorID:       e.ActorID,
+		ActorUsername: e.ActorUsername,
+		PostID:        e.PostID,
+		PostTitle:     e.PostTitle,
+		CreatedAt:     e.At.UTC(),
+	}
+	if e.At.IsZero() {
+		notification.CreatedAt = s.notifications.now().UTC()
+	}
+
+	// insert should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.notifications.notifications.Insert(dbCtx, notification); err != nil {
+		logging.FromContext(ctx).Error("Error returned while inserting notification", zap.Error(err), zap.String("kind", notification.Kind))
+		return
+	}
+	s.notifications.hub.deliver(notification)
+}
+
+// Shutdown ends every subscription with Unavailable, so that the server
+// drains without waiting on them and clients subscribe again elsewhere
+func (s *Service) Shutdown() {
+	s.notifications.hub.shutdown()
+}
+
+type notificationServer struct {
+	notifications store.Notifications
+	hub           *hub
+	pages         *paging.Codec
+	now           func() time.Time
+}
+
+// inboxListing binds the page tokens of an inbox to its owner and the filter
+type inboxListing struct {
+	UserID     string `json:"user_id"`
+	UnreadOnly bool   `json:"unread_only,omitempty"`
+}
+
+func (n *notificationServer) ListNotifications(ctx context.Context, in *proto.ListNotificationsRequest) (*proto.ListNotificationsResponse, error) {
+	user := global.UserFromContext(ctx)
+	page, err := n.pages.Start(in.GetPageToken(), in.GetPageSize(), inboxListing{UserID: user.ID.Hex(), UnreadOnly: in.GetUnreadOnly()})
+	if err != nil {
+		return nil, err
+	}
+	notifications, total, err := n.page(ctx, user.ID, in.GetUnreadOnly(), page)
+	if err != nil {
+		return nil, err
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	unread, err := n.unread(dbCtx, user.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while counting unread notifications", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	var last interface{}
+	if len(notifications) > 0 {
+		last = notifications[len(notifications)-1].ID
+	}
+	next, err := n.pages.Next(page, len(notifications), total, last)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while issuing page token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	res := &proto.ListNotificationsResponse{
+		Notifications: make([]*proto.Notification, 0, len(notifications)),
+		Total:         total,
+		Unread:        unread,
+		PageSize:      page.Size,
+		NextPageToken: next,
+	}
+	for _, notification := range notifications {
+		res.Notifications = append(res.Notifications, notificationToProto(notification))
+	}
+	return res, nil
+}
+
+// StreamNotifications sends the inbox of the caller from the page token on
+func (n *notificationServer) StreamNotifications(in *proto.ListNotificationsRequest, stream proto.NotificationService_StreamNotificationsServer) error {
+	user := global.UserFromContext(stream.Context())
+	page, err := n.pages.Start(in.GetPageToken(), in.GetPageSize(), inboxListing{UserID: user.ID.Hex(), UnreadOnly: in.GetUnreadOnly()})
+	if err != nil {
+		return err
+	}
+	return paging.Walk(page, func(page paging.Page) (int, interface{}, error) {
+		notifications, _, err := n.page(stream.Context(), user.ID, in.GetUnreadOnly(), page)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, notification := range notifications {
+			if err := stream.Send(notificationToProto(notification)); err != nil {
+				return 0, nil, err
+			}
+		}
+		if len(notifications) == 0 {
+			return 0, nil, nil
+		}
+		return len(notifications), notifications[len(notifications)-1].ID, nil
+	})
+}
+
+// page returns the notifications of page in the inbox of the user, and the
+// number of all of them
+func (n *notificationServer) page(ctx context.Context, userID primitive.ObjectID, unreadOnly bool, page paging.Page) ([]store.Notification, int64, error) {
+	filter := store.NotificationFilter{UserID: userID, Unread: unreadOnly}
+	if _, err := page.Key(&filter.After); err != nil {
+		return nil, 0, err
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	notifications, total, err := n.notifications.List(dbCtx, filter, 0, page.Size)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while listing notifications", zap.Error(err))
+		return nil, 0, status.Error(codes.Internal, "Internal Error")
+	}
+	return notifications, total, nil
+}
+
+// MarkRead leaves the notifications of other users alone, as if their ids
+// were unknown
+func (n *notificationServer) MarkRead(ctx context.Context, in *proto.MarkReadRequest) (*proto.MarkReadResponse, error) {
+	user := global.UserFromContext(ctx)
+	ids := make([]primitive.ObjectID, 0, len(in.GetIDs()))
+	for _, hex := range in.GetIDs() {
+		id, _ := primitive.ObjectIDFromHex(hex)
+		ids = append(ids, id)
+	}
+
+	// update should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	marked, err := n.notifications.MarkRead(dbCtx, user.ID, ids, n.now().UTC())
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while marking notifications read", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	unread, err := n.unread(dbCtx, user.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while counting unread notifications", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	return &proto.MarkReadResponse{Marked: marked, Unread: unread}, nil
+}
+
+// Subscribe sends the headers once the subscription is live, clients that
+// wait for them miss nothing raised after
+func (n *notificationServer) Subscribe(in *proto.SubscribeRequest, stream proto.NotificationService_SubscribeServer) error {
+	ctx := stream.Context()
+	live, unsubscribe := n.hub.subscribe(global.UserFromContext(ctx).ID)
+	defer unsubscribe()
+
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-n.hub.closed:
+			return status.Error(codes.Unavailable, "Server shutting down")
+		case notification := <-live:
+			if err := stream.Send(notificationToProto(notification)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// unread counts the unread notifications of the user
+func (n *notificationServer) unread(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	_, total, err := n.notifications.List(ctx, store.NotificationFilter{UserID: userID, Unread: true}, 0, 1)
+	return total, err
+}
+
+func notificationToProto(notification store.Notification) *proto.Notification {
+	res := &proto.Notification{
+		ID:            notification.ID.Hex(),
+		Kind:          notification.Kind,
+		ActorID:       notification.ActorID.Hex(),
+		ActorUsername: notification.ActorUsername,
+		PostTitle:     notification.PostTitle,
+		CreatedAt:     notification.CreatedAt.Unix(),
+		Read:          !notification.ReadAt.IsZero(),
+	}
+	if !notification.PostID.IsZero() {
+		res.PostID = notification.PostID.Hex()
+	}
+	return res
+}
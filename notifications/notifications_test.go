@@ -0,0 +1,230 @@
+package notifications
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/events"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/server"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var (
+	ada   = global.User{ID: primitive.NewObjectID(), Username: "ada", Email: "ada@example.com"}
+	grace = global.User{ID: primitive.NewObjectID(), Username: "grace", Email: "grace@example.com"}
+)
+
+// authenticate trusts the token, the auth service checks it against the db
+func authenticate(ctx context.Context) (context.Context, []policy.Role, error) {
+	user := global.UserFromToken(policy.TokenFromContext(ctx))
+	if user.IsNil() {
+		return ctx, nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	return global.ContextWithUser(ctx, user), user.GetRoles(), nil
+}
+
+// newTestClient serves the NotificationService over bufconn on the production server bootstrap
+func newTestClient(t *testing.T, service *Service) proto.NotificationServiceClient {
+	t.Helper()
+
+	grpcServer := server.New(server.Config{Logger: zap.NewNop(), Authenticate: authenticate}, service).GRPCServer()
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcServer.Serve(listener)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error dialing bufconn : %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+	})
+	return proto.NewNotificationServiceClient(conn)
+}
+
+// as returns a context that calls the service with user's token
+func as(user global.User) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+user.GetToken())
+}
+
+// follow returns actor following user
+func follow(actor, user global.User) events.Event {
+	return events.Event{Kind: events.Follow, UserID: user.ID, ActorID: actor.ID, ActorUsername: actor.Username, At: time.Now()}
+}
+
+func Test_notificationServer_Inbox(t *testing.T) {
+
+	service := New(store.NewMemoryNotifications(), paging.NewCodec(nil))
+	client := newTestClient(t, service)
+	ctx := context.Background()
+
+	post := primitive.NewObjectID()
+	service.Handle(ctx, follow(grace, ada))
+	service.Handle(ctx, events.Event{Kind: events.Like, UserID: ada.ID, ActorID: grace.ID, ActorUsername: grace.Username, PostID: post, PostTitle: "Hello", At: time.Now()})
+	service.Handle(ctx, follow(ada, grace))
+	// nobody is told about what they did themselves
+	service.Handle(ctx, events.Event{Kind: events.Like, UserID: grace.ID, ActorID: grace.ID, PostID: post, At: time.Now()})
+
+	_, err := client.ListNotifications(ctx, &proto.ListNotificationsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// latest first, page by page, bound to the caller
+	res, err := client.ListNotifications(as(ada), &proto.ListNotificationsRequest{PageSize: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), res.GetTotal())
+	assert.Equal(t, int64(2), res.GetUnread())
+	if assert.Len(t, res.GetNotifications(), 1) {
+		like := res.GetNotifications()[0]
+		assert.Equal(t, "like", like.GetKind())
+		assert.Equal(t, "grace", like.GetActorUsername())
+		assert.Equal(t, post.Hex(), like.GetPostID())
+		assert.Equal(t, "Hello", like.GetPostTitle())
+		assert.False(t, like.GetRead())
+	}
+	next, err := client.ListNotifications(as(ada), &proto.ListNotificationsRequest{PageSize: 1, PageToken: res.GetNextPageToken()})
+	assert.NoError(t, err)
+	if assert.Len(t, next.GetNotifications(), 1) {
+		assert.Equal(t, "follow", next.GetNotifications()[0].GetKind())
+		assert.Empty(t, next.GetNotifications()[0].GetPostID())
+	}
+	assert.Empty(t, next.GetNextPageToken())
+	_, err = client.ListNotifications(as(grace), &proto.ListNotificationsRequest{PageSize: 1, PageToken: res.GetNextPageToken()})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"ids": []string{"not-an-id"}, "code": codes.InvalidArgument},
+		// the notifications of others are left alone
+		map[string]interface{}{"ids": []string{primitive.NewObjectID().Hex()}, "marked": int64(0), "unread": int64(2)},
+		map[string]interface{}{"ids": []string{res.GetNotifications()[0].GetID()}, "marked": int64(1), "unread": int64(1)},
+		// marking again changes nothing
+		map[string]interface{}{"ids": []string{res.GetNotifications()[0].GetID()}, "marked": int64(0), "unread": int64(1)},
+		// no ids marks everything
+		map[string]interface{}{"ids": []string{}, "marked": int64(1), "unread": int64(0)},
+	}
+
+	for _, tcase := range testCases {
+		marked, err := client.MarkRead(as(ada), &proto.MarkReadRequest{IDs: tcase["ids"].([]string)})
+		code, _ := tcase["code"].(codes.Code)
+		assert.Equalf(t, code, status.Code(err), "case: %v", tcase)
+		if code != codes.OK {
+			continue
+		}
+		assert.Equalf(t, tcase["marked"], marked.GetMarked(), "case: %v", tcase)
+		assert.Equalf(t, tcase["unread"], marked.GetUnread(), "case: %v", tcase)
+	}
+
+	res, err = client.ListNotifications(as(ada), &proto.ListNotificationsRequest{UnreadOnly: true})
+	assert.NoError(t, err)
+	assert.Empty(t, res.GetNotifications())
+	res, err = client.ListNotifications(as(grace), &proto.ListNotificationsRequest{UnreadOnly: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), res.GetUnread())
+}
+
+func Test_notificationServer_StreamNotifications(t *testing.T) {
+
+	service := New(store.NewMemoryNotifications(), paging.NewCodec(nil))
+	client := newTestClient(t, service)
+	ctx := context.Background()
+
+	linus := global.User{ID: primitive.NewObjectID(), Username: "linus"}
+	service.Handle(ctx, follow(grace, ada))
+	service.Handle(ctx, follow(linus, ada))
+	service.Handle(ctx, follow(ada, grace))
+
+	stream, err := client.StreamNotifications(ctx, &proto.ListNotificationsRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// every page is read, latest first, and only the inbox of the caller
+	stream, err = client.StreamNotifications(as(ada), &proto.ListNotificationsRequest{PageSize: 1})
+	assert.NoError(t, err)
+	got := []string{}
+	for {
+		notification, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			break
+		}
+		got = append(got, notification.GetActorUsername())
+	}
+	assert.Equal(t, []string{"linus", "grace"}, got)
+
+	// the stream starts from the page token, bound to the caller like the list
+	res, err := client.ListNotifications(as(ada), &proto.ListNotificationsRequest{PageSize: 1})
+	assert.NoError(t, err)
+	stream, err = client.StreamNotifications(as(ada), &proto.ListNotificationsRequest{PageSize: 1, PageToken: res.GetNextPageToken()})
+	assert.NoError(t, err)
+	notification, err := stream.Recv()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "grace", notification.GetActorUsername())
+	}
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+	stream, err = client.StreamNotifications(as(grace), &proto.ListNotificationsRequest{PageSize: 1, PageToken: res.GetNextPageToken()})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_notificationServer_Subscribe(t *testing.T) {
+
+	service := New(store.NewMemoryNotifications(), paging.NewCodec(nil))
+	client := newTestClient(t, service)
+
+	stream, err := client.Subscribe(context.Background(), &proto.SubscribeRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx, cancel := context.WithTimeout(as(ada), 5*time.Second)
+	defer cancel()
+	stream, err = client.Subscribe(ctx, &proto.SubscribeRequest{})
+	assert.NoError(t, err)
+	// the headers come once the subscription is live
+	_, err = stream.Header()
+	assert.NoError(t, err)
+
+	// only the notifications of the caller come through, as they arrive
+	service.Handle(context.Background(), follow(ada, grace))
+	service.Handle(context.Background(), follow(grace, ada))
+	got, err := stream.Recv()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "follow", got.GetKind())
+		assert.Equal(t, grace.ID.Hex(), got.GetActorID())
+	}
+	inbox, err := client.ListNotifications(as(ada), &proto.ListNotificationsRequest{})
+	assert.NoError(t, err)
+	if assert.Len(t, inbox.GetNotifications(), 1) {
+		assert.Equal(t, inbox.GetNotifications()[0].GetID(), got.GetID())
+	}
+
+	// shutting down ends the subscription, clients subscribe again elsewhere
+	service.Shutdown()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
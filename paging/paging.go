@@ -0,0 +1,213 @@
+// Package paging is the pagination contract of the listing RPCs. A listing
+// returns pages of at most MaxSize items, in an order with a unique sort key,
+// along with an opaque token for the next page. Tokens are signed, so clients
+// cannot forge them, and bound to the filter of the listing, so that a token
+// only ever continues the listing it came from.
+package paging
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultSize is the size of a page when the request sets none
+	DefaultSize = 20
+	// MaxSize bounds the size of a page, larger requests are cut down to it
+	MaxSize = 100
+)
+
+var (
+	// ErrInvalidToken is returned for tokens that were not issued by the codec
+	ErrInvalidToken = status.Error(codes.InvalidArgument, "Invalid page token")
+	// ErrFilterChanged is returned for tokens issued for another filter
+	ErrFilterChanged = status.Error(codes.InvalidArgument, "Page token does not match the filter")
+)
+
+// Codec issues and reads page tokens
+type Codec struct {
+	key []byte
+}
+
+// NewCodec returns a codec signing tokens with key. Without key, a random one
+// is used and tokens only work on this process.
+func NewCodec(key []byte) *Codec {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &Codec{key: key}
+}
+
+// Page is where a listing starts
+type Page struct {
+	// Size is the number of items to list
+	Size int64
+	// Offset is the number of items listed by the previous pages
+	Offset int64
+	// key is the sort key of the last item of the previous page, in json
+	key    json.RawMessage
+	filter string
+}
+
+// cursor is the content of a token
+type cursor struct {
+	Size   int64           `json:"s"`
+	Offset int64           `json:"o"`
+	Key    json.RawMessage `json:"k,omitempty"`
+	Filter string          `json:"f"`
+}
+
+// Size returns the page size to use for a requested one
+func Size(requested int64) int64 {
+	if requested <= 0 {
+		return DefaultSize
+	}
+	if requested > MaxSize {
+		return MaxSize
+	}
+	return requested
+}
+
+// Start returns the page a request asks for: the first one without token,
+// the one the token points at otherwise. filter is anything encoding to json
+// and holding every filter of the request, the token must have been issued
+// for the same filter. size overrides the size kept in the token when set.
+func (c *Codec) Start(token string, size int64, filter interface{}) (Page, error) {
+	hash, err := hashOf(filter)
+	if err != nil {
+		return Page{}, err
+	}
+	if token == "" {
+		return Page{Size: Size(size), filter: hash}, nil
+	}
+
+	cur, err := c.decode(token)
+	if err != nil {
+		return Page{}, err
+	}
+	if cur.Filter != hash {
+		return Page{}, ErrFilterChanged
+	}
+	if size <= 0 {
+		size = cur.Size
+	}
+	return Page{Size: Size(size), Offset: cur.Offset, key: cur.Key, filter: hash}, nil
+}
+
+// Key reads the sort key of the last item of the previous page into v and
+// reports whether there is one, there is none on the first page and for
+// listings paged by offset
+func (p Page) Key(v interface{}) (bool, error) {
+	if len(p.key) == 0 {
+		return false, nil
+	}
+	if err := json.Unmarshal(p.key, v); err != nil {
+		return false, ErrInvalidToken
+	}
+	return true, nil
+}
+
+// Next returns the token of the page following p, empty when p listed the
+// last of total items. listed is the number of items p returned, key the
+// sort key of the last one, nil for listings paged by offset.
+func (c *Codec) Next(p Page, listed int, total int64, key interface{}) (string, error) {
+	offset := p.Offset + int64(listed)
+	if listed == 0 || offset >= total {
+		return "", nil
+	}
+	cur := cursor{Size: p.Size, Offset: offset, Filter: p.filter}
+	if key != nil {
+		raw, err := json.Marshal(key)
+		if err != nil {
+			return "", err
+		}
+		cur.Key = raw
+	}
+	return c.encode(cur)
+}
+
+// Next moves p past listed items ending with the item with key, for walking a
+// listing without tokens
+func (p Page) Next(listed int, key interface{}) (Page, error) {
+	p.Offset += int64(listed)
+	p.key = nil
+	if key != nil {
+		raw, err := json.Marshal(key)
+		if err != nil {
+			return p, err
+		}
+		p.key = raw
+	}
+	return p, nil
+}
+
+// Walk lists every item from p on, for streaming a listing: list lists page p
+// and returns how many items it listed and the sort key of the last one. Walk
+// stops after a short page or at the first error.
+func Walk(p Page, list func(p Page) (int, interface{}, error)) error {
+	for {
+		listed, key, err := list(p)
+		if err != nil {
+			return err
+		}
+		if int64(listed) < p.Size {
+			return nil
+		}
+		if p, err = p.Next(listed, key); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Codec) encode(cur cursor) (string, error) {
+	payload, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload)), nil
+}
+
+func (c *Codec) decode(token string) (cursor, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return cursor{}, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return cursor{}, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, c.sign(payload)) {
+		return cursor{}, ErrInvalidToken
+	}
+	var cur cursor
+	if err := json.Unmarshal(payload, &cur); err != nil {
+		return cursor{}, ErrInvalidToken
+	}
+	return cur, nil
+}
+
+// sign returns the first 16 bytes of the hmac of payload, enough against forgery
+func (c *Codec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(payload)
+	return mac.Sum(nil)[:16]
+}
+
+// hashOf returns a short digest of the json encoding of filter
+func hashOf(filter interface{}) (string, error) {
+	raw, err := json.Marshal(filter)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:9]), nil
+}
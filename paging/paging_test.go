@@ -0,0 +1,122 @@
+package paging
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testFilter struct {
+	Tag string
+}
+
+func Test_Size(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"requested": int64(0), "size": int64(DefaultSize)},
+		map[string]interface{}{"requested": int64(-1), "size": int64(DefaultSize)},
+		map[string]interface{}{"requested": int64(5), "size": int64(5)},
+		map[string]interface{}{"requested": int64(MaxSize + 1), "size": int64(MaxSize)},
+	}
+
+	for _, tcase := range testCases {
+		assert.Equalf(t, tcase["size"], Size(tcase["requested"].(int64)), "case: %v", tcase)
+	}
+}
+
+func Test_Codec(t *testing.T) {
+
+	codec := NewCodec([]byte("test-key"))
+	filter := testFilter{Tag: "go"}
+
+	first, err := codec.Start("", 2, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), first.Size)
+	assert.Equal(t, int64(0), first.Offset)
+	var key string
+	ok, err := first.Key(&key)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	token, err := codec.Next(first, 2, 5, "b")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	second, err := codec.Start(token, 0, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), second.Size, "the size is kept in the token")
+	assert.Equal(t, int64(2), second.Offset)
+	ok, err = second.Key(&key)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "b", key)
+
+	resized, err := codec.Start(token, 3, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), resized.Size)
+
+	// the last page has no next one
+	last, err := codec.Next(second, 3, 5, "e")
+	assert.NoError(t, err)
+	assert.Empty(t, last)
+	empty, err := codec.Next(first, 0, 5, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+
+	payload := strings.SplitN(token, ".", 2)[0]
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"token": "garbage", "filter": filter, "err": ErrInvalidToken},
+		map[string]interface{}{"token": payload + ".AAAA", "filter": filter, "err": ErrInvalidToken},
+		map[string]interface{}{"token": payload + "." + strings.SplitN(token, ".", 2)[1][1:], "filter": filter, "err": ErrInvalidToken},
+		map[string]interface{}{"token": token, "filter": testFilter{Tag: "rust"}, "err": ErrFilterChanged},
+	}
+
+	for _, tcase := range testCases {
+		_, err := codec.Start(tcase["token"].(string), 0, tcase["filter"])
+		assert.Equalf(t, tcase["err"], err, "case: %v", tcase)
+	}
+
+	// tokens of another key are forgeries
+	_, err = NewCodec([]byte("other-key")).Start(token, 0, filter)
+	assert.Equal(t, ErrInvalidToken, err)
+	_, err = NewCodec(nil).Start(token, 0, filter)
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func Test_Walk(t *testing.T) {
+
+	items := []string{"a", "b", "c", "d", "e"}
+	page, _ := NewCodec(nil).Start("", 2, nil)
+
+	var walked []string
+	err := Walk(page, func(p Page) (int, interface{}, error) {
+		var after string
+		p.Key(&after)
+		from := 0
+		for from < len(items) && after != "" && items[from] <= after {
+			from++
+		}
+		to := from + int(p.Size)
+		if to > len(items) {
+			to = len(items)
+		}
+		walked = append(walked, items[from:to]...)
+		if to == from {
+			return 0, nil, nil
+		}
+		return to - from, items[to-1], nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, items, walked)
+
+	boom := errors.New("boom")
+	calls := 0
+	err = Walk(page, func(p Page) (int, interface{}, error) {
+		calls++
+		return 0, nil, boom
+	})
+	assert.Equal(t, boom, err)
+	assert.Equal(t, 1, calls)
+}
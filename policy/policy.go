@@ -0,0 +1,139 @@
+package policy
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticator identifies the caller of an RPC. It returns a context carrying
+// whatever the service needs to know about the caller, along with the caller's roles.
+type Authenticator func(ctx context.Context) (context.Context, []Role, error)
+
+// Policy maps full gRPC method names to the permissions they require.
+// Methods without a rule are public.
+type Policy struct {
+	rules map[string][]Permission
+	// optional methods are public, but know who calls them with a token
+	optional map[string]bool
+}
+
+type rolesKey struct{}
+
+// New returns an empty policy where every method is public
+func New() *Policy {
+	return &Policy{rules: map[string][]Permission{}, optional: map[string]bool{}}
+}
+
+// Require guards method so that callers need every one of perms.
+// Passing no permissions only requires the caller to be authenticated.
+func (p *Policy) Require(method string, perms ...Permission) *Policy {
+	p.rules[method] = perms
+	return p
+}
+
+// Optional lets anyone call method, but authenticates callers sending a
+// token, for methods whose answer depends on who asks
+func (p *Policy) Optional(method string) *Policy {
+	p.optional[method] = true
+	return p
+}
+
+// Guarded reports whether method needs an authenticated caller
+func (p *Policy) Guarded(method string) bool {
+	_, ok := p.rules[method]
+	return ok
+}
+
+// Authorize checks roles against the rule for method
+func (p *Policy) Authorize(method string, roles []Role) error {
+	for _, perm := range p.rules[method] {
+		if !Can(roles, perm) {
+			return status.Errorf(codes.PermissionDenied, "Missing permission %s", perm)
+		}
+	}
+	return nil
+}
+
+// UnaryServerInterceptor authenticates callers of guarded methods and enforces the policy
+func (p *Policy) UnaryServerInterceptor(authenticate Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !p.Guarded(info.FullMethod) && (!p.optional[info.FullMethod] || TokenFromContext(ctx) == "") {
+			return handler(ctx, req)
+		}
+		ctx, roles, err := authenticate(ctx)
+		if err != nil {
+			// keep the authenticator's message, but not its code
+			return nil, status.Error(codes.Unauthenticated, status.Convert(err).Message())
+		}
+		if err := p.Authorize(info.FullMethod, roles); err != nil {
+			return nil, err
+		}
+		return handler(ContextWithRoles(ctx, roles), req)
+	}
+}
+
+// StreamServerInterceptor does for streams what UnaryServerInterceptor does
+// for unary calls, callers are checked before the stream starts
+func (p *Policy) StreamServerInterceptor(authenticate Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		if !p.Guarded(info.FullMethod) && (!p.optional[info.FullMethod] || TokenFromContext(ctx) == "") {
+			return handler(srv, ss)
+		}
+		ctx, roles, err := authenticate(ctx)
+		if err != nil {
+			return status.Error(codes.Unauthenticated, status.Convert(err).Message())
+		}
+		if err := p.Authorize(info.FullMethod, roles); err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ContextWithRoles(ctx, roles)})
+	}
+}
+
+// serverStream is a stream with the context of the authenticated caller
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// ContextWithRoles returns a copy of ctx carrying the caller's roles
+func ContextWithRoles(ctx context.Context, roles []Role) context.Context {
+	return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+// RolesFromContext returns the roles stored by the interceptor, if any
+func RolesFromContext(ctx context.Context) []Role {
+	roles, _ := ctx.Value(rolesKey{}).([]Role)
+	return roles
+}
+
+// Check lets handlers enforce permissions that depend on the request itself
+func Check(ctx context.Context, perm Permission) error {
+	if !Can(RolesFromContext(ctx), perm) {
+		return status.Errorf(codes.PermissionDenied, "Missing permission %s", perm)
+	}
+	return nil
+}
+
+// TokenFromContext reads the bearer token from the incoming "authorization" metadata
+func TokenFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer "))
+}
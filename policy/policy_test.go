@@ -0,0 +1,211 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func Test_Can(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"roles": []Role{RoleReader},
+			"perm":  PermReadPosts,
+			"can":   true,
+		},
+		map[string]interface{}{
+			"roles": []Role{RoleReader},
+			"perm":  PermWritePosts,
+			"can":   false,
+		},
+		map[string]interface{}{
+			"roles": []Role{RoleAuthor},
+			"perm":  PermEditAnyPost,
+			"can":   false,
+		},
+		map[string]interface{}{
+			"roles": []Role{RoleReader, RoleEditor},
+			"perm":  PermEditAnyPost,
+			"can":   true,
+		},
+		map[string]interface{}{
+			"roles": []Role{RoleEditor},
+			"perm":  PermManageRoles,
+			"can":   false,
+		},
+		map[string]interface{}{
+			"roles": []Role{RoleAdmin},
+			"perm":  PermManageRoles,
+			"can":   true,
+		},
+		map[string]interface{}{
+			"roles": []Role{RoleAuthor},
+			"perm":  PermManageTaxonomy,
+			"can":   false,
+		},
+		map[string]interface{}{
+			"roles": []Role{RoleEditor},
+			"perm":  PermManageTaxonomy,
+			"can":   true,
+		},
+		map[string]interface{}{
+			"roles": []Role{},
+			"perm":  PermReadPosts,
+			"can":   false,
+		},
+	}
+
+	for _, tcase := range testCases {
+		assert.Equalf(t, tcase["can"].(bool), Can(tcase["roles"].([]Role), tcase["perm"].(Permission)), "case: %v", tcase)
+	}
+}
+
+func Test_ParseRole(t *testing.T) {
+
+	role, err := ParseRole(" Admin ")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleAdmin, role)
+
+	_, err = ParseRole("superuser")
+	assert.Error(t, err)
+}
+
+func Test_Policy_UnaryServerInterceptor(t *testing.T) {
+
+	p := New().
+		Require("/test/Admin", PermManageRoles).
+		Require("/test/Member").
+		Optional("/test/Viewer")
+
+	authenticate := func(ctx context.Context) (context.Context, []Role, error) {
+		switch TokenFromContext(ctx) {
+		case "admin-token":
+			return ctx, []Role{RoleAdmin}, nil
+		case "reader-token":
+			return ctx, []Role{RoleReader}, nil
+		}
+		return ctx, nil, errors.New("Invalid token")
+	}
+	interceptor := p.UnaryServerInterceptor(authenticate)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return RolesFromContext(ctx), nil
+	}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"method": "/test/Public",
+			"token":  "",
+		},
+		map[string]interface{}{
+			"method": "/test/Member",
+			"token":  "",
+			"code":   codes.Unauthenticated,
+		},
+		map[string]interface{}{
+			"method": "/test/Member",
+			"token":  "reader-token",
+		},
+		map[string]interface{}{
+			"method": "/test/Admin",
+			"token":  "reader-token",
+			"code":   codes.PermissionDenied,
+		},
+		map[string]interface{}{
+			"method": "/test/Admin",
+			"token":  "admin-token",
+		},
+		map[string]interface{}{
+			"method": "/test/Viewer",
+			"token":  "",
+			"roles":  []Role(nil),
+		},
+		map[string]interface{}{
+			"method": "/test/Viewer",
+			"token":  "reader-token",
+			"roles":  []Role{RoleReader},
+		},
+		map[string]interface{}{
+			// a bad token is an error even where none is needed
+			"method": "/test/Viewer",
+			"token":  "expired-token",
+			"code":   codes.Unauthenticated,
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+tcase["token"].(string)))
+		res, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tcase["method"].(string)}, handler)
+		if roles, ok := tcase["roles"]; ok {
+			assert.Equalf(t, roles, res, "case: %v", tcase)
+		}
+
+		if code, ok := tcase["code"]; ok {
+			assert.Errorf(t, err, "case: %v", tcase)
+			assert.Equalf(t, code.(codes.Code), status.Code(err), "case: %v", tcase)
+		} else {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+		}
+	}
+}
+
+// testStream is a server stream with nothing but a context
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+func Test_Policy_StreamServerInterceptor(t *testing.T) {
+
+	p := New().Require("/test/Admin", PermManageRoles)
+	authenticate := func(ctx context.Context) (context.Context, []Role, error) {
+		if TokenFromContext(ctx) == "admin-token" {
+			return ctx, []Role{RoleAdmin}, nil
+		}
+		return ctx, []Role{RoleReader}, nil
+	}
+	interceptor := p.StreamServerInterceptor(authenticate)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"method": "/test/Public", "token": "", "roles": []Role(nil)},
+		map[string]interface{}{"method": "/test/Admin", "token": "reader-token", "code": codes.PermissionDenied},
+		map[string]interface{}{"method": "/test/Admin", "token": "admin-token", "roles": []Role{RoleAdmin}},
+	}
+
+	for _, tcase := range testCases {
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+tcase["token"].(string)))
+		var roles []Role
+		handler := func(srv interface{}, ss grpc.ServerStream) error {
+			roles = RolesFromContext(ss.Context())
+			return nil
+		}
+		err := interceptor(nil, &testStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: tcase["method"].(string)}, handler)
+		if code, ok := tcase["code"]; ok {
+			assert.Equalf(t, code.(codes.Code), status.Code(err), "case: %v", tcase)
+			continue
+		}
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		assert.Equalf(t, tcase["roles"], roles, "case: %v", tcase)
+	}
+}
+
+func Test_Check(t *testing.T) {
+
+	ctx := ContextWithRoles(context.Background(), []Role{RoleEditor})
+	assert.NoError(t, Check(ctx, PermModerateComments))
+	assert.Equal(t, codes.PermissionDenied, status.Code(Check(ctx, PermManageUsers)))
+	assert.Equal(t, codes.PermissionDenied, status.Code(Check(context.Background(), PermReadPosts)))
+}
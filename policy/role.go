@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+)
+
+// Role is a named set of permissions granted to a user
+type Role string
+
+// Permission is a single action a role may perform
+type Permission string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleEditor Role = "editor"
+	RoleAuthor Role = "author"
+	RoleReader Role = "reader"
+)
+
+// DefaultRole is granted to every new signup and to users stored without roles
+const DefaultRole = RoleAuthor
+
+const (
+	PermReadPosts        Permission = "posts:read"
+	PermWritePosts       Permission = "posts:write"
+	PermEditAnyPost      Permission = "posts:edit_any"
+	PermModerateComments Permission = "comments:moderate"
+	PermManageTaxonomy   Permission = "taxonomy:manage"
+	PermManageUsers      Permission = "users:manage"
+	PermManageRoles      Permission = "roles:manage"
+	PermReadAudit        Permission = "audit:read"
+)
+
+// every role inherits the permissions of the roles below it
+var rolePermissions = map[Role][]Permission{
+	RoleReader: {PermReadPosts},
+	RoleAuthor: {PermReadPosts, PermWritePosts},
+	RoleEditor: {PermReadPosts, PermWritePosts, PermEditAnyPost, PermModerateComments, PermManageTaxonomy},
+	RoleAdmin:  {PermReadPosts, PermWritePosts, PermEditAnyPost, PermModerateComments, PermManageTaxonomy, PermManageUsers, PermManageRoles, PermReadAudit},
+}
+
+// Roles lists every known role, most privileged first
+var Roles = []Role{RoleAdmin, RoleEditor, RoleAuthor, RoleReader}
+
+// ParseRole converts user input into a known role
+func ParseRole(s string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(s)))
+	if !role.Valid() {
+		return "", errors.New("Unknown role : " + s)
+	}
+	return role, nil
+}
+
+// Valid reports whether r is one of the known roles
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Permissions returns the permissions granted by r
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// RoleNames converts roles into plain strings for responses
+func RoleNames(roles []Role) []string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, string(role))
+	}
+	return names
+}
+
+// Can reports whether any of the roles grants p
+func Can(roles []Role, p Permission) bool {
+	for _, role := range roles {
+		for _, perm := range rolePermissions[role] {
+			if perm == p {
+				return true
+			}
+		}
+	}
+	return false
+}
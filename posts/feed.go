@@ -0,0 +1,110 @@
+package posts
+
+import (
+	"context"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/feed"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type feedServer struct {
+	feed  feed.Fanout
+	pages *paging.Codec
+}
+
+// feedListing binds the page tokens of a feed to its reader
+type feedListing struct {
+	UserID string `json:"user_id"`
+}
+
+// GetFeed returns the published posts of the users the caller follows
+func (s *feedServer) GetFeed(ctx context.Context, in *proto.GetFeedRequest) (*proto.PostsResponse, error) {
+	user := global.UserFromContext(ctx)
+	page, err := s.pages.Start(in.GetPageToken(), in.GetPageSize(), feedListing{UserID: user.ID.Hex()})
+	if err != nil {
+		return nil, err
+	}
+	read, err := s.page(ctx, user.ID, page)
+	if err != nil {
+		return nil, err
+	}
+
+	// pages follow on from what was read, posts left out included
+	var last interface{}
+	if read.Last != nil {
+		last = *read.Last
+	}
+	next, err := s.pages.Next(page, read.Read, read.Total, last)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while issuing page token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	res := &proto.PostsResponse{
+		Posts:         make([]*proto.Post, 0, len(read.Posts)),
+		Total:         read.Total,
+		PageSize:      page.Size,
+		NextPageToken: next,
+	}
+	for _, post := range read.Posts {
+		res.Posts = append(res.Posts, summaryToProto(post))
+	}
+	return res, nil
+}
+
+// StreamFeed sends the posts of the caller's feed from the page token on
+func (s *feedServer) StreamFeed(in *proto.GetFeedRequest, stream proto.FeedService_StreamFeedServer) error {
+	user := global.UserFromContext(stream.Context())
+	page, err := s.pages.Start(in.GetPageToken(), in.GetPageSize(), feedListing{UserID: user.ID.Hex()})
+	if err != nil {
+		return err
+	}
+	return paging.Walk(page, func(page paging.Page) (int, interface{}, error) {
+		read, err := s.page(stream.Context(), user.ID, page)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, post := range read.Posts {
+			if err := stream.Send(summaryToProto(post)); err != nil {
+				return 0, nil, err
+			}
+		}
+		if read.Last == nil {
+			return 0, nil, nil
+		}
+		return read.Read, *read.Last, nil
+	})
+}
+
+// page reads page of the feed of the user
+func (s *feedServer) page(ctx context.Context, userID primitive.ObjectID, page paging.Page) (feed.Page, error) {
+	var after store.PostKey
+	ok, err := page.Key(&after)
+	if err != nil {
+		return feed.Page{}, err
+	}
+	var key *store.PostKey
+	if ok {
+		key = &after
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	read, err := s.feed.Feed(dbCtx, userID, key, page.Size)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while reading feed", zap.Error(err))
+		return feed.Page{}, status.Error(codes.Internal, "Internal Error")
+	}
+	return read, nil
+}
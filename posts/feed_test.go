@@ -0,0 +1,144 @@
+package posts
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/feed"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/render"
+	"github.com/HiteshRepo/blog-application/search"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_feedServer_GetFeed(t *testing.T) {
+
+	ctx := context.Background()
+	posts, follows := store.NewMemoryPosts(), store.NewMemoryFollows()
+	fanout := feed.OnWrite(follows, posts, store.NewMemoryTimelines())
+	stores := Stores{Posts: posts, Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: fanout}
+	conn := newTestConn(t, New(stores, render.New(), paging.NewCodec(nil)))
+	postClient, client := proto.NewPostServiceClient(conn), proto.NewFeedServiceClient(conn)
+
+	follow := store.Follow{ID: primitive.NewObjectID(), FollowerID: testReader.ID, FolloweeID: testAuthor.ID, CreatedAt: time.Now()}
+	assert.NoError(t, follows.Insert(ctx, follow))
+	assert.NoError(t, fanout.Followed(ctx, follow))
+
+	feedIDs := func() []string {
+		res, err := client.GetFeed(as(testReader), &proto.GetFeedRequest{})
+		assert.NoError(t, err)
+		ids := []string{}
+		for _, post := range res.GetPosts() {
+			assert.Empty(t, post.GetBody())
+			ids = append(ids, post.GetID())
+		}
+		return ids
+	}
+
+	_, err := client.GetFeed(ctx, &proto.GetFeedRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	first, err := postClient.CreatePost(as(testAuthor), &proto.CreatePostRequest{Title: "First", Body: "Body"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, feedIDs(), "drafts are not in feeds")
+	_, err = postClient.PublishPost(as(testAuthor), &proto.PostRequest{PostID: first.GetID()})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{first.GetID()}, feedIDs(), "publishing adds")
+
+	// scheduled posts are added once the scheduler publishes them
+	now := time.Now().UTC().Add(time.Hour)
+	scheduled := store.Post{ID: primitive.NewObjectID(), AuthorID: testAuthor.ID, Title: "Scheduled", State: store.PostScheduled, PublishAt: now}
+	assert.NoError(t, posts.Insert(ctx, scheduled))
+	scheduler := NewScheduler(stores)
+	scheduler.Now = func() time.Time { return now }
+	_, err = scheduler.PublishDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{scheduled.ID.Hex(), first.GetID()}, feedIDs(), "latest first")
+
+	// pages are bound to the reader
+	res, err := client.GetFeed(as(testReader), &proto.GetFeedRequest{PageSize: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), res.GetTotal())
+	next, err := client.GetFeed(as(testReader), &proto.GetFeedRequest{PageSize: 1, PageToken: res.GetNextPageToken()})
+	assert.NoError(t, err)
+	if assert.Len(t, next.GetPosts(), 1) {
+		assert.Equal(t, first.GetID(), next.GetPosts()[0].GetID())
+	}
+	_, err = client.GetFeed(as(testOther), &proto.GetFeedRequest{PageSize: 1, PageToken: res.GetNextPageToken()})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = postClient.UnlistPost(as(testAuthor), &proto.PostRequest{PostID: first.GetID()})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{scheduled.ID.Hex()}, feedIDs(), "unlisting removes")
+
+	// the author follows nobody
+	res, err = client.GetFeed(as(testAuthor), &proto.GetFeedRequest{})
+	assert.NoError(t, err)
+	assert.Empty(t, res.GetPosts())
+}
+
+func Test_feedServer_GetFeed_withdrawn(t *testing.T) {
+
+	ctx := context.Background()
+	posts, follows := store.NewMemoryPosts(), store.NewMemoryFollows()
+	fanout := feed.OnWrite(follows, posts, store.NewMemoryTimelines())
+	stores := Stores{Posts: posts, Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: fanout}
+	client := proto.NewFeedServiceClient(newTestConn(t, New(stores, render.New(), paging.NewCodec(nil))))
+
+	follow := store.Follow{ID: primitive.NewObjectID(), FollowerID: testReader.ID, FolloweeID: testAuthor.ID, CreatedAt: time.Now()}
+	assert.NoError(t, follows.Insert(ctx, follow))
+	start := time.Now().UTC().Truncate(time.Millisecond)
+	published := []store.Post{}
+	for i := 0; i < 4; i++ {
+		post := store.Post{ID: primitive.NewObjectID(), AuthorID: testAuthor.ID, Title: "Post", State: store.PostPublished, PublishedAt: start.Add(time.Duration(i) * time.Minute)}
+		assert.NoError(t, posts.Insert(ctx, post))
+		assert.NoError(t, fanout.Published(ctx, post))
+		published = append(published, post)
+	}
+	// the two latest are archived while taking them off the timelines failed
+	for _, post := range published[2:] {
+		post.State = store.PostArchived
+		_, err := posts.SetState(ctx, store.PostPublished, post)
+		assert.NoError(t, err)
+	}
+
+	// a page of withdrawn posts does not end the feed
+	res, err := client.GetFeed(as(testReader), &proto.GetFeedRequest{PageSize: 2})
+	assert.NoError(t, err)
+	assert.Empty(t, res.GetPosts())
+	assert.NotEmpty(t, res.GetNextPageToken())
+	next, err := client.GetFeed(as(testReader), &proto.GetFeedRequest{PageSize: 2, PageToken: res.GetNextPageToken()})
+	assert.NoError(t, err)
+	ids := []string{}
+	for _, post := range next.GetPosts() {
+		ids = append(ids, post.GetID())
+	}
+	assert.Equal(t, []string{published[1].ID.Hex(), published[0].ID.Hex()}, ids)
+	assert.Empty(t, next.GetNextPageToken())
+
+	// nor a stream
+	stream, err := client.StreamFeed(as(testReader), &proto.GetFeedRequest{PageSize: 2})
+	assert.NoError(t, err)
+	streamed := []string{}
+	for {
+		post, err := stream.Recv()
+		if err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+		streamed = append(streamed, post.GetID())
+	}
+	assert.Equal(t, ids, streamed)
+
+	stream, err = client.StreamFeed(ctx, &proto.GetFeedRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
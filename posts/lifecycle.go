@@ -0,0 +1,152 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// transitions lists the states each state may move to. Scheduled posts may
+// be rescheduled, every post may go back to draft.
+var transitions = map[store.PostState][]store.PostState{
+	store.PostDraft:     {store.PostScheduled, store.PostPublished, store.PostArchived},
+	store.PostScheduled: {store.PostDraft, store.PostScheduled, store.PostPublished, store.PostArchived},
+	store.PostPublished: {store.PostDraft, store.PostUnlisted, store.PostArchived},
+	store.PostUnlisted:  {store.PostDraft, store.PostPublished, store.PostArchived},
+	store.PostArchived:  {store.PostDraft},
+}
+
+// canMove reports whether a post in state from may move to state to
+func canMove(from, to store.PostState) bool {
+	for _, state := range transitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// visible reports whether anyone may read the post, not only its editors
+func visible(post store.Post) bool {
+	state := post.GetState()
+	return state == store.PostPublished || state == store.PostUnlisted
+}
+
+func (p *postServer) PublishPost(ctx context.Context, in *proto.PostRequest) (*proto.Post, error) {
+	return p.transition(ctx, in.GetPostID(), store.PostPublished, time.Time{})
+}
+
+func (p *postServer) SchedulePost(ctx context.Context, in *proto.SchedulePostRequest) (*proto.Post, error) {
+	publishAt := time.Unix(in.GetPublishAt(), 0).UTC()
+	if !publishAt.After(p.now()) {
+		return nil, status.Error(codes.InvalidArgument, "PublishAt should be in the future")
+	}
+	return p.transition(ctx, in.GetPostID(), store.PostScheduled, publishAt)
+}
+
+func (p *postServer) UnlistPost(ctx context.Context, in *proto.PostRequest) (*proto.Post, error) {
+	return p.transition(ctx, in.GetPostID(), store.PostUnlisted, time.Time{})
+}
+
+func (p *postServer) ArchivePost(ctx context.Context, in *proto.PostRequest) (*proto.Post, error) {
+	return p.transition(ctx, in.GetPostID(), store.PostArchived, time.Time{})
+}
+
+func (p *postServer) UnpublishPost(ctx context.Context, in *proto.PostRequest) (*proto.Post, error) {
+	return p.transition(ctx, in.GetPostID(), store.PostDraft, time.Time{})
+}
+
+// DeletePost takes the post off the search index, the feeds and the tag and
+// category counts, and drops its revisions and reactions
+func (p *postServer) DeletePost(ctx context.Context, in *proto.PostRequest) (*proto.Post, error) {
+	post, err := p.find(ctx, in.GetPostID())
+	if err != nil {
+		return nil, err
+	}
+	if err := canEdit(ctx, post); err != nil {
+		return nil, err
+	}
+
+	// delete should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	deleted, err := p.posts.Delete(dbCtx, post.ID)
+	if err == store.ErrNotFound {
+		return nil, status.Error(codes.NotFound, "Post not found")
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while deleting post", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	// deleted posts are withdrawn from wherever published posts go
+	withdrawn := deleted
+	withdrawn.State = store.PostArchived
+	p.reindex(ctx, withdrawn)
+	if deleted.GetState() == store.PostPublished {
+		p.recount(ctx, deleted)
+	}
+
+	// cleaning up should not take more that 5 seconds
+	cleanCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	if err := p.reactions.DeletePost(cleanCtx, deleted.ID); err != nil {
+		// the post is gone, only log
+		logging.FromContext(ctx).Error("Error returned while deleting reactions", zap.Error(err), zap.String("post_id", deleted.ID.Hex()))
+	}
+	if err := p.revisions.DeletePost(cleanCtx, deleted.ID); err != nil {
+		logging.FromContext(ctx).Error("Error returned while deleting revisions", zap.Error(err), zap.String("post_id", deleted.ID.Hex()))
+	}
+	return postToProto(deleted), nil
+}
+
+// transition moves the post to state, publishAt is only kept for scheduled posts
+func (p *postServer) transition(ctx context.Context, hex string, to store.PostState, publishAt time.Time) (*proto.Post, error) {
+	post, err := p.find(ctx, hex)
+	if err != nil {
+		return nil, err
+	}
+	if err := canEdit(ctx, post); err != nil {
+		return nil, err
+	}
+	from := post.GetState()
+	if !canMove(from, to) {
+		return nil, status.Error(codes.FailedPrecondition, fmt.Sprintf("Cannot move a post from %s to %s", from, to))
+	}
+
+	now := p.now().UTC()
+	stored := post.State
+	post.State, post.PublishAt, post.UpdatedAt = to, publishAt, now
+	if to == store.PostPublished && post.PublishedAt.IsZero() {
+		post.PublishedAt = now
+	}
+
+	// update should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	updated, err := p.posts.SetState(dbCtx, stored, post)
+	if err == store.ErrConflict {
+		// e.g. the scheduler published it meanwhile
+		return nil, status.Error(codes.Aborted, "Post changed meanwhile, try again")
+	}
+	if err == store.ErrNotFound {
+		return nil, status.Error(codes.NotFound, "Post not found")
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while changing post state", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	if from == store.PostPublished || to == store.PostPublished {
+		p.recount(ctx, updated)
+		p.reindex(ctx, updated)
+	}
+	return postToProto(updated), nil
+}
@@ -0,0 +1,235 @@
+package posts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/feed"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/render"
+	"github.com/HiteshRepo/blog-application/search"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_canMove(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"from": store.PostDraft, "to": store.PostPublished, "ok": true},
+		map[string]interface{}{"from": store.PostDraft, "to": store.PostScheduled, "ok": true},
+		map[string]interface{}{"from": store.PostDraft, "to": store.PostUnlisted, "ok": false},
+		map[string]interface{}{"from": store.PostDraft, "to": store.PostDraft, "ok": false},
+		map[string]interface{}{"from": store.PostScheduled, "to": store.PostScheduled, "ok": true},
+		map[string]interface{}{"from": store.PostScheduled, "to": store.PostUnlisted, "ok": false},
+		map[string]interface{}{"from": store.PostPublished, "to": store.PostUnlisted, "ok": true},
+		map[string]interface{}{"from": store.PostPublished, "to": store.PostScheduled, "ok": false},
+		map[string]interface{}{"from": store.PostUnlisted, "to": store.PostPublished, "ok": true},
+		map[string]interface{}{"from": store.PostArchived, "to": store.PostDraft, "ok": true},
+		map[string]interface{}{"from": store.PostArchived, "to": store.PostPublished, "ok": false},
+	}
+
+	for _, tcase := range testCases {
+		assert.Equalf(t, tcase["ok"], canMove(tcase["from"].(store.PostState), tcase["to"].(store.PostState)), "case: %v", tcase)
+	}
+}
+
+func Test_postServer_transitions(t *testing.T) {
+
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	posts := store.NewMemoryPosts()
+	service := New(Stores{Posts: posts, Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil))
+	service.posts.now = func() time.Time { return now }
+	client := newTestClient(t, service)
+
+	post := store.Post{ID: primitive.NewObjectID(), AuthorID: testAuthor.ID, Title: "Lifecycle", State: store.PostDraft}
+	posts.Insert(context.Background(), post)
+	id := &proto.PostRequest{PostID: post.ID.Hex()}
+
+	// every step runs on the post as the previous one left it
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"call":    func() (*proto.Post, error) { return client.PublishPost(as(testOther), id) },
+			"code":    codes.PermissionDenied,
+			"message": "Only the author can edit this post",
+		},
+		map[string]interface{}{
+			"call": func() (*proto.Post, error) { return client.PublishPost(as(testReader), id) },
+			"code": codes.PermissionDenied,
+		},
+		map[string]interface{}{
+			"call":    func() (*proto.Post, error) { return client.UnlistPost(as(testAuthor), id) },
+			"code":    codes.FailedPrecondition,
+			"message": "Cannot move a post from draft to unlisted",
+		},
+		map[string]interface{}{
+			"call": func() (*proto.Post, error) {
+				return client.SchedulePost(as(testAuthor), &proto.SchedulePostRequest{PostID: post.ID.Hex(), PublishAt: now.Add(-time.Minute).Unix()})
+			},
+			"code":    codes.InvalidArgument,
+			"message": "PublishAt should be in the future",
+		},
+		map[string]interface{}{
+			"call": func() (*proto.Post, error) {
+				return client.SchedulePost(as(testAuthor), &proto.SchedulePostRequest{PostID: post.ID.Hex(), PublishAt: now.Add(time.Hour).Unix()})
+			},
+			"state":     "scheduled",
+			"publishAt": now.Add(time.Hour).Unix(),
+		},
+		map[string]interface{}{
+			// rescheduling
+			"call": func() (*proto.Post, error) {
+				return client.SchedulePost(as(testAuthor), &proto.SchedulePostRequest{PostID: post.ID.Hex(), PublishAt: now.Add(2 * time.Hour).Unix()})
+			},
+			"state":     "scheduled",
+			"publishAt": now.Add(2 * time.Hour).Unix(),
+		},
+		map[string]interface{}{
+			"call":        func() (*proto.Post, error) { return client.PublishPost(as(testAuthor), id) },
+			"state":       "published",
+			"publishedAt": now.Unix(),
+		},
+		map[string]interface{}{
+			// editors manage anyone's posts
+			"call":        func() (*proto.Post, error) { return client.UnlistPost(as(testEditor), id) },
+			"state":       "unlisted",
+			"publishedAt": now.Unix(),
+		},
+		map[string]interface{}{
+			"call":        func() (*proto.Post, error) { return client.ArchivePost(as(testAuthor), id) },
+			"state":       "archived",
+			"publishedAt": now.Unix(),
+		},
+		map[string]interface{}{
+			"call":    func() (*proto.Post, error) { return client.PublishPost(as(testAuthor), id) },
+			"code":    codes.FailedPrecondition,
+			"message": "Cannot move a post from archived to published",
+		},
+		map[string]interface{}{
+			"call":        func() (*proto.Post, error) { return client.UnpublishPost(as(testAuthor), id) },
+			"state":       "draft",
+			"publishedAt": now.Unix(),
+		},
+		map[string]interface{}{
+			"call": func() (*proto.Post, error) {
+				return client.PublishPost(as(testAuthor), &proto.PostRequest{PostID: primitive.NewObjectID().Hex()})
+			},
+			"code": codes.NotFound,
+		},
+	}
+
+	for _, tcase := range testCases {
+		res, err := tcase["call"].(func() (*proto.Post, error))()
+		code, _ := tcase["code"].(codes.Code)
+		assert.Equalf(t, code, status.Code(err), "case: %v", tcase)
+		if message, ok := tcase["message"]; ok {
+			assert.Equalf(t, message, status.Convert(err).Message(), "case: %v", tcase)
+		}
+		if err != nil {
+			continue
+		}
+
+		assert.Equalf(t, tcase["state"], res.GetState(), "case: %v", tcase)
+		publishAt, _ := tcase["publishAt"].(int64)
+		assert.Equalf(t, publishAt, res.GetPublishAt(), "case: %v", tcase)
+		publishedAt, _ := tcase["publishedAt"].(int64)
+		assert.Equalf(t, publishedAt, res.GetPublishedAt(), "case: %v", tcase)
+
+		stored, _ := posts.FindByID(context.Background(), post.ID)
+		assert.Equalf(t, tcase["state"], string(stored.State), "case: %v", tcase)
+	}
+}
+
+// racingPosts changes the state of the post between the read and the write
+// of a transition, the way another instance would
+type racingPosts struct {
+	store.Posts
+}
+
+func (r racingPosts) SetState(ctx context.Context, from store.PostState, post store.Post) (store.Post, error) {
+	r.Posts.SetState(ctx, from, store.Post{ID: post.ID, State: store.PostPublished})
+	return r.Posts.SetState(ctx, from, post)
+}
+
+func Test_postServer_transitions_conflict(t *testing.T) {
+
+	posts := store.NewMemoryPosts()
+	client := newTestClient(t, New(Stores{Posts: racingPosts{posts}, Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+
+	post := store.Post{ID: primitive.NewObjectID(), AuthorID: testAuthor.ID, State: store.PostScheduled}
+	posts.Insert(context.Background(), post)
+
+	_, err := client.ArchivePost(as(testAuthor), &proto.PostRequest{PostID: post.ID.Hex()})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	stored, _ := posts.FindByID(context.Background(), post.ID)
+	assert.Equal(t, store.PostPublished, stored.State)
+}
+
+func Test_postServer_DeletePost(t *testing.T) {
+
+	ctx := context.Background()
+	posts, follows, tags, index := store.NewMemoryPosts(), store.NewMemoryFollows(), store.NewMemoryTags(), search.NewMemoryIndex()
+	revisions, reactions := store.NewMemoryRevisions(0), store.NewMemoryReactions()
+	fanout := feed.OnWrite(follows, posts, store.NewMemoryTimelines())
+	stores := Stores{Posts: posts, Revisions: revisions, Tags: tags, Categories: store.NewMemoryCategories(), Index: index, Feed: fanout, Reactions: reactions}
+	conn := newTestConn(t, New(stores, render.New(), paging.NewCodec(nil)))
+	client, feedClient := proto.NewPostServiceClient(conn), proto.NewFeedServiceClient(conn)
+
+	follow := store.Follow{ID: primitive.NewObjectID(), FollowerID: testReader.ID, FolloweeID: testAuthor.ID, CreatedAt: time.Now()}
+	assert.NoError(t, follows.Insert(ctx, follow))
+	assert.NoError(t, fanout.Followed(ctx, follow))
+
+	post, err := client.CreatePost(as(testAuthor), &proto.CreatePostRequest{Title: "Doomed", Body: "Unicorns", Tags: []string{"go"}})
+	assert.NoError(t, err)
+	_, err = client.PublishPost(as(testAuthor), &proto.PostRequest{PostID: post.GetID()})
+	assert.NoError(t, err)
+	postID, _ := primitive.ObjectIDFromHex(post.GetID())
+	assert.NoError(t, reactions.Insert(ctx, store.Reaction{ID: primitive.NewObjectID(), Kind: store.ReactionLike, UserID: testReader.ID, PostID: postID}))
+	tag, err := tags.Find(ctx, "go")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), tag.PostCount)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"ctx": context.Background(), "post": post.GetID(), "code": codes.Unauthenticated},
+		map[string]interface{}{"ctx": as(testReader), "post": post.GetID(), "code": codes.PermissionDenied},
+		map[string]interface{}{"ctx": as(testOther), "post": post.GetID(), "code": codes.PermissionDenied},
+		map[string]interface{}{"ctx": as(testAuthor), "post": "not-an-id", "code": codes.InvalidArgument},
+		map[string]interface{}{"ctx": as(testAuthor), "post": post.GetID(), "title": "Doomed"},
+		// the post is gone
+		map[string]interface{}{"ctx": as(testAuthor), "post": post.GetID(), "code": codes.NotFound},
+	}
+
+	for _, tcase := range testCases {
+		deleted, err := client.DeletePost(tcase["ctx"].(context.Context), &proto.PostRequest{PostID: tcase["post"].(string)})
+		code, _ := tcase["code"].(codes.Code)
+		assert.Equalf(t, code, status.Code(err), "case: %v", tcase)
+		if code != codes.OK {
+			continue
+		}
+		assert.Equalf(t, tcase["title"], deleted.GetTitle(), "case: %v", tcase)
+	}
+
+	// and so is everything about it
+	_, err = client.GetPost(as(testAuthor), &proto.PostRequest{PostID: post.GetID()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	hits, _, err := index.Search(ctx, search.Query{Text: "unicorns"})
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+	res, err := feedClient.GetFeed(as(testReader), &proto.GetFeedRequest{})
+	assert.NoError(t, err)
+	assert.Empty(t, res.GetPosts())
+	tag, err = tags.Find(ctx, "go")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), tag.PostCount)
+	listed, total, err := reactions.List(ctx, store.ReactionFilter{PostID: postID}, 0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, listed)
+	assert.Equal(t, int64(0), total)
+	history, err := revisions.List(ctx, postID)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+}
@@ -0,0 +1,442 @@
+// Package posts serves the PostService. Posts are written in Markdown and
+// rendered to sanitized HTML when saved.
+package posts
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/events"
+	"github.com/HiteshRepo/blog-application/feed"
+	"github.com/HiteshRepo/blog-application/gateway"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/render"
+	"github.com/HiteshRepo/blog-application/search"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Service serves the PostService, the TaxonomyService, the FeedService and
+// the ReactionService
+type Service struct {
+	posts     *postServer
+	taxonomy  *taxonomyServer
+	feed      *feedServer
+	reactions *reactionServer
+}
+
+// Stores holds where the post services keep their data
+type Stores struct {
+	Posts      store.Posts
+	Revisions  store.Revisions
+	Tags       store.Tags
+	Categories store.Categories
+	// Index is kept in line with the published posts
+	Index search.Indexer
+	// Feed is told about posts as they are published and withdrawn
+	Feed feed.Fanout
+	// Reactions holds the likes and bookmarks, counted on the posts and
+	// dropped with them
+	Reactions store.Reactions
+	// Events carries new likes to whoever listens
+	Events events.Publisher
+}
+
+// New returns the post services on the given stores, issuing page tokens with pages
+func New(stores Stores, renderer *render.Renderer, pages *paging.Codec) *Service {
+	counts := &counts{posts: stores.Posts, tags: stores.Tags, categories: stores.Categories}
+	return &Service{
+		posts: &postServer{
+			posts:      stores.Posts,
+			revisions:  stores.Revisions,
+			tags:       stores.Tags,
+			categories: stores.Categories,
+			index:      stores.Index,
+			feed:       stores.Feed,
+			reactions:  stores.Reactions,
+			counts:     counts,
+			renderer:   renderer,
+			now:        time.Now,
+		},
+		taxonomy:  &taxonomyServer{posts: stores.Posts, tags: stores.Tags, categories: stores.Categories, counts: counts, pages: pages},
+		feed:      &feedServer{feed: stores.Feed, pages: pages},
+		reactions: &reactionServer{posts: stores.Posts, reactions: stores.Reactions, events: stores.Events, pages: pages, now: time.Now},
+	}
+}
+
+// Register adds the PostService, the TaxonomyService, the FeedService and the
+// ReactionService to server
+func (s *Service) Register(server *grpc.Server) {
+	proto.RegisterPostServiceServer(server, s.posts)
+	proto.RegisterTaxonomyServiceServer(server, s.taxonomy)
+	proto.RegisterFeedServiceServer(server, s.feed)
+	proto.RegisterReactionServiceServer(server, s.reactions)
+}
+
+// Require lists the permissions needed by every guarded RPC
+func (s *Service) Require(p *policy.Policy) {
+	p.Require("/proto.PostService/CreatePost", policy.PermWritePosts).
+		Require("/proto.PostService/UpdatePost", policy.PermWritePosts).
+		Require("/proto.PostService/PublishPost", policy.PermWritePosts).
+		Require("/proto.PostService/SchedulePost", policy.PermWritePosts).
+		Require("/proto.PostService/UnlistPost", policy.PermWritePosts).
+		Require("/proto.PostService/ArchivePost", policy.PermWritePosts).
+		Require("/proto.PostService/UnpublishPost", policy.PermWritePosts).
+		Require("/proto.PostService/DeletePost", policy.PermWritePosts).
+		Require("/proto.PostService/PreviewPost", policy.PermWritePosts).
+		Require("/proto.PostService/ListRevisions", policy.PermWritePosts).
+		Require("/proto.PostService/GetRevision", policy.PermWritePosts).
+		Require("/proto.PostService/DiffRevisions", policy.PermWritePosts).
+		Require("/proto.PostService/RestoreRevision", policy.PermWritePosts).
+		Require("/proto.TaxonomyService/CreateCategory", policy.PermManageTaxonomy).
+		Require("/proto.TaxonomyService/RenameTag", policy.PermManageTaxonomy).
+		Require("/proto.TaxonomyService/MergeTag", policy.PermManageTaxonomy).
+		Require("/proto.FeedService/GetFeed").
+		Require("/proto.FeedService/StreamFeed").
+		Require("/proto.ReactionService/Like").
+		Require("/proto.ReactionService/Unlike").
+		Require("/proto.ReactionService/Bookmark").
+		Require("/proto.ReactionService/Unbookmark").
+		Require("/proto.ReactionService/Clap").
+		Require("/proto.ReactionService/Unclap").
+		Require("/proto.ReactionService/ListMyBookmarks").
+		Require("/proto.ReactionService/StreamMyBookmarks").
+		// authors see their own drafts
+		Optional("/proto.PostService/GetPost")
+}
+
+// Routes maps the PostService to the JSON API
+func (s *Service) Routes(g *gateway.Gateway) {
+	g.Handle(http.MethodPost, "/v1/posts", "/proto.PostService/CreatePost").
+		Handle(http.MethodPost, "/v1/posts/preview", "/proto.PostService/PreviewPost").
+		Handle(http.MethodGet, "/v1/posts/{postID}", "/proto.PostService/GetPost").
+		Handle(http.MethodPut, "/v1/posts/{postID}", "/proto.PostService/UpdatePost").
+		Handle(http.MethodDelete, "/v1/posts/{postID}", "/proto.PostService/DeletePost").
+		Handle(http.MethodPost, "/v1/posts/{postID}/publish", "/proto.PostService/PublishPost").
+		Handle(http.MethodPost, "/v1/posts/{postID}/schedule", "/proto.PostService/SchedulePost").
+		Handle(http.MethodPost, "/v1/posts/{postID}/unlist", "/proto.PostService/UnlistPost").
+		Handle(http.MethodPost, "/v1/posts/{postID}/archive", "/proto.PostService/ArchivePost").
+		Handle(http.MethodPost, "/v1/posts/{postID}/unpublish", "/proto.PostService/UnpublishPost").
+		Handle(http.MethodGet, "/v1/posts/{postID}/revisions", "/proto.PostService/ListRevisions").
+		Handle(http.MethodGet, "/v1/posts/{postID}/revisions/{number}", "/proto.PostService/GetRevision").
+		Handle(http.MethodGet, "/v1/posts/{postID}/diff", "/proto.PostService/DiffRevisions").
+		Handle(http.MethodPost, "/v1/posts/{postID}/revisions/{number}/restore", "/proto.PostService/RestoreRevision").
+		Handle(http.MethodGet, "/v1/tags", "/proto.TaxonomyService/ListTags").
+		Handle(http.MethodGet, "/v1/tags/{tag}/posts", "/proto.TaxonomyService/GetPostsByTag").
+		Handle(http.MethodPut, "/v1/tags/{tag}", "/proto.TaxonomyService/RenameTag").
+		Handle(http.MethodPost, "/v1/tags/{tag}/merge", "/proto.TaxonomyService/MergeTag").
+		Handle(http.MethodGet, "/v1/categories", "/proto.TaxonomyService/ListCategories").
+		Handle(http.MethodPost, "/v1/categories", "/proto.TaxonomyService/CreateCategory").
+		Handle(http.MethodGet, "/v1/categories/{category}/posts", "/proto.TaxonomyService/GetPostsByCategory").
+		Handle(http.MethodGet, "/v1/feed", "/proto.FeedService/GetFeed").
+		Handle(http.MethodPut, "/v1/posts/{postID}/like", "/proto.ReactionService/Like").
+		Handle(http.MethodDelete, "/v1/posts/{postID}/like", "/proto.ReactionService/Unlike").
+		Handle(http.MethodPut, "/v1/posts/{postID}/bookmark", "/proto.ReactionService/Bookmark").
+		Handle(http.MethodDelete, "/v1/posts/{postID}/bookmark", "/proto.ReactionService/Unbookmark").
+		Handle(http.MethodPut, "/v1/posts/{postID}/clap", "/proto.ReactionService/Clap").
+		Handle(http.MethodDelete, "/v1/posts/{postID}/clap", "/proto.ReactionService/Unclap").
+		Handle(http.MethodGet, "/v1/bookmarks", "/proto.ReactionService/ListMyBookmarks")
+}
+
+type postServer struct {
+	posts      store.Posts
+	revisions  store.Revisions
+	tags       store.Tags
+	categories store.Categories
+	index      search.Indexer
+	feed       feed.Fanout
+	reactions  store.Reactions
+	counts     *counts
+	renderer   *render.Renderer
+	now        func() time.Time
+}
+
+func (p *postServer) CreatePost(ctx context.Context, in *proto.CreatePostRequest) (*proto.Post, error) {
+	tags, err := p.classify(ctx, in.GetTags(), in.GetCategory())
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.render(ctx, in.GetBody())
+	if err != nil {
+		return nil, err
+	}
+
+	now := p.now().UTC()
+	post := store.Post{
+		ID:        primitive.NewObjectID(),
+		AuthorID:  global.UserFromContext(ctx).ID,
+		Title:     in.GetTitle(),
+		Body:      in.GetBody(),
+		BodyHTML:  result.HTML,
+		TOC:       result.TOC,
+		CreatedAt: now,
+		UpdatedAt: now,
+		State:     store.PostDraft,
+		Revision:  1,
+		Tags:      tags,
+		Category:  slug(in.GetCategory()),
+	}
+
+	// insert should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	if err := p.posts.Insert(dbCtx, post); err != nil {
+		logging.FromContext(ctx).Error("Error returned while inserting post", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	p.record(ctx, post)
+	return postToProto(post), nil
+}
+
+func (p *postServer) UpdatePost(ctx context.Context, in *proto.UpdatePostRequest) (*proto.Post, error) {
+	post, err := p.find(ctx, in.GetPostID())
+	if err != nil {
+		return nil, err
+	}
+	if err := canEdit(ctx, post); err != nil {
+		return nil, err
+	}
+	tags, err := p.classify(ctx, in.GetTags(), in.GetCategory())
+	if err != nil {
+		return nil, err
+	}
+
+	before := post
+	post.Tags, post.Category = tags, slug(in.GetCategory())
+	return p.save(ctx, before, post, in.GetTitle(), in.GetBody())
+}
+
+// save renders and stores new content for post as its next revision, before
+// is the post as stored
+func (p *postServer) save(ctx context.Context, before, post store.Post, title, body string) (*proto.Post, error) {
+	result, err := p.render(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	post.Title, post.Body = title, body
+	post.BodyHTML, post.TOC = result.HTML, result.TOC
+	post.UpdatedAt = p.now().UTC()
+
+	// update should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	updated, err := p.posts.UpdateContent(dbCtx, post)
+	if err == store.ErrNotFound {
+		return nil, status.Error(codes.NotFound, "Post not found")
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while updating post", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	p.record(ctx, updated)
+	if updated.GetState() == store.PostPublished {
+		p.recount(ctx, before, updated)
+		p.reindex(ctx, updated)
+	}
+	return postToProto(updated), nil
+}
+
+// classify checks the category of a post and creates its new tags, it
+// returns the slugs of the tags
+func (p *postServer) classify(ctx context.Context, names []string, category string) ([]string, error) {
+	tags, err := tagsOf(names)
+	if err != nil {
+		return nil, err
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	if category != "" {
+		_, err := p.categories.Find(dbCtx, slug(category))
+		if err == store.ErrNotFound {
+			return nil, status.Error(codes.InvalidArgument, "Unknown category")
+		}
+		if err != nil {
+			logging.FromContext(ctx).Error("Error returned while looking up category", zap.Error(err))
+			return nil, status.Error(codes.Internal, "Internal Error")
+		}
+	}
+	for _, tag := range tags {
+		if err := p.tags.Ensure(dbCtx, tag); err != nil {
+			logging.FromContext(ctx).Error("Error returned while inserting tag", zap.Error(err))
+			return nil, status.Error(codes.Internal, "Internal Error")
+		}
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return slugsOf(tags), nil
+}
+
+// recount updates the post counts of the tags and categories of posts
+func (p *postServer) recount(ctx context.Context, posts ...store.Post) {
+	// counting should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	if err := p.counts.update(dbCtx, posts...); err != nil {
+		// the posts are saved, only log
+		logging.FromContext(ctx).Error("Error returned while counting posts", zap.Error(err))
+	}
+}
+
+func (p *postServer) GetPost(ctx context.Context, in *proto.PostRequest) (*proto.Post, error) {
+	post, err := p.find(ctx, in.GetPostID())
+	if err != nil {
+		return nil, err
+	}
+	// the existence of hidden posts is not revealed
+	if !visible(post) && canEdit(ctx, post) != nil {
+		return nil, status.Error(codes.NotFound, "Post not found")
+	}
+	return postToProto(post), nil
+}
+
+func (p *postServer) PreviewPost(ctx context.Context, in *proto.PreviewPostRequest) (*proto.PreviewPostResponse, error) {
+	result, err := p.render(ctx, in.GetBody())
+	if err != nil {
+		return nil, err
+	}
+	return &proto.PreviewPostResponse{BodyHTML: result.HTML, TOC: tocToProto(result.TOC)}, nil
+}
+
+// find returns the post with the hex id
+func (p *postServer) find(ctx context.Context, hex string) (store.Post, error) {
+	return findPost(ctx, p.posts, hex)
+}
+
+// findPost returns the post with the hex id from posts
+func findPost(ctx context.Context, posts store.Posts, hex string) (store.Post, error) {
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return store.Post{}, status.Error(codes.InvalidArgument, "Invalid post id")
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	post, err := posts.FindByID(dbCtx, id)
+	if err == store.ErrNotFound {
+		return store.Post{}, status.Error(codes.NotFound, "Post not found")
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while looking up post", zap.Error(err))
+		return store.Post{}, status.Error(codes.Internal, "Internal Error")
+	}
+	return post, nil
+}
+
+// canEdit lets editors change anyone's post, authors only their own
+func canEdit(ctx context.Context, post store.Post) error {
+	if post.AuthorID != global.UserFromContext(ctx).ID && policy.Check(ctx, policy.PermEditAnyPost) != nil {
+		return status.Error(codes.PermissionDenied, "Only the author can edit this post")
+	}
+	return nil
+}
+
+// render renders body the same way for previews and saves
+func (p *postServer) render(ctx context.Context, body string) (render.Result, error) {
+	result, err := p.renderer.Render(body)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while rendering post", zap.Error(err))
+		return render.Result{}, status.Error(codes.Internal, "Internal Error")
+	}
+	return result, nil
+}
+
+// reindex adds post to the search index and the feeds when it is published
+// and removes it otherwise
+func (p *postServer) reindex(ctx context.Context, post store.Post) {
+	// indexing should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	if err := index(dbCtx, p.index, post); err != nil {
+		// the post is saved, only log
+		logging.FromContext(ctx).Error("Error returned while indexing post", zap.Error(err), zap.String("post_id", post.ID.Hex()))
+	}
+
+	// fanning out should not take more that 5 seconds
+	feedCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	if err := spread(feedCtx, p.feed, post); err != nil {
+		logging.FromContext(ctx).Error("Error returned while updating feeds", zap.Error(err), zap.String("post_id", post.ID.Hex()))
+	}
+}
+
+// index keeps the search index in line with post
+func index(ctx context.Context, index search.Indexer, post store.Post) error {
+	if post.GetState() != store.PostPublished {
+		return index.Remove(ctx, post.ID.Hex())
+	}
+	return index.Index(ctx, Document(post))
+}
+
+// spread keeps the feeds in line with post
+func spread(ctx context.Context, fanout feed.Fanout, post store.Post) error {
+	if post.GetState() != store.PostPublished {
+		return fanout.Withdrawn(ctx, post)
+	}
+	return fanout.Published(ctx, post)
+}
+
+// Document returns the searchable part of a published post
+func Document(post store.Post) search.Document {
+	return search.Document{
+		ID:          post.ID.Hex(),
+		Kind:        search.KindPost,
+		AuthorID:    post.AuthorID.Hex(),
+		Title:       post.Title,
+		Body:        render.PlainText(post.BodyHTML),
+		Tags:        post.Tags,
+		PublishedAt: post.PublishedAt,
+	}
+}
+
+func postToProto(post store.Post) *proto.Post {
+	return &proto.Post{
+		ID:          post.ID.Hex(),
+		AuthorID:    post.AuthorID.Hex(),
+		Title:       post.Title,
+		Body:        post.Body,
+		BodyHTML:    post.BodyHTML,
+		TOC:         tocToProto(post.TOC),
+		CreatedAt:   post.CreatedAt.Unix(),
+		UpdatedAt:   post.UpdatedAt.Unix(),
+		State:       string(post.GetState()),
+		PublishAt:   unix(post.PublishAt),
+		PublishedAt: unix(post.PublishedAt),
+		Revision:    int32(post.Revision),
+		Tags:        post.Tags,
+		Category:    post.Category,
+		Likes:       post.Likes,
+		Bookmarks:   post.Bookmarks,
+		Claps:       post.Claps,
+	}
+}
+
+// unix returns t in unix seconds, zero for the zero time
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func tocToProto(toc []render.Heading) []*proto.Heading {
+	headings := make([]*proto.Heading, 0, len(toc))
+	for _, h := range toc {
+		headings = append(headings, &proto.Heading{Level: int32(h.Level), ID: h.ID, Text: h.Text})
+	}
+	return headings
+}
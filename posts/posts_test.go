@@ -0,0 +1,266 @@
+package posts
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/feed"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/render"
+	"github.com/HiteshRepo/blog-application/search"
+	"github.com/HiteshRepo/blog-application/server"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var (
+	testAuthor = global.User{ID: primitive.NewObjectID(), Username: "posts-author", Roles: []policy.Role{policy.RoleAuthor}}
+	testOther  = global.User{ID: primitive.NewObjectID(), Username: "posts-other", Roles: []policy.Role{policy.RoleAuthor}}
+	testEditor = global.User{ID: primitive.NewObjectID(), Username: "posts-editor", Roles: []policy.Role{policy.RoleEditor}}
+	testReader = global.User{ID: primitive.NewObjectID(), Username: "posts-reader", Roles: []policy.Role{policy.RoleReader}}
+	// noFeed is a feed built on read without follows, publishing leaves it alone
+	noFeed = feed.OnRead(store.NewMemoryFollows(), store.NewMemoryPosts())
+)
+
+// authenticate trusts the token, the auth service checks it against the db
+func authenticate(ctx context.Context) (context.Context, []policy.Role, error) {
+	user := global.UserFromToken(policy.TokenFromContext(ctx))
+	if user.IsNil() {
+		return ctx, nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	return global.ContextWithUser(ctx, user), user.GetRoles(), nil
+}
+
+// newTestConn serves service over bufconn on the production server bootstrap
+func newTestConn(t *testing.T, service *Service) *grpc.ClientConn {
+	t.Helper()
+
+	grpcServer := server.New(server.Config{Logger: zap.NewNop(), Authenticate: authenticate}, service).GRPCServer()
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcServer.Serve(listener)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error dialing bufconn : %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+	})
+	return conn
+}
+
+// newTestClient serves the PostService over bufconn
+func newTestClient(t *testing.T, service *Service) proto.PostServiceClient {
+	t.Helper()
+	return proto.NewPostServiceClient(newTestConn(t, service))
+}
+
+// as returns a context that calls the service with user's token
+func as(user global.User) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+user.GetToken())
+}
+
+func Test_postServer_CreatePost(t *testing.T) {
+
+	posts := store.NewMemoryPosts()
+	client := newTestClient(t, New(Stores{Posts: posts, Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"ctx":  context.Background(),
+			"in":   &proto.CreatePostRequest{Title: "Title", Body: "Body"},
+			"code": codes.Unauthenticated,
+		},
+		map[string]interface{}{
+			"ctx":  as(testReader),
+			"in":   &proto.CreatePostRequest{Title: "Title", Body: "Body"},
+			"code": codes.PermissionDenied,
+		},
+		map[string]interface{}{
+			"ctx":     as(testAuthor),
+			"in":      &proto.CreatePostRequest{Title: "Title"},
+			"code":    codes.InvalidArgument,
+			"message": "Validation failed : Body is required",
+		},
+		map[string]interface{}{
+			"ctx":  as(testAuthor),
+			"in":   &proto.CreatePostRequest{Title: "Hello", Body: "# Hello\n\nSome *text*<script>alert(1)</script>"},
+			"code": codes.OK,
+			"html": "<h1 id=\"hello\">Hello</h1>\n<p>Some <em>text</em></p>\n",
+		},
+	}
+
+	for _, tcase := range testCases {
+		res, err := client.CreatePost(tcase["ctx"].(context.Context), tcase["in"].(*proto.CreatePostRequest))
+		assert.Equalf(t, tcase["code"], status.Code(err), "case: %v", tcase)
+		if message, ok := tcase["message"]; ok {
+			assert.Equalf(t, message, status.Convert(err).Message(), "case: %v", tcase)
+		}
+		if err != nil {
+			continue
+		}
+
+		assert.Equalf(t, tcase["html"], res.GetBodyHTML(), "case: %v", tcase)
+		assert.Equalf(t, testAuthor.ID.Hex(), res.GetAuthorID(), "case: %v", tcase)
+		assert.Equalf(t, "draft", res.GetState(), "case: %v", tcase)
+
+		// the rendering is stored with the source
+		id, _ := primitive.ObjectIDFromHex(res.GetID())
+		stored, err := posts.FindByID(context.Background(), id)
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		assert.Equalf(t, tcase["in"].(*proto.CreatePostRequest).GetBody(), stored.Body, "case: %v", tcase)
+		assert.Equalf(t, tcase["html"], stored.BodyHTML, "case: %v", tcase)
+		assert.Equalf(t, []render.Heading{render.Heading{Level: 1, ID: "hello", Text: "Hello"}}, stored.TOC, "case: %v", tcase)
+	}
+}
+
+func Test_postServer_UpdatePost(t *testing.T) {
+
+	posts := store.NewMemoryPosts()
+	created := time.Now().UTC().Add(-time.Hour)
+	post := store.Post{ID: primitive.NewObjectID(), AuthorID: testAuthor.ID, Title: "Old", Body: "Old", BodyHTML: "<p>Old</p>\n", CreatedAt: created, UpdatedAt: created}
+	posts.Insert(context.Background(), post)
+	client := newTestClient(t, New(Stores{Posts: posts, Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+
+	update := func(body string) *proto.UpdatePostRequest {
+		return &proto.UpdatePostRequest{PostID: post.ID.Hex(), Title: "New", Body: body}
+	}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"ctx":     as(testOther),
+			"in":      update("By someone else"),
+			"code":    codes.PermissionDenied,
+			"message": "Only the author can edit this post",
+		},
+		map[string]interface{}{
+			"ctx":     as(testAuthor),
+			"in":      &proto.UpdatePostRequest{PostID: primitive.NewObjectID().Hex(), Title: "New", Body: "New"},
+			"code":    codes.NotFound,
+			"message": "Post not found",
+		},
+		map[string]interface{}{
+			"ctx":  as(testAuthor),
+			"in":   update("By the *author*"),
+			"code": codes.OK,
+			"html": "<p>By the <em>author</em></p>\n",
+		},
+		map[string]interface{}{
+			// editors may edit any post
+			"ctx":  as(testEditor),
+			"in":   update("## By the editor"),
+			"code": codes.OK,
+			"html": "<h2 id=\"by-the-editor\">By the editor</h2>\n",
+		},
+	}
+
+	for _, tcase := range testCases {
+		res, err := client.UpdatePost(tcase["ctx"].(context.Context), tcase["in"].(*proto.UpdatePostRequest))
+		assert.Equalf(t, tcase["code"], status.Code(err), "case: %v", tcase)
+		if message, ok := tcase["message"]; ok {
+			assert.Equalf(t, message, status.Convert(err).Message(), "case: %v", tcase)
+		}
+		if err != nil {
+			continue
+		}
+
+		assert.Equalf(t, "New", res.GetTitle(), "case: %v", tcase)
+		assert.Equalf(t, tcase["html"], res.GetBodyHTML(), "case: %v", tcase)
+		assert.Equalf(t, testAuthor.ID.Hex(), res.GetAuthorID(), "case: %v", tcase)
+		assert.Equalf(t, created.Unix(), res.GetCreatedAt(), "case: %v", tcase)
+		assert.Greaterf(t, res.GetUpdatedAt(), created.Unix(), "case: %v", tcase)
+
+		stored, _ := posts.FindByID(context.Background(), post.ID)
+		assert.Equalf(t, tcase["html"], stored.BodyHTML, "case: %v", tcase)
+	}
+}
+
+func Test_postServer_GetPost(t *testing.T) {
+
+	posts := store.NewMemoryPosts()
+	post := store.Post{ID: primitive.NewObjectID(), AuthorID: testAuthor.ID, Title: "Title", Body: "# Title", BodyHTML: "<h1 id=\"title\">Title</h1>\n", TOC: []render.Heading{render.Heading{Level: 1, ID: "title", Text: "Title"}}}
+	posts.Insert(context.Background(), post)
+	client := newTestClient(t, New(Stores{Posts: posts, Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+
+	// reading needs no token
+	res, err := client.GetPost(context.Background(), &proto.PostRequest{PostID: post.ID.Hex()})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Title", res.GetTitle())
+		assert.Equal(t, post.BodyHTML, res.GetBodyHTML())
+		assert.Equal(t, "title", res.GetTOC()[0].GetID())
+	}
+
+	// posts stored before the lifecycle existed were published
+	assert.Equal(t, "published", res.GetState())
+
+	_, err = client.GetPost(context.Background(), &proto.PostRequest{PostID: primitive.NewObjectID().Hex()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetPost(context.Background(), &proto.PostRequest{PostID: "not-an-id"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_postServer_GetPost_hidden(t *testing.T) {
+
+	posts := store.NewMemoryPosts()
+	client := newTestClient(t, New(Stores{Posts: posts, Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"state": store.PostDraft, "ctx": context.Background(), "code": codes.NotFound},
+		map[string]interface{}{"state": store.PostDraft, "ctx": as(testOther), "code": codes.NotFound},
+		map[string]interface{}{"state": store.PostDraft, "ctx": as(testAuthor), "code": codes.OK},
+		map[string]interface{}{"state": store.PostDraft, "ctx": as(testEditor), "code": codes.OK},
+		map[string]interface{}{"state": store.PostScheduled, "ctx": as(testReader), "code": codes.NotFound},
+		map[string]interface{}{"state": store.PostArchived, "ctx": context.Background(), "code": codes.NotFound},
+		map[string]interface{}{"state": store.PostArchived, "ctx": as(testAuthor), "code": codes.OK},
+		// unlisted posts are read by link
+		map[string]interface{}{"state": store.PostUnlisted, "ctx": context.Background(), "code": codes.OK},
+		map[string]interface{}{"state": store.PostPublished, "ctx": as(testReader), "code": codes.OK},
+		// a bad token is refused, rather than treated as anonymous
+		map[string]interface{}{"state": store.PostPublished, "ctx": metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer bad"), "code": codes.Unauthenticated},
+	}
+
+	for _, tcase := range testCases {
+		post := store.Post{ID: primitive.NewObjectID(), AuthorID: testAuthor.ID, Title: "Hidden", State: tcase["state"].(store.PostState)}
+		posts.Insert(context.Background(), post)
+
+		_, err := client.GetPost(tcase["ctx"].(context.Context), &proto.PostRequest{PostID: post.ID.Hex()})
+		assert.Equalf(t, tcase["code"], status.Code(err), "case: %v", tcase)
+	}
+}
+
+func Test_postServer_PreviewPost(t *testing.T) {
+
+	posts := store.NewMemoryPosts()
+	client := newTestClient(t, New(Stores{Posts: posts, Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+
+	res, err := client.PreviewPost(as(testAuthor), &proto.PreviewPostRequest{Body: "# Intro\n\n## Usage\n\n<img src=x onerror=alert(1)>"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "<h1 id=\"intro\">Intro</h1>\n<h2 id=\"usage\">Usage</h2>\n<img src=\"x\">", res.GetBodyHTML())
+		if assert.Len(t, res.GetTOC(), 2) {
+			assert.Equal(t, int32(2), res.GetTOC()[1].GetLevel())
+			assert.Equal(t, "Usage", res.GetTOC()[1].GetText())
+		}
+	}
+
+	_, err = client.PreviewPost(as(testReader), &proto.PreviewPostRequest{Body: "Body"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,237 @@
+package posts
+
+import (
+	"context"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/events"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type reactionServer struct {
+	posts     store.Posts
+	reactions store.Reactions
+	events    events.Publisher
+	pages     *paging.Codec
+	now       func() time.Time
+}
+
+func (r *reactionServer) Like(ctx context.Context, in *proto.ReactionRequest) (*proto.ReactionResponse, error) {
+	return r.react(ctx, in.GetPostID(), store.ReactionLike)
+}
+
+func (r *reactionServer) Unlike(ctx context.Context, in *proto.ReactionRequest) (*proto.ReactionResponse, error) {
+	return r.unreact(ctx, in.GetPostID(), store.ReactionLike)
+}
+
+func (r *reactionServer) Bookmark(ctx context.Context, in *proto.ReactionRequest) (*proto.ReactionResponse, error) {
+	return r.react(ctx, in.GetPostID(), store.ReactionBookmark)
+}
+
+func (r *reactionServer) Unbookmark(ctx context.Context, in *proto.ReactionRequest) (*proto.ReactionResponse, error) {
+	return r.unreact(ctx, in.GetPostID(), store.ReactionBookmark)
+}
+
+func (r *reactionServer) Clap(ctx context.Context, in *proto.ReactionRequest) (*proto.ReactionResponse, error) {
+	return r.react(ctx, in.GetPostID(), store.ReactionClap)
+}
+
+func (r *reactionServer) Unclap(ctx context.Context, in *proto.ReactionRequest) (*proto.ReactionResponse, error) {
+	return r.unreact(ctx, in.GetPostID(), store.ReactionClap)
+}
+
+// bookmarkListing binds the page tokens of a list of bookmarks to its owner
+type bookmarkListing struct {
+	UserID string `json:"user_id"`
+}
+
+// ListMyBookmarks leaves out the posts no longer visible
+func (r *reactionServer) ListMyBookmarks(ctx context.Context, in *proto.ListMyBookmarksRequest) (*proto.PostsResponse, error) {
+	user := global.UserFromContext(ctx)
+	page, err := r.pages.Start(in.GetPageToken(), in.GetPageSize(), bookmarkListing{UserID: user.ID.Hex()})
+	if err != nil {
+		return nil, err
+	}
+	read, err := r.bookmarks(ctx, user.ID, page)
+	if err != nil {
+		return nil, err
+	}
+	next, err := r.pages.Next(page, read.read, read.total, read.last)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while issuing page token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	res := &proto.PostsResponse{
+		Posts:         make([]*proto.Post, 0, len(read.posts)),
+		Total:         read.total,
+		PageSize:      page.Size,
+		NextPageToken: next,
+	}
+	for _, post := range read.posts {
+		res.Posts = append(res.Posts, summaryToProto(post))
+	}
+	return res, nil
+}
+
+// StreamMyBookmarks sends the bookmarked posts of the caller from the page
+// token on, leaving out those no longer visible
+func (r *reactionServer) StreamMyBookmarks(in *proto.ListMyBookmarksRequest, stream proto.ReactionService_StreamMyBookmarksServer) error {
+	user := global.UserFromContext(stream.Context())
+	page, err := r.pages.Start(in.GetPageToken(), in.GetPageSize(), bookmarkListing{UserID: user.ID.Hex()})
+	if err != nil {
+		return err
+	}
+	return paging.Walk(page, func(page paging.Page) (int, interface{}, error) {
+		read, err := r.bookmarks(stream.Context(), user.ID, page)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, post := range read.posts {
+			if err := stream.Send(summaryToProto(post)); err != nil {
+				return 0, nil, err
+			}
+		}
+		return read.read, read.last, nil
+	})
+}
+
+// bookmarkPage is a page of bookmarks, as the posts still visible
+type bookmarkPage struct {
+	posts []store.Post
+	// read is the number of bookmarks the page went through, those of posts
+	// no longer visible included, and last the id of the last of them
+	read  int
+	last  interface{}
+	total int64
+}
+
+// bookmarks reads page of the bookmarks of the user
+func (r *reactionServer) bookmarks(ctx context.Context, userID primitive.ObjectID, page paging.Page) (bookmarkPage, error) {
+	filter := store.ReactionFilter{Kind: store.ReactionBookmark, UserID: userID}
+	if _, err := page.Key(&filter.After); err != nil {
+		return bookmarkPage{}, err
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	bookmarks, total, err := r.reactions.List(dbCtx, filter, 0, page.Size)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while listing bookmarks", zap.Error(err))
+		return bookmarkPage{}, status.Error(codes.Internal, "Internal Error")
+	}
+	ids := make([]primitive.ObjectID, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		ids = append(ids, b.PostID)
+	}
+	posts, err := r.posts.FindByIDs(dbCtx, ids)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while looking up posts", zap.Error(err))
+		return bookmarkPage{}, status.Error(codes.Internal, "Internal Error")
+	}
+	byID := make(map[primitive.ObjectID]store.Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+
+	res := bookmarkPage{posts: make([]store.Post, 0, len(bookmarks)), read: len(bookmarks), total: total}
+	if len(bookmarks) > 0 {
+		res.last = bookmarks[len(bookmarks)-1].ID
+	}
+	for _, b := range bookmarks {
+		if post, ok := byID[b.PostID]; ok && visible(post) {
+			res.posts = append(res.posts, post)
+		}
+	}
+	return res, nil
+}
+
+// react stores the reaction of kind of the caller to the visible post with
+// the hex id, once. The unique reaction is what keeps the count right: only
+// the call that stored it counts it.
+func (r *reactionServer) react(ctx context.Context, hex string, kind store.ReactionKind) (*proto.ReactionResponse, error) {
+	post, err := findPost(ctx, r.posts, hex)
+	if err != nil {
+		return nil, err
+	}
+	if !visible(post) {
+		return nil, status.Error(codes.NotFound, "Post not found")
+	}
+
+	// update should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	caller := global.UserFromContext(ctx)
+	reaction := store.Reaction{ID: primitive.NewObjectID(), Kind: kind, UserID: caller.ID, PostID: post.ID, CreatedAt: r.now().UTC()}
+	err = r.reactions.Insert(dbCtx, reaction)
+	if err == store.ErrConflict {
+		return reactionToProto(post, true), nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while inserting reaction", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	counted, err := r.posts.CountReaction(dbCtx, post.ID, kind, 1)
+	if err != nil {
+		// uncounted, the reaction goes, so that retrying counts it
+		logging.FromContext(ctx).Error("Error returned while counting reaction", zap.Error(err))
+		if _, err := r.reactions.Delete(dbCtx, kind, reaction.UserID, post.ID); err != nil {
+			logging.FromContext(ctx).Error("Error returned while deleting uncounted reaction", zap.Error(err))
+		}
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	// bookmarks are private, only likes are news to the author
+	if kind == store.ReactionLike {
+		r.events.Publish(ctx, events.Event{Kind: events.Like, UserID: post.AuthorID, ActorID: caller.ID, ActorUsername: caller.Username, PostID: post.ID, PostTitle: post.Title, At: reaction.CreatedAt})
+	}
+	return reactionToProto(counted, true), nil
+}
+
+// unreact deletes the reaction of kind of the caller to the post with the hex
+// id, visible or not, and uncounts it
+func (r *reactionServer) unreact(ctx context.Context, hex string, kind store.ReactionKind) (*proto.ReactionResponse, error) {
+	post, err := findPost(ctx, r.posts, hex)
+	if err != nil {
+		return nil, err
+	}
+
+	// update should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	reaction, err := r.reactions.Delete(dbCtx, kind, global.UserFromContext(ctx).ID, post.ID)
+	if err == store.ErrNotFound {
+		return reactionToProto(post, false), nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while deleting reaction", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	counted, err := r.posts.CountReaction(dbCtx, post.ID, kind, -1)
+	if err != nil {
+		// still counted, the reaction stays, so that retrying uncounts it
+		logging.FromContext(ctx).Error("Error returned while uncounting reaction", zap.Error(err))
+		if err := r.reactions.Insert(dbCtx, reaction); err != nil && err != store.ErrConflict {
+			logging.FromContext(ctx).Error("Error returned while restoring counted reaction", zap.Error(err))
+		}
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	return reactionToProto(counted, false), nil
+}
+
+func reactionToProto(post store.Post, reacted bool) *proto.ReactionResponse {
+	return &proto.ReactionResponse{PostID: post.ID.Hex(), Reacted: reacted, Likes: post.Likes, Bookmarks: post.Bookmarks, Claps: post.Claps}
+}
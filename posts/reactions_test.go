@@ -0,0 +1,240 @@
+package posts
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/events"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/render"
+	"github.com/HiteshRepo/blog-application/search"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newReactionClients serves the post services on memory stores holding a
+// published post, an archived one and a draft, publishing likes on publisher
+func newReactionClients(t *testing.T, publisher events.Publisher) (proto.PostServiceClient, proto.ReactionServiceClient, []store.Post) {
+	t.Helper()
+
+	posts := store.NewMemoryPosts()
+	now := time.Now().UTC()
+	all := []store.Post{
+		store.Post{ID: primitive.NewObjectID(), AuthorID: testAuthor.ID, Title: "Published", State: store.PostPublished, PublishedAt: now},
+		store.Post{ID: primitive.NewObjectID(), AuthorID: testAuthor.ID, Title: "Archived", State: store.PostArchived, PublishedAt: now},
+		store.Post{ID: primitive.NewObjectID(), AuthorID: testAuthor.ID, Title: "Draft", State: store.PostDraft},
+	}
+	for _, post := range all {
+		if err := posts.Insert(context.Background(), post); err != nil {
+			t.Fatalf("Error inserting post : %v", err)
+		}
+	}
+	stores := Stores{Posts: posts, Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed, Reactions: store.NewMemoryReactions(), Events: publisher}
+	conn := newTestConn(t, New(stores, render.New(), paging.NewCodec(nil)))
+	return proto.NewPostServiceClient(conn), proto.NewReactionServiceClient(conn), all
+}
+
+func Test_reactionServer_Like(t *testing.T) {
+
+	bus := events.NewBus(16)
+	var likes []events.Event
+	bus.Subscribe(func(ctx context.Context, e events.Event) { likes = append(likes, e) })
+	postClient, client, all := newReactionClients(t, bus)
+	published, archived, draft := all[0], all[1], all[2]
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"ctx": context.Background(), "post": published.ID.Hex(), "code": codes.Unauthenticated},
+		map[string]interface{}{"ctx": as(testReader), "post": published.ID.Hex(), "likes": int64(1)},
+		// liking again changes nothing
+		map[string]interface{}{"ctx": as(testReader), "post": published.ID.Hex(), "likes": int64(1)},
+		map[string]interface{}{"ctx": as(testOther), "post": published.ID.Hex(), "likes": int64(2)},
+		map[string]interface{}{"ctx": as(testReader), "post": archived.ID.Hex(), "code": codes.NotFound},
+		map[string]interface{}{"ctx": as(testAuthor), "post": draft.ID.Hex(), "code": codes.NotFound},
+		map[string]interface{}{"ctx": as(testReader), "post": primitive.NewObjectID().Hex(), "code": codes.NotFound},
+		map[string]interface{}{"ctx": as(testReader), "post": "not-an-id", "code": codes.InvalidArgument},
+	}
+
+	for _, tcase := range testCases {
+		res, err := client.Like(tcase["ctx"].(context.Context), &proto.ReactionRequest{PostID: tcase["post"].(string)})
+		code, _ := tcase["code"].(codes.Code)
+		assert.Equalf(t, code, status.Code(err), "case: %v", tcase)
+		if code != codes.OK {
+			continue
+		}
+		assert.Truef(t, res.GetReacted(), "case: %v", tcase)
+		assert.Equalf(t, tcase["likes"], res.GetLikes(), "case: %v", tcase)
+	}
+
+	// the author is told about every new like, not about likes repeated, once
+	// the bus handled what was published
+	bus.Close()
+	if assert.Len(t, likes, 2) {
+		assert.Equal(t, events.Like, likes[0].Kind)
+		assert.Equal(t, testAuthor.ID, likes[0].UserID)
+		assert.Equal(t, testReader.ID, likes[0].ActorID)
+		assert.Equal(t, published.ID, likes[0].PostID)
+		assert.Equal(t, "Published", likes[0].PostTitle)
+		assert.Equal(t, testOther.ID, likes[1].ActorID)
+	}
+
+	// unliking twice uncounts once
+	for i := 0; i < 2; i++ {
+		res, err := client.Unlike(as(testOther), &proto.ReactionRequest{PostID: published.ID.Hex()})
+		assert.NoError(t, err)
+		assert.False(t, res.GetReacted())
+		assert.Equal(t, int64(1), res.GetLikes())
+	}
+	post, err := postClient.GetPost(context.Background(), &proto.PostRequest{PostID: published.ID.Hex()})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), post.GetLikes())
+	assert.Equal(t, int64(0), post.GetBookmarks())
+}
+
+func Test_reactionServer_Like_concurrently(t *testing.T) {
+
+	postClient, client, all := newReactionClients(t, events.Discard)
+	published := all[0]
+
+	// the same user liking at once is counted once
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Like(as(testReader), &proto.ReactionRequest{PostID: published.ID.Hex()})
+			if assert.NoError(t, err) {
+				assert.True(t, res.GetReacted())
+			}
+		}()
+	}
+	wg.Wait()
+
+	post, err := postClient.GetPost(context.Background(), &proto.PostRequest{PostID: published.ID.Hex()})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), post.GetLikes())
+}
+
+func Test_reactionServer_Clap(t *testing.T) {
+
+	bus := events.NewBus(16)
+	var published []events.Event
+	bus.Subscribe(func(ctx context.Context, e events.Event) { published = append(published, e) })
+	postClient, client, all := newReactionClients(t, bus)
+	post, archived := all[0], all[1]
+
+	_, err := client.Clap(context.Background(), &proto.ReactionRequest{PostID: post.ID.Hex()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.Clap(as(testReader), &proto.ReactionRequest{PostID: archived.ID.Hex()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// clapping at once, and again, is counted once per user
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Clap(as(testReader), &proto.ReactionRequest{PostID: post.ID.Hex()})
+			if assert.NoError(t, err) {
+				assert.True(t, res.GetReacted())
+			}
+		}()
+	}
+	wg.Wait()
+	res, err := client.Clap(as(testOther), &proto.ReactionRequest{PostID: post.ID.Hex()})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), res.GetClaps())
+	assert.Equal(t, int64(0), res.GetLikes())
+
+	// unclapping twice uncounts once
+	for i := 0; i < 2; i++ {
+		res, err := client.Unclap(as(testOther), &proto.ReactionRequest{PostID: post.ID.Hex()})
+		assert.NoError(t, err)
+		assert.False(t, res.GetReacted())
+		assert.Equal(t, int64(1), res.GetClaps())
+	}
+	got, err := postClient.GetPost(context.Background(), &proto.PostRequest{PostID: post.ID.Hex()})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), got.GetClaps())
+
+	// claps are no like, the author is not told
+	bus.Close()
+	assert.Empty(t, published)
+}
+
+func Test_reactionServer_Bookmark(t *testing.T) {
+
+	postClient, client, all := newReactionClients(t, events.Discard)
+	published, archived := all[0], all[1]
+	unlisted, err := postClient.CreatePost(as(testAuthor), &proto.CreatePostRequest{Title: "Unlisted", Body: "Body"})
+	assert.NoError(t, err)
+	_, err = postClient.PublishPost(as(testAuthor), &proto.PostRequest{PostID: unlisted.GetID()})
+	assert.NoError(t, err)
+	_, err = postClient.UnlistPost(as(testAuthor), &proto.PostRequest{PostID: unlisted.GetID()})
+	assert.NoError(t, err)
+
+	_, err = client.Bookmark(context.Background(), &proto.ReactionRequest{PostID: published.ID.Hex()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.Bookmark(as(testReader), &proto.ReactionRequest{PostID: archived.ID.Hex()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	for _, id := range []string{published.ID.Hex(), unlisted.GetID(), unlisted.GetID()} {
+		res, err := client.Bookmark(as(testReader), &proto.ReactionRequest{PostID: id})
+		assert.NoError(t, err)
+		assert.True(t, res.GetReacted())
+		assert.Equal(t, int64(1), res.GetBookmarks())
+	}
+
+	// latest bookmarks first, page by page, bound to the caller
+	res, err := client.ListMyBookmarks(as(testReader), &proto.ListMyBookmarksRequest{PageSize: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), res.GetTotal())
+	if assert.Len(t, res.GetPosts(), 1) {
+		assert.Equal(t, unlisted.GetID(), res.GetPosts()[0].GetID())
+		assert.Empty(t, res.GetPosts()[0].GetBody())
+	}
+	next, err := client.ListMyBookmarks(as(testReader), &proto.ListMyBookmarksRequest{PageSize: 1, PageToken: res.GetNextPageToken()})
+	assert.NoError(t, err)
+	if assert.Len(t, next.GetPosts(), 1) {
+		assert.Equal(t, published.ID.Hex(), next.GetPosts()[0].GetID())
+	}
+	assert.Empty(t, next.GetNextPageToken())
+	_, err = client.ListMyBookmarks(as(testOther), &proto.ListMyBookmarksRequest{PageSize: 1, PageToken: res.GetNextPageToken()})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	res, err = client.ListMyBookmarks(as(testOther), &proto.ListMyBookmarksRequest{})
+	assert.NoError(t, err)
+	assert.Empty(t, res.GetPosts())
+
+	// archived posts drop out of the list, they can still be unbookmarked
+	_, err = postClient.ArchivePost(as(testAuthor), &proto.PostRequest{PostID: unlisted.GetID()})
+	assert.NoError(t, err)
+	res, err = client.ListMyBookmarks(as(testReader), &proto.ListMyBookmarksRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, res.GetPosts(), 1)
+	// streams page on past them
+	stream, err := client.StreamMyBookmarks(as(testReader), &proto.ListMyBookmarksRequest{PageSize: 1})
+	assert.NoError(t, err)
+	streamed := []string{}
+	for {
+		post, err := stream.Recv()
+		if err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+		streamed = append(streamed, post.GetID())
+	}
+	assert.Equal(t, []string{published.ID.Hex()}, streamed)
+	unbookmarked, err := client.Unbookmark(as(testReader), &proto.ReactionRequest{PostID: unlisted.GetID()})
+	assert.NoError(t, err)
+	assert.False(t, unbookmarked.GetReacted())
+	assert.Equal(t, int64(0), unbookmarked.GetBookmarks())
+	res, err = client.ListMyBookmarks(as(testReader), &proto.ListMyBookmarksRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), res.GetTotal())
+}
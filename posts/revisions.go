@@ -0,0 +1,151 @@
+package posts
+
+import (
+	"context"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/diff"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (p *postServer) ListRevisions(ctx context.Context, in *proto.PostRequest) (*proto.ListRevisionsResponse, error) {
+	post, err := p.find(ctx, in.GetPostID())
+	if err != nil {
+		return nil, err
+	}
+	if err := canEdit(ctx, post); err != nil {
+		return nil, err
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	revisions, err := p.revisions.List(dbCtx, post.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while listing revisions", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	res := &proto.ListRevisionsResponse{Revisions: make([]*proto.Revision, 0, len(revisions))}
+	for _, revision := range revisions {
+		res.Revisions = append(res.Revisions, revisionToProto(revision))
+	}
+	return res, nil
+}
+
+func (p *postServer) GetRevision(ctx context.Context, in *proto.RevisionRequest) (*proto.Revision, error) {
+	post, err := p.find(ctx, in.GetPostID())
+	if err != nil {
+		return nil, err
+	}
+	if err := canEdit(ctx, post); err != nil {
+		return nil, err
+	}
+	revision, err := p.findRevision(ctx, post.ID, in.GetNumber())
+	if err != nil {
+		return nil, err
+	}
+	return revisionToProto(revision), nil
+}
+
+func (p *postServer) DiffRevisions(ctx context.Context, in *proto.DiffRevisionsRequest) (*proto.DiffRevisionsResponse, error) {
+	post, err := p.find(ctx, in.GetPostID())
+	if err != nil {
+		return nil, err
+	}
+	if err := canEdit(ctx, post); err != nil {
+		return nil, err
+	}
+	from, err := p.findRevision(ctx, post.ID, in.GetFrom())
+	if err != nil {
+		return nil, err
+	}
+	to, err := p.findRevision(ctx, post.ID, in.GetTo())
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []diff.Chunk
+	if in.GetMode() == "word" {
+		chunks = diff.Words(from.Body, to.Body)
+	} else {
+		chunks = diff.Lines(from.Body, to.Body)
+	}
+	res := &proto.DiffRevisionsResponse{Chunks: make([]*proto.DiffChunk, 0, len(chunks))}
+	for _, chunk := range chunks {
+		res.Chunks = append(res.Chunks, &proto.DiffChunk{Op: string(chunk.Op), Text: chunk.Text})
+	}
+	return res, nil
+}
+
+func (p *postServer) RestoreRevision(ctx context.Context, in *proto.RevisionRequest) (*proto.Post, error) {
+	post, err := p.find(ctx, in.GetPostID())
+	if err != nil {
+		return nil, err
+	}
+	if err := canEdit(ctx, post); err != nil {
+		return nil, err
+	}
+	revision, err := p.findRevision(ctx, post.ID, in.GetNumber())
+	if err != nil {
+		return nil, err
+	}
+	// restoring adds to the history rather than rewriting it
+	return p.save(ctx, post, post, revision.Title, revision.Body)
+}
+
+// findRevision returns the revision number of the post
+func (p *postServer) findRevision(ctx context.Context, postID primitive.ObjectID, number int32) (store.Revision, error) {
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	revision, err := p.revisions.Find(dbCtx, postID, int(number))
+	if err == store.ErrNotFound {
+		return store.Revision{}, status.Error(codes.NotFound, "Revision not found")
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while looking up revision", zap.Error(err))
+		return store.Revision{}, status.Error(codes.Internal, "Internal Error")
+	}
+	return revision, nil
+}
+
+// record adds the content just saved to the history of the post
+func (p *postServer) record(ctx context.Context, post store.Post) {
+	revision := store.Revision{
+		ID:        primitive.NewObjectID(),
+		PostID:    post.ID,
+		Number:    post.Revision,
+		AuthorID:  global.UserFromContext(ctx).ID,
+		Title:     post.Title,
+		Body:      post.Body,
+		CreatedAt: post.UpdatedAt,
+	}
+
+	// insert should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+	if err := p.revisions.Insert(dbCtx, revision); err != nil {
+		// the save went through, only log
+		logging.FromContext(ctx).Error("Error returned while inserting revision", zap.Error(err), zap.String("post_id", post.ID.Hex()))
+	}
+}
+
+func revisionToProto(revision store.Revision) *proto.Revision {
+	return &proto.Revision{
+		PostID:    revision.PostID.Hex(),
+		Number:    int32(revision.Number),
+		AuthorID:  revision.AuthorID.Hex(),
+		Title:     revision.Title,
+		Body:      revision.Body,
+		CreatedAt: revision.CreatedAt.Unix(),
+	}
+}
@@ -0,0 +1,170 @@
+package posts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/render"
+	"github.com/HiteshRepo/blog-application/search"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newRevisedPost creates a post as testAuthor and saves it with each body in turn
+func newRevisedPost(t *testing.T, client proto.PostServiceClient, bodies ...string) *proto.Post {
+	t.Helper()
+
+	post, err := client.CreatePost(as(testAuthor), &proto.CreatePostRequest{Title: "Revised", Body: bodies[0]})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for _, body := range bodies[1:] {
+		post, err = client.UpdatePost(as(testAuthor), &proto.UpdatePostRequest{PostID: post.GetID(), Title: "Revised", Body: body})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+	return post
+}
+
+func Test_postServer_ListRevisions(t *testing.T) {
+
+	client := newTestClient(t, New(Stores{Posts: store.NewMemoryPosts(), Revisions: store.NewMemoryRevisions(2), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+	post := newRevisedPost(t, client, "one", "two", "three")
+	assert.Equal(t, int32(3), post.GetRevision())
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"ctx": as(testOther), "code": codes.PermissionDenied},
+		map[string]interface{}{"ctx": as(testReader), "code": codes.PermissionDenied},
+		// the oldest revision is beyond the limit
+		map[string]interface{}{"ctx": as(testAuthor), "code": codes.OK, "numbers": []int32{3, 2}},
+		map[string]interface{}{"ctx": as(testEditor), "code": codes.OK, "numbers": []int32{3, 2}},
+	}
+
+	for _, tcase := range testCases {
+		res, err := client.ListRevisions(tcase["ctx"].(context.Context), &proto.PostRequest{PostID: post.GetID()})
+		assert.Equalf(t, tcase["code"], status.Code(err), "case: %v", tcase)
+		if err != nil {
+			continue
+		}
+
+		var numbers []int32
+		for _, revision := range res.GetRevisions() {
+			numbers = append(numbers, revision.GetNumber())
+			assert.Equalf(t, testAuthor.ID.Hex(), revision.GetAuthorID(), "case: %v", tcase)
+			assert.Emptyf(t, revision.GetBody(), "case: %v", tcase)
+		}
+		assert.Equalf(t, tcase["numbers"], numbers, "case: %v", tcase)
+	}
+}
+
+func Test_postServer_GetRevision(t *testing.T) {
+
+	client := newTestClient(t, New(Stores{Posts: store.NewMemoryPosts(), Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+	post := newRevisedPost(t, client, "one", "two")
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"ctx": as(testAuthor), "number": int32(1), "code": codes.OK, "body": "one"},
+		map[string]interface{}{"ctx": as(testAuthor), "number": int32(2), "code": codes.OK, "body": "two"},
+		map[string]interface{}{"ctx": as(testAuthor), "number": int32(3), "code": codes.NotFound},
+		map[string]interface{}{"ctx": as(testOther), "number": int32(1), "code": codes.PermissionDenied},
+	}
+
+	for _, tcase := range testCases {
+		res, err := client.GetRevision(tcase["ctx"].(context.Context), &proto.RevisionRequest{PostID: post.GetID(), Number: tcase["number"].(int32)})
+		assert.Equalf(t, tcase["code"], status.Code(err), "case: %v", tcase)
+		if err != nil {
+			continue
+		}
+		assert.Equalf(t, tcase["body"], res.GetBody(), "case: %v", tcase)
+		assert.Equalf(t, post.GetID(), res.GetPostID(), "case: %v", tcase)
+	}
+}
+
+func Test_postServer_DiffRevisions(t *testing.T) {
+
+	client := newTestClient(t, New(Stores{Posts: store.NewMemoryPosts(), Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+	post := newRevisedPost(t, client, "# Title\n\nThe quick fox\n", "# Title\n\nThe slow fox\n")
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"in": &proto.DiffRevisionsRequest{PostID: post.GetID(), From: 1, To: 2},
+			"chunks": []*proto.DiffChunk{
+				&proto.DiffChunk{Op: "equal", Text: "# Title\n\n"},
+				&proto.DiffChunk{Op: "delete", Text: "The quick fox\n"},
+				&proto.DiffChunk{Op: "insert", Text: "The slow fox\n"},
+			},
+		},
+		map[string]interface{}{
+			"in": &proto.DiffRevisionsRequest{PostID: post.GetID(), From: 1, To: 2, Mode: "word"},
+			"chunks": []*proto.DiffChunk{
+				&proto.DiffChunk{Op: "equal", Text: "# Title\n\nThe "},
+				&proto.DiffChunk{Op: "delete", Text: "quick"},
+				&proto.DiffChunk{Op: "insert", Text: "slow"},
+				&proto.DiffChunk{Op: "equal", Text: " fox\n"},
+			},
+		},
+		map[string]interface{}{
+			"in":     &proto.DiffRevisionsRequest{PostID: post.GetID(), From: 2, To: 2},
+			"chunks": []*proto.DiffChunk{&proto.DiffChunk{Op: "equal", Text: "# Title\n\nThe slow fox\n"}},
+		},
+		map[string]interface{}{
+			"in":   &proto.DiffRevisionsRequest{PostID: post.GetID(), From: 1, To: 5},
+			"code": codes.NotFound,
+		},
+		map[string]interface{}{
+			"in":   &proto.DiffRevisionsRequest{PostID: post.GetID(), From: 1, To: 2, Mode: "char"},
+			"code": codes.InvalidArgument,
+		},
+	}
+
+	for _, tcase := range testCases {
+		res, err := client.DiffRevisions(as(testAuthor), tcase["in"].(*proto.DiffRevisionsRequest))
+		code, _ := tcase["code"].(codes.Code)
+		assert.Equalf(t, code, status.Code(err), "case: %v", tcase)
+		if err != nil {
+			continue
+		}
+
+		chunks := tcase["chunks"].([]*proto.DiffChunk)
+		if assert.Lenf(t, res.GetChunks(), len(chunks), "case: %v", tcase) {
+			for i, chunk := range chunks {
+				assert.Equalf(t, chunk.GetOp(), res.GetChunks()[i].GetOp(), "case: %v", tcase)
+				assert.Equalf(t, chunk.GetText(), res.GetChunks()[i].GetText(), "case: %v", tcase)
+			}
+		}
+	}
+}
+
+func Test_postServer_RestoreRevision(t *testing.T) {
+
+	posts, revisions := store.NewMemoryPosts(), store.NewMemoryRevisions(0)
+	client := newTestClient(t, New(Stores{Posts: posts, Revisions: revisions, Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+	post := newRevisedPost(t, client, "*first*", "second")
+
+	_, err := client.RestoreRevision(as(testOther), &proto.RevisionRequest{PostID: post.GetID(), Number: 1})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.RestoreRevision(as(testAuthor), &proto.RevisionRequest{PostID: post.GetID(), Number: 7})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// the restore is a new revision, saved by whoever restored it
+	restored, err := client.RestoreRevision(as(testEditor), &proto.RevisionRequest{PostID: post.GetID(), Number: 1})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "*first*", restored.GetBody())
+		assert.Equal(t, "<p><em>first</em></p>\n", restored.GetBodyHTML())
+		assert.Equal(t, int32(3), restored.GetRevision())
+	}
+
+	id, _ := primitive.ObjectIDFromHex(post.GetID())
+	revision, err := revisions.Find(context.Background(), id, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "*first*", revision.Body)
+	assert.Equal(t, testEditor.ID, revision.AuthorID)
+	stored, _ := posts.FindByID(context.Background(), id)
+	assert.Equal(t, 3, stored.Revision)
+}
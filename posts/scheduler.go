@@ -0,0 +1,87 @@
+package posts
+
+import (
+	"context"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/feed"
+	"github.com/HiteshRepo/blog-application/search"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.uber.org/zap"
+)
+
+// Scheduler publishes scheduled posts once their time has come. Any number
+// of instances may run against the same store, the store hands every due
+// post to one of them only.
+type Scheduler struct {
+	posts  store.Posts
+	index  search.Indexer
+	feed   feed.Fanout
+	counts *counts
+	// Now tells the time, tests replace it to move the clock by hand
+	Now func() time.Time
+}
+
+// NewScheduler returns a scheduler on the given stores, on the system clock
+func NewScheduler(stores Stores) *Scheduler {
+	return &Scheduler{
+		posts:  stores.Posts,
+		index:  stores.Index,
+		feed:   stores.Feed,
+		counts: &counts{posts: stores.Posts, tags: stores.Tags, categories: stores.Categories},
+		Now:    time.Now,
+	}
+}
+
+// PublishDue publishes every post due by now, indexes them, adds them to the
+// feeds, counts them in their tags and categories and returns them
+func (s *Scheduler) PublishDue(ctx context.Context) ([]store.Post, error) {
+	now := s.Now().UTC()
+	var published []store.Post
+	var err error
+	for {
+		var post store.Post
+		post, err = s.posts.PublishDue(ctx, now)
+		if err != nil {
+			break
+		}
+		published = append(published, post)
+	}
+	if err == store.ErrNotFound {
+		err = nil
+	}
+
+	// the posts are out, follow up on every one of them whatever fails
+	for _, post := range published {
+		if indexErr := index(ctx, s.index, post); indexErr != nil && err == nil {
+			err = indexErr
+		}
+		if feedErr := spread(ctx, s.feed, post); feedErr != nil && err == nil {
+			err = feedErr
+		}
+	}
+	if countErr := s.counts.update(ctx, published...); countErr != nil && err == nil {
+		err = countErr
+	}
+	return published, err
+}
+
+// Run publishes the due posts every interval until ctx is done
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			published, err := s.PublishDue(ctx)
+			for _, post := range published {
+				logger.Info("Published scheduled post", zap.String("post_id", post.ID.Hex()), zap.Time("published_at", post.PublishedAt))
+			}
+			if err != nil {
+				logger.Error("Error publishing scheduled posts", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package posts
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/search"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+func Test_Scheduler_PublishDue(t *testing.T) {
+
+	ctx := context.Background()
+	start := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+
+	posts := store.NewMemoryPosts()
+	scheduler := NewScheduler(Stores{Posts: posts, Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed})
+	scheduler.Now = func() time.Time { return now }
+
+	soon := store.Post{ID: primitive.NewObjectID(), State: store.PostScheduled, PublishAt: start.Add(time.Minute)}
+	later := store.Post{ID: primitive.NewObjectID(), State: store.PostScheduled, PublishAt: start.Add(time.Hour)}
+	draft := store.Post{ID: primitive.NewObjectID(), State: store.PostDraft}
+	for _, p := range []store.Post{soon, later, draft} {
+		posts.Insert(ctx, p)
+	}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"now": start, "published": []primitive.ObjectID(nil)},
+		map[string]interface{}{"now": start.Add(time.Minute), "published": []primitive.ObjectID{soon.ID}},
+		map[string]interface{}{"now": start.Add(30 * time.Minute), "published": []primitive.ObjectID(nil)},
+		map[string]interface{}{"now": start.Add(2 * time.Hour), "published": []primitive.ObjectID{later.ID}},
+	}
+
+	for _, tcase := range testCases {
+		now = tcase["now"].(time.Time)
+		published, err := scheduler.PublishDue(ctx)
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		var ids []primitive.ObjectID
+		for _, p := range published {
+			ids = append(ids, p.ID)
+			assert.Equalf(t, store.PostPublished, p.State, "case: %v", tcase)
+		}
+		assert.Equalf(t, tcase["published"], ids, "case: %v", tcase)
+	}
+
+	// posts go out dated when they were due, not when the scheduler ran
+	stored, _ := posts.FindByID(ctx, later.ID)
+	assert.Equal(t, later.PublishAt, stored.PublishedAt)
+	stored, _ = posts.FindByID(ctx, draft.ID)
+	assert.Equal(t, store.PostDraft, stored.State)
+}
+
+func Test_Scheduler_PublishDue_instances(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	posts := store.NewMemoryPosts()
+	for i := 0; i < 50; i++ {
+		posts.Insert(ctx, store.Post{ID: primitive.NewObjectID(), State: store.PostScheduled, PublishAt: now.Add(-time.Duration(i) * time.Second)})
+	}
+
+	// instances share the store, each post is published by one of them
+	var mu sync.Mutex
+	total := 0
+	seen := map[primitive.ObjectID]bool{}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		scheduler := NewScheduler(Stores{Posts: posts, Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed})
+		scheduler.Now = func() time.Time { return now }
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			published, err := scheduler.PublishDue(ctx)
+			assert.NoError(t, err)
+			mu.Lock()
+			defer mu.Unlock()
+			for _, p := range published {
+				total++
+				seen[p.ID] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 50, total)
+	assert.Len(t, seen, 50)
+}
+
+func Test_Scheduler_Run(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	posts := store.NewMemoryPosts()
+	post := store.Post{ID: primitive.NewObjectID(), State: store.PostScheduled, PublishAt: time.Now().Add(-time.Second)}
+	posts.Insert(context.Background(), post)
+
+	done := make(chan struct{})
+	go func() {
+		NewScheduler(Stores{Posts: posts, Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}).Run(ctx, time.Millisecond, zap.NewNop())
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		stored, _ := posts.FindByID(context.Background(), post.ID)
+		return stored.State == store.PostPublished
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,99 @@
+package posts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/render"
+	"github.com/HiteshRepo/blog-application/search"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_Document(t *testing.T) {
+
+	published := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	post := store.Post{
+		ID:          primitive.NewObjectID(),
+		AuthorID:    testAuthor.ID,
+		Title:       "Searching",
+		BodyHTML:    "<h1 id=\"intro\">Intro</h1>\n<p>Fish &amp; <em>chips</em></p>\n",
+		Tags:        []string{"go"},
+		PublishedAt: published,
+	}
+
+	assert.Equal(t, search.Document{
+		ID:          post.ID.Hex(),
+		Kind:        search.KindPost,
+		AuthorID:    testAuthor.ID.Hex(),
+		Title:       "Searching",
+		Body:        "Intro\nFish & chips",
+		Tags:        []string{"go"},
+		PublishedAt: published,
+	}, Document(post))
+}
+
+func Test_postServer_index(t *testing.T) {
+
+	ctx := context.Background()
+	index := search.NewMemoryIndex()
+	client := newTestClient(t, New(Stores{Posts: store.NewMemoryPosts(), Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: index, Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+
+	found := func(text string) []string {
+		hits, _, err := index.Search(ctx, search.Query{Text: text})
+		assert.NoError(t, err)
+		ids := []string{}
+		for _, hit := range hits {
+			ids = append(ids, hit.ID)
+		}
+		return ids
+	}
+
+	post, err := client.CreatePost(as(testAuthor), &proto.CreatePostRequest{Title: "Draft", Body: "Unicorns"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, found("unicorns"), "drafts are not searchable")
+
+	_, err = client.PublishPost(as(testAuthor), &proto.PostRequest{PostID: post.GetID()})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{post.GetID()}, found("unicorns"), "publishing indexes")
+
+	_, err = client.UpdatePost(as(testAuthor), &proto.UpdatePostRequest{PostID: post.GetID(), Title: "Edited", Body: "Dragons"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, found("unicorns"), "editing reindexes")
+	assert.Equal(t, []string{post.GetID()}, found("dragons"), "editing reindexes")
+
+	_, err = client.UnlistPost(as(testAuthor), &proto.PostRequest{PostID: post.GetID()})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, found("dragons"), "unlisting removes")
+
+	_, err = client.PublishPost(as(testAuthor), &proto.PostRequest{PostID: post.GetID()})
+	assert.NoError(t, err)
+	_, err = client.ArchivePost(as(testAuthor), &proto.PostRequest{PostID: post.GetID()})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, found("dragons"), "archiving removes")
+}
+
+func Test_Scheduler_PublishDue_index(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	posts, index := store.NewMemoryPosts(), search.NewMemoryIndex()
+	post := store.Post{ID: primitive.NewObjectID(), Title: "Scheduled", BodyHTML: "<p>Unicorns</p>", State: store.PostScheduled, PublishAt: now}
+	posts.Insert(ctx, post)
+
+	scheduler := NewScheduler(Stores{Posts: posts, Tags: store.NewMemoryTags(), Categories: store.NewMemoryCategories(), Index: index, Feed: noFeed})
+	scheduler.Now = func() time.Time { return now }
+	_, err := scheduler.PublishDue(ctx)
+	assert.NoError(t, err)
+
+	hits, _, err := index.Search(ctx, search.Query{Text: "unicorns"})
+	assert.NoError(t, err)
+	if assert.Len(t, hits, 1) {
+		assert.Equal(t, post.ID.Hex(), hits[0].ID)
+		assert.True(t, now.Equal(hits[0].PublishedAt))
+	}
+}
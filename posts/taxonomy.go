@@ -0,0 +1,524 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxTags is the number of tags a post may have
+const maxTags = 10
+
+// slug turns a name into its url form, lower case letters and digits
+// separated by single dashes: "Go & gRPC" becomes "go-grpc"
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// tagsOf turns the tag names of a request into tags, without duplicates
+func tagsOf(names []string) ([]store.Tag, error) {
+	tags := []store.Tag{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		s := slug(name)
+		if s == "" {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Tag %q should have letters or digits", name))
+		}
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		tags = append(tags, store.Tag{Slug: s, Name: strings.TrimSpace(name)})
+	}
+	if len(tags) > maxTags {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("A post has at most %d tags", maxTags))
+	}
+	return tags, nil
+}
+
+// descendants returns the slug of the category and of every category below it
+func descendants(categories []store.Category, slug string) []string {
+	children := map[string][]string{}
+	for _, c := range categories {
+		children[c.Parent] = append(children[c.Parent], c.Slug)
+	}
+	slugs := []string{slug}
+	for i := 0; i < len(slugs); i++ {
+		slugs = append(slugs, children[slugs[i]]...)
+	}
+	return slugs
+}
+
+// ancestors returns the slug of the category and of every category above it
+func ancestors(categories []store.Category, slug string) []string {
+	parents := map[string]string{}
+	for _, c := range categories {
+		parents[c.Slug] = c.Parent
+	}
+	slugs := []string{}
+	seen := map[string]bool{}
+	for s := slug; s != "" && !seen[s]; s = parents[s] {
+		seen[s] = true
+		slugs = append(slugs, s)
+	}
+	return slugs
+}
+
+// counts keeps the post counts of tags and categories in line with the posts.
+// Counts are recomputed from the posts rather than incremented, so a failed
+// update is repaired by the next change to the same tag or category.
+type counts struct {
+	posts      store.Posts
+	tags       store.Tags
+	categories store.Categories
+}
+
+// update recounts the tags and categories of posts
+func (c *counts) update(ctx context.Context, posts ...store.Post) error {
+	var tags, categories []string
+	for _, post := range posts {
+		tags = append(tags, post.Tags...)
+		if post.Category != "" {
+			categories = append(categories, post.Category)
+		}
+	}
+	if err := c.updateTags(ctx, tags...); err != nil {
+		return err
+	}
+	return c.updateCategories(ctx, categories...)
+}
+
+func (c *counts) updateTags(ctx context.Context, slugs ...string) error {
+	seen := map[string]bool{}
+	for _, s := range slugs {
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		count, err := c.posts.Count(ctx, store.PostFilter{Tag: s})
+		if err != nil {
+			return err
+		}
+		// a tag merged away meanwhile has nothing left to count
+		if err := c.tags.SetCount(ctx, s, count); err != nil && err != store.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateCategories recounts the categories and their parents, which count
+// the posts of their subcategories
+func (c *counts) updateCategories(ctx context.Context, slugs ...string) error {
+	if len(slugs) == 0 {
+		return nil
+	}
+	all, err := c.categories.List(ctx)
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, s := range slugs {
+		for _, a := range ancestors(all, s) {
+			if seen[a] {
+				continue
+			}
+			seen[a] = true
+			count, err := c.posts.Count(ctx, store.PostFilter{Categories: descendants(all, a)})
+			if err != nil {
+				return err
+			}
+			if err := c.categories.SetCount(ctx, a, count); err != nil && err != store.ErrNotFound {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type taxonomyServer struct {
+	posts      store.Posts
+	tags       store.Tags
+	categories store.Categories
+	counts     *counts
+	pages      *paging.Codec
+}
+
+func (s *taxonomyServer) ListTags(ctx context.Context, in *proto.ListTagsRequest) (*proto.ListTagsResponse, error) {
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	tags, err := s.tags.List(dbCtx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while listing tags", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	res := &proto.ListTagsResponse{Tags: make([]*proto.Tag, 0, len(tags))}
+	for _, tag := range tags {
+		res.Tags = append(res.Tags, tagToProto(tag))
+	}
+	return res, nil
+}
+
+func (s *taxonomyServer) GetPostsByTag(ctx context.Context, in *proto.TagPostsRequest) (*proto.PostsResponse, error) {
+	l, filter, err := s.tagListing(ctx, in.GetTag())
+	if err != nil {
+		return nil, err
+	}
+	return s.list(ctx, l, filter, in.GetPageToken(), in.GetPageSize())
+}
+
+func (s *taxonomyServer) StreamPostsByTag(in *proto.TagPostsRequest, stream proto.TaxonomyService_StreamPostsByTagServer) error {
+	l, filter, err := s.tagListing(stream.Context(), in.GetTag())
+	if err != nil {
+		return err
+	}
+	return s.stream(l, filter, in.GetPageToken(), in.GetPageSize(), stream)
+}
+
+func (s *taxonomyServer) ListCategories(ctx context.Context, in *proto.ListCategoriesRequest) (*proto.ListCategoriesResponse, error) {
+	categories, err := s.listCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// depth first, so that parents come before their children
+	res := &proto.ListCategoriesResponse{Categories: make([]*proto.Category, 0, len(categories))}
+	children := map[string][]store.Category{}
+	for _, c := range categories {
+		children[c.Parent] = append(children[c.Parent], c)
+	}
+	var walk func(parent string)
+	walk = func(parent string) {
+		for _, c := range children[parent] {
+			res.Categories = append(res.Categories, categoryToProto(c))
+			walk(c.Slug)
+		}
+	}
+	walk("")
+	return res, nil
+}
+
+func (s *taxonomyServer) GetPostsByCategory(ctx context.Context, in *proto.CategoryPostsRequest) (*proto.PostsResponse, error) {
+	l, filter, err := s.categoryListing(ctx, in.GetCategory())
+	if err != nil {
+		return nil, err
+	}
+	return s.list(ctx, l, filter, in.GetPageToken(), in.GetPageSize())
+}
+
+func (s *taxonomyServer) StreamPostsByCategory(in *proto.CategoryPostsRequest, stream proto.TaxonomyService_StreamPostsByCategoryServer) error {
+	l, filter, err := s.categoryListing(stream.Context(), in.GetCategory())
+	if err != nil {
+		return err
+	}
+	return s.stream(l, filter, in.GetPageToken(), in.GetPageSize(), stream)
+}
+
+func (s *taxonomyServer) CreateCategory(ctx context.Context, in *proto.CreateCategoryRequest) (*proto.Category, error) {
+	category := store.Category{Slug: slug(in.GetName()), Name: strings.TrimSpace(in.GetName()), Parent: slug(in.GetParent())}
+	if category.Slug == "" {
+		return nil, status.Error(codes.InvalidArgument, "Name should have letters or digits")
+	}
+
+	// insert should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	if category.Parent != "" {
+		_, err := s.categories.Find(dbCtx, category.Parent)
+		if err == store.ErrNotFound {
+			return nil, status.Error(codes.InvalidArgument, "Unknown parent category")
+		}
+		if err != nil {
+			logging.FromContext(ctx).Error("Error returned while looking up category", zap.Error(err))
+			return nil, status.Error(codes.Internal, "Internal Error")
+		}
+	}
+	err := s.categories.Insert(dbCtx, category)
+	if err == store.ErrConflict {
+		return nil, status.Error(codes.AlreadyExists, "Category already exists")
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while inserting category", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	return categoryToProto(category), nil
+}
+
+func (s *taxonomyServer) RenameTag(ctx context.Context, in *proto.RenameTagRequest) (*proto.Tag, error) {
+	tag, err := s.findTag(ctx, in.GetTag())
+	if err != nil {
+		return nil, err
+	}
+	renamed := store.Tag{Slug: slug(in.GetName()), Name: strings.TrimSpace(in.GetName()), PostCount: tag.PostCount}
+	if renamed.Slug == "" {
+		return nil, status.Error(codes.InvalidArgument, "Name should have letters or digits")
+	}
+
+	// update should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	// a new spelling of the same slug only changes the name
+	if renamed.Slug == tag.Slug {
+		if err := s.tags.Rename(dbCtx, tag.Slug, renamed.Name); err != nil {
+			logging.FromContext(ctx).Error("Error returned while renaming tag", zap.Error(err))
+			return nil, status.Error(codes.Internal, "Internal Error")
+		}
+		return tagToProto(renamed), nil
+	}
+
+	_, err = s.tags.Find(dbCtx, renamed.Slug)
+	if err == nil {
+		return nil, status.Error(codes.AlreadyExists, fmt.Sprintf("Tag %s exists, merge into it instead", renamed.Slug))
+	}
+	if err != store.ErrNotFound {
+		logging.FromContext(ctx).Error("Error returned while looking up tag", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	if err := s.tags.Ensure(dbCtx, renamed); err != nil {
+		logging.FromContext(ctx).Error("Error returned while inserting tag", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	return s.move(ctx, tag, renamed)
+}
+
+func (s *taxonomyServer) MergeTag(ctx context.Context, in *proto.MergeTagRequest) (*proto.Tag, error) {
+	tag, err := s.findTag(ctx, in.GetTag())
+	if err != nil {
+		return nil, err
+	}
+	into, err := s.findTag(ctx, in.GetInto())
+	if err != nil {
+		return nil, err
+	}
+	if tag.Slug == into.Slug {
+		return nil, status.Error(codes.InvalidArgument, "Cannot merge a tag into itself")
+	}
+	return s.move(ctx, tag, into)
+}
+
+// move retags the posts of from with to, then deletes from. The tag from is
+// deleted last, so that a failed move can be run again.
+func (s *taxonomyServer) move(ctx context.Context, from, to store.Tag) (*proto.Tag, error) {
+	// every post may be updated, this should not take more that 30 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 30*time.Second)
+	defer cancel()
+
+	if err := s.posts.ReplaceTag(dbCtx, from.Slug, to.Slug); err != nil {
+		logging.FromContext(ctx).Error("Error returned while retagging posts", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	if err := s.tags.Delete(dbCtx, from.Slug); err != nil && err != store.ErrNotFound {
+		logging.FromContext(ctx).Error("Error returned while deleting tag", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	if err := s.counts.updateTags(dbCtx, to.Slug); err != nil {
+		logging.FromContext(ctx).Error("Error returned while counting posts", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	updated, err := s.tags.Find(dbCtx, to.Slug)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while looking up tag", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	return tagToProto(updated), nil
+}
+
+// findTag returns the tag with the slug of name
+func (s *taxonomyServer) findTag(ctx context.Context, name string) (store.Tag, error) {
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	tag, err := s.tags.Find(dbCtx, slug(name))
+	if err == store.ErrNotFound {
+		return store.Tag{}, status.Error(codes.NotFound, "Tag not found")
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while looking up tag", zap.Error(err))
+		return store.Tag{}, status.Error(codes.Internal, "Internal Error")
+	}
+	return tag, nil
+}
+
+func (s *taxonomyServer) listCategories(ctx context.Context) ([]store.Category, error) {
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	categories, err := s.categories.List(dbCtx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while listing categories", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	return categories, nil
+}
+
+// list returns a page of the published posts matching filter
+// listing is the filter of a post listing, as requested. Page tokens are
+// bound to it rather than to the store filter, which holds the subcategories
+// of the moment.
+type listing struct {
+	Tag      string `json:"tag,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+func (s *taxonomyServer) tagListing(ctx context.Context, name string) (listing, store.PostFilter, error) {
+	tag, err := s.findTag(ctx, name)
+	if err != nil {
+		return listing{}, store.PostFilter{}, err
+	}
+	return listing{Tag: tag.Slug}, store.PostFilter{Tag: tag.Slug}, nil
+}
+
+func (s *taxonomyServer) categoryListing(ctx context.Context, name string) (listing, store.PostFilter, error) {
+	categories, err := s.listCategories(ctx)
+	if err != nil {
+		return listing{}, store.PostFilter{}, err
+	}
+	category := slug(name)
+	for _, c := range categories {
+		if c.Slug == category {
+			return listing{Category: category}, store.PostFilter{Categories: descendants(categories, category)}, nil
+		}
+	}
+	return listing{}, store.PostFilter{}, status.Error(codes.NotFound, "Category not found")
+}
+
+func (s *taxonomyServer) list(ctx context.Context, l listing, filter store.PostFilter, token string, size int64) (*proto.PostsResponse, error) {
+	page, err := s.pages.Start(token, size, l)
+	if err != nil {
+		return nil, err
+	}
+	posts, total, err := s.page(ctx, filter, page)
+	if err != nil {
+		return nil, err
+	}
+
+	var last interface{}
+	if len(posts) > 0 {
+		last = posts[len(posts)-1].Key()
+	}
+	next, err := s.pages.Next(page, len(posts), total, last)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while issuing page token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	res := &proto.PostsResponse{
+		Posts:         make([]*proto.Post, 0, len(posts)),
+		Total:         total,
+		PageSize:      page.Size,
+		NextPageToken: next,
+		Tag:           l.Tag,
+		Category:      l.Category,
+	}
+	for _, post := range posts {
+		res.Posts = append(res.Posts, summaryToProto(post))
+	}
+	return res, nil
+}
+
+// postSender is a stream of posts
+type postSender interface {
+	Context() context.Context
+	Send(*proto.Post) error
+}
+
+func (s *taxonomyServer) stream(l listing, filter store.PostFilter, token string, size int64, stream postSender) error {
+	page, err := s.pages.Start(token, size, l)
+	if err != nil {
+		return err
+	}
+	return paging.Walk(page, func(page paging.Page) (int, interface{}, error) {
+		posts, _, err := s.page(stream.Context(), filter, page)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, post := range posts {
+			if err := stream.Send(summaryToProto(post)); err != nil {
+				return 0, nil, err
+			}
+		}
+		if len(posts) == 0 {
+			return 0, nil, nil
+		}
+		return len(posts), posts[len(posts)-1].Key(), nil
+	})
+}
+
+// page returns the posts of page among those matching filter, and the number
+// of all of them
+func (s *taxonomyServer) page(ctx context.Context, filter store.PostFilter, page paging.Page) ([]store.Post, int64, error) {
+	var after store.PostKey
+	ok, err := page.Key(&after)
+	if err != nil {
+		return nil, 0, err
+	}
+	if ok {
+		filter.After = &after
+	}
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	posts, total, err := s.posts.List(dbCtx, filter, 0, page.Size)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while listing posts", zap.Error(err))
+		return nil, 0, status.Error(codes.Internal, "Internal Error")
+	}
+	return posts, total, nil
+}
+
+// summaryToProto converts a post for listings, without its body
+func summaryToProto(post store.Post) *proto.Post {
+	summary := postToProto(post)
+	summary.Body, summary.BodyHTML, summary.TOC = "", "", nil
+	return summary
+}
+
+func tagToProto(tag store.Tag) *proto.Tag {
+	return &proto.Tag{Slug: tag.Slug, Name: tag.Name, PostCount: tag.PostCount}
+}
+
+func categoryToProto(category store.Category) *proto.Category {
+	return &proto.Category{Slug: category.Slug, Name: category.Name, Parent: category.Parent, PostCount: category.PostCount}
+}
+
+// slugsOf returns the slugs of tags, in order
+func slugsOf(tags []store.Tag) []string {
+	slugs := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		slugs = append(slugs, tag.Slug)
+	}
+	return slugs
+}
@@ -0,0 +1,423 @@
+package posts
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/render"
+	"github.com/HiteshRepo/blog-application/search"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTaxonomyClients serves both services of service over bufconn
+func newTaxonomyClients(t *testing.T, service *Service) (proto.PostServiceClient, proto.TaxonomyServiceClient) {
+	t.Helper()
+	conn := newTestConn(t, service)
+	return proto.NewPostServiceClient(conn), proto.NewTaxonomyServiceClient(conn)
+}
+
+func Test_slug(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"name": "Go", "slug": "go"},
+		map[string]interface{}{"name": "  Go & gRPC!  ", "slug": "go-grpc"},
+		map[string]interface{}{"name": "machine_learning", "slug": "machine-learning"},
+		map[string]interface{}{"name": "Café Crème", "slug": "café-crème"},
+		map[string]interface{}{"name": "web3.0", "slug": "web3-0"},
+		map[string]interface{}{"name": "--", "slug": ""},
+	}
+
+	for _, tcase := range testCases {
+		assert.Equalf(t, tcase["slug"], slug(tcase["name"].(string)), "case: %v", tcase)
+	}
+}
+
+func Test_tagsOf(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"names": []string{"Go", "gRPC", "go", " GO "},
+			"tags":  []store.Tag{store.Tag{Slug: "go", Name: "Go"}, store.Tag{Slug: "grpc", Name: "gRPC"}},
+		},
+		map[string]interface{}{
+			"names":   []string{"Go", "!!"},
+			"message": "Tag \"!!\" should have letters or digits",
+		},
+		map[string]interface{}{
+			"names":   []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"},
+			"message": "A post has at most 10 tags",
+		},
+		map[string]interface{}{
+			"names": []string{},
+			"tags":  []store.Tag{},
+		},
+	}
+
+	for _, tcase := range testCases {
+		tags, err := tagsOf(tcase["names"].([]string))
+		if message, ok := tcase["message"]; ok {
+			assert.Equalf(t, codes.InvalidArgument, status.Code(err), "case: %v", tcase)
+			assert.Equalf(t, message, status.Convert(err).Message(), "case: %v", tcase)
+			continue
+		}
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		assert.Equalf(t, tcase["tags"], tags, "case: %v", tcase)
+	}
+}
+
+func Test_taxonomy_tags(t *testing.T) {
+
+	posts, tags := store.NewMemoryPosts(), store.NewMemoryTags()
+	postClient, client := newTaxonomyClients(t, New(Stores{Posts: posts, Revisions: store.NewMemoryRevisions(0), Tags: tags, Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+
+	create := func(title string, tags ...string) *proto.Post {
+		post, err := postClient.CreatePost(as(testAuthor), &proto.CreatePostRequest{Title: title, Body: title, Tags: tags})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return post
+	}
+	first := create("First", "Go", "gRPC")
+	second := create("Second", "go", "Golang")
+	draft := create("Draft", "Go", "Secret plans")
+	assert.Equal(t, []string{"go", "grpc"}, first.GetTags())
+
+	// drafts are not counted, tags without published posts are not listed
+	listed, err := client.ListTags(context.Background(), &proto.ListTagsRequest{})
+	assert.NoError(t, err)
+	assert.Empty(t, listed.GetTags())
+
+	for _, post := range []*proto.Post{first, second} {
+		_, err := postClient.PublishPost(as(testAuthor), &proto.PostRequest{PostID: post.GetID()})
+		assert.NoError(t, err)
+	}
+	listed, err = client.ListTags(context.Background(), &proto.ListTagsRequest{})
+	if assert.NoError(t, err) && assert.Len(t, listed.GetTags(), 3) {
+		assert.Equal(t, "go", listed.GetTags()[0].GetSlug())
+		assert.Equal(t, "Go", listed.GetTags()[0].GetName())
+		assert.Equal(t, int64(2), listed.GetTags()[0].GetPostCount())
+	}
+
+	res, err := client.GetPostsByTag(context.Background(), &proto.TagPostsRequest{Tag: "Go"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(2), res.GetTotal())
+		for _, post := range res.GetPosts() {
+			assert.NotEqual(t, draft.GetID(), post.GetID())
+			assert.Empty(t, post.GetBodyHTML())
+		}
+	}
+	_, err = client.GetPostsByTag(context.Background(), &proto.TagPostsRequest{Tag: "rust"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// retagging a published post moves its count
+	_, err = postClient.UpdatePost(as(testAuthor), &proto.UpdatePostRequest{PostID: second.GetID(), Title: "Second", Body: "Second", Tags: []string{"golang"}})
+	assert.NoError(t, err)
+	tag, _ := tags.Find(context.Background(), "go")
+	assert.Equal(t, int64(1), tag.PostCount)
+
+	// unpublishing too
+	_, err = postClient.UnpublishPost(as(testAuthor), &proto.PostRequest{PostID: first.GetID()})
+	assert.NoError(t, err)
+	tag, _ = tags.Find(context.Background(), "grpc")
+	assert.Equal(t, int64(0), tag.PostCount)
+}
+
+func Test_taxonomy_RenameTag_MergeTag(t *testing.T) {
+
+	ctx := context.Background()
+	posts, tags := store.NewMemoryPosts(), store.NewMemoryTags()
+	_, client := newTaxonomyClients(t, New(Stores{Posts: posts, Revisions: store.NewMemoryRevisions(0), Tags: tags, Categories: store.NewMemoryCategories(), Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+
+	for _, tag := range []store.Tag{{Slug: "go", Name: "Go"}, {Slug: "golang", Name: "Golang"}, {Slug: "db", Name: "DB"}} {
+		tags.Ensure(ctx, tag)
+	}
+	one := store.Post{ID: primitive.NewObjectID(), State: store.PostPublished, Tags: []string{"go", "db"}}
+	two := store.Post{ID: primitive.NewObjectID(), State: store.PostPublished, Tags: []string{"golang"}}
+	posts.Insert(ctx, one)
+	posts.Insert(ctx, two)
+
+	// every step runs on the tags as the previous one left them
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"call": func(ctx context.Context) (*proto.Tag, error) {
+				return client.RenameTag(ctx, &proto.RenameTagRequest{Tag: "db", Name: "Databases"})
+			},
+			"ctx":  as(testAuthor),
+			"code": codes.PermissionDenied,
+		},
+		map[string]interface{}{
+			"call": func(ctx context.Context) (*proto.Tag, error) {
+				return client.RenameTag(ctx, &proto.RenameTagRequest{Tag: "db", Name: "Golang"})
+			},
+			"code":    codes.AlreadyExists,
+			"message": "Tag golang exists, merge into it instead",
+		},
+		map[string]interface{}{
+			"call": func(ctx context.Context) (*proto.Tag, error) {
+				return client.RenameTag(ctx, &proto.RenameTagRequest{Tag: "rust", Name: "Rust"})
+			},
+			"code": codes.NotFound,
+		},
+		map[string]interface{}{
+			// a new spelling keeps the slug
+			"call": func(ctx context.Context) (*proto.Tag, error) {
+				return client.RenameTag(ctx, &proto.RenameTagRequest{Tag: "db", Name: "dB"})
+			},
+			"tag":  &proto.Tag{Slug: "db", Name: "dB"},
+			"tags": map[primitive.ObjectID][]string{one.ID: {"go", "db"}, two.ID: {"golang"}},
+		},
+		map[string]interface{}{
+			"call": func(ctx context.Context) (*proto.Tag, error) {
+				return client.RenameTag(ctx, &proto.RenameTagRequest{Tag: "db", Name: "Databases"})
+			},
+			"tag":  &proto.Tag{Slug: "databases", Name: "Databases", PostCount: 1},
+			"tags": map[primitive.ObjectID][]string{one.ID: {"go", "databases"}, two.ID: {"golang"}},
+		},
+		map[string]interface{}{
+			"call": func(ctx context.Context) (*proto.Tag, error) {
+				return client.MergeTag(ctx, &proto.MergeTagRequest{Tag: "go", Into: "go"})
+			},
+			"code": codes.InvalidArgument,
+		},
+		map[string]interface{}{
+			"call": func(ctx context.Context) (*proto.Tag, error) {
+				return client.MergeTag(ctx, &proto.MergeTagRequest{Tag: "go", Into: "rust"})
+			},
+			"code": codes.NotFound,
+		},
+		map[string]interface{}{
+			"call": func(ctx context.Context) (*proto.Tag, error) {
+				return client.MergeTag(ctx, &proto.MergeTagRequest{Tag: "go", Into: "golang"})
+			},
+			"tag":  &proto.Tag{Slug: "golang", Name: "Golang", PostCount: 2},
+			"tags": map[primitive.ObjectID][]string{one.ID: {"databases", "golang"}, two.ID: {"golang"}},
+		},
+	}
+
+	for _, tcase := range testCases {
+		ctx, ok := tcase["ctx"].(context.Context)
+		if !ok {
+			ctx = as(testEditor)
+		}
+		res, err := tcase["call"].(func(context.Context) (*proto.Tag, error))(ctx)
+		code, _ := tcase["code"].(codes.Code)
+		assert.Equalf(t, code, status.Code(err), "case: %v", tcase)
+		if message, ok := tcase["message"]; ok {
+			assert.Equalf(t, message, status.Convert(err).Message(), "case: %v", tcase)
+		}
+		if err != nil {
+			continue
+		}
+
+		expected := tcase["tag"].(*proto.Tag)
+		assert.Equalf(t, expected.GetSlug(), res.GetSlug(), "case: %v", tcase)
+		assert.Equalf(t, expected.GetName(), res.GetName(), "case: %v", tcase)
+		assert.Equalf(t, expected.GetPostCount(), res.GetPostCount(), "case: %v", tcase)
+		for id, slugs := range tcase["tags"].(map[primitive.ObjectID][]string) {
+			stored, _ := posts.FindByID(context.Background(), id)
+			assert.Equalf(t, slugs, stored.Tags, "case: %v", tcase)
+		}
+	}
+
+	// the old tags are gone
+	for _, s := range []string{"db", "go"} {
+		_, err := tags.Find(ctx, s)
+		assert.Equal(t, store.ErrNotFound, err)
+	}
+}
+
+func Test_taxonomy_categories(t *testing.T) {
+
+	categories := store.NewMemoryCategories()
+	postClient, client := newTaxonomyClients(t, New(Stores{Posts: store.NewMemoryPosts(), Revisions: store.NewMemoryRevisions(0), Tags: store.NewMemoryTags(), Categories: categories, Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"ctx": as(testAuthor), "in": &proto.CreateCategoryRequest{Name: "Backend"}, "code": codes.PermissionDenied},
+		map[string]interface{}{"in": &proto.CreateCategoryRequest{Name: "Backend"}, "slug": "backend"},
+		map[string]interface{}{"in": &proto.CreateCategoryRequest{Name: "Go", Parent: "backend"}, "slug": "go"},
+		map[string]interface{}{"in": &proto.CreateCategoryRequest{Name: "Concurrency", Parent: "Go"}, "slug": "concurrency"},
+		map[string]interface{}{"in": &proto.CreateCategoryRequest{Name: "Frontend"}, "slug": "frontend"},
+		map[string]interface{}{"in": &proto.CreateCategoryRequest{Name: "BACKEND"}, "code": codes.AlreadyExists},
+		map[string]interface{}{"in": &proto.CreateCategoryRequest{Name: "Rust", Parent: "systems"}, "code": codes.InvalidArgument},
+		map[string]interface{}{"in": &proto.CreateCategoryRequest{Name: "???"}, "code": codes.InvalidArgument},
+	}
+
+	for _, tcase := range testCases {
+		ctx, ok := tcase["ctx"].(context.Context)
+		if !ok {
+			ctx = as(testEditor)
+		}
+		res, err := client.CreateCategory(ctx, tcase["in"].(*proto.CreateCategoryRequest))
+		code, _ := tcase["code"].(codes.Code)
+		assert.Equalf(t, code, status.Code(err), "case: %v", tcase)
+		if err == nil {
+			assert.Equalf(t, tcase["slug"], res.GetSlug(), "case: %v", tcase)
+		}
+	}
+
+	_, err := postClient.CreatePost(as(testAuthor), &proto.CreatePostRequest{Title: "Lost", Body: "Lost", Category: "systems"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "Unknown category", status.Convert(err).Message())
+
+	for _, category := range []string{"concurrency", "go", "frontend"} {
+		post, err := postClient.CreatePost(as(testAuthor), &proto.CreatePostRequest{Title: category, Body: category, Category: category})
+		if assert.NoError(t, err) {
+			assert.Equal(t, category, post.GetCategory())
+			_, err = postClient.PublishPost(as(testAuthor), &proto.PostRequest{PostID: post.GetID()})
+			assert.NoError(t, err)
+		}
+	}
+
+	// parents come first and count the posts below them
+	listed, err := client.ListCategories(context.Background(), &proto.ListCategoriesRequest{})
+	if assert.NoError(t, err) {
+		var slugs []string
+		counts := map[string]int64{}
+		for _, c := range listed.GetCategories() {
+			slugs = append(slugs, c.GetSlug())
+			counts[c.GetSlug()] = c.GetPostCount()
+		}
+		assert.Equal(t, []string{"backend", "go", "concurrency", "frontend"}, slugs)
+		assert.Equal(t, map[string]int64{"backend": 2, "go": 2, "concurrency": 1, "frontend": 1}, counts)
+	}
+
+	res, err := client.GetPostsByCategory(context.Background(), &proto.CategoryPostsRequest{Category: "backend"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(2), res.GetTotal())
+	}
+	res, err = client.GetPostsByCategory(context.Background(), &proto.CategoryPostsRequest{Category: "Concurrency", PageSize: 1})
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), res.GetTotal())
+		assert.Len(t, res.GetPosts(), 1)
+		assert.Equal(t, "concurrency", res.GetCategory())
+		assert.Empty(t, res.GetNextPageToken())
+	}
+	_, err = client.GetPostsByCategory(context.Background(), &proto.CategoryPostsRequest{Category: "rust"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func Test_taxonomy_paging(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	posts, tags, categories := store.NewMemoryPosts(), store.NewMemoryTags(), store.NewMemoryCategories()
+	tags.Ensure(ctx, store.Tag{Slug: "go", Name: "Go"})
+	tags.Ensure(ctx, store.Tag{Slug: "rust", Name: "Rust"})
+	categories.Insert(ctx, store.Category{Slug: "backend", Name: "Backend"})
+	var want []string
+	for i := 0; i < 5; i++ {
+		// two posts share each publication time, the id breaks the tie
+		post := store.Post{ID: primitive.NewObjectID(), State: store.PostPublished, PublishedAt: now.Add(-time.Duration(i/2) * time.Hour), Tags: []string{"go"}, Category: "backend"}
+		posts.Insert(ctx, post)
+		want = append(want, post.ID.Hex())
+	}
+	// latest first, then the highest id
+	want = []string{want[1], want[0], want[3], want[2], want[4]}
+	_, client := newTaxonomyClients(t, New(Stores{Posts: posts, Revisions: store.NewMemoryRevisions(0), Tags: tags, Categories: categories, Index: search.NewMemoryIndex(), Feed: noFeed}, render.New(), paging.NewCodec(nil)))
+
+	var ids []string
+	token := ""
+	for pages := 0; pages < 5; pages++ {
+		res, err := client.GetPostsByTag(ctx, &proto.TagPostsRequest{Tag: "Go", PageSize: 2, PageToken: token})
+		if !assert.NoError(t, err) {
+			break
+		}
+		if pages == 0 {
+			assert.Equal(t, int64(5), res.GetTotal())
+		}
+		assert.Equal(t, int64(2), res.GetPageSize())
+		assert.Equal(t, "go", res.GetTag())
+		for _, post := range res.GetPosts() {
+			ids = append(ids, post.GetID())
+		}
+		if token = res.GetNextPageToken(); token == "" {
+			break
+		}
+
+		// a post published meanwhile does not shift the next pages
+		if pages == 0 {
+			posts.Insert(ctx, store.Post{ID: primitive.NewObjectID(), State: store.PostPublished, PublishedAt: now.Add(time.Hour), Tags: []string{"go"}})
+		}
+	}
+	assert.Equal(t, want, ids)
+
+	first, err := client.GetPostsByTag(ctx, &proto.TagPostsRequest{Tag: "go", PageSize: 1})
+	assert.NoError(t, err)
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"token": "forged", "message": "Invalid page token"},
+		map[string]interface{}{"token": first.GetNextPageToken()[1:], "message": "Invalid page token"},
+		map[string]interface{}{"token": first.GetNextPageToken(), "tag": "rust", "message": "Page token does not match the filter"},
+	}
+	for _, tcase := range testCases {
+		tag, _ := tcase["tag"].(string)
+		if tag == "" {
+			tag = "go"
+		}
+		_, err := client.GetPostsByTag(ctx, &proto.TagPostsRequest{Tag: tag, PageToken: tcase["token"].(string)})
+		assert.Equalf(t, codes.InvalidArgument, status.Code(err), "case: %v", tcase)
+		assert.Equalf(t, tcase["message"], status.Convert(err).Message(), "case: %v", tcase)
+	}
+	// tokens of a tag do not continue a category
+	_, err = client.GetPostsByCategory(ctx, &proto.CategoryPostsRequest{Category: "backend", PageToken: first.GetNextPageToken()})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// streams send everything from the token on, a page at a time
+	stream, err := client.StreamPostsByCategory(ctx, &proto.CategoryPostsRequest{Category: "backend", PageSize: 2})
+	assert.NoError(t, err)
+	streamed := []string{}
+	for {
+		post, err := stream.Recv()
+		if err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+		streamed = append(streamed, post.GetID())
+	}
+	assert.Equal(t, want, streamed)
+
+	tagStream, err := client.StreamPostsByTag(ctx, &proto.TagPostsRequest{Tag: "go", PageToken: first.GetNextPageToken()})
+	assert.NoError(t, err)
+	streamed = []string{}
+	for {
+		post, err := tagStream.Recv()
+		if err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+		streamed = append(streamed, post.GetID())
+	}
+	assert.Len(t, streamed, 5)
+
+	tagStream, err = client.StreamPostsByTag(ctx, &proto.TagPostsRequest{Tag: "missing"})
+	assert.NoError(t, err)
+	_, err = tagStream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func Test_Scheduler_PublishDue_counts(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	posts, tags, categories := store.NewMemoryPosts(), store.NewMemoryTags(), store.NewMemoryCategories()
+	tags.Ensure(ctx, store.Tag{Slug: "go", Name: "Go"})
+	categories.Insert(ctx, store.Category{Slug: "backend", Name: "Backend"})
+	posts.Insert(ctx, store.Post{ID: primitive.NewObjectID(), State: store.PostScheduled, PublishAt: now, Tags: []string{"go"}, Category: "backend"})
+
+	scheduler := NewScheduler(Stores{Posts: posts, Tags: tags, Categories: categories, Index: search.NewMemoryIndex(), Feed: noFeed})
+	scheduler.Now = func() time.Time { return now }
+	_, err := scheduler.PublishDue(ctx)
+	assert.NoError(t, err)
+
+	tag, _ := tags.Find(ctx, "go")
+	assert.Equal(t, int64(1), tag.PostCount)
+	category, _ := categories.Find(ctx, "backend")
+	assert.Equal(t, int64(1), category.PostCount)
+}
@@ -0,0 +1,175 @@
+// Package profiles serves the ProfileService, the public pages of users.
+// Profiles are kept on the user records, users choose which of their fields
+// others see.
+package profiles
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/gateway"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Service serves the ProfileService
+type Service struct {
+	profiles *profileServer
+}
+
+// New returns the profile service on the given stores, posts are counted on profiles
+func New(users store.Users, posts store.Posts) *Service {
+	return &Service{profiles: &profileServer{users: users, posts: posts}}
+}
+
+// Register adds the ProfileService to server
+func (s *Service) Register(server *grpc.Server) {
+	proto.RegisterProfileServiceServer(server, s.profiles)
+}
+
+// Require lists the permissions needed by every guarded RPC, any user manages their own profile
+func (s *Service) Require(p *policy.Policy) {
+	p.Require("/proto.ProfileService/GetMyProfile").
+		Require("/proto.ProfileService/UpdateProfile").
+		Require("/proto.ProfileService/UpdatePrivacy")
+}
+
+// Routes maps the ProfileService to the JSON API
+func (s *Service) Routes(g *gateway.Gateway) {
+	g.Handle(http.MethodGet, "/v1/profiles/{username}", "/proto.ProfileService/GetProfileByUsername").
+		Handle(http.MethodGet, "/v1/profile", "/proto.ProfileService/GetMyProfile").
+		Handle(http.MethodPut, "/v1/profile", "/proto.ProfileService/UpdateProfile").
+		Handle(http.MethodPut, "/v1/profile/privacy", "/proto.ProfileService/UpdatePrivacy")
+}
+
+type profileServer struct {
+	users store.Users
+	posts store.Posts
+}
+
+// GetProfileByUsername hides suspended users, as if they did not exist
+func (p *profileServer) GetProfileByUsername(ctx context.Context, in *proto.GetProfileByUsernameRequest) (*proto.Profile, error) {
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := p.users.FindByUsername(dbCtx, in.GetUsername())
+	if err != nil && err != store.ErrNotFound {
+		logging.FromContext(ctx).Error("Error returned while looking up user", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+	if err == store.ErrNotFound || user.Suspended {
+		return nil, status.Error(codes.NotFound, "Profile not found")
+	}
+	return p.profile(ctx, user, false)
+}
+
+func (p *profileServer) GetMyProfile(ctx context.Context, in *proto.GetMyProfileRequest) (*proto.Profile, error) {
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := p.users.FindByID(dbCtx, global.UserFromContext(ctx).ID)
+	if err != nil {
+		return nil, p.storeError(ctx, err)
+	}
+	return p.profile(ctx, user, true)
+}
+
+func (p *profileServer) UpdateProfile(ctx context.Context, in *proto.UpdateProfileRequest) (*proto.Profile, error) {
+	// update should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := p.users.SetProfile(dbCtx, global.UserFromContext(ctx).ID, global.Profile{
+		DisplayName: in.GetDisplayName(),
+		Bio:         in.GetBio(),
+		AvatarURL:   in.GetAvatarURL(),
+	})
+	if err != nil {
+		return nil, p.storeError(ctx, err)
+	}
+	return p.profile(ctx, user, true)
+}
+
+func (p *profileServer) UpdatePrivacy(ctx context.Context, in *proto.Privacy) (*proto.Profile, error) {
+	// update should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := p.users.SetPrivacy(dbCtx, global.UserFromContext(ctx).ID, global.Privacy{
+		HideDisplayName: in.GetHideDisplayName(),
+		HideBio:         in.GetHideBio(),
+		HideAvatar:      in.GetHideAvatar(),
+		HideJoined:      in.GetHideJoined(),
+		HidePostCount:   in.GetHidePostCount(),
+	})
+	if err != nil {
+		return nil, p.storeError(ctx, err)
+	}
+	return p.profile(ctx, user, true)
+}
+
+// storeError maps the error of reading or updating the caller's record
+func (p *profileServer) storeError(ctx context.Context, err error) error {
+	if err == store.ErrNotFound {
+		return status.Error(codes.NotFound, "Profile not found")
+	}
+	logging.FromContext(ctx).Error("Error returned while accessing profile", zap.Error(err))
+	return status.Error(codes.Internal, "Internal Error")
+}
+
+// profile returns the profile of user, the fields they hide are left out
+// unless own is set, for the user themselves
+func (p *profileServer) profile(ctx context.Context, user global.User, own bool) (*proto.Profile, error) {
+	hidden := user.Profile.Privacy
+	if own {
+		hidden = global.Privacy{}
+	}
+
+	res := &proto.Profile{ID: user.ID.Hex(), Username: user.Username}
+	if !hidden.HideDisplayName {
+		res.DisplayName = user.Profile.DisplayName
+	}
+	if !hidden.HideBio {
+		res.Bio = user.Profile.Bio
+	}
+	if !hidden.HideAvatar {
+		res.AvatarURL = user.Profile.AvatarURL
+	}
+	if !hidden.HideJoined {
+		res.JoinedAt = user.CreatedAt().Unix()
+	}
+	if !hidden.HidePostCount {
+		// count should not take more that 5 seconds
+		dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+		defer cancel()
+
+		count, err := p.posts.Count(dbCtx, store.PostFilter{Authors: []primitive.ObjectID{user.ID}})
+		if err != nil {
+			logging.FromContext(ctx).Error("Error returned while counting posts", zap.Error(err))
+			return nil, status.Error(codes.Internal, "Internal Error")
+		}
+		res.PostCount = count
+	}
+	if own {
+		privacy := user.Profile.Privacy
+		res.Privacy = &proto.Privacy{
+			HideDisplayName: privacy.HideDisplayName,
+			HideBio:         privacy.HideBio,
+			HideAvatar:      privacy.HideAvatar,
+			HideJoined:      privacy.HideJoined,
+			HidePostCount:   privacy.HidePostCount,
+		}
+	}
+	return res, nil
+}
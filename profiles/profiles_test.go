@@ -0,0 +1,209 @@
+package profiles
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/server"
+	"github.com/HiteshRepo/blog-application/store"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// authenticate trusts the token, the auth service checks it against the db
+func authenticate(ctx context.Context) (context.Context, []policy.Role, error) {
+	user := global.UserFromToken(policy.TokenFromContext(ctx))
+	if user.IsNil() {
+		return ctx, nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	return global.ContextWithUser(ctx, user), user.GetRoles(), nil
+}
+
+// newTestClient serves the ProfileService over bufconn on the production server bootstrap
+func newTestClient(t *testing.T, service *Service) proto.ProfileServiceClient {
+	t.Helper()
+
+	grpcServer := server.New(server.Config{Logger: zap.NewNop(), Authenticate: authenticate}, service).GRPCServer()
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcServer.Serve(listener)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error dialing bufconn : %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+	})
+	return proto.NewProfileServiceClient(conn)
+}
+
+// as returns a context that calls the service with user's token
+func as(user global.User) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+user.GetToken())
+}
+
+// newTestStores returns stores holding an author with a public profile, one
+// hiding everything, and a suspended user. The author has two published posts
+// and a draft.
+func newTestStores(t *testing.T) (store.Users, store.Posts, []global.User) {
+	t.Helper()
+
+	ctx := context.Background()
+	users, posts := store.NewMemoryUsers(), store.NewMemoryPosts()
+	profile := global.Profile{DisplayName: "Ada L.", Bio: "Writes about engines", AvatarURL: "https://example.com/ada.png"}
+	all := []global.User{
+		global.User{ID: primitive.NewObjectID(), Username: "ada", Email: "ada@example.com", Profile: profile},
+		global.User{ID: primitive.NewObjectID(), Username: "grace", Email: "grace@example.com", Profile: global.Profile{
+			DisplayName: "Grace H.",
+			Bio:         "Compilers",
+			AvatarURL:   "https://example.com/grace.png",
+			Privacy:     global.Privacy{HideDisplayName: true, HideBio: true, HideAvatar: true, HideJoined: true, HidePostCount: true},
+		}},
+		global.User{ID: primitive.NewObjectID(), Username: "mallory", Email: "mallory@example.com", Suspended: true, Profile: profile},
+	}
+	for _, u := range all {
+		if err := users.Insert(ctx, u); err != nil {
+			t.Fatalf("Error inserting user : %v", err)
+		}
+	}
+	for _, state := range []store.PostState{store.PostPublished, store.PostPublished, store.PostDraft} {
+		if err := posts.Insert(ctx, store.Post{ID: primitive.NewObjectID(), AuthorID: all[0].ID, State: state, PublishedAt: time.Now()}); err != nil {
+			t.Fatalf("Error inserting post : %v", err)
+		}
+	}
+	return users, posts, all
+}
+
+func Test_profileServer_GetProfileByUsername(t *testing.T) {
+
+	users, posts, all := newTestStores(t)
+	client := newTestClient(t, New(users, posts))
+	ada, grace := all[0], all[1]
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"username": "ada",
+			"profile": &proto.Profile{
+				ID:          ada.ID.Hex(),
+				Username:    "ada",
+				DisplayName: "Ada L.",
+				Bio:         "Writes about engines",
+				AvatarURL:   "https://example.com/ada.png",
+				JoinedAt:    ada.CreatedAt().Unix(),
+				PostCount:   2,
+			},
+		},
+		map[string]interface{}{
+			// hidden fields are left out, privacy settings too
+			"username": "grace",
+			"profile":  &proto.Profile{ID: grace.ID.Hex(), Username: "grace"},
+		},
+		map[string]interface{}{
+			"username": "mallory",
+			"code":     codes.NotFound,
+		},
+		map[string]interface{}{
+			"username": "nobody",
+			"code":     codes.NotFound,
+		},
+		map[string]interface{}{
+			"username": "",
+			"code":     codes.InvalidArgument,
+		},
+	}
+
+	for _, tcase := range testCases {
+		// the owner gets the public profile too
+		for _, ctx := range []context.Context{context.Background(), as(grace)} {
+			res, err := client.GetProfileByUsername(ctx, &proto.GetProfileByUsernameRequest{Username: tcase["username"].(string)})
+			code, _ := tcase["code"].(codes.Code)
+			assert.Equalf(t, code, status.Code(err), "case: %v", tcase)
+			if code != codes.OK {
+				continue
+			}
+			assert.Truef(t, protobuf.Equal(tcase["profile"].(*proto.Profile), res), "case: %v, got: %v", tcase, res)
+		}
+	}
+}
+
+func Test_profileServer_GetMyProfile(t *testing.T) {
+
+	users, posts, all := newTestStores(t)
+	client := newTestClient(t, New(users, posts))
+	grace := all[1]
+
+	_, err := client.GetMyProfile(context.Background(), &proto.GetMyProfileRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// the owner sees every field along with the privacy settings
+	res, err := client.GetMyProfile(as(grace), &proto.GetMyProfileRequest{})
+	assert.NoError(t, err)
+	assert.True(t, protobuf.Equal(&proto.Profile{
+		ID:          grace.ID.Hex(),
+		Username:    "grace",
+		DisplayName: "Grace H.",
+		Bio:         "Compilers",
+		AvatarURL:   "https://example.com/grace.png",
+		JoinedAt:    grace.CreatedAt().Unix(),
+		Privacy:     &proto.Privacy{HideDisplayName: true, HideBio: true, HideAvatar: true, HideJoined: true, HidePostCount: true},
+	}, res), "got: %v", res)
+
+	_, err = client.GetMyProfile(as(global.User{ID: primitive.NewObjectID()}), &proto.GetMyProfileRequest{})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func Test_profileServer_Update(t *testing.T) {
+
+	users, posts, all := newTestStores(t)
+	client := newTestClient(t, New(users, posts))
+	ada := all[0]
+
+	_, err := client.UpdateProfile(context.Background(), &proto.UpdateProfileRequest{DisplayName: "Ada"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.UpdateProfile(as(ada), &proto.UpdateProfileRequest{AvatarURL: "ftp://example.com/ada.png"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.UpdatePrivacy(context.Background(), &proto.Privacy{HideBio: true})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// updating the profile keeps the privacy settings and the other way round
+	res, err := client.UpdatePrivacy(as(ada), &proto.Privacy{HideBio: true, HidePostCount: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "Writes about engines", res.GetBio())
+	assert.Equal(t, int64(2), res.GetPostCount())
+	res, err = client.UpdateProfile(as(ada), &proto.UpdateProfileRequest{DisplayName: "Ada Lovelace", Bio: "Notes on the engine"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Ada Lovelace", res.GetDisplayName())
+	assert.Empty(t, res.GetAvatarURL())
+	assert.True(t, res.GetPrivacy().GetHideBio())
+
+	res, err = client.GetProfileByUsername(context.Background(), &proto.GetProfileByUsernameRequest{Username: "ada"})
+	assert.NoError(t, err)
+	assert.True(t, protobuf.Equal(&proto.Profile{
+		ID:          ada.ID.Hex(),
+		Username:    "ada",
+		DisplayName: "Ada Lovelace",
+		JoinedAt:    ada.CreatedAt().Unix(),
+	}, res), "got: %v", res)
+
+	stored, _ := users.FindByID(context.Background(), ada.ID)
+	assert.Equal(t, "ada@example.com", stored.Email)
+}
@@ -0,0 +1,753 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0-devel
+// 	protoc        v3.15.8
+// source: reactions.proto
+
+package proto
+
+import (
+	context "context"
+	reflect "reflect"
+	sync "sync"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ReactionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PostID string `protobuf:"bytes,1,opt,name=PostID,proto3" json:"PostID,omitempty"`
+}
+
+func (x *ReactionRequest) Reset() {
+	*x = ReactionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reactions_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReactionRequest) ProtoMessage() {}
+
+func (x *ReactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_reactions_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReactionRequest.ProtoReflect.Descriptor instead.
+func (*ReactionRequest) Descriptor() ([]byte, []int) {
+	return file_reactions_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ReactionRequest) GetPostID() string {
+	if x != nil {
+		return x.PostID
+	}
+	return ""
+}
+
+type ReactionResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PostID string `protobuf:"bytes,1,opt,name=PostID,proto3" json:"PostID,omitempty"`
+	// whether the caller likes, bookmarked or clapped for the post after the call
+	Reacted bool `protobuf:"varint,2,opt,name=Reacted,proto3" json:"Reacted,omitempty"`
+	// the counts of the post
+	Likes     int64 `protobuf:"varint,3,opt,name=Likes,proto3" json:"Likes,omitempty"`
+	Bookmarks int64 `protobuf:"varint,4,opt,name=Bookmarks,proto3" json:"Bookmarks,omitempty"`
+	Claps     int64 `protobuf:"varint,5,opt,name=Claps,proto3" json:"Claps,omitempty"`
+}
+
+func (x *ReactionResponse) Reset() {
+	*x = ReactionResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reactions_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReactionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReactionResponse) ProtoMessage() {}
+
+func (x *ReactionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_reactions_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReactionResponse.ProtoReflect.Descriptor instead.
+func (*ReactionResponse) Descriptor() ([]byte, []int) {
+	return file_reactions_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ReactionResponse) GetPostID() string {
+	if x != nil {
+		return x.PostID
+	}
+	return ""
+}
+
+func (x *ReactionResponse) GetReacted() bool {
+	if x != nil {
+		return x.Reacted
+	}
+	return false
+}
+
+func (x *ReactionResponse) GetLikes() int64 {
+	if x != nil {
+		return x.Likes
+	}
+	return 0
+}
+
+func (x *ReactionResponse) GetBookmarks() int64 {
+	if x != nil {
+		return x.Bookmarks
+	}
+	return 0
+}
+
+func (x *ReactionResponse) GetClaps() int64 {
+	if x != nil {
+		return x.Claps
+	}
+	return 0
+}
+
+type ListMyBookmarksRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PageSize int64 `protobuf:"varint,1,opt,name=PageSize,proto3" json:"PageSize,omitempty"`
+	// NextPageToken of the previous page, the first page when empty
+	PageToken string `protobuf:"bytes,2,opt,name=PageToken,proto3" json:"PageToken,omitempty"`
+}
+
+func (x *ListMyBookmarksRequest) Reset() {
+	*x = ListMyBookmarksRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reactions_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListMyBookmarksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMyBookmarksRequest) ProtoMessage() {}
+
+func (x *ListMyBookmarksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_reactions_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMyBookmarksRequest.ProtoReflect.Descriptor instead.
+func (*ListMyBookmarksRequest) Descriptor() ([]byte, []int) {
+	return file_reactions_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListMyBookmarksRequest) GetPageSize() int64 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListMyBookmarksRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+var File_reactions_proto protoreflect.FileDescriptor
+
+var file_reactions_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x72, 0x65, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x0e, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61,
+	0x74, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x0b, 0x70, 0x6f, 0x73, 0x74, 0x73, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x0e, 0x74, 0x61, 0x78, 0x6f, 0x6e, 0x6f, 0x6d, 0x79, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x3c, 0x0a, 0x0f, 0x52, 0x65, 0x61, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x29, 0x0a, 0x06, 0x50, 0x6f, 0x73, 0x74,
+	0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x42, 0x11, 0x8a, 0xb5, 0x18, 0x0d, 0x08, 0x01,
+	0x22, 0x09, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x69, 0x64, 0x52, 0x06, 0x50, 0x6f, 0x73,
+	0x74, 0x49, 0x44, 0x22, 0x8e, 0x01, 0x0a, 0x10, 0x52, 0x65, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x50, 0x6f, 0x73, 0x74,
+	0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x50, 0x6f, 0x73, 0x74, 0x49, 0x44,
+	0x12, 0x18, 0x0a, 0x07, 0x52, 0x65, 0x61, 0x63, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x07, 0x52, 0x65, 0x61, 0x63, 0x74, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x4c, 0x69,
+	0x6b, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x4c, 0x69, 0x6b, 0x65, 0x73,
+	0x12, 0x1c, 0x0a, 0x09, 0x42, 0x6f, 0x6f, 0x6b, 0x6d, 0x61, 0x72, 0x6b, 0x73, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x09, 0x42, 0x6f, 0x6f, 0x6b, 0x6d, 0x61, 0x72, 0x6b, 0x73, 0x12, 0x14,
+	0x0a, 0x05, 0x43, 0x6c, 0x61, 0x70, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x43,
+	0x6c, 0x61, 0x70, 0x73, 0x22, 0x52, 0x0a, 0x16, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x79, 0x42, 0x6f,
+	0x6f, 0x6b, 0x6d, 0x61, 0x72, 0x6b, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a,
+	0x0a, 0x08, 0x50, 0x61, 0x67, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x08, 0x50, 0x61, 0x67, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x50, 0x61,
+	0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x50,
+	0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x32, 0x80, 0x04, 0x0a, 0x0f, 0x52, 0x65, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x37, 0x0a, 0x04,
+	0x4c, 0x69, 0x6b, 0x65, 0x12, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x39, 0x0a, 0x06, 0x55, 0x6e, 0x6c, 0x69, 0x6b, 0x65, 0x12,
+	0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x52, 0x65, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x3b, 0x0a, 0x08, 0x42, 0x6f, 0x6f, 0x6b, 0x6d, 0x61, 0x72, 0x6b, 0x12, 0x16, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3d, 0x0a,
+	0x0a, 0x55, 0x6e, 0x62, 0x6f, 0x6f, 0x6b, 0x6d, 0x61, 0x72, 0x6b, 0x12, 0x16, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x37, 0x0a, 0x04,
+	0x43, 0x6c, 0x61, 0x70, 0x12, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x39, 0x0a, 0x06, 0x55, 0x6e, 0x63, 0x6c, 0x61, 0x70, 0x12,
+	0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x52, 0x65, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x46, 0x0a, 0x0f, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x79, 0x42, 0x6f, 0x6f, 0x6b, 0x6d, 0x61,
+	0x72, 0x6b, 0x73, 0x12, 0x1d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x69, 0x73, 0x74,
+	0x4d, 0x79, 0x42, 0x6f, 0x6f, 0x6b, 0x6d, 0x61, 0x72, 0x6b, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x6f, 0x73, 0x74, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x41, 0x0a, 0x11, 0x53, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x4d, 0x79, 0x42, 0x6f, 0x6f, 0x6b, 0x6d, 0x61, 0x72, 0x6b, 0x73, 0x12, 0x1d, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x79, 0x42, 0x6f, 0x6f, 0x6b,
+	0x6d, 0x61, 0x72, 0x6b, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x6f, 0x73, 0x74, 0x30, 0x01, 0x42, 0x04, 0x5a, 0x02, 0x2e,
+	0x2f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_reactions_proto_rawDescOnce sync.Once
+	file_reactions_proto_rawDescData = file_reactions_proto_rawDesc
+)
+
+func file_reactions_proto_rawDescGZIP() []byte {
+	file_reactions_proto_rawDescOnce.Do(func() {
+		file_reactions_proto_rawDescData = protoimpl.X.CompressGZIP(file_reactions_proto_rawDescData)
+	})
+	return file_reactions_proto_rawDescData
+}
+
+var file_reactions_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_reactions_proto_goTypes = []interface{}{
+	(*ReactionRequest)(nil),        // 0: proto.ReactionRequest
+	(*ReactionResponse)(nil),       // 1: proto.ReactionResponse
+	(*ListMyBookmarksRequest)(nil), // 2: proto.ListMyBookmarksRequest
+	(*PostsResponse)(nil),          // 3: proto.PostsResponse
+	(*Post)(nil),                   // 4: proto.Post
+}
+var file_reactions_proto_depIdxs = []int32{
+	0, // 0: proto.ReactionService.Like:input_type -> proto.ReactionRequest
+	0, // 1: proto.ReactionService.Unlike:input_type -> proto.ReactionRequest
+	0, // 2: proto.ReactionService.Bookmark:input_type -> proto.ReactionRequest
+	0, // 3: proto.ReactionService.Unbookmark:input_type -> proto.ReactionRequest
+	0, // 4: proto.ReactionService.Clap:input_type -> proto.ReactionRequest
+	0, // 5: proto.ReactionService.Unclap:input_type -> proto.ReactionRequest
+	2, // 6: proto.ReactionService.ListMyBookmarks:input_type -> proto.ListMyBookmarksRequest
+	2, // 7: proto.ReactionService.StreamMyBookmarks:input_type -> proto.ListMyBookmarksRequest
+	1, // 8: proto.ReactionService.Like:output_type -> proto.ReactionResponse
+	1, // 9: proto.ReactionService.Unlike:output_type -> proto.ReactionResponse
+	1, // 10: proto.ReactionService.Bookmark:output_type -> proto.ReactionResponse
+	1, // 11: proto.ReactionService.Unbookmark:output_type -> proto.ReactionResponse
+	1, // 12: proto.ReactionService.Clap:output_type -> proto.ReactionResponse
+	1, // 13: proto.ReactionService.Unclap:output_type -> proto.ReactionResponse
+	3, // 14: proto.ReactionService.ListMyBookmarks:output_type -> proto.PostsResponse
+	4, // 15: proto.ReactionService.StreamMyBookmarks:output_type -> proto.Post
+	8, // [8:16] is the sub-list for method output_type
+	0, // [0:8] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_reactions_proto_init() }
+func file_reactions_proto_init() {
+	if File_reactions_proto != nil {
+		return
+	}
+	file_validate_proto_init()
+	file_posts_proto_init()
+	file_taxonomy_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_reactions_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReactionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reactions_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReactionResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reactions_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListMyBookmarksRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_reactions_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_reactions_proto_goTypes,
+		DependencyIndexes: file_reactions_proto_depIdxs,
+		MessageInfos:      file_reactions_proto_msgTypes,
+	}.Build()
+	File_reactions_proto = out.File
+	file_reactions_proto_rawDesc = nil
+	file_reactions_proto_goTypes = nil
+	file_reactions_proto_depIdxs = nil
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// ReactionServiceClient is the client API for ReactionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ReactionServiceClient interface {
+	// Like, Unlike, Bookmark, Unbookmark, Clap and Unclap act for the
+	// caller, repeating them changes nothing
+	Like(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*ReactionResponse, error)
+	Unlike(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*ReactionResponse, error)
+	Bookmark(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*ReactionResponse, error)
+	Unbookmark(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*ReactionResponse, error)
+	Clap(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*ReactionResponse, error)
+	Unclap(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*ReactionResponse, error)
+	// ListMyBookmarks returns the posts the caller bookmarked, latest
+	// bookmarks first
+	ListMyBookmarks(ctx context.Context, in *ListMyBookmarksRequest, opts ...grpc.CallOption) (*PostsResponse, error)
+	// StreamMyBookmarks sends every bookmarked post from the page token on,
+	// PageSize sets how many are read at once
+	StreamMyBookmarks(ctx context.Context, in *ListMyBookmarksRequest, opts ...grpc.CallOption) (ReactionService_StreamMyBookmarksClient, error)
+}
+
+type reactionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReactionServiceClient(cc grpc.ClientConnInterface) ReactionServiceClient {
+	return &reactionServiceClient{cc}
+}
+
+func (c *reactionServiceClient) Like(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*ReactionResponse, error) {
+	out := new(ReactionResponse)
+	err := c.cc.Invoke(ctx, "/proto.ReactionService/Like", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reactionServiceClient) Unlike(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*ReactionResponse, error) {
+	out := new(ReactionResponse)
+	err := c.cc.Invoke(ctx, "/proto.ReactionService/Unlike", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reactionServiceClient) Bookmark(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*ReactionResponse, error) {
+	out := new(ReactionResponse)
+	err := c.cc.Invoke(ctx, "/proto.ReactionService/Bookmark", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reactionServiceClient) Unbookmark(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*ReactionResponse, error) {
+	out := new(ReactionResponse)
+	err := c.cc.Invoke(ctx, "/proto.ReactionService/Unbookmark", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reactionServiceClient) Clap(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*ReactionResponse, error) {
+	out := new(ReactionResponse)
+	err := c.cc.Invoke(ctx, "/proto.ReactionService/Clap", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reactionServiceClient) Unclap(ctx context.Context, in *ReactionRequest, opts ...grpc.CallOption) (*ReactionResponse, error) {
+	out := new(ReactionResponse)
+	err := c.cc.Invoke(ctx, "/proto.ReactionService/Unclap", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reactionServiceClient) ListMyBookmarks(ctx context.Context, in *ListMyBookmarksRequest, opts ...grpc.CallOption) (*PostsResponse, error) {
+	out := new(PostsResponse)
+	err := c.cc.Invoke(ctx, "/proto.ReactionService/ListMyBookmarks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reactionServiceClient) StreamMyBookmarks(ctx context.Context, in *ListMyBookmarksRequest, opts ...grpc.CallOption) (ReactionService_StreamMyBookmarksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ReactionService_serviceDesc.Streams[0], "/proto.ReactionService/StreamMyBookmarks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &reactionServiceStreamMyBookmarksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ReactionService_StreamMyBookmarksClient interface {
+	Recv() (*Post, error)
+	grpc.ClientStream
+}
+
+type reactionServiceStreamMyBookmarksClient struct {
+	grpc.ClientStream
+}
+
+func (x *reactionServiceStreamMyBookmarksClient) Recv() (*Post, error) {
+	m := new(Post)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReactionServiceServer is the server API for ReactionService service.
+type ReactionServiceServer interface {
+	// Like, Unlike, Bookmark, Unbookmark, Clap and Unclap act for the
+	// caller, repeating them changes nothing
+	Like(context.Context, *ReactionRequest) (*ReactionResponse, error)
+	Unlike(context.Context, *ReactionRequest) (*ReactionResponse, error)
+	Bookmark(context.Context, *ReactionRequest) (*ReactionResponse, error)
+	Unbookmark(context.Context, *ReactionRequest) (*ReactionResponse, error)
+	Clap(context.Context, *ReactionRequest) (*ReactionResponse, error)
+	Unclap(context.Context, *ReactionRequest) (*ReactionResponse, error)
+	// ListMyBookmarks returns the posts the caller bookmarked, latest
+	// bookmarks first
+	ListMyBookmarks(context.Context, *ListMyBookmarksRequest) (*PostsResponse, error)
+	// StreamMyBookmarks sends every bookmarked post from the page token on,
+	// PageSize sets how many are read at once
+	StreamMyBookmarks(*ListMyBookmarksRequest, ReactionService_StreamMyBookmarksServer) error
+}
+
+// UnimplementedReactionServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedReactionServiceServer struct {
+}
+
+func (*UnimplementedReactionServiceServer) Like(context.Context, *ReactionRequest) (*ReactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Like not implemented")
+}
+func (*UnimplementedReactionServiceServer) Unlike(context.Context, *ReactionRequest) (*ReactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unlike not implemented")
+}
+func (*UnimplementedReactionServiceServer) Bookmark(context.Context, *ReactionRequest) (*ReactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Bookmark not implemented")
+}
+func (*UnimplementedReactionServiceServer) Unbookmark(context.Context, *ReactionRequest) (*ReactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unbookmark not implemented")
+}
+func (*UnimplementedReactionServiceServer) Clap(context.Context, *ReactionRequest) (*ReactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Clap not implemented")
+}
+func (*UnimplementedReactionServiceServer) Unclap(context.Context, *ReactionRequest) (*ReactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unclap not implemented")
+}
+func (*UnimplementedReactionServiceServer) ListMyBookmarks(context.Context, *ListMyBookmarksRequest) (*PostsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMyBookmarks not implemented")
+}
+func (*UnimplementedReactionServiceServer) StreamMyBookmarks(*ListMyBookmarksRequest, ReactionService_StreamMyBookmarksServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMyBookmarks not implemented")
+}
+
+func RegisterReactionServiceServer(s *grpc.Server, srv ReactionServiceServer) {
+	s.RegisterService(&_ReactionService_serviceDesc, srv)
+}
+
+func _ReactionService_Like_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReactionServiceServer).Like(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ReactionService/Like",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReactionServiceServer).Like(ctx, req.(*ReactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReactionService_Unlike_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReactionServiceServer).Unlike(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ReactionService/Unlike",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReactionServiceServer).Unlike(ctx, req.(*ReactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReactionService_Bookmark_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReactionServiceServer).Bookmark(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ReactionService/Bookmark",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReactionServiceServer).Bookmark(ctx, req.(*ReactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReactionService_Unbookmark_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReactionServiceServer).Unbookmark(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ReactionService/Unbookmark",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReactionServiceServer).Unbookmark(ctx, req.(*ReactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReactionService_Clap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReactionServiceServer).Clap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ReactionService/Clap",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReactionServiceServer).Clap(ctx, req.(*ReactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReactionService_Unclap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReactionServiceServer).Unclap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ReactionService/Unclap",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReactionServiceServer).Unclap(ctx, req.(*ReactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReactionService_ListMyBookmarks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMyBookmarksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReactionServiceServer).ListMyBookmarks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ReactionService/ListMyBookmarks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReactionServiceServer).ListMyBookmarks(ctx, req.(*ListMyBookmarksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReactionService_StreamMyBookmarks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListMyBookmarksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReactionServiceServer).StreamMyBookmarks(m, &reactionServiceStreamMyBookmarksServer{stream})
+}
+
+type ReactionService_StreamMyBookmarksServer interface {
+	Send(*Post) error
+	grpc.ServerStream
+}
+
+type reactionServiceStreamMyBookmarksServer struct {
+	grpc.ServerStream
+}
+
+func (x *reactionServiceStreamMyBookmarksServer) Send(m *Post) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _ReactionService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.ReactionService",
+	HandlerType: (*ReactionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Like",
+			Handler:    _ReactionService_Like_Handler,
+		},
+		{
+			MethodName: "Unlike",
+			Handler:    _ReactionService_Unlike_Handler,
+		},
+		{
+			MethodName: "Bookmark",
+			Handler:    _ReactionService_Bookmark_Handler,
+		},
+		{
+			MethodName: "Unbookmark",
+			Handler:    _ReactionService_Unbookmark_Handler,
+		},
+		{
+			MethodName: "Clap",
+			Handler:    _ReactionService_Clap_Handler,
+		},
+		{
+			MethodName: "Unclap",
+			Handler:    _ReactionService_Unclap_Handler,
+		},
+		{
+			MethodName: "ListMyBookmarks",
+			Handler:    _ReactionService_ListMyBookmarks_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMyBookmarks",
+			Handler:       _ReactionService_StreamMyBookmarks_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "reactions.proto",
+}
@@ -0,0 +1,131 @@
+// Package render turns the Markdown source of posts into sanitized HTML.
+// Sources are CommonMark with the GitHub extensions (tables, task lists,
+// strikethrough, autolinks) and footnotes. Headings get anchors and make up
+// the table of contents, fenced code blocks are highlighted.
+package render
+
+import (
+	"bytes"
+	stdhtml "html"
+	"io"
+	"regexp"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// Heading is an entry of the table of contents, ID is the anchor of the heading
+type Heading struct {
+	Level int    `bson:"level"`
+	ID    string `bson:"id"`
+	Text  string `bson:"text"`
+}
+
+// Result is a rendered source
+type Result struct {
+	HTML string
+	// TOC lists the headings in document order
+	TOC []Heading
+}
+
+// Renderer converts Markdown to HTML, it is safe for concurrent use
+type Renderer struct {
+	markdown goldmark.Markdown
+	policy   *bluemonday.Policy
+}
+
+// New returns a renderer producing the HTML allowed by Policy
+func New() *Renderer {
+	return &Renderer{
+		markdown: goldmark.New(
+			goldmark.WithExtensions(
+				extension.GFM,
+				extension.Footnote,
+				// classes instead of inline styles, see Stylesheet
+				highlighting.NewHighlighting(highlighting.WithFormatOptions(chromahtml.WithClasses(true))),
+			),
+			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+			// raw HTML is kept for the sanitizer to filter, rather than dropped
+			goldmark.WithRendererOptions(html.WithUnsafe()),
+		),
+		policy: Policy(),
+	}
+}
+
+// Render converts source into sanitized HTML along with its table of contents
+func (r *Renderer) Render(source string) (Result, error) {
+	src := []byte(source)
+	doc := r.markdown.Parser().Parse(text.NewReader(src))
+
+	var buf bytes.Buffer
+	if err := r.markdown.Renderer().Render(&buf, src, doc); err != nil {
+		return Result{}, err
+	}
+	return Result{
+		HTML: r.policy.SanitizeReader(&buf).String(),
+		TOC:  tableOfContents(doc, src),
+	}, nil
+}
+
+// tableOfContents collects the headings of doc, sanitization keeps their ids
+func tableOfContents(doc ast.Node, src []byte) []Heading {
+	toc := []Heading{}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		var id string
+		if value, ok := heading.AttributeString("id"); ok {
+			id = string(value.([]byte))
+		}
+		toc = append(toc, Heading{Level: heading.Level, ID: id, Text: string(heading.Text(src))})
+		return ast.WalkSkipChildren, nil
+	})
+	return toc
+}
+
+var (
+	// classes set by the highlighter and the footnotes extension
+	codeClass     = regexp.MustCompile(`^language-[\w+#-]+$`)
+	markupClasses = regexp.MustCompile(`^[a-z][a-z0-9-]*( [a-z][a-z0-9-]*)*$`)
+)
+
+// Policy returns the allow-list applied to rendered HTML: the user generated
+// content policy of bluemonday (no scripts, styles, event handlers or
+// javascript: urls, ids for the anchors), plus the classes and checkboxes
+// Markdown rendering itself produces
+func Policy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(codeClass).OnElements("code")
+	p.AllowAttrs("class").Matching(markupClasses).OnElements("pre", "span", "div", "a")
+	// task list items render as disabled checkboxes
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AllowElements("input")
+	return p
+}
+
+// strictPolicy strips every tag
+var strictPolicy = bluemonday.StrictPolicy()
+
+// PlainText returns the text of rendered HTML without its markup, for
+// searching and excerpts
+func PlainText(html string) string {
+	return strings.TrimSpace(stdhtml.UnescapeString(strictPolicy.Sanitize(html)))
+}
+
+// Stylesheet writes the css of the highlighted code blocks in the named
+// chroma style, e.g. "github" or "monokai"
+func Stylesheet(w io.Writer, style string) error {
+	return chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(w, styles.Get(style))
+}
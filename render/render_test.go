@@ -0,0 +1,121 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Renderer_Render(t *testing.T) {
+
+	renderer := New()
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"source":   "Some *emphasis* and **strong** text",
+			"contains": []string{"<p>Some <em>emphasis</em> and <strong>strong</strong> text</p>"},
+		},
+		map[string]interface{}{
+			"source":   "# Getting started\n\n## Getting started",
+			"contains": []string{`<h1 id="getting-started">Getting started</h1>`, `<h2 id="getting-started-1">Getting started</h2>`},
+		},
+		map[string]interface{}{
+			"source":   "| a | b |\n|---|---|\n| 1 | 2 |",
+			"contains": []string{"<table>", "<th>a</th>", "<td>2</td>"},
+		},
+		map[string]interface{}{
+			"source":   "- [x] done\n- [ ] todo",
+			"contains": []string{`<li><input checked="" disabled="" type="checkbox"> done</li>`, `<li><input disabled="" type="checkbox"> todo</li>`},
+		},
+		map[string]interface{}{
+			"source":   "Text[^1]\n\n[^1]: The note",
+			"contains": []string{`<sup id="fnref:1"><a href="#fn:1" class="footnote-ref"`, `<li id="fn:1">`, "The note"},
+		},
+		map[string]interface{}{
+			"source":   "~~gone~~ and https://example.com",
+			"contains": []string{"<del>gone</del>", `<a href="https://example.com" rel="nofollow">https://example.com</a>`},
+		},
+		map[string]interface{}{
+			"source":   "```go\npackage main\n```",
+			"contains": []string{`<pre class="chroma">`, `<span class="kn">package</span>`},
+		},
+		map[string]interface{}{
+			// raw HTML goes through the sanitizer
+			"source":   "H<sub>2</sub>O <script>alert(1)</script>",
+			"contains": []string{"H<sub>2</sub>O"},
+			"excludes": []string{"<script", "alert(1)"},
+		},
+		map[string]interface{}{
+			"source":   `<img src="x.png" onerror="alert(1)"> <a href="#top" onclick="alert(1)" style="color: red">top</a>`,
+			"contains": []string{`<img src="x.png">`, `<a href="#top" rel="nofollow">top</a>`},
+			"excludes": []string{"onerror", "onclick", "style"},
+		},
+		map[string]interface{}{
+			"source":   "[click](javascript:alert(1)) <a href=\"javascript:alert(1)\">raw</a> <iframe src=\"https://example.com\"></iframe>",
+			"excludes": []string{"javascript:", "<iframe"},
+		},
+		map[string]interface{}{
+			"source":   `<h2 onmouseover="alert(1)">t</h2><input type="text" value="x"><form action="/"><button>go</button></form>`,
+			"excludes": []string{"onmouseover", `type="text"`, "<form", "<button"},
+		},
+	}
+
+	for _, tcase := range testCases {
+		result, err := renderer.Render(tcase["source"].(string))
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		if contains, ok := tcase["contains"]; ok {
+			for _, s := range contains.([]string) {
+				assert.Containsf(t, result.HTML, s, "case: %v", tcase)
+			}
+		}
+		if excludes, ok := tcase["excludes"]; ok {
+			for _, s := range excludes.([]string) {
+				assert.NotContainsf(t, result.HTML, s, "case: %v", tcase)
+			}
+		}
+	}
+}
+
+func Test_Renderer_Render_toc(t *testing.T) {
+
+	result, err := New().Render("# Title\n\nIntro\n\n## Setup *steps*\n\n```\n# not a heading\n```\n\n### Details\n\n## Setup steps")
+	assert.NoError(t, err)
+	assert.Equal(t, []Heading{
+		Heading{Level: 1, ID: "title", Text: "Title"},
+		Heading{Level: 2, ID: "setup-steps", Text: "Setup steps"},
+		Heading{Level: 3, ID: "details", Text: "Details"},
+		Heading{Level: 2, ID: "setup-steps-1", Text: "Setup steps"},
+	}, result.TOC)
+
+	// the anchors survive sanitization
+	for _, h := range result.TOC {
+		assert.Contains(t, result.HTML, `id="`+h.ID+`"`)
+	}
+
+	result, err = New().Render("")
+	assert.NoError(t, err)
+	assert.Empty(t, result.HTML)
+	assert.Equal(t, []Heading{}, result.TOC)
+}
+
+func Test_PlainText(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"html": "<h1 id=\"intro\">Intro</h1>\n<p>Some <em>text</em> &amp; more</p>\n", "text": "Intro\nSome text & more"},
+		map[string]interface{}{"html": "<pre><code class=\"language-go\">x &lt; y\n</code></pre>", "text": "x < y"},
+		map[string]interface{}{"html": "", "text": ""},
+	}
+
+	for _, tcase := range testCases {
+		assert.Equalf(t, tcase["text"], PlainText(tcase["html"].(string)), "case: %v", tcase)
+	}
+}
+
+func Test_Stylesheet(t *testing.T) {
+
+	var buf bytes.Buffer
+	assert.NoError(t, Stylesheet(&buf, "github"))
+	assert.True(t, strings.Contains(buf.String(), ".chroma"))
+}
@@ -0,0 +1,80 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// testIndexer checks the behaviour every Indexer shares
+func testIndexer(t *testing.T, index Indexer) {
+	ctx := context.Background()
+	day := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, doc := range []Document{
+		Document{ID: "a", Kind: KindPost, AuthorID: "ann", Title: "Concurrency in Go", Body: "Goroutines and channels make concurrency simple.", Tags: []string{"go"}, PublishedAt: day},
+		Document{ID: "b", Kind: KindPost, AuthorID: "bob", Title: "Mongo text index", Body: "A text index finds words, go and search.", Tags: []string{"mongo"}, PublishedAt: day.Add(24 * time.Hour)},
+		Document{ID: "c", Kind: KindPost, AuthorID: "ann", Title: "Testing", Body: "Index the text before searching the index of text.", Tags: []string{"go", "mongo"}, PublishedAt: day.Add(48 * time.Hour)},
+		Document{ID: "d", Kind: KindPost, AuthorID: "bob", Title: "Removed", Body: "concurrency", PublishedAt: day},
+	} {
+		assert.NoError(t, index.Index(ctx, doc))
+	}
+	assert.NoError(t, index.Remove(ctx, "d"))
+	assert.NoError(t, index.Remove(ctx, "missing"))
+	// indexing again replaces the document
+	assert.NoError(t, index.Index(ctx, Document{ID: "c", Kind: KindPost, AuthorID: "ann", Title: "Testing", Body: "Index the text before searching the index of text.", Tags: []string{"go", "mongo"}, PublishedAt: day.Add(48 * time.Hour)}))
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"query": Query{}, "ids": []string{"c", "b", "a"}, "total": int64(3)},
+		map[string]interface{}{"query": Query{Text: "concurrency"}, "ids": []string{"a"}, "total": int64(1)},
+		// the title weighs more
+		map[string]interface{}{"query": Query{Text: "go"}, "ids": []string{"a", "b"}, "total": int64(2)},
+		map[string]interface{}{"query": Query{Text: `"text index"`}, "ids": []string{"b"}, "total": int64(1)},
+		map[string]interface{}{"query": Query{Text: `"index of text"`}, "ids": []string{"c"}, "total": int64(1)},
+		map[string]interface{}{"query": Query{Text: "concurrency mongo"}, "ids": []string{"a", "b"}, "total": int64(2)},
+		map[string]interface{}{"query": Query{Tags: []string{"go", "mongo"}}, "ids": []string{"c"}, "total": int64(1)},
+		map[string]interface{}{"query": Query{AuthorID: "ann"}, "ids": []string{"c", "a"}, "total": int64(2)},
+		map[string]interface{}{"query": Query{From: day.Add(24 * time.Hour), To: day.Add(48 * time.Hour)}, "ids": []string{"b"}, "total": int64(1)},
+		map[string]interface{}{"query": Query{Skip: 1, Limit: 1}, "ids": []string{"b"}, "total": int64(3)},
+		map[string]interface{}{"query": Query{Text: "missing"}, "ids": []string{}, "total": int64(0)},
+	}
+
+	for _, tcase := range testCases {
+		hits, total, err := index.Search(ctx, tcase["query"].(Query))
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		assert.Equalf(t, tcase["total"], total, "case: %v", tcase)
+		ids := []string{}
+		for _, hit := range hits {
+			ids = append(ids, hit.ID)
+		}
+		assert.Equalf(t, tcase["ids"], ids, "case: %v", tcase)
+	}
+
+	hits, _, err := index.Search(ctx, Query{Text: "channels"})
+	assert.NoError(t, err)
+	if assert.Len(t, hits, 1) {
+		assert.Equal(t, "Goroutines and <mark>channels</mark> make concurrency simple.", hits[0].Snippet)
+		assert.Equal(t, []string{"go"}, hits[0].Tags)
+		assert.True(t, day.Equal(hits[0].PublishedAt))
+		assert.Greater(t, hits[0].Score, 0.0)
+	}
+}
+
+func Test_MemoryIndex(t *testing.T) {
+	testIndexer(t, NewMemoryIndex())
+}
+
+func Test_Query_filter(t *testing.T) {
+
+	day := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, bson.M{}, Query{Text: "go"}.filter())
+	assert.Equal(t, bson.M{
+		"tags":         bson.M{"$all": []string{"go"}},
+		"author_id":    "ann",
+		"published_at": bson.M{"$gte": day, "$lt": day.Add(time.Hour)},
+	}, Query{Tags: []string{"go"}, AuthorID: "ann", From: day, To: day.Add(time.Hour)}.filter())
+}
@@ -0,0 +1,231 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// titleWeight makes a word in the title count as much as this many in the body
+const titleWeight = 3
+
+// MemoryIndex is an inverted index kept in memory, for tests and local runs
+type MemoryIndex struct {
+	mu   sync.RWMutex
+	docs map[string]indexed
+	// postings maps every word to the documents holding it
+	postings map[string]map[string]bool
+}
+
+// indexed is a document with its words
+type indexed struct {
+	doc         Document
+	title, body []token
+}
+
+// NewMemoryIndex returns an empty in-memory index
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{docs: map[string]indexed{}, postings: map[string]map[string]bool{}}
+}
+
+func (m *MemoryIndex) Index(ctx context.Context, doc Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(doc.ID)
+	doc.Tags = append([]string{}, doc.Tags...)
+	entry := indexed{doc: doc, title: tokenize(doc.Title), body: tokenize(doc.Body)}
+	m.docs[doc.ID] = entry
+	for _, tokens := range [][]token{entry.title, entry.body} {
+		for _, tok := range tokens {
+			if m.postings[tok.text] == nil {
+				m.postings[tok.text] = map[string]bool{}
+			}
+			m.postings[tok.text][doc.ID] = true
+		}
+	}
+	return nil
+}
+
+func (m *MemoryIndex) Remove(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(id)
+	return nil
+}
+
+func (m *MemoryIndex) remove(id string) {
+	entry, ok := m.docs[id]
+	if !ok {
+		return
+	}
+	for _, tokens := range [][]token{entry.title, entry.body} {
+		for _, tok := range tokens {
+			delete(m.postings[tok.text], id)
+			if len(m.postings[tok.text]) == 0 {
+				delete(m.postings, tok.text)
+			}
+		}
+	}
+	delete(m.docs, id)
+}
+
+func (m *MemoryIndex) Search(ctx context.Context, q Query) ([]Hit, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t := parse(q.Text)
+	var hits []Hit
+	for id := range m.candidates(t) {
+		entry := m.docs[id]
+		if !q.matches(entry.doc) || !entry.contains(t) {
+			continue
+		}
+		hits = append(hits, Hit{Document: entry.doc, Score: m.score(entry, t)})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if !hits[i].PublishedAt.Equal(hits[j].PublishedAt) {
+			return hits[i].PublishedAt.After(hits[j].PublishedAt)
+		}
+		return hits[i].ID > hits[j].ID
+	})
+
+	total := int64(len(hits))
+	skip := q.Skip
+	if skip > total {
+		skip = total
+	}
+	hits = hits[skip:]
+	if q.Limit > 0 && q.Limit < int64(len(hits)) {
+		hits = hits[:q.Limit]
+	}
+	page := make([]Hit, 0, len(hits))
+	for _, hit := range hits {
+		hit.Snippet = snippet(hit.Body, t)
+		page = append(page, hit)
+	}
+	return page, total, nil
+}
+
+// candidates returns the ids of the documents holding the words the terms
+// need, every document without terms
+func (m *MemoryIndex) candidates(t terms) map[string]bool {
+	ids := map[string]bool{}
+	if t.empty() {
+		for id := range m.docs {
+			ids[id] = true
+		}
+		return ids
+	}
+	if len(t.phrases) == 0 {
+		for _, word := range t.words {
+			for id := range m.postings[word] {
+				ids[id] = true
+			}
+		}
+		return ids
+	}
+	// every phrase is needed, start from the documents with its first word
+	for id := range m.postings[t.phrases[0][0]] {
+		ids[id] = true
+	}
+	return ids
+}
+
+// contains reports whether the document holds every phrase of t, and one of
+// its words when there is no phrase
+func (e indexed) contains(t terms) bool {
+	for _, phrase := range t.phrases {
+		if !hasPhrase(e.title, phrase) && !hasPhrase(e.body, phrase) {
+			return false
+		}
+	}
+	if len(t.phrases) > 0 || len(t.words) == 0 {
+		return true
+	}
+	for _, tokens := range [][]token{e.title, e.body} {
+		for _, tok := range tokens {
+			for _, word := range t.words {
+				if tok.text == word {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func hasPhrase(tokens []token, phrase []string) bool {
+	for i := range tokens {
+		if matchAt(tokens, i, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// score ranks a document with BM25, words in the title weigh more
+func (m *MemoryIndex) score(e indexed, t terms) float64 {
+	const k1, b = 1.2, 0.75
+	n := float64(len(m.docs))
+	var length float64
+	for _, entry := range m.docs {
+		length += float64(titleWeight*len(entry.title) + len(entry.body))
+	}
+	average := length / n
+	docLength := float64(titleWeight*len(e.title) + len(e.body))
+
+	score := 0.0
+	for _, word := range t.all() {
+		tf := 0.0
+		for _, tok := range e.title {
+			if tok.text == word {
+				tf += titleWeight
+			}
+		}
+		for _, tok := range e.body {
+			if tok.text == word {
+				tf++
+			}
+		}
+		if tf == 0 {
+			continue
+		}
+		df := float64(len(m.postings[word]))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		score += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*docLength/average))
+	}
+	return score
+}
+
+// matches reports whether doc passes the filters of q
+func (q Query) matches(doc Document) bool {
+	if q.AuthorID != "" && doc.AuthorID != q.AuthorID {
+		return false
+	}
+	if !q.From.IsZero() && doc.PublishedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !doc.PublishedAt.Before(q.To) {
+		return false
+	}
+	for _, tag := range q.Tags {
+		found := false
+		for _, t := range doc.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package search
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoIndex keeps documents in a collection with a text index
+type MongoIndex struct {
+	collection *mongo.Collection
+}
+
+// NewMongoIndex returns an index backed by collection
+func NewMongoIndex(collection *mongo.Collection) *MongoIndex {
+	return &MongoIndex{collection: collection}
+}
+
+// EnsureIndexes creates the text and filter indexes
+func (m *MongoIndex) EnsureIndexes(ctx context.Context) error {
+	_, err := m.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "body", Value: "text"}},
+			// no stemming nor stop words, words match as the memory index
+			// matches them, so that snippets highlight what matched
+			Options: options.Index().
+				SetWeights(bson.M{"title": titleWeight, "body": 1}).
+				SetDefaultLanguage("none").
+				SetName("text"),
+		},
+		{
+			Keys: bson.D{{Key: "published_at", Value: -1}},
+		},
+	})
+	return err
+}
+
+func (m *MongoIndex) Index(ctx context.Context, doc Document) error {
+	_, err := m.collection.ReplaceOne(ctx, bson.M{"_id": doc.ID}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *MongoIndex) Remove(ctx context.Context, id string) error {
+	_, err := m.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (m *MongoIndex) Search(ctx context.Context, q Query) ([]Hit, int64, error) {
+	t := parse(q.Text)
+	filter := q.filter()
+	opts := options.Find().SetSkip(q.Skip).SetLimit(q.Limit)
+	if t.empty() {
+		opts.SetSort(bson.D{{Key: "published_at", Value: -1}, {Key: "_id", Value: -1}})
+	} else {
+		filter["$text"] = bson.M{"$search": t.String()}
+		opts.SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+			SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "published_at", Value: -1}, {Key: "_id", Value: -1}})
+	}
+
+	total, err := m.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	cursor, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	var found []struct {
+		Document `bson:",inline"`
+		Score    float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]Hit, 0, len(found))
+	for _, f := range found {
+		hits = append(hits, Hit{Document: f.Document, Score: f.Score, Snippet: snippet(f.Body, t)})
+	}
+	return hits, total, nil
+}
+
+// String writes the terms in the $text search syntax, phrases in quotes
+func (t terms) String() string {
+	parts := append([]string{}, t.words...)
+	for _, phrase := range t.phrases {
+		parts = append(parts, `"`+strings.Join(phrase, " ")+`"`)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (q Query) filter() bson.M {
+	filter := bson.M{}
+	if len(q.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": q.Tags}
+	}
+	if q.AuthorID != "" {
+		filter["author_id"] = q.AuthorID
+	}
+	publishedAt := bson.M{}
+	if !q.From.IsZero() {
+		publishedAt["$gte"] = q.From
+	}
+	if !q.To.IsZero() {
+		publishedAt["$lt"] = q.To
+	}
+	if len(publishedAt) > 0 {
+		filter["published_at"] = publishedAt
+	}
+	return filter
+}
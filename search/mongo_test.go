@@ -0,0 +1,46 @@
+//go:build integration
+// +build integration
+
+package search
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/database"
+	"github.com/HiteshRepo/blog-application/global"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testDatabase connects to a fresh database on the mongod at MONGO_TEST_URI
+// (default mongodb://localhost:27017), dropped after the test
+func testDatabase(t *testing.T) *database.Client {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	db, err := database.Connect(ctx, database.Config{URI: uri, Name: global.DefaultDBName + "_test_" + primitive.NewObjectID().Hex(), Retries: -1})
+	if err != nil {
+		t.Fatalf("Error connecting to db : %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		db.Database().Drop(ctx)
+		db.Close(ctx)
+	})
+	return db
+}
+
+func Test_MongoIndex(t *testing.T) {
+	index := NewMongoIndex(testDatabase(t).Collection("search"))
+	if err := index.EnsureIndexes(context.Background()); err != nil {
+		t.Fatalf("Error creating indexes : %v", err)
+	}
+	testIndexer(t, index)
+}
@@ -0,0 +1,213 @@
+// Package search finds published content. An Indexer keeps the documents,
+// MemoryIndex for tests and local runs, MongoIndex on a mongo text index.
+package search
+
+import (
+	"context"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// KindPost is the kind of the documents indexing blog posts
+const KindPost = "post"
+
+// Document is the searchable part of a piece of content
+type Document struct {
+	// ID is the id of the content, unique across kinds
+	ID       string `bson:"_id"`
+	Kind     string `bson:"kind"`
+	AuthorID string `bson:"author_id"`
+	Title    string `bson:"title"`
+	// Body is plain text, without markup
+	Body        string    `bson:"body"`
+	Tags        []string  `bson:"tags,omitempty"`
+	PublishedAt time.Time `bson:"published_at"`
+}
+
+// Query selects documents, zero values match everything
+type Query struct {
+	// Text holds words and "quoted phrases". Documents must contain every
+	// phrase, and one of the words when there is no phrase.
+	Text string
+	// Tags matches documents with all of them
+	Tags     []string
+	AuthorID string
+	// From and To bound PublishedAt, To excluded
+	From, To    time.Time
+	Skip, Limit int64
+}
+
+// Hit is a document matching a query
+type Hit struct {
+	Document
+	// Score ranks hits, higher first
+	Score float64
+	// Snippet is an html excerpt of the body with the matches in <mark>
+	Snippet string
+}
+
+// Indexer keeps documents searchable
+type Indexer interface {
+	// Index adds doc, or replaces the document with its id
+	Index(ctx context.Context, doc Document) error
+	// Remove drops the document with id, if any
+	Remove(ctx context.Context, id string) error
+	// Search returns a page of hits, best first, and the number of all hits.
+	// Without text, hits are ordered by PublishedAt, latest first.
+	Search(ctx context.Context, q Query) ([]Hit, int64, error)
+}
+
+// terms are the parts of a query text
+type terms struct {
+	words   []string
+	phrases [][]string
+}
+
+// parse splits text into words and phrases, normalized like the documents
+func parse(text string) terms {
+	var t terms
+	parts := strings.Split(text, `"`)
+	for i, part := range parts {
+		tokens := tokenize(part)
+		// odd parts are between quotes, an unclosed quote runs to the end
+		words := make([]string, 0, len(tokens))
+		for _, token := range tokens {
+			words = append(words, token.text)
+		}
+		if i%2 == 1 && len(words) > 0 {
+			t.phrases = append(t.phrases, words)
+			continue
+		}
+		t.words = append(t.words, words...)
+	}
+	return t
+}
+
+// all returns every word of the terms, phrases included
+func (t terms) all() []string {
+	words := append([]string{}, t.words...)
+	for _, phrase := range t.phrases {
+		words = append(words, phrase...)
+	}
+	return words
+}
+
+func (t terms) empty() bool {
+	return len(t.words) == 0 && len(t.phrases) == 0
+}
+
+// token is a word of a text, lower cased, with its byte offsets
+type token struct {
+	text       string
+	start, end int
+}
+
+// tokenize splits s into runs of letters and digits
+func tokenize(s string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range s {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if word && start < 0 {
+			start = i
+		}
+		if !word && start >= 0 {
+			tokens = append(tokens, token{text: strings.ToLower(s[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{text: strings.ToLower(s[start:]), start: start, end: len(s)})
+	}
+	return tokens
+}
+
+// snippetWords is the length of a snippet, in words
+const snippetWords = 30
+
+// snippet excerpts body around the first match of t, the matched words are
+// wrapped in <mark> and the rest is escaped
+func snippet(body string, t terms) string {
+	tokens := tokenize(body)
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	marked := make([]bool, len(tokens))
+	first := -1
+	mark := func(i int) {
+		marked[i] = true
+		if first < 0 || i < first {
+			first = i
+		}
+	}
+	words := map[string]bool{}
+	for _, w := range t.words {
+		words[w] = true
+	}
+	for i, tok := range tokens {
+		if words[tok.text] {
+			mark(i)
+		}
+		for _, phrase := range t.phrases {
+			if matchAt(tokens, i, phrase) {
+				for j := range phrase {
+					mark(i + j)
+				}
+			}
+		}
+	}
+
+	// start a few words before the first match, end with the body if it fits
+	from := 0
+	if first > 5 {
+		from = first - 5
+	}
+	to := from + snippetWords
+	if to > len(tokens) {
+		to = len(tokens)
+		from = to - snippetWords
+		if from < 0 {
+			from = 0
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := 0
+	if from > 0 {
+		pos = tokens[from].start
+	}
+	for i := from; i < to; i++ {
+		b.WriteString(html.EscapeString(body[pos:tokens[i].start]))
+		if marked[i] {
+			b.WriteString("<mark>" + html.EscapeString(body[tokens[i].start:tokens[i].end]) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(body[tokens[i].start:tokens[i].end]))
+		}
+		pos = tokens[i].end
+	}
+	if to < len(tokens) {
+		b.WriteString("…")
+	} else {
+		b.WriteString(html.EscapeString(body[pos:]))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// matchAt reports whether phrase starts at tokens[i]
+func matchAt(tokens []token, i int, phrase []string) bool {
+	if i+len(phrase) > len(tokens) {
+		return false
+	}
+	for j, word := range phrase {
+		if tokens[i+j].text != word {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parse(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"text": "", "terms": terms{}},
+		map[string]interface{}{"text": "Go gRPC!", "terms": terms{words: []string{"go", "grpc"}}},
+		map[string]interface{}{
+			"text":  `mongo "Text Index" go`,
+			"terms": terms{words: []string{"mongo", "go"}, phrases: [][]string{[]string{"text", "index"}}},
+		},
+		map[string]interface{}{
+			"text":  `"unclosed phrase`,
+			"terms": terms{phrases: [][]string{[]string{"unclosed", "phrase"}}},
+		},
+		map[string]interface{}{"text": `"" ?!`, "terms": terms{}},
+	}
+
+	for _, tcase := range testCases {
+		got, want := parse(tcase["text"].(string)), tcase["terms"].(terms)
+		assert.ElementsMatchf(t, want.words, got.words, "case: %v", tcase)
+		assert.ElementsMatchf(t, want.phrases, got.phrases, "case: %v", tcase)
+	}
+}
+
+func Test_terms_String(t *testing.T) {
+
+	assert.Equal(t, `mongo go "text index"`, parse(`mongo "Text Index" go`).String())
+}
+
+func Test_snippet(t *testing.T) {
+
+	long := strings.Repeat("filler ", 40)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"body":    "Go makes <concurrency> easy",
+			"text":    "concurrency",
+			"snippet": "Go makes &lt;<mark>concurrency</mark>&gt; easy",
+		},
+		map[string]interface{}{
+			"body":    "The text index and the index of text",
+			"text":    `"text index"`,
+			"snippet": "The <mark>text</mark> <mark>index</mark> and the index of text",
+		},
+		map[string]interface{}{
+			"body":    long + "the needle " + long,
+			"text":    "needle",
+			"snippet": "…filler filler filler filler the <mark>needle</mark> " + strings.TrimSpace(strings.Repeat("filler ", 24)) + "…",
+		},
+		map[string]interface{}{
+			"body":    "no match here",
+			"text":    "missing",
+			"snippet": "no match here",
+		},
+		map[string]interface{}{
+			"body":    "",
+			"text":    "missing",
+			"snippet": "",
+		},
+	}
+
+	for _, tcase := range testCases {
+		assert.Equalf(t, tcase["snippet"], snippet(tcase["body"].(string), parse(tcase["text"].(string))), "case: %v", tcase)
+	}
+}
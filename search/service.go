@@ -0,0 +1,150 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/gateway"
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Service serves the SearchService
+type Service struct {
+	search *searchServer
+}
+
+// New returns the search service on the given index, issuing page tokens with pages
+func New(index Indexer, pages *paging.Codec) *Service {
+	return &Service{search: &searchServer{index: index, pages: pages}}
+}
+
+// Register adds the SearchService to server
+func (s *Service) Register(server *grpc.Server) {
+	proto.RegisterSearchServiceServer(server, s.search)
+}
+
+// Require lists the permissions needed by every guarded RPC, searching is open to anyone
+func (s *Service) Require(p *policy.Policy) {}
+
+// Routes maps the SearchService to the JSON API
+func (s *Service) Routes(g *gateway.Gateway) {
+	g.Handle(http.MethodGet, "/v1/search", "/proto.SearchService/Search")
+}
+
+type searchServer struct {
+	index Indexer
+	pages *paging.Codec
+}
+
+// Search pages by offset, hits have no stable key as their score changes with
+// the index
+func (s *searchServer) Search(ctx context.Context, in *proto.SearchRequest) (*proto.SearchResponse, error) {
+	q, page, err := s.query(in)
+	if err != nil {
+		return nil, err
+	}
+
+	hits, total, err := s.search(ctx, q, page)
+	if err != nil {
+		return nil, err
+	}
+	next, err := s.pages.Next(page, len(hits), total, nil)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while issuing page token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Internal Error")
+	}
+
+	res := &proto.SearchResponse{
+		Hits:          make([]*proto.SearchHit, 0, len(hits)),
+		Total:         total,
+		PageSize:      page.Size,
+		NextPageToken: next,
+		Query:         in.GetQuery(),
+		Tags:          in.GetTags(),
+		AuthorID:      in.GetAuthorID(),
+		From:          in.GetFrom(),
+		To:            in.GetTo(),
+	}
+	for _, hit := range hits {
+		res.Hits = append(res.Hits, hitToProto(hit))
+	}
+	return res, nil
+}
+
+func (s *searchServer) StreamSearch(in *proto.SearchRequest, stream proto.SearchService_StreamSearchServer) error {
+	q, page, err := s.query(in)
+	if err != nil {
+		return err
+	}
+	return paging.Walk(page, func(page paging.Page) (int, interface{}, error) {
+		hits, _, err := s.search(stream.Context(), q, page)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, hit := range hits {
+			if err := stream.Send(hitToProto(hit)); err != nil {
+				return 0, nil, err
+			}
+		}
+		return len(hits), nil, nil
+	})
+}
+
+// query returns the query and first page of a request
+func (s *searchServer) query(in *proto.SearchRequest) (Query, paging.Page, error) {
+	q := Query{
+		Text:     in.GetQuery(),
+		Tags:     in.GetTags(),
+		AuthorID: in.GetAuthorID(),
+	}
+	if in.GetFrom() != 0 {
+		q.From = time.Unix(in.GetFrom(), 0).UTC()
+	}
+	if in.GetTo() != 0 {
+		q.To = time.Unix(in.GetTo(), 0).UTC()
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return Query{}, paging.Page{}, status.Error(codes.InvalidArgument, "From should be before To")
+	}
+
+	page, err := s.pages.Start(in.GetPageToken(), in.GetPageSize(), q)
+	return q, page, err
+}
+
+// search returns a page of the hits of q
+func (s *searchServer) search(ctx context.Context, q Query, page paging.Page) ([]Hit, int64, error) {
+	q.Skip, q.Limit = page.Offset, page.Size
+
+	// fetch from db should not take more that 5 seconds
+	dbCtx, cancel := global.NewDBContextFrom(ctx, 5*time.Second)
+	defer cancel()
+
+	hits, total, err := s.index.Search(dbCtx, q)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error returned while searching", zap.Error(err))
+		return nil, 0, status.Error(codes.Internal, "Internal Error")
+	}
+	return hits, total, nil
+}
+
+func hitToProto(hit Hit) *proto.SearchHit {
+	return &proto.SearchHit{
+		Kind:        hit.Kind,
+		ID:          hit.ID,
+		AuthorID:    hit.AuthorID,
+		Title:       hit.Title,
+		Tags:        hit.Tags,
+		PublishedAt: hit.PublishedAt.Unix(),
+		Snippet:     hit.Snippet,
+		Score:       hit.Score,
+	}
+}
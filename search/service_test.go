@@ -0,0 +1,182 @@
+package search
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/paging"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/HiteshRepo/blog-application/server"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the SearchService over bufconn, without callers
+func newTestClient(t *testing.T, service *Service) proto.SearchServiceClient {
+	t.Helper()
+
+	authenticate := func(ctx context.Context) (context.Context, []policy.Role, error) {
+		return ctx, nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	grpcServer := server.New(server.Config{Logger: zap.NewNop(), Authenticate: authenticate}, service).GRPCServer()
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcServer.Serve(listener)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error dialing bufconn : %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+	})
+	return proto.NewSearchServiceClient(conn)
+}
+
+func Test_searchServer_Search(t *testing.T) {
+
+	day := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	index := NewMemoryIndex()
+	for i, title := range []string{"Go generics", "Go modules", "Rust traits"} {
+		index.Index(context.Background(), Document{
+			ID:          string(rune('a' + i)),
+			Kind:        KindPost,
+			AuthorID:    "507f1f77bcf86cd799439011",
+			Title:       title,
+			Body:        title + " explained",
+			Tags:        []string{"lang"},
+			PublishedAt: day.Add(time.Duration(i) * 24 * time.Hour),
+		})
+	}
+	client := newTestClient(t, New(index, paging.NewCodec(nil)))
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"req":   &proto.SearchRequest{Query: "go"},
+			"ids":   []string{"b", "a"},
+			"total": int64(2),
+		},
+		map[string]interface{}{
+			"req":   &proto.SearchRequest{From: day.Add(24 * time.Hour).Unix()},
+			"ids":   []string{"c", "b"},
+			"total": int64(2),
+		},
+		map[string]interface{}{
+			"req":   &proto.SearchRequest{Tags: []string{"lang"}, AuthorID: "507f1f77bcf86cd799439011", PageSize: 1000},
+			"ids":   []string{"c", "b", "a"},
+			"total": int64(3),
+		},
+		map[string]interface{}{
+			"req":     &proto.SearchRequest{From: day.Unix(), To: day.Unix()},
+			"code":    codes.InvalidArgument,
+			"message": "From should be before To",
+		},
+		map[string]interface{}{
+			"req":  &proto.SearchRequest{AuthorID: "ann"},
+			"code": codes.InvalidArgument,
+		},
+		map[string]interface{}{
+			"req":     &proto.SearchRequest{PageToken: "forged"},
+			"code":    codes.InvalidArgument,
+			"message": "Invalid page token",
+		},
+	}
+
+	for _, tcase := range testCases {
+		res, err := client.Search(context.Background(), tcase["req"].(*proto.SearchRequest))
+		if code, ok := tcase["code"]; ok {
+			assert.Equalf(t, code, status.Code(err), "case: %v", tcase)
+			if message, ok := tcase["message"]; ok {
+				assert.Equalf(t, message, status.Convert(err).Message(), "case: %v", tcase)
+			}
+			continue
+		}
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		assert.Equalf(t, tcase["total"], res.GetTotal(), "case: %v", tcase)
+		ids := []string{}
+		for _, hit := range res.GetHits() {
+			ids = append(ids, hit.GetID())
+		}
+		assert.Equalf(t, tcase["ids"], ids, "case: %v", tcase)
+	}
+
+	res, err := client.Search(context.Background(), &proto.SearchRequest{Query: "generics"})
+	assert.NoError(t, err)
+	if assert.Len(t, res.GetHits(), 1) {
+		hit := res.GetHits()[0]
+		assert.Equal(t, KindPost, hit.GetKind())
+		assert.Equal(t, "Go generics", hit.GetTitle())
+		assert.Equal(t, "Go <mark>generics</mark> explained", hit.GetSnippet())
+		assert.Equal(t, day.Unix(), hit.GetPublishedAt())
+		assert.Equal(t, int64(20), res.GetPageSize())
+		assert.Equal(t, "", res.GetNextPageToken())
+		assert.Equal(t, "generics", res.GetQuery())
+	}
+
+	// the token continues the same search only
+	res, err = client.Search(context.Background(), &proto.SearchRequest{Query: "go", PageSize: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", res.GetHits()[0].GetID())
+	assert.NotEmpty(t, res.GetNextPageToken())
+	next, err := client.Search(context.Background(), &proto.SearchRequest{Query: "go", PageToken: res.GetNextPageToken()})
+	assert.NoError(t, err)
+	assert.Equal(t, "a", next.GetHits()[0].GetID())
+	assert.Equal(t, int64(1), next.GetPageSize())
+	assert.Equal(t, "", next.GetNextPageToken())
+	_, err = client.Search(context.Background(), &proto.SearchRequest{Query: "rust", PageToken: res.GetNextPageToken()})
+	assert.Equal(t, "Page token does not match the filter", status.Convert(err).Message())
+}
+
+func Test_searchServer_StreamSearch(t *testing.T) {
+
+	index := NewMemoryIndex()
+	for i := 0; i < 5; i++ {
+		index.Index(context.Background(), Document{ID: string(rune('a' + i)), Kind: KindPost, Title: "Go", PublishedAt: time.Unix(int64(i), 0)})
+	}
+	client := newTestClient(t, New(index, paging.NewCodec(nil)))
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"req": &proto.SearchRequest{Query: "go", PageSize: 2}, "ids": []string{"e", "d", "c", "b", "a"}},
+		map[string]interface{}{"req": &proto.SearchRequest{Query: "go", PageSize: 5}, "ids": []string{"e", "d", "c", "b", "a"}},
+		map[string]interface{}{"req": &proto.SearchRequest{Query: "rust"}, "ids": []string{}},
+		map[string]interface{}{"req": &proto.SearchRequest{Query: strings.Repeat("go ", 100)}, "code": codes.InvalidArgument},
+	}
+
+	for _, tcase := range testCases {
+		stream, err := client.StreamSearch(context.Background(), tcase["req"].(*proto.SearchRequest))
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		ids := []string{}
+		for {
+			hit, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if code, ok := tcase["code"]; ok {
+				assert.Equalf(t, code, status.Code(err), "case: %v", tcase)
+				break
+			}
+			if !assert.NoErrorf(t, err, "case: %v", tcase) {
+				break
+			}
+			ids = append(ids, hit.GetID())
+		}
+		if _, ok := tcase["code"]; !ok {
+			assert.Equalf(t, tcase["ids"], ids, "case: %v", tcase)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+)
+
+const defaultCORSMaxAge = 10 * time.Minute
+
+// corsAllowedHeaders are the request headers grpc-web clients send
+var corsAllowedHeaders = []string{
+	"Accept", "Accept-Encoding", "Authorization", "Content-Length", "Content-Type", "Grpc-Timeout",
+	"Traceparent", "Tracestate", "X-CSRF-Token", "X-Grpc-Web", "X-Request-Id", "X-User-Agent",
+}
+
+// CORSConfig selects the browser origins allowed to call the grpc-web proxy
+type CORSConfig struct {
+	// AllowedOrigins lists origins such as "https://blog.example.com".
+	// "*" allows every origin, a single "*" inside an origin matches any
+	// subdomain, e.g. "https://*.example.com". An empty list denies every
+	// cross-origin request.
+	AllowedOrigins []string
+	// AllowCredentials lets browsers send cookies and HTTP authentication
+	// along, it cannot be combined with "*"
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight answer, 10 minutes by default
+	MaxAge time.Duration
+	// Websockets enables the grpc-web websocket transport, needed for client streaming
+	Websockets bool
+}
+
+// Validate reports a config that would let every origin send credentials
+func (c CORSConfig) Validate() error {
+	if !c.AllowCredentials {
+		return nil
+	}
+	for _, origin := range c.AllowedOrigins {
+		if strings.TrimSpace(origin) == "*" {
+			return errors.New(`credentials cannot be allowed for every origin, list the origins instead of "*"`)
+		}
+	}
+	return nil
+}
+
+// corsPolicy answers CORS requests according to a CORSConfig
+type corsPolicy struct {
+	any         bool
+	origins     map[string]bool
+	wildcards   [][2]string
+	credentials bool
+	maxAge      string
+}
+
+func newCORSPolicy(c CORSConfig) *corsPolicy {
+	p := &corsPolicy{origins: map[string]bool{}, credentials: c.AllowCredentials}
+	for _, origin := range c.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "*":
+			p.any = true
+		case strings.Count(origin, "*") == 1:
+			i := strings.Index(origin, "*")
+			p.wildcards = append(p.wildcards, [2]string{origin[:i], origin[i+1:]})
+		case origin != "":
+			p.origins[origin] = true
+		}
+	}
+	// every origin with credentials is refused by Validate, never send them
+	if p.any {
+		p.credentials = false
+	}
+	maxAge := c.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultCORSMaxAge
+	}
+	p.maxAge = strconv.Itoa(int(maxAge.Seconds()))
+	return p
+}
+
+// allowOrigin reports whether browsers on origin may call the proxy
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.any {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if len(origin) > len(w[0])+len(w[1]) && strings.HasPrefix(origin, w[0]) && strings.HasSuffix(origin, w[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowRequest reports whether r comes from an allowed origin, requests
+// without an Origin do not come from browsers and are always allowed
+func (p *corsPolicy) allowRequest(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || p.allowOrigin(origin)
+}
+
+// handler answers preflight requests itself and adds the CORS headers to
+// the responses of next. Requests from other origins are refused.
+func (p *corsPolicy) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers := w.Header()
+		headers.Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !p.allowOrigin(origin) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		if p.any {
+			headers.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			headers.Set("Access-Control-Allow-Origin", origin)
+		}
+		if p.credentials {
+			headers.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			headers.Add("Vary", "Access-Control-Request-Method")
+			headers.Add("Vary", "Access-Control-Request-Headers")
+			headers.Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
+			headers.Set("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+			headers.Set("Access-Control-Max-Age", p.maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// grpcWebHandler serves grpc-web, websocket and native grpc requests to
+// server behind the CORS policy
+func grpcWebHandler(server *grpc.Server, c CORSConfig) http.Handler {
+	policy := newCORSPolicy(c)
+	wrapped := grpcweb.WrapServer(server,
+		// CORS is answered by policy, the wrapper must not add headers of its own
+		grpcweb.WithOriginFunc(func(string) bool { return false }),
+		grpcweb.WithWebsockets(c.Websockets),
+		grpcweb.WithWebsocketOriginFunc(policy.allowRequest),
+	)
+
+	return policy.handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case wrapped.IsGrpcWebRequest(r),
+			c.Websockets && wrapped.IsGrpcWebSocketRequest(r),
+			// native grpc over h2c
+			r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc"):
+			wrapped.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
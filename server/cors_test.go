@@ -0,0 +1,277 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func Test_corsPolicy_allowOrigin(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"origins": []string{"*"},
+			"origin":  "https://anything.test",
+			"allowed": true,
+		},
+		map[string]interface{}{
+			"origins": []string{"https://blog.example.com"},
+			"origin":  "https://blog.example.com",
+			"allowed": true,
+		},
+		map[string]interface{}{
+			// origins are compared case-insensitively
+			"origins": []string{"https://Blog.Example.com"},
+			"origin":  "https://blog.example.COM",
+			"allowed": true,
+		},
+		map[string]interface{}{
+			"origins": []string{"https://blog.example.com"},
+			"origin":  "http://blog.example.com",
+			"allowed": false,
+		},
+		map[string]interface{}{
+			"origins": []string{"https://*.example.com"},
+			"origin":  "https://admin.example.com",
+			"allowed": true,
+		},
+		map[string]interface{}{
+			"origins": []string{"https://*.example.com"},
+			"origin":  "https://example.com",
+			"allowed": false,
+		},
+		map[string]interface{}{
+			"origins": []string{"https://*.example.com"},
+			"origin":  "https://admin.example.com.evil.test",
+			"allowed": false,
+		},
+		map[string]interface{}{
+			"origins": []string{},
+			"origin":  "https://blog.example.com",
+			"allowed": false,
+		},
+	}
+
+	for _, tcase := range testCases {
+		p := newCORSPolicy(CORSConfig{AllowedOrigins: tcase["origins"].([]string)})
+		assert.Equalf(t, tcase["allowed"], p.allowOrigin(tcase["origin"].(string)), "case: %v", tcase)
+	}
+}
+
+func Test_corsPolicy_handler(t *testing.T) {
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("next"))
+	})
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			// not from a browser
+			"cors":   CORSConfig{},
+			"method": http.MethodPost,
+			"code":   http.StatusOK,
+			"body":   "next",
+			"headers": map[string]string{
+				"Access-Control-Allow-Origin": "",
+				"Vary":                        "Origin",
+			},
+		},
+		map[string]interface{}{
+			"cors":   CORSConfig{AllowedOrigins: []string{"*"}},
+			"method": http.MethodPost,
+			"origin": "https://blog.example.com",
+			"code":   http.StatusOK,
+			"body":   "next",
+			"headers": map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		map[string]interface{}{
+			// credentials are never allowed for "*", Validate refuses the config
+			"cors":   CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			"method": http.MethodPost,
+			"origin": "https://blog.example.com",
+			"code":   http.StatusOK,
+			"body":   "next",
+			"headers": map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		map[string]interface{}{
+			"cors":   CORSConfig{AllowedOrigins: []string{"https://*.example.com"}},
+			"method": http.MethodPost,
+			"origin": "https://admin.example.com",
+			"code":   http.StatusOK,
+			"body":   "next",
+			"headers": map[string]string{
+				"Access-Control-Allow-Origin": "https://admin.example.com",
+			},
+		},
+		map[string]interface{}{
+			"cors":   CORSConfig{AllowedOrigins: []string{"https://blog.example.com"}},
+			"method": http.MethodPost,
+			"origin": "https://evil.test",
+			"code":   http.StatusForbidden,
+			"headers": map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		map[string]interface{}{
+			"cors":      CORSConfig{AllowedOrigins: []string{"https://blog.example.com"}, AllowCredentials: true, MaxAge: time.Hour},
+			"method":    http.MethodOptions,
+			"origin":    "https://blog.example.com",
+			"preflight": true,
+			"code":      http.StatusNoContent,
+			"headers": map[string]string{
+				"Access-Control-Allow-Origin":      "https://blog.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "POST, GET, OPTIONS",
+				"Access-Control-Max-Age":           "3600",
+			},
+		},
+		map[string]interface{}{
+			"cors":      CORSConfig{AllowedOrigins: []string{"https://blog.example.com"}},
+			"method":    http.MethodOptions,
+			"origin":    "https://blog.example.com",
+			"preflight": true,
+			"code":      http.StatusNoContent,
+			"headers": map[string]string{
+				"Access-Control-Max-Age": "600",
+			},
+		},
+		map[string]interface{}{
+			"cors":      CORSConfig{AllowedOrigins: []string{"https://blog.example.com"}},
+			"method":    http.MethodOptions,
+			"origin":    "https://evil.test",
+			"preflight": true,
+			"code":      http.StatusForbidden,
+			"headers": map[string]string{
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		map[string]interface{}{
+			// an OPTIONS request without Access-Control-Request-Method is no preflight
+			"cors":   CORSConfig{AllowedOrigins: []string{"https://blog.example.com"}},
+			"method": http.MethodOptions,
+			"origin": "https://blog.example.com",
+			"code":   http.StatusOK,
+			"body":   "next",
+			"headers": map[string]string{
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+	}
+
+	for _, tcase := range testCases {
+		req := httptest.NewRequest(tcase["method"].(string), "/proto.AuthService/Login", nil)
+		if origin, ok := tcase["origin"]; ok {
+			req.Header.Set("Origin", origin.(string))
+		}
+		if _, ok := tcase["preflight"]; ok {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+		}
+		rec := httptest.NewRecorder()
+		newCORSPolicy(tcase["cors"].(CORSConfig)).handler(next).ServeHTTP(rec, req)
+
+		assert.Equalf(t, tcase["code"], rec.Code, "case: %v", tcase)
+		if body, ok := tcase["body"]; ok {
+			assert.Equalf(t, body, rec.Body.String(), "case: %v", tcase)
+		}
+		for name, value := range tcase["headers"].(map[string]string) {
+			assert.Equalf(t, value, rec.Header().Get(name), "case: %v, header: %v", tcase, name)
+		}
+	}
+}
+
+func Test_CORSConfig_Validate(t *testing.T) {
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"cors": CORSConfig{AllowedOrigins: []string{"*"}}, "valid": true},
+		map[string]interface{}{"cors": CORSConfig{AllowedOrigins: []string{"https://blog.example.com"}, AllowCredentials: true}, "valid": true},
+		map[string]interface{}{"cors": CORSConfig{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true}, "valid": true},
+		map[string]interface{}{"cors": CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, "valid": false},
+		map[string]interface{}{"cors": CORSConfig{AllowedOrigins: []string{"https://blog.example.com", " *"}, AllowCredentials: true}, "valid": false},
+	}
+
+	for _, tcase := range testCases {
+		err := tcase["cors"].(CORSConfig).Validate()
+		assert.Equalf(t, tcase["valid"], err == nil, "case: %v", tcase)
+	}
+}
+
+func Test_corsPolicy_allowRequest(t *testing.T) {
+
+	p := newCORSPolicy(CORSConfig{AllowedOrigins: []string{"https://blog.example.com"}})
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"origin":  "",
+			"allowed": true,
+		},
+		map[string]interface{}{
+			"origin":  "https://blog.example.com",
+			"allowed": true,
+		},
+		map[string]interface{}{
+			"origin":  "https://evil.test",
+			"allowed": false,
+		},
+	}
+
+	for _, tcase := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/proto.AuthService/Login", nil)
+		if origin := tcase["origin"].(string); origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		assert.Equalf(t, tcase["allowed"], p.allowRequest(req), "case: %v", tcase)
+	}
+}
+
+func Test_grpcWebHandler(t *testing.T) {
+
+	s := grpc.NewServer()
+	(&fakeService{}).Register(s)
+	handler := grpcWebHandler(s, CORSConfig{AllowedOrigins: []string{"https://blog.example.com"}})
+
+	// a grpc-web call to UsernameUsed from an allowed origin
+	msg, _ := protobuf.Marshal(&proto.UsernameUsedRequest{Username: "someone"})
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	frame = append(frame, msg...)
+
+	req := httptest.NewRequest(http.MethodPost, "/proto.AuthService/UsernameUsed", bytes.NewReader(frame))
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("Origin", "https://blog.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://blog.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rec.Body.String(), "grpc-status: 0")
+
+	// anything else on the proxy is not found
+	req = httptest.NewRequest(http.MethodGet, "/unknown", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// websocket upgrades are only served when enabled
+	req = httptest.NewRequest(http.MethodGet, "/proto.AuthService/UsernameUsed", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-Websocket-Protocol", "grpc-websockets")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
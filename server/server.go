@@ -0,0 +1,278 @@
+// Package server runs any number of services on one grpc server, next to an
+// http server for grpc-web clients, the JSON API, metrics and health probes.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/gateway"
+	"github.com/HiteshRepo/blog-application/health"
+	"github.com/HiteshRepo/blog-application/logging"
+	"github.com/HiteshRepo/blog-application/metrics"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/tracing"
+	"github.com/HiteshRepo/blog-application/validate"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	defaultGRPCAddr       = "0.0.0.0:5000"
+	defaultHTTPAddr       = "0.0.0.0:9001"
+	defaultHealthInterval = 10 * time.Second
+	// in-flight requests get this long to finish on shutdown
+	defaultDrainTimeout = 15 * time.Second
+	// the in-process connection of the JSON API buffers up to 1MB
+	gatewayBufferSize = 1 << 20
+)
+
+// Service is a set of grpc services served together
+type Service interface {
+	// Register adds the grpc services to s
+	Register(s *grpc.Server)
+	// Require adds the permissions needed by the guarded RPCs to p
+	Require(p *policy.Policy)
+	// Routes maps RPCs to the JSON API served under /v1/
+	Routes(g *gateway.Gateway)
+}
+
+// Config configures a Server, zero values take the defaults
+type Config struct {
+	// GRPCAddr defaults to 0.0.0.0:5000, HTTPAddr to 0.0.0.0:9001
+	GRPCAddr string
+	HTTPAddr string
+
+	Logger *zap.Logger
+	// Authenticate resolves the caller of guarded RPCs
+	Authenticate policy.Authenticator
+
+	// Checks are run every HealthInterval (default 10s) to report readiness
+	Checks         map[string]health.Check
+	HealthInterval time.Duration
+	// DrainTimeout bounds the graceful shutdown, 15s by default
+	DrainTimeout time.Duration
+
+	// CORS selects the browsers allowed to use grpc-web, none by default
+	CORS CORSConfig
+
+	// TLS serves both listeners over tls when set, plaintext (h2c on the
+	// http server) otherwise. GRPCTLS overrides it for the grpc listener,
+	// e.g. to require client certificates from other services.
+	TLS     *tls.Config
+	GRPCTLS *tls.Config
+}
+
+// Server serves the registered services until its context is cancelled
+type Server struct {
+	cfg     Config
+	grpc    *grpc.Server
+	mux     *http.ServeMux
+	checker *health.Checker
+
+	// the gateway calls the grpc server in-process, through the interceptors
+	gatewayListener *bufconn.Listener
+	gatewayConn     *grpc.ClientConn
+}
+
+// New returns a server with services registered behind the tracing,
+// logging, metrics, authorization and validation interceptors, and their routes on the
+// JSON API
+func New(cfg Config, services ...Service) *Server {
+	if cfg.GRPCAddr == "" {
+		cfg.GRPCAddr = defaultGRPCAddr
+	}
+	if cfg.HTTPAddr == "" {
+		cfg.HTTPAddr = defaultHTTPAddr
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.L()
+	}
+	if cfg.HealthInterval <= 0 {
+		cfg.HealthInterval = defaultHealthInterval
+	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = defaultDrainTimeout
+	}
+	if cfg.GRPCTLS == nil {
+		cfg.GRPCTLS = cfg.TLS
+	}
+
+	rules := policy.New()
+	routes := gateway.New()
+	for _, service := range services {
+		service.Require(rules)
+		service.Routes(routes)
+	}
+
+	s := &Server{
+		cfg:     cfg,
+		mux:     http.NewServeMux(),
+		checker: health.NewChecker(2*time.Second, cfg.Checks),
+	}
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			tracing.UnaryServerInterceptor(),
+			logging.UnaryServerInterceptor(cfg.Logger),
+			metrics.UnaryServerInterceptor(),
+			rules.UnaryServerInterceptor(cfg.Authenticate),
+			validate.UnaryServerInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			tracing.StreamServerInterceptor(),
+			logging.StreamServerInterceptor(cfg.Logger),
+			metrics.StreamServerInterceptor(),
+			rules.StreamServerInterceptor(cfg.Authenticate),
+			validate.StreamServerInterceptor(),
+		),
+	}
+	if cfg.GRPCTLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg.GRPCTLS)))
+	}
+	s.grpc = grpc.NewServer(opts...)
+	for _, service := range services {
+		service.Register(s.grpc)
+	}
+	healthpb.RegisterHealthServer(s.grpc, s.checker.GRPCServer())
+
+	s.mux.Handle("/metrics", metrics.Handler())
+	s.mux.Handle("/healthz", health.LivenessHandler())
+	s.mux.Handle("/readyz", s.checker.ReadinessHandler())
+	s.mux.Handle("/", grpcWebHandler(s.grpc, cfg.CORS))
+
+	s.gatewayListener = bufconn.Listen(gatewayBufferSize)
+	// dialing does not connect yet, it only fails on invalid options
+	s.gatewayConn, _ = grpc.Dial("gateway",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.gatewayListener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	s.mux.Handle("/v1/", routes.Handler(s.gatewayConn))
+	s.mux.Handle("/v1/openapi.json", routes.OpenAPIHandler("blog-application", "v1"))
+	return s
+}
+
+// GRPCServer returns the grpc server the services are registered on
+func (s *Server) GRPCServer() *grpc.Server {
+	return s.grpc
+}
+
+// Handle serves pattern on the http server, next to grpc-web
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Run listens on the configured addresses and serves until ctx is done
+func (s *Server) Run(ctx context.Context) error {
+	grpcListener, err := net.Listen("tcp", s.cfg.GRPCAddr)
+	if err != nil {
+		return err
+	}
+	httpListener, err := net.Listen("tcp", s.cfg.HTTPAddr)
+	if err != nil {
+		grpcListener.Close()
+		return err
+	}
+	return s.Serve(ctx, grpcListener, httpListener)
+}
+
+// Serve serves grpc and http on the given listeners until ctx is done or
+// either server fails, then shuts both down gracefully
+func (s *Server) Serve(ctx context.Context, grpcListener, httpListener net.Listener) error {
+	httpServer, httpListener, err := s.httpServer(httpListener)
+	if err != nil {
+		grpcListener.Close()
+		httpListener.Close()
+		return err
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	go s.checker.Watch(ctx, s.cfg.HealthInterval)
+
+	errs := make(chan error, 2)
+	go func() {
+		s.cfg.Logger.Info("GRPC server serving....", zap.String("addr", grpcListener.Addr().String()), zap.Bool("tls", s.cfg.GRPCTLS != nil))
+		if err := s.grpc.Serve(grpcListener); err != nil {
+			s.cfg.Logger.Error("serving gRPC", zap.Error(err))
+			errs <- err
+			stop()
+		}
+	}()
+
+	go s.grpc.Serve(s.gatewayListener)
+	go func() {
+		s.cfg.Logger.Info("Proxy server is going up....", zap.String("addr", httpListener.Addr().String()), zap.Bool("tls", s.cfg.TLS != nil))
+		if err := httpServer.Serve(httpListener); err != http.ErrServerClosed {
+			s.cfg.Logger.Error("serving proxy", zap.Error(err))
+			errs <- err
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	s.cfg.Logger.Info("Shutting down....", zap.Duration("drain_timeout", s.cfg.DrainTimeout))
+	s.shutdown(httpServer)
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+// httpServer returns the http server and the listener it serves on, over
+// tls when configured. Both HTTP/2 and HTTP/1.1 are served either way, the
+// latter for grpc-web clients.
+func (s *Server) httpServer(listener net.Listener) (*http.Server, net.Listener, error) {
+	// failed tls handshakes and the like are logged along with the rest
+	errorLog := zap.NewStdLog(s.cfg.Logger)
+	if s.cfg.TLS == nil {
+		return &http.Server{Handler: h2c.NewHandler(s.mux, &http2.Server{}), ErrorLog: errorLog}, listener, nil
+	}
+	httpServer := &http.Server{Handler: s.mux, TLSConfig: s.cfg.TLS.Clone(), ErrorLog: errorLog}
+	if err := http2.ConfigureServer(httpServer, &http2.Server{}); err != nil {
+		return nil, listener, err
+	}
+	return httpServer, tls.NewListener(listener, httpServer.TLSConfig), nil
+}
+
+// shutdown marks the service unready, then lets both servers finish in-flight
+// requests for up to DrainTimeout before closing the remaining connections
+func (s *Server) shutdown(httpServer *http.Server) {
+	s.checker.Drain()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.DrainTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(ctx); err != nil {
+		s.cfg.Logger.Warn("Proxy server did not drain in time", zap.Error(err))
+		httpServer.Close()
+	}
+	s.gatewayConn.Close()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.cfg.Logger.Warn("GRPC server did not drain in time")
+		s.grpc.Stop()
+	}
+	s.cfg.Logger.Info("Stopped")
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/certs"
+	"github.com/HiteshRepo/blog-application/gateway"
+	"github.com/HiteshRepo/blog-application/health"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// fakeService guards Login and answers every UsernameUsed with used
+type fakeService struct {
+	proto.UnimplementedAuthServiceServer
+}
+
+func (f *fakeService) Register(s *grpc.Server) {
+	proto.RegisterAuthServiceServer(s, f)
+}
+
+func (f *fakeService) Require(p *policy.Policy) {
+	p.Require("/proto.AuthService/Login", policy.PermManageUsers)
+}
+
+func (f *fakeService) Routes(g *gateway.Gateway) {
+	g.Handle(http.MethodGet, "/v1/usernames/{username}", "/proto.AuthService/UsernameUsed").
+		Handle(http.MethodPost, "/v1/login", "/proto.AuthService/Login")
+}
+
+func (f *fakeService) UsernameUsed(ctx context.Context, in *proto.UsernameUsedRequest) (*proto.UsedResponse, error) {
+	return &proto.UsedResponse{Used: true}, nil
+}
+
+func (f *fakeService) Login(ctx context.Context, in *proto.LoginRequest) (*proto.AuthResponse, error) {
+	return &proto.AuthResponse{Token: "token"}, nil
+}
+
+func listen(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listener
+}
+
+func Test_Server_Serve(t *testing.T) {
+
+	s := New(Config{
+		Logger: zap.NewNop(),
+		Authenticate: func(ctx context.Context) (context.Context, []policy.Role, error) {
+			return ctx, []policy.Role{policy.RoleReader}, nil
+		},
+		Checks:       map[string]health.Check{"db": func(context.Context) error { return nil }},
+		DrainTimeout: time.Second,
+		CORS:         CORSConfig{AllowedOrigins: []string{"https://blog.example.com"}},
+	}, &fakeService{})
+	s.Handle("/hello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+
+	grpcListener, httpListener := listen(t), listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- s.Serve(ctx, grpcListener, httpListener) }()
+
+	conn, err := grpc.Dial(grpcListener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer conn.Close()
+
+	// public, guarded and built-in services share the server
+	used, err := proto.NewAuthServiceClient(conn).UsernameUsed(ctx, &proto.UsernameUsedRequest{Username: "someone"})
+	if assert.NoError(t, err) {
+		assert.True(t, used.GetUsed())
+	}
+	_, err = proto.NewAuthServiceClient(conn).Login(ctx, &proto.LoginRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+
+	base := "http://" + httpListener.Addr().String()
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"method": http.MethodGet,
+			"path":   "/healthz",
+			"code":   http.StatusOK,
+		},
+		map[string]interface{}{
+			"method": http.MethodGet,
+			"path":   "/metrics",
+			"code":   http.StatusOK,
+		},
+		map[string]interface{}{
+			"method": http.MethodGet,
+			"path":   "/hello",
+			"code":   http.StatusOK,
+		},
+		map[string]interface{}{
+			// the JSON API goes through the same interceptors
+			"method": http.MethodGet,
+			"path":   "/v1/usernames/someone",
+			"code":   http.StatusOK,
+		},
+		map[string]interface{}{
+			"method": http.MethodPost,
+			"path":   "/v1/login",
+			"code":   http.StatusForbidden,
+		},
+		map[string]interface{}{
+			"method": http.MethodGet,
+			"path":   "/v1/openapi.json",
+			"code":   http.StatusOK,
+		},
+		map[string]interface{}{
+			// browsers' CORS preflight for grpc-web
+			"method": http.MethodOptions,
+			"path":   "/proto.AuthService/Login",
+			"origin": "https://blog.example.com",
+			"code":   http.StatusNoContent,
+			"cors":   "https://blog.example.com",
+		},
+		map[string]interface{}{
+			"method": http.MethodOptions,
+			"path":   "/proto.AuthService/Login",
+			"origin": "https://evil.example.com",
+			"code":   http.StatusForbidden,
+			"cors":   "",
+		},
+	}
+
+	for _, tcase := range testCases {
+		req, _ := http.NewRequest(tcase["method"].(string), base+tcase["path"].(string), nil)
+		if origin, ok := tcase["origin"]; ok {
+			req.Header.Set("Origin", origin.(string))
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if !assert.NoErrorf(t, err, "case: %v", tcase) {
+			continue
+		}
+		res.Body.Close()
+		assert.Equalf(t, tcase["code"], res.StatusCode, "case: %v", tcase)
+		if cors, ok := tcase["cors"]; ok {
+			assert.Equalf(t, cors, res.Header.Get("Access-Control-Allow-Origin"), "case: %v", tcase)
+		}
+	}
+
+	// cancelling drains and stops both servers
+	cancel()
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after cancel")
+	}
+	_, err = http.Get(base + "/healthz")
+	assert.Error(t, err)
+}
+
+func Test_Server_Serve_tls(t *testing.T) {
+
+	ca, err := certs.NewAuthority("test CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files := map[string][]byte{"ca.pem": ca.PEM}
+	files["server.pem"], files["server-key.pem"], _ = ca.Issue("server", "127.0.0.1")
+	files["client.pem"], files["client-key.pem"], _ = ca.Issue("client")
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reloader, err := certs.NewReloader(certs.Config{
+		CertFile:     filepath.Join(dir, "server.pem"),
+		KeyFile:      filepath.Join(dir, "server-key.pem"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// other services must present a certificate, browsers need not
+	s := New(Config{
+		Logger:       zap.NewNop(),
+		DrainTimeout: time.Second,
+		TLS:          reloader.ServerConfig(false),
+		GRPCTLS:      reloader.ServerConfig(true),
+	}, &fakeService{})
+
+	grpcListener, httpListener := listen(t), listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Serve(ctx, grpcListener, httpListener)
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"cert":  true,
+			"valid": true,
+		},
+		map[string]interface{}{
+			"cert":  false,
+			"valid": false,
+		},
+	}
+
+	for _, tcase := range testCases {
+		certFile, keyFile := "", ""
+		if tcase["cert"].(bool) {
+			certFile, keyFile = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+		}
+		tlsCfg, err := certs.ClientConfig(filepath.Join(dir, "ca.pem"), certFile, keyFile)
+		if !assert.NoErrorf(t, err, "case: %v", tcase) {
+			continue
+		}
+		conn, err := grpc.Dial(grpcListener.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+		if !assert.NoErrorf(t, err, "case: %v", tcase) {
+			continue
+		}
+		callCtx, callCancel := context.WithTimeout(ctx, 5*time.Second)
+		_, err = proto.NewAuthServiceClient(conn).UsernameUsed(callCtx, &proto.UsernameUsedRequest{Username: "someone"})
+		callCancel()
+		conn.Close()
+		if tcase["valid"].(bool) {
+			assert.NoErrorf(t, err, "case: %v", tcase)
+		} else {
+			assert.Equalf(t, codes.Unavailable, status.Code(err), "case: %v", tcase)
+		}
+	}
+
+	// the http server speaks HTTP/2 over tls
+	tlsCfg, _ := certs.ClientConfig(filepath.Join(dir, "ca.pem"), "", "")
+	client := &http.Client{Transport: &http2.Transport{TLSClientConfig: tlsCfg}}
+	res, err := client.Get("https://" + httpListener.Addr().String() + "/healthz")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, 2, res.ProtoMajor)
+	}
+	res, err = http.Get("http://" + httpListener.Addr().String() + "/healthz")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	}
+}
+
+func Test_New_defaults(t *testing.T) {
+
+	s := New(Config{})
+	assert.Equal(t, "0.0.0.0:5000", s.cfg.GRPCAddr)
+	assert.Equal(t, "0.0.0.0:9001", s.cfg.HTTPAddr)
+	assert.Equal(t, 15*time.Second, s.cfg.DrainTimeout)
+	assert.NotNil(t, s.GRPCServer())
+}
@@ -0,0 +1,1026 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/render"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryUsers struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]global.User
+}
+
+// NewMemoryUsers returns a user store kept in memory, for tests and local runs
+func NewMemoryUsers() Users {
+	return &memoryUsers{users: map[primitive.ObjectID]global.User{}}
+}
+
+func (m *memoryUsers) Insert(ctx context.Context, user global.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[user.ID]; ok {
+		return errors.New("duplicate user id " + user.ID.Hex())
+	}
+	m.users[user.ID] = copyUser(user)
+	return nil
+}
+
+func (m *memoryUsers) FindByID(ctx context.Context, id primitive.ObjectID) (global.User, error) {
+	return m.find(func(u global.User) bool { return u.ID == id })
+}
+
+func (m *memoryUsers) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]global.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users := []global.User{}
+	for _, id := range ids {
+		if u, ok := m.users[id]; ok {
+			users = append(users, copyUser(u))
+		}
+	}
+	return users, nil
+}
+
+func (m *memoryUsers) FindByUsername(ctx context.Context, username string) (global.User, error) {
+	return m.find(func(u global.User) bool { return u.Username == username })
+}
+
+func (m *memoryUsers) FindByEmail(ctx context.Context, email string) (global.User, error) {
+	return m.find(func(u global.User) bool { return u.Email == email })
+}
+
+func (m *memoryUsers) FindByLogin(ctx context.Context, login string) (global.User, error) {
+	return m.find(func(u global.User) bool { return u.Username == login || u.Email == login })
+}
+
+// find returns the first user in id order that matches
+func (m *memoryUsers) find(match func(global.User) bool) (global.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, u := range m.sorted() {
+		if match(u) {
+			return copyUser(u), nil
+		}
+	}
+	return global.NilUser, ErrNotFound
+}
+
+func (m *memoryUsers) List(ctx context.Context, filter UserFilter, skip, limit int64) ([]global.User, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	query := strings.ToLower(filter.Query)
+	var matches []global.User
+	for _, u := range m.sorted() {
+		if query != "" && !strings.HasPrefix(strings.ToLower(u.Username), query) && !strings.HasPrefix(strings.ToLower(u.Email), query) {
+			continue
+		}
+		// like mongo, the role filter only sees stored roles
+		if filter.Role != "" && !hasRole(u.Roles, filter.Role) {
+			continue
+		}
+		if filter.Suspended != nil && u.Suspended != *filter.Suspended {
+			continue
+		}
+		matches = append(matches, copyUser(u))
+	}
+
+	total := int64(len(matches))
+	if !filter.After.IsZero() {
+		after := sort.Search(len(matches), func(i int) bool {
+			return bytes.Compare(matches[i].ID[:], filter.After[:]) > 0
+		})
+		matches = matches[after:]
+	}
+	if skip > int64(len(matches)) {
+		skip = int64(len(matches))
+	}
+	matches = matches[skip:]
+	if limit > 0 && limit < int64(len(matches)) {
+		matches = matches[:limit]
+	}
+	return matches, total, nil
+}
+
+func (m *memoryUsers) AddRole(ctx context.Context, id primitive.ObjectID, role policy.Role) (global.User, error) {
+	return m.update(id, func(u *global.User) {
+		u.Roles = u.GetRoles()
+		if !hasRole(u.Roles, role) {
+			u.Roles = append(u.Roles, role)
+		}
+	})
+}
+
+func (m *memoryUsers) RemoveRole(ctx context.Context, id primitive.ObjectID, role policy.Role) (global.User, error) {
+	return m.update(id, func(u *global.User) {
+		roles := []policy.Role{}
+		for _, r := range u.GetRoles() {
+			if r != role {
+				roles = append(roles, r)
+			}
+		}
+		u.Roles = roles
+	})
+}
+
+func (m *memoryUsers) Suspend(ctx context.Context, id primitive.ObjectID, reason string) (global.User, error) {
+	return m.update(id, func(u *global.User) {
+		u.Suspended = true
+		u.SuspendReason = reason
+	})
+}
+
+func (m *memoryUsers) Unsuspend(ctx context.Context, id primitive.ObjectID) (global.User, error) {
+	return m.update(id, func(u *global.User) {
+		u.Suspended = false
+		u.SuspendReason = ""
+	})
+}
+
+func (m *memoryUsers) RevokeTokens(ctx context.Context, id primitive.ObjectID) (global.User, error) {
+	return m.update(id, func(u *global.User) {
+		u.TokenVersion++
+	})
+}
+
+func (m *memoryUsers) SetPassword(ctx context.Context, id primitive.ObjectID, hash string) (global.User, error) {
+	return m.update(id, func(u *global.User) {
+		u.Password = hash
+		u.TokenVersion++
+	})
+}
+
+func (m *memoryUsers) SetProfile(ctx context.Context, id primitive.ObjectID, profile global.Profile) (global.User, error) {
+	return m.update(id, func(u *global.User) {
+		u.Profile.DisplayName, u.Profile.Bio, u.Profile.AvatarURL = profile.DisplayName, profile.Bio, profile.AvatarURL
+	})
+}
+
+func (m *memoryUsers) SetPrivacy(ctx context.Context, id primitive.ObjectID, privacy global.Privacy) (global.User, error) {
+	return m.update(id, func(u *global.User) {
+		u.Profile.Privacy = privacy
+	})
+}
+
+// update applies change to the user with the given id and returns the updated record
+func (m *memoryUsers) update(id primitive.ObjectID, change func(*global.User)) (global.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return global.NilUser, ErrNotFound
+	}
+	user = copyUser(user)
+	change(&user)
+	m.users[id] = user
+	return copyUser(user), nil
+}
+
+// sorted returns the users ordered by id, callers hold the lock
+func (m *memoryUsers) sorted() []global.User {
+	users := make([]global.User, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return bytes.Compare(users[i].ID[:], users[j].ID[:]) < 0
+	})
+	return users
+}
+
+// copyUser keeps callers from sharing the stored roles slice
+func copyUser(u global.User) global.User {
+	if u.Roles != nil {
+		u.Roles = append([]policy.Role{}, u.Roles...)
+	}
+	return u
+}
+
+func hasRole(roles []policy.Role, role policy.Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryRoleAudit struct {
+	mu      sync.RWMutex
+	changes []RoleChange
+}
+
+// NewMemoryRoleAudit returns a role audit trail kept in memory, for tests and local runs
+func NewMemoryRoleAudit() RoleAudit {
+	return &memoryRoleAudit{}
+}
+
+func (m *memoryRoleAudit) Insert(ctx context.Context, change RoleChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.changes = append(m.changes, change)
+	return nil
+}
+
+func (m *memoryRoleAudit) List(ctx context.Context, targetID primitive.ObjectID, limit int64) ([]RoleChange, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var changes []RoleChange
+	// entries are appended as they happen, so walking backwards is newest first
+	for i := len(m.changes) - 1; i >= 0; i-- {
+		if limit > 0 && int64(len(changes)) == limit {
+			break
+		}
+		if !targetID.IsZero() && m.changes[i].TargetID != targetID {
+			continue
+		}
+		changes = append(changes, m.changes[i])
+	}
+	return changes, nil
+}
+
+type memoryPosts struct {
+	mu    sync.RWMutex
+	posts map[primitive.ObjectID]Post
+}
+
+// NewMemoryPosts returns a post store kept in memory, for tests and local runs
+func NewMemoryPosts() Posts {
+	return &memoryPosts{posts: map[primitive.ObjectID]Post{}}
+}
+
+func (m *memoryPosts) Insert(ctx context.Context, post Post) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.posts[post.ID]; ok {
+		return errors.New("duplicate post id " + post.ID.Hex())
+	}
+	m.posts[post.ID] = copyPost(post)
+	return nil
+}
+
+func (m *memoryPosts) FindByID(ctx context.Context, id primitive.ObjectID) (Post, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	post, ok := m.posts[id]
+	if !ok {
+		return Post{}, ErrNotFound
+	}
+	return copyPost(post), nil
+}
+
+func (m *memoryPosts) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Post, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	posts := []Post{}
+	for _, id := range ids {
+		if p, ok := m.posts[id]; ok {
+			posts = append(posts, copyPost(p))
+		}
+	}
+	return posts, nil
+}
+
+func (m *memoryPosts) List(ctx context.Context, filter PostFilter, skip, limit int64) ([]Post, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := m.match(filter)
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Key().Before(matches[j].Key())
+	})
+
+	total := int64(len(matches))
+	if filter.After != nil {
+		after := sort.Search(len(matches), func(i int) bool {
+			return filter.After.Before(matches[i].Key())
+		})
+		matches = matches[after:]
+	}
+	if skip > int64(len(matches)) {
+		skip = int64(len(matches))
+	}
+	matches = matches[skip:]
+	if limit > 0 && limit < int64(len(matches)) {
+		matches = matches[:limit]
+	}
+	return matches, total, nil
+}
+
+func (m *memoryPosts) Count(ctx context.Context, filter PostFilter) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.match(filter))), nil
+}
+
+// match returns copies of the published posts matching filter
+func (m *memoryPosts) match(filter PostFilter) []Post {
+	var authors map[primitive.ObjectID]bool
+	if filter.Authors != nil {
+		authors = map[primitive.ObjectID]bool{}
+		for _, id := range filter.Authors {
+			authors[id] = true
+		}
+	}
+
+	matches := []Post{}
+	for _, p := range m.posts {
+		if p.GetState() != PostPublished {
+			continue
+		}
+		if authors != nil && !authors[p.AuthorID] {
+			continue
+		}
+		if filter.Tag != "" && !contains(p.Tags, filter.Tag) {
+			continue
+		}
+		if filter.Categories != nil && !contains(filter.Categories, p.Category) {
+			continue
+		}
+		matches = append(matches, copyPost(p))
+	}
+	return matches
+}
+
+func (m *memoryPosts) UpdateContent(ctx context.Context, post Post) (Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[post.ID]
+	if !ok {
+		return Post{}, ErrNotFound
+	}
+	stored.Title, stored.Body, stored.BodyHTML = post.Title, post.Body, post.BodyHTML
+	stored.TOC, stored.Tags, stored.Category = post.TOC, post.Tags, post.Category
+	stored.UpdatedAt = post.UpdatedAt
+	stored.Revision++
+	m.posts[post.ID] = copyPost(stored)
+	return copyPost(stored), nil
+}
+
+func (m *memoryPosts) SetState(ctx context.Context, from PostState, post Post) (Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[post.ID]
+	if !ok {
+		return Post{}, ErrNotFound
+	}
+	if stored.State != from {
+		return Post{}, ErrConflict
+	}
+	stored.State, stored.PublishAt, stored.PublishedAt = post.State, post.PublishAt, post.PublishedAt
+	stored.UpdatedAt = post.UpdatedAt
+	m.posts[post.ID] = stored
+	return copyPost(stored), nil
+}
+
+func (m *memoryPosts) PublishDue(ctx context.Context, now time.Time) (Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due *Post
+	for _, p := range m.posts {
+		if p.State != PostScheduled || p.PublishAt.After(now) {
+			continue
+		}
+		if due == nil || p.PublishAt.Before(due.PublishAt) {
+			p := p
+			due = &p
+		}
+	}
+	if due == nil {
+		return Post{}, ErrNotFound
+	}
+	due.State = PostPublished
+	if due.PublishedAt.IsZero() {
+		due.PublishedAt = due.PublishAt
+	}
+	due.PublishAt = time.Time{}
+	due.UpdatedAt = now
+	m.posts[due.ID] = *due
+	return copyPost(*due), nil
+}
+
+func (m *memoryPosts) ReplaceTag(ctx context.Context, from, to string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, p := range m.posts {
+		if !contains(p.Tags, from) {
+			continue
+		}
+		// like mongo, the new tag goes last
+		tags := []string{}
+		for _, tag := range p.Tags {
+			if tag != from && tag != to {
+				tags = append(tags, tag)
+			}
+		}
+		p.Tags = append(tags, to)
+		m.posts[id] = p
+	}
+	return nil
+}
+
+func (m *memoryPosts) CountReaction(ctx context.Context, id primitive.ObjectID, kind ReactionKind, delta int64) (Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.posts[id]
+	if !ok {
+		return Post{}, ErrNotFound
+	}
+	switch kind {
+	case ReactionLike:
+		p.Likes += delta
+	case ReactionBookmark:
+		p.Bookmarks += delta
+	case ReactionClap:
+		p.Claps += delta
+	}
+	m.posts[id] = p
+	return copyPost(p), nil
+}
+
+func (m *memoryPosts) Delete(ctx context.Context, id primitive.ObjectID) (Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.posts[id]
+	if !ok {
+		return Post{}, ErrNotFound
+	}
+	delete(m.posts, id)
+	return p, nil
+}
+
+// copyPost keeps callers from sharing the stored table of contents and tags
+func copyPost(p Post) Post {
+	if p.TOC != nil {
+		p.TOC = append([]render.Heading{}, p.TOC...)
+	}
+	if p.Tags != nil {
+		p.Tags = append([]string{}, p.Tags...)
+	}
+	return p
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryRevisions struct {
+	mu sync.RWMutex
+	// revisions of each post, oldest first
+	revisions map[primitive.ObjectID][]Revision
+	keep      int
+}
+
+// NewMemoryRevisions returns a post history kept in memory, for tests and
+// local runs, keeping the last keep revisions of every post, all when zero
+func NewMemoryRevisions(keep int) Revisions {
+	return &memoryRevisions{revisions: map[primitive.ObjectID][]Revision{}, keep: keep}
+}
+
+func (m *memoryRevisions) Insert(ctx context.Context, revision Revision) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revisions := m.revisions[revision.PostID]
+	for _, r := range revisions {
+		if r.Number == revision.Number {
+			return fmt.Errorf("duplicate revision %d of post %s", revision.Number, revision.PostID.Hex())
+		}
+	}
+	revisions = append(revisions, revision)
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Number < revisions[j].Number })
+	if m.keep > 0 && len(revisions) > m.keep {
+		revisions = append([]Revision{}, revisions[len(revisions)-m.keep:]...)
+	}
+	m.revisions[revision.PostID] = revisions
+	return nil
+}
+
+func (m *memoryRevisions) Find(ctx context.Context, postID primitive.ObjectID, number int) (Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, r := range m.revisions[postID] {
+		if r.Number == number {
+			return r, nil
+		}
+	}
+	return Revision{}, ErrNotFound
+}
+
+func (m *memoryRevisions) List(ctx context.Context, postID primitive.ObjectID) ([]Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored := m.revisions[postID]
+	revisions := make([]Revision, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		r := stored[i]
+		r.Body = ""
+		revisions = append(revisions, r)
+	}
+	return revisions, nil
+}
+
+func (m *memoryRevisions) DeletePost(ctx context.Context, postID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.revisions, postID)
+	return nil
+}
+
+type memoryTags struct {
+	mu   sync.RWMutex
+	tags map[string]Tag
+}
+
+// NewMemoryTags returns a tag store kept in memory, for tests and local runs
+func NewMemoryTags() Tags {
+	return &memoryTags{tags: map[string]Tag{}}
+}
+
+func (m *memoryTags) Ensure(ctx context.Context, tag Tag) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tags[tag.Slug]; !ok {
+		m.tags[tag.Slug] = tag
+	}
+	return nil
+}
+
+func (m *memoryTags) Find(ctx context.Context, slug string) (Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tag, ok := m.tags[slug]
+	if !ok {
+		return Tag{}, ErrNotFound
+	}
+	return tag, nil
+}
+
+func (m *memoryTags) List(ctx context.Context) ([]Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tags := []Tag{}
+	for _, tag := range m.tags {
+		if tag.PostCount > 0 {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].PostCount != tags[j].PostCount {
+			return tags[i].PostCount > tags[j].PostCount
+		}
+		return tags[i].Slug < tags[j].Slug
+	})
+	return tags, nil
+}
+
+func (m *memoryTags) Rename(ctx context.Context, slug, name string) error {
+	return m.update(slug, func(t *Tag) { t.Name = name })
+}
+
+func (m *memoryTags) SetCount(ctx context.Context, slug string, count int64) error {
+	return m.update(slug, func(t *Tag) { t.PostCount = count })
+}
+
+func (m *memoryTags) update(slug string, change func(*Tag)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tag, ok := m.tags[slug]
+	if !ok {
+		return ErrNotFound
+	}
+	change(&tag)
+	m.tags[slug] = tag
+	return nil
+}
+
+func (m *memoryTags) Delete(ctx context.Context, slug string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tags[slug]; !ok {
+		return ErrNotFound
+	}
+	delete(m.tags, slug)
+	return nil
+}
+
+type memoryCategories struct {
+	mu         sync.RWMutex
+	categories map[string]Category
+}
+
+// NewMemoryCategories returns a category store kept in memory, for tests and local runs
+func NewMemoryCategories() Categories {
+	return &memoryCategories{categories: map[string]Category{}}
+}
+
+func (m *memoryCategories) Insert(ctx context.Context, category Category) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.categories[category.Slug]; ok {
+		return ErrConflict
+	}
+	m.categories[category.Slug] = category
+	return nil
+}
+
+func (m *memoryCategories) Find(ctx context.Context, slug string) (Category, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	category, ok := m.categories[slug]
+	if !ok {
+		return Category{}, ErrNotFound
+	}
+	return category, nil
+}
+
+func (m *memoryCategories) List(ctx context.Context) ([]Category, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	categories := make([]Category, 0, len(m.categories))
+	for _, category := range m.categories {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Slug < categories[j].Slug })
+	return categories, nil
+}
+
+func (m *memoryCategories) SetCount(ctx context.Context, slug string, count int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	category, ok := m.categories[slug]
+	if !ok {
+		return ErrNotFound
+	}
+	category.PostCount = count
+	m.categories[slug] = category
+	return nil
+}
+
+type memoryFollows struct {
+	mu      sync.RWMutex
+	follows []Follow
+}
+
+// NewMemoryFollows returns a follow store kept in memory, for tests and local runs
+func NewMemoryFollows() Follows {
+	return &memoryFollows{}
+}
+
+func (m *memoryFollows) Insert(ctx context.Context, follow Follow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.follows {
+		if f.FollowerID == follow.FollowerID && f.FolloweeID == follow.FolloweeID {
+			return ErrConflict
+		}
+	}
+	m.follows = append(m.follows, follow)
+	return nil
+}
+
+func (m *memoryFollows) Delete(ctx context.Context, followerID, followeeID primitive.ObjectID) (Follow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, f := range m.follows {
+		if f.FollowerID == followerID && f.FolloweeID == followeeID {
+			m.follows = append(m.follows[:i:i], m.follows[i+1:]...)
+			return f, nil
+		}
+	}
+	return Follow{}, ErrNotFound
+}
+
+func (m *memoryFollows) List(ctx context.Context, filter FollowFilter, skip, limit int64) ([]Follow, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := []Follow{}
+	for _, f := range m.follows {
+		if !filter.FollowerID.IsZero() && f.FollowerID != filter.FollowerID {
+			continue
+		}
+		if !filter.FolloweeID.IsZero() && f.FolloweeID != filter.FolloweeID {
+			continue
+		}
+		matches = append(matches, f)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return bytes.Compare(matches[i].ID[:], matches[j].ID[:]) > 0
+	})
+
+	total := int64(len(matches))
+	if !filter.After.IsZero() {
+		after := sort.Search(len(matches), func(i int) bool {
+			return bytes.Compare(matches[i].ID[:], filter.After[:]) < 0
+		})
+		matches = matches[after:]
+	}
+	if skip > int64(len(matches)) {
+		skip = int64(len(matches))
+	}
+	matches = matches[skip:]
+	if limit > 0 && limit < int64(len(matches)) {
+		matches = matches[:limit]
+	}
+	return matches, total, nil
+}
+
+type memoryTimelines struct {
+	mu sync.RWMutex
+	// entries of each user by post
+	timelines map[primitive.ObjectID]map[primitive.ObjectID]TimelineEntry
+}
+
+// NewMemoryTimelines returns timelines kept in memory, for tests and local runs
+func NewMemoryTimelines() Timelines {
+	return &memoryTimelines{timelines: map[primitive.ObjectID]map[primitive.ObjectID]TimelineEntry{}}
+}
+
+func (m *memoryTimelines) Add(ctx context.Context, entries []TimelineEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range entries {
+		timeline, ok := m.timelines[e.UserID]
+		if !ok {
+			timeline = map[primitive.ObjectID]TimelineEntry{}
+			m.timelines[e.UserID] = timeline
+		}
+		timeline[e.PostID] = e
+	}
+	return nil
+}
+
+func (m *memoryTimelines) RemovePost(ctx context.Context, postID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, timeline := range m.timelines {
+		delete(timeline, postID)
+	}
+	return nil
+}
+
+func (m *memoryTimelines) RemoveAuthor(ctx context.Context, userID, authorID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for postID, e := range m.timelines[userID] {
+		if e.AuthorID == authorID {
+			delete(m.timelines[userID], postID)
+		}
+	}
+	return nil
+}
+
+func (m *memoryTimelines) List(ctx context.Context, userID primitive.ObjectID, after *PostKey, limit int64) ([]TimelineEntry, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := make([]TimelineEntry, 0, len(m.timelines[userID]))
+	for _, e := range m.timelines[userID] {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key().Before(entries[j].Key())
+	})
+
+	total := int64(len(entries))
+	if after != nil {
+		from := sort.Search(len(entries), func(i int) bool {
+			return after.Before(entries[i].Key())
+		})
+		entries = entries[from:]
+	}
+	if limit > 0 && limit < int64(len(entries)) {
+		entries = entries[:limit]
+	}
+	return entries, total, nil
+}
+
+type memoryReactions struct {
+	mu        sync.RWMutex
+	reactions []Reaction
+}
+
+// NewMemoryReactions returns a reaction store kept in memory, for tests and local runs
+func NewMemoryReactions() Reactions {
+	return &memoryReactions{}
+}
+
+func (m *memoryReactions) Insert(ctx context.Context, reaction Reaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.reactions {
+		if r.Kind == reaction.Kind && r.UserID == reaction.UserID && r.PostID == reaction.PostID {
+			return ErrConflict
+		}
+	}
+	m.reactions = append(m.reactions, reaction)
+	return nil
+}
+
+func (m *memoryReactions) Delete(ctx context.Context, kind ReactionKind, userID, postID primitive.ObjectID) (Reaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, r := range m.reactions {
+		if r.Kind == kind && r.UserID == userID && r.PostID == postID {
+			m.reactions = append(m.reactions[:i:i], m.reactions[i+1:]...)
+			return r, nil
+		}
+	}
+	return Reaction{}, ErrNotFound
+}
+
+func (m *memoryReactions) List(ctx context.Context, filter ReactionFilter, skip, limit int64) ([]Reaction, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := []Reaction{}
+	for _, r := range m.reactions {
+		if filter.Kind != "" && r.Kind != filter.Kind {
+			continue
+		}
+		if !filter.UserID.IsZero() && r.UserID != filter.UserID {
+			continue
+		}
+		if !filter.PostID.IsZero() && r.PostID != filter.PostID {
+			continue
+		}
+		matches = append(matches, r)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return bytes.Compare(matches[i].ID[:], matches[j].ID[:]) > 0
+	})
+
+	total := int64(len(matches))
+	if !filter.After.IsZero() {
+		after := sort.Search(len(matches), func(i int) bool {
+			return bytes.Compare(matches[i].ID[:], filter.After[:]) < 0
+		})
+		matches = matches[after:]
+	}
+	if skip > int64(len(matches)) {
+		skip = int64(len(matches))
+	}
+	matches = matches[skip:]
+	if limit > 0 && limit < int64(len(matches)) {
+		matches = matches[:limit]
+	}
+	return matches, total, nil
+}
+
+func (m *memoryReactions) DeletePost(ctx context.Context, postID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := []Reaction{}
+	for _, r := range m.reactions {
+		if r.PostID != postID {
+			kept = append(kept, r)
+		}
+	}
+	m.reactions = kept
+	return nil
+}
+
+type memoryNotifications struct {
+	mu            sync.RWMutex
+	notifications []Notification
+}
+
+// NewMemoryNotifications returns a notification store kept in memory, for tests and local runs
+func NewMemoryNotifications() Notifications {
+	return &memoryNotifications{}
+}
+
+func (m *memoryNotifications) Insert(ctx context.Context, notification Notification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, n := range m.notifications {
+		if n.ID == notification.ID {
+			return ErrConflict
+		}
+	}
+	m.notifications = append(m.notifications, notification)
+	return nil
+}
+
+func (m *memoryNotifications) List(ctx context.Context, filter NotificationFilter, skip, limit int64) ([]Notification, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := []Notification{}
+	for _, n := range m.notifications {
+		if !filter.UserID.IsZero() && n.UserID != filter.UserID {
+			continue
+		}
+		if filter.Unread && !n.ReadAt.IsZero() {
+			continue
+		}
+		if filter.Undigested && !n.DigestedAt.IsZero() {
+			continue
+		}
+		matches = append(matches, n)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return bytes.Compare(matches[i].ID[:], matches[j].ID[:]) > 0
+	})
+
+	total := int64(len(matches))
+	if !filter.After.IsZero() {
+		after := sort.Search(len(matches), func(i int) bool {
+			return bytes.Compare(matches[i].ID[:], filter.After[:]) < 0
+		})
+		matches = matches[after:]
+	}
+	if skip > int64(len(matches)) {
+		skip = int64(len(matches))
+	}
+	matches = matches[skip:]
+	if limit > 0 && limit < int64(len(matches)) {
+		matches = matches[:limit]
+	}
+	return matches, total, nil
+}
+
+func (m *memoryNotifications) MarkRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID, at time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	selected := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		selected[id] = true
+	}
+	var marked int64
+	for i, n := range m.notifications {
+		if n.UserID != userID || !n.ReadAt.IsZero() || (len(ids) > 0 && !selected[n.ID]) {
+			continue
+		}
+		m.notifications[i].ReadAt = at
+		marked++
+	}
+	return marked, nil
+}
+
+func (m *memoryNotifications) MarkDigested(ctx context.Context, userID, upTo primitive.ObjectID, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, n := range m.notifications {
+		if n.UserID == userID && n.DigestedAt.IsZero() && bytes.Compare(n.ID[:], upTo[:]) <= 0 {
+			m.notifications[i].DigestedAt = at
+		}
+	}
+	return nil
+}
@@ -0,0 +1,891 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/policy"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUsers struct {
+	collection *mongo.Collection
+}
+
+// NewMongoUsers returns a user store backed by collection
+func NewMongoUsers(collection *mongo.Collection) Users {
+	return &mongoUsers{collection: collection}
+}
+
+func (m *mongoUsers) Insert(ctx context.Context, user global.User) error {
+	_, err := m.collection.InsertOne(ctx, user)
+	return err
+}
+
+func (m *mongoUsers) FindByID(ctx context.Context, id primitive.ObjectID) (global.User, error) {
+	return m.findOne(ctx, bson.M{"_id": id})
+}
+
+func (m *mongoUsers) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]global.User, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	users := []global.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (m *mongoUsers) FindByUsername(ctx context.Context, username string) (global.User, error) {
+	return m.findOne(ctx, bson.M{"username": username})
+}
+
+func (m *mongoUsers) FindByEmail(ctx context.Context, email string) (global.User, error) {
+	return m.findOne(ctx, bson.M{"email": email})
+}
+
+func (m *mongoUsers) FindByLogin(ctx context.Context, login string) (global.User, error) {
+	return m.findOne(ctx, bson.M{"$or": []bson.M{bson.M{"username": login}, bson.M{"email": login}}})
+}
+
+func (m *mongoUsers) findOne(ctx context.Context, filter bson.M) (global.User, error) {
+	var user global.User
+	err := m.collection.FindOne(ctx, filter).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return global.NilUser, ErrNotFound
+	}
+	if err != nil {
+		return global.NilUser, err
+	}
+	return user, nil
+}
+
+func (m *mongoUsers) List(ctx context.Context, filter UserFilter, skip, limit int64) ([]global.User, int64, error) {
+	query := bson.M{}
+	if filter.Query != "" {
+		prefix := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(filter.Query), Options: "i"}
+		query["$or"] = []bson.M{bson.M{"username": prefix}, bson.M{"email": prefix}}
+	}
+	if filter.Role != "" {
+		query["roles"] = filter.Role
+	}
+	if filter.Suspended != nil {
+		if *filter.Suspended {
+			query["suspended"] = true
+		} else {
+			query["suspended"] = bson.M{"$ne": true}
+		}
+	}
+
+	total, err := m.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !filter.After.IsZero() {
+		query["_id"] = bson.M{"$gt": filter.After}
+	}
+	cursor, err := m.collection.Find(ctx, query, options.Find().SetSort(bson.M{"_id": 1}).SetSkip(skip).SetLimit(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	var users []global.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (m *mongoUsers) AddRole(ctx context.Context, id primitive.ObjectID, role policy.Role) (global.User, error) {
+	if err := m.persistDefaultRoles(ctx, id); err != nil {
+		return global.NilUser, err
+	}
+	return m.update(ctx, id, bson.M{"$addToSet": bson.M{"roles": role}})
+}
+
+func (m *mongoUsers) RemoveRole(ctx context.Context, id primitive.ObjectID, role policy.Role) (global.User, error) {
+	if err := m.persistDefaultRoles(ctx, id); err != nil {
+		return global.NilUser, err
+	}
+	return m.update(ctx, id, bson.M{"$pull": bson.M{"roles": role}})
+}
+
+// persistDefaultRoles stores the implicit default role of accounts created
+// before roles existed, so that role changes apply on top of it
+func (m *mongoUsers) persistDefaultRoles(ctx context.Context, id primitive.ObjectID) error {
+	_, err := m.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "roles": bson.M{"$in": bson.A{nil, bson.A{}}}},
+		bson.M{"$set": bson.M{"roles": []policy.Role{policy.DefaultRole}}},
+	)
+	return err
+}
+
+func (m *mongoUsers) Suspend(ctx context.Context, id primitive.ObjectID, reason string) (global.User, error) {
+	return m.update(ctx, id, bson.M{"$set": bson.M{"suspended": true, "suspend_reason": reason}})
+}
+
+func (m *mongoUsers) Unsuspend(ctx context.Context, id primitive.ObjectID) (global.User, error) {
+	return m.update(ctx, id, bson.M{"$set": bson.M{"suspended": false}, "$unset": bson.M{"suspend_reason": ""}})
+}
+
+func (m *mongoUsers) RevokeTokens(ctx context.Context, id primitive.ObjectID) (global.User, error) {
+	return m.update(ctx, id, bson.M{"$inc": bson.M{"token_version": 1}})
+}
+
+func (m *mongoUsers) SetPassword(ctx context.Context, id primitive.ObjectID, hash string) (global.User, error) {
+	return m.update(ctx, id, bson.M{"$set": bson.M{"password": hash}, "$inc": bson.M{"token_version": 1}})
+}
+
+func (m *mongoUsers) SetProfile(ctx context.Context, id primitive.ObjectID, profile global.Profile) (global.User, error) {
+	return m.update(ctx, id, bson.M{"$set": bson.M{
+		"profile.display_name": profile.DisplayName,
+		"profile.bio":          profile.Bio,
+		"profile.avatar_url":   profile.AvatarURL,
+	}})
+}
+
+func (m *mongoUsers) SetPrivacy(ctx context.Context, id primitive.ObjectID, privacy global.Privacy) (global.User, error) {
+	return m.update(ctx, id, bson.M{"$set": bson.M{"profile.privacy": privacy}})
+}
+
+// update applies update to the user with the given id and returns the updated record
+func (m *mongoUsers) update(ctx context.Context, id primitive.ObjectID, update bson.M) (global.User, error) {
+	var user global.User
+	err := m.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return global.NilUser, ErrNotFound
+	}
+	if err != nil {
+		return global.NilUser, err
+	}
+	return user, nil
+}
+
+type mongoRoleAudit struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRoleAudit returns a role audit trail backed by collection
+func NewMongoRoleAudit(collection *mongo.Collection) RoleAudit {
+	return &mongoRoleAudit{collection: collection}
+}
+
+func (m *mongoRoleAudit) Insert(ctx context.Context, change RoleChange) error {
+	_, err := m.collection.InsertOne(ctx, change)
+	return err
+}
+
+func (m *mongoRoleAudit) List(ctx context.Context, targetID primitive.ObjectID, limit int64) ([]RoleChange, error) {
+	filter := bson.M{}
+	if !targetID.IsZero() {
+		filter["target_id"] = targetID
+	}
+
+	cursor, err := m.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	var changes []RoleChange
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+type mongoPosts struct {
+	collection *mongo.Collection
+}
+
+// NewMongoPosts returns a post store backed by collection
+func NewMongoPosts(collection *mongo.Collection) Posts {
+	return &mongoPosts{collection: collection}
+}
+
+func (m *mongoPosts) Insert(ctx context.Context, post Post) error {
+	_, err := m.collection.InsertOne(ctx, post)
+	return err
+}
+
+func (m *mongoPosts) FindByID(ctx context.Context, id primitive.ObjectID) (Post, error) {
+	var post Post
+	err := m.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		return Post{}, ErrNotFound
+	}
+	if err != nil {
+		return Post{}, err
+	}
+	return post, nil
+}
+
+func (m *mongoPosts) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Post, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	posts := []Post{}
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (m *mongoPosts) List(ctx context.Context, filter PostFilter, skip, limit int64) ([]Post, int64, error) {
+	query := postQuery(filter)
+	total, err := m.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if filter.After != nil {
+		query["$or"] = []bson.M{
+			bson.M{"published_at": bson.M{"$lt": filter.After.PublishedAt}},
+			bson.M{"published_at": filter.After.PublishedAt, "_id": bson.M{"$lt": filter.After.ID}},
+		}
+	}
+	cursor, err := m.collection.Find(ctx, query, options.Find().
+		SetSort(bson.D{{Key: "published_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).SetLimit(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	posts := []Post{}
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+func (m *mongoPosts) Count(ctx context.Context, filter PostFilter) (int64, error) {
+	return m.collection.CountDocuments(ctx, postQuery(filter))
+}
+
+// postQuery matches the published posts selected by filter
+func postQuery(filter PostFilter) bson.M {
+	// posts stored before the lifecycle existed have no state and are published
+	query := bson.M{"state": bson.M{"$in": bson.A{PostPublished, "", nil}}}
+	if filter.Authors != nil {
+		query["author_id"] = bson.M{"$in": filter.Authors}
+	}
+	if filter.Tag != "" {
+		query["tags"] = filter.Tag
+	}
+	if filter.Categories != nil {
+		query["category"] = bson.M{"$in": filter.Categories}
+	}
+	return query
+}
+
+func (m *mongoPosts) UpdateContent(ctx context.Context, post Post) (Post, error) {
+	set := bson.M{
+		"title":      post.Title,
+		"body":       post.Body,
+		"body_html":  post.BodyHTML,
+		"toc":        post.TOC,
+		"updated_at": post.UpdatedAt,
+	}
+	unset := bson.M{}
+	if len(post.Tags) > 0 {
+		set["tags"] = post.Tags
+	} else {
+		unset["tags"] = ""
+	}
+	if post.Category != "" {
+		set["category"] = post.Category
+	} else {
+		unset["category"] = ""
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"revision": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return m.update(ctx, bson.M{"_id": post.ID}, update)
+}
+
+func (m *mongoPosts) SetState(ctx context.Context, from PostState, post Post) (Post, error) {
+	set := bson.M{"state": post.State, "updated_at": post.UpdatedAt}
+	unset := bson.M{}
+	// zero times are left out rather than stored as year 1
+	for field, value := range map[string]time.Time{"publish_at": post.PublishAt, "published_at": post.PublishedAt} {
+		if value.IsZero() {
+			unset[field] = ""
+		} else {
+			set[field] = value
+		}
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	filter := bson.M{"_id": post.ID, "state": from}
+	if from == "" {
+		// posts stored before the lifecycle existed have no state
+		filter["state"] = bson.M{"$in": bson.A{nil, ""}}
+	}
+	updated, err := m.update(ctx, filter, update)
+	if err != ErrNotFound {
+		return updated, err
+	}
+	// tell a missing post from one that changed state meanwhile
+	if _, err := m.FindByID(ctx, post.ID); err != nil {
+		return Post{}, err
+	}
+	return Post{}, ErrConflict
+}
+
+func (m *mongoPosts) PublishDue(ctx context.Context, now time.Time) (Post, error) {
+	// the filter and the update apply atomically, so a post only goes out once
+	var post Post
+	err := m.collection.FindOneAndUpdate(ctx,
+		bson.M{"state": PostScheduled, "publish_at": bson.M{"$lte": now}},
+		bson.A{
+			bson.M{"$set": bson.M{
+				"state":        PostPublished,
+				"published_at": bson.M{"$ifNull": bson.A{"$published_at", "$publish_at"}},
+				"updated_at":   now,
+			}},
+			bson.M{"$unset": "publish_at"},
+		},
+		options.FindOneAndUpdate().SetSort(bson.M{"publish_at": 1}).SetReturnDocument(options.After),
+	).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		return Post{}, ErrNotFound
+	}
+	if err != nil {
+		return Post{}, err
+	}
+	return post, nil
+}
+
+func (m *mongoPosts) ReplaceTag(ctx context.Context, from, to string) error {
+	// adding first, a failure in between leaves posts with both tags, which
+	// the next call cleans up
+	if _, err := m.collection.UpdateMany(ctx, bson.M{"tags": from}, bson.M{"$addToSet": bson.M{"tags": to}}); err != nil {
+		return err
+	}
+	_, err := m.collection.UpdateMany(ctx, bson.M{"tags": from}, bson.M{"$pull": bson.M{"tags": from}})
+	return err
+}
+
+// reactionCounts names the field counting each kind of reaction
+var reactionCounts = map[ReactionKind]string{
+	ReactionLike:     "likes",
+	ReactionBookmark: "bookmarks",
+	ReactionClap:     "claps",
+}
+
+func (m *mongoPosts) CountReaction(ctx context.Context, id primitive.ObjectID, kind ReactionKind, delta int64) (Post, error) {
+	return m.update(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{reactionCounts[kind]: delta}})
+}
+
+func (m *mongoPosts) Delete(ctx context.Context, id primitive.ObjectID) (Post, error) {
+	var post Post
+	err := m.collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		return Post{}, ErrNotFound
+	}
+	if err != nil {
+		return Post{}, err
+	}
+	return post, nil
+}
+
+// update applies update to the post matching filter and returns the updated record
+func (m *mongoPosts) update(ctx context.Context, filter bson.M, update bson.M) (Post, error) {
+	var post Post
+	err := m.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		return Post{}, ErrNotFound
+	}
+	if err != nil {
+		return Post{}, err
+	}
+	return post, nil
+}
+
+type mongoRevisions struct {
+	collection *mongo.Collection
+	keep       int
+}
+
+// NewMongoRevisions returns a post history backed by collection, keeping the
+// last keep revisions of every post, all when zero
+func NewMongoRevisions(collection *mongo.Collection, keep int) Revisions {
+	return &mongoRevisions{collection: collection, keep: keep}
+}
+
+func (m *mongoRevisions) Insert(ctx context.Context, revision Revision) error {
+	if _, err := m.collection.InsertOne(ctx, revision); err != nil {
+		return err
+	}
+	if m.keep <= 0 {
+		return nil
+	}
+	// everything older than the oldest revision kept goes
+	var oldest Revision
+	err := m.collection.FindOne(ctx, bson.M{"post_id": revision.PostID},
+		options.FindOne().SetSort(bson.M{"number": -1}).SetSkip(int64(m.keep-1)).SetProjection(bson.M{"number": 1}),
+	).Decode(&oldest)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = m.collection.DeleteMany(ctx, bson.M{"post_id": revision.PostID, "number": bson.M{"$lt": oldest.Number}})
+	return err
+}
+
+func (m *mongoRevisions) Find(ctx context.Context, postID primitive.ObjectID, number int) (Revision, error) {
+	var revision Revision
+	err := m.collection.FindOne(ctx, bson.M{"post_id": postID, "number": number}).Decode(&revision)
+	if err == mongo.ErrNoDocuments {
+		return Revision{}, ErrNotFound
+	}
+	if err != nil {
+		return Revision{}, err
+	}
+	return revision, nil
+}
+
+func (m *mongoRevisions) List(ctx context.Context, postID primitive.ObjectID) ([]Revision, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{"post_id": postID},
+		options.Find().SetSort(bson.M{"number": -1}).SetProjection(bson.M{"body": 0}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	revisions := []Revision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (m *mongoRevisions) DeletePost(ctx context.Context, postID primitive.ObjectID) error {
+	_, err := m.collection.DeleteMany(ctx, bson.M{"post_id": postID})
+	return err
+}
+
+type mongoTags struct {
+	collection *mongo.Collection
+}
+
+// NewMongoTags returns a tag store backed by collection
+func NewMongoTags(collection *mongo.Collection) Tags {
+	return &mongoTags{collection: collection}
+}
+
+func (m *mongoTags) Ensure(ctx context.Context, tag Tag) error {
+	_, err := m.collection.UpdateOne(ctx, bson.M{"_id": tag.Slug},
+		bson.M{"$setOnInsert": bson.M{"name": tag.Name, "post_count": tag.PostCount}},
+		options.Update().SetUpsert(true),
+	)
+	// two first uses racing, the other one inserted it
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func (m *mongoTags) Find(ctx context.Context, slug string) (Tag, error) {
+	var tag Tag
+	err := m.collection.FindOne(ctx, bson.M{"_id": slug}).Decode(&tag)
+	if err == mongo.ErrNoDocuments {
+		return Tag{}, ErrNotFound
+	}
+	if err != nil {
+		return Tag{}, err
+	}
+	return tag, nil
+}
+
+func (m *mongoTags) List(ctx context.Context) ([]Tag, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{"post_count": bson.M{"$gt": 0}},
+		options.Find().SetSort(bson.D{{Key: "post_count", Value: -1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	tags := []Tag{}
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (m *mongoTags) Rename(ctx context.Context, slug, name string) error {
+	return m.update(ctx, slug, bson.M{"name": name})
+}
+
+func (m *mongoTags) SetCount(ctx context.Context, slug string, count int64) error {
+	return m.update(ctx, slug, bson.M{"post_count": count})
+}
+
+func (m *mongoTags) update(ctx context.Context, slug string, set bson.M) error {
+	res, err := m.collection.UpdateOne(ctx, bson.M{"_id": slug}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *mongoTags) Delete(ctx context.Context, slug string) error {
+	res, err := m.collection.DeleteOne(ctx, bson.M{"_id": slug})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type mongoCategories struct {
+	collection *mongo.Collection
+}
+
+// NewMongoCategories returns a category store backed by collection
+func NewMongoCategories(collection *mongo.Collection) Categories {
+	return &mongoCategories{collection: collection}
+}
+
+func (m *mongoCategories) Insert(ctx context.Context, category Category) error {
+	_, err := m.collection.InsertOne(ctx, category)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (m *mongoCategories) Find(ctx context.Context, slug string) (Category, error) {
+	var category Category
+	err := m.collection.FindOne(ctx, bson.M{"_id": slug}).Decode(&category)
+	if err == mongo.ErrNoDocuments {
+		return Category{}, ErrNotFound
+	}
+	if err != nil {
+		return Category{}, err
+	}
+	return category, nil
+}
+
+func (m *mongoCategories) List(ctx context.Context) ([]Category, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	categories := []Category{}
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (m *mongoCategories) SetCount(ctx context.Context, slug string, count int64) error {
+	res, err := m.collection.UpdateOne(ctx, bson.M{"_id": slug}, bson.M{"$set": bson.M{"post_count": count}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type mongoFollows struct {
+	collection *mongo.Collection
+}
+
+// NewMongoFollows returns a follow store backed by collection, which needs a
+// unique index on follower and followee
+func NewMongoFollows(collection *mongo.Collection) Follows {
+	return &mongoFollows{collection: collection}
+}
+
+// EnsureFollowIndexes creates the indexes of the follow collection: a user
+// follows another once, followers are listed latest first
+func EnsureFollowIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "follower_id", Value: 1}, {Key: "followee_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "followee_id", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	return err
+}
+
+func (m *mongoFollows) Insert(ctx context.Context, follow Follow) error {
+	_, err := m.collection.InsertOne(ctx, follow)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (m *mongoFollows) Delete(ctx context.Context, followerID, followeeID primitive.ObjectID) (Follow, error) {
+	var follow Follow
+	err := m.collection.FindOneAndDelete(ctx, bson.M{"follower_id": followerID, "followee_id": followeeID}).Decode(&follow)
+	if err == mongo.ErrNoDocuments {
+		return Follow{}, ErrNotFound
+	}
+	if err != nil {
+		return Follow{}, err
+	}
+	return follow, nil
+}
+
+func (m *mongoFollows) List(ctx context.Context, filter FollowFilter, skip, limit int64) ([]Follow, int64, error) {
+	query := bson.M{}
+	if !filter.FollowerID.IsZero() {
+		query["follower_id"] = filter.FollowerID
+	}
+	if !filter.FolloweeID.IsZero() {
+		query["followee_id"] = filter.FolloweeID
+	}
+
+	total, err := m.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !filter.After.IsZero() {
+		query["_id"] = bson.M{"$lt": filter.After}
+	}
+	cursor, err := m.collection.Find(ctx, query, options.Find().SetSort(bson.M{"_id": -1}).SetSkip(skip).SetLimit(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	follows := []Follow{}
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, 0, err
+	}
+	return follows, total, nil
+}
+
+type mongoTimelines struct {
+	collection *mongo.Collection
+}
+
+// NewMongoTimelines returns timelines backed by collection, one document per
+// entry, which needs a unique index on user and post
+func NewMongoTimelines(collection *mongo.Collection) Timelines {
+	return &mongoTimelines{collection: collection}
+}
+
+// EnsureTimelineIndexes creates the indexes of the timeline collection: a post
+// is on a timeline once, timelines are read latest first
+func EnsureTimelineIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "published_at", Value: -1}, {Key: "post_id", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "author_id", Value: 1}}},
+		{Keys: bson.D{{Key: "post_id", Value: 1}}},
+	})
+	return err
+}
+
+func (m *mongoTimelines) Add(ctx context.Context, entries []TimelineEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, 0, len(entries))
+	for _, e := range entries {
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"user_id": e.UserID, "post_id": e.PostID}).
+			SetReplacement(e).
+			SetUpsert(true))
+	}
+	_, err := m.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (m *mongoTimelines) RemovePost(ctx context.Context, postID primitive.ObjectID) error {
+	_, err := m.collection.DeleteMany(ctx, bson.M{"post_id": postID})
+	return err
+}
+
+func (m *mongoTimelines) RemoveAuthor(ctx context.Context, userID, authorID primitive.ObjectID) error {
+	_, err := m.collection.DeleteMany(ctx, bson.M{"user_id": userID, "author_id": authorID})
+	return err
+}
+
+func (m *mongoTimelines) List(ctx context.Context, userID primitive.ObjectID, after *PostKey, limit int64) ([]TimelineEntry, int64, error) {
+	query := bson.M{"user_id": userID}
+	total, err := m.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if after != nil {
+		query["$or"] = []bson.M{
+			bson.M{"published_at": bson.M{"$lt": after.PublishedAt}},
+			bson.M{"published_at": after.PublishedAt, "post_id": bson.M{"$lt": after.ID}},
+		}
+	}
+	cursor, err := m.collection.Find(ctx, query, options.Find().
+		SetSort(bson.D{{Key: "published_at", Value: -1}, {Key: "post_id", Value: -1}}).
+		SetLimit(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := []TimelineEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+type mongoReactions struct {
+	collection *mongo.Collection
+}
+
+// NewMongoReactions returns a reaction store backed by collection, which
+// needs a unique index on kind, user and post
+func NewMongoReactions(collection *mongo.Collection) Reactions {
+	return &mongoReactions{collection: collection}
+}
+
+// EnsureReactionIndexes creates the indexes of the reaction collection: a
+// user reacts to a post once per kind, bookmarks are listed latest first
+func EnsureReactionIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "kind", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	return err
+}
+
+func (m *mongoReactions) Insert(ctx context.Context, reaction Reaction) error {
+	_, err := m.collection.InsertOne(ctx, reaction)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (m *mongoReactions) Delete(ctx context.Context, kind ReactionKind, userID, postID primitive.ObjectID) (Reaction, error) {
+	var reaction Reaction
+	err := m.collection.FindOneAndDelete(ctx, bson.M{"kind": kind, "user_id": userID, "post_id": postID}).Decode(&reaction)
+	if err == mongo.ErrNoDocuments {
+		return Reaction{}, ErrNotFound
+	}
+	if err != nil {
+		return Reaction{}, err
+	}
+	return reaction, nil
+}
+
+func (m *mongoReactions) List(ctx context.Context, filter ReactionFilter, skip, limit int64) ([]Reaction, int64, error) {
+	query := bson.M{}
+	if filter.Kind != "" {
+		query["kind"] = filter.Kind
+	}
+	if !filter.UserID.IsZero() {
+		query["user_id"] = filter.UserID
+	}
+	if !filter.PostID.IsZero() {
+		query["post_id"] = filter.PostID
+	}
+
+	total, err := m.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !filter.After.IsZero() {
+		query["_id"] = bson.M{"$lt": filter.After}
+	}
+	cursor, err := m.collection.Find(ctx, query, options.Find().SetSort(bson.M{"_id": -1}).SetSkip(skip).SetLimit(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	reactions := []Reaction{}
+	if err := cursor.All(ctx, &reactions); err != nil {
+		return nil, 0, err
+	}
+	return reactions, total, nil
+}
+
+func (m *mongoReactions) DeletePost(ctx context.Context, postID primitive.ObjectID) error {
+	_, err := m.collection.DeleteMany(ctx, bson.M{"post_id": postID})
+	return err
+}
+
+type mongoNotifications struct {
+	collection *mongo.Collection
+}
+
+// NewMongoNotifications returns a notification store backed by collection,
+// which lists faster with an index on user and id
+func NewMongoNotifications(collection *mongo.Collection) Notifications {
+	return &mongoNotifications{collection: collection}
+}
+
+func (m *mongoNotifications) Insert(ctx context.Context, notification Notification) error {
+	_, err := m.collection.InsertOne(ctx, notification)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (m *mongoNotifications) List(ctx context.Context, filter NotificationFilter, skip, limit int64) ([]Notification, int64, error) {
+	query := bson.M{}
+	if !filter.UserID.IsZero() {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Unread {
+		query["read_at"] = bson.M{"$exists": false}
+	}
+	if filter.Undigested {
+		query["digested_at"] = bson.M{"$exists": false}
+	}
+
+	total, err := m.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !filter.After.IsZero() {
+		query["_id"] = bson.M{"$lt": filter.After}
+	}
+	cursor, err := m.collection.Find(ctx, query, options.Find().SetSort(bson.M{"_id": -1}).SetSkip(skip).SetLimit(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	notifications := []Notification{}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
+func (m *mongoNotifications) MarkRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID, at time.Time) (int64, error) {
+	query := bson.M{"user_id": userID, "read_at": bson.M{"$exists": false}}
+	if len(ids) > 0 {
+		query["_id"] = bson.M{"$in": ids}
+	}
+	res, err := m.collection.UpdateMany(ctx, query, bson.M{"$set": bson.M{"read_at": at}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (m *mongoNotifications) MarkDigested(ctx context.Context, userID, upTo primitive.ObjectID, at time.Time) error {
+	_, err := m.collection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "digested_at": bson.M{"$exists": false}, "_id": bson.M{"$lte": upTo}},
+		bson.M{"$set": bson.M{"digested_at": at}},
+	)
+	return err
+}
@@ -0,0 +1,102 @@
+//go:build integration
+// +build integration
+
+package store
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/database"
+	"github.com/HiteshRepo/blog-application/global"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDatabase connects to a fresh database on the mongod at MONGO_TEST_URI
+// (default mongodb://localhost:27017), dropped after the test
+func testDatabase(t *testing.T) *database.Client {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	db, err := database.Connect(ctx, database.Config{URI: uri, Name: global.DefaultDBName + "_test_" + primitive.NewObjectID().Hex(), Retries: -1})
+	if err != nil {
+		t.Fatalf("Error connecting to db : %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		db.Database().Drop(ctx)
+		db.Close(ctx)
+	})
+	return db
+}
+
+func Test_mongoUsers(t *testing.T) {
+	testUsers(t, NewMongoUsers(testDatabase(t).Collection("user")))
+}
+
+func Test_mongoRoleAudit(t *testing.T) {
+	testRoleAudit(t, NewMongoRoleAudit(testDatabase(t).Collection("role_audit")))
+}
+
+func Test_mongoPosts(t *testing.T) {
+	testPosts(t, NewMongoPosts(testDatabase(t).Collection("posts")))
+	testPostList(t, NewMongoPosts(testDatabase(t).Collection("posts")))
+	testPublishDue(t, NewMongoPosts(testDatabase(t).Collection("posts")))
+	testPublishDueConcurrently(t, NewMongoPosts(testDatabase(t).Collection("posts")))
+}
+
+func Test_mongoRevisions(t *testing.T) {
+	testRevisions(t, NewMongoRevisions(testDatabase(t).Collection("post_revisions"), 3))
+}
+
+func Test_mongoTags(t *testing.T) {
+	testTags(t, NewMongoTags(testDatabase(t).Collection("tags")))
+}
+
+func Test_mongoCategories(t *testing.T) {
+	testCategories(t, NewMongoCategories(testDatabase(t).Collection("categories")))
+}
+
+func Test_mongoFollows(t *testing.T) {
+	collection := testDatabase(t).Collection("follows")
+	// the follow_indexes migration creates it for the backend
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "follower_id", Value: 1}, {Key: "followee_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		t.Fatalf("Error creating index : %v", err)
+	}
+	testFollows(t, NewMongoFollows(collection))
+}
+
+func Test_mongoTimelines(t *testing.T) {
+	testTimelines(t, NewMongoTimelines(testDatabase(t).Collection("timelines")))
+}
+
+func Test_mongoReactions(t *testing.T) {
+	collection := testDatabase(t).Collection("reactions")
+	// the reaction_indexes migration creates it for the backend
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "kind", Value: 1}, {Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		t.Fatalf("Error creating index : %v", err)
+	}
+	testReactions(t, NewMongoReactions(collection))
+}
+
+func Test_mongoNotifications(t *testing.T) {
+	testNotifications(t, NewMongoNotifications(testDatabase(t).Collection("notifications")))
+}
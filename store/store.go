@@ -0,0 +1,398 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/render"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound is returned when no record matches
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned when a record changed since it was read
+var ErrConflict = errors.New("conflict")
+
+// UserFilter selects users, zero values match everything
+type UserFilter struct {
+	// Query matches a case insensitive prefix of the username or email
+	Query string
+	Role  policy.Role
+	// Suspended, when set, matches only suspended or only active users
+	Suspended *bool
+	// After, when set, lists the users after the one with this id, for paging.
+	// It leaves the total as is.
+	After primitive.ObjectID
+}
+
+// Users stores user accounts.
+// Every update returns the record as it is after the update.
+type Users interface {
+	Insert(ctx context.Context, user global.User) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (global.User, error)
+	// FindByIDs returns the users with the given ids in no particular order,
+	// leaving out missing ones
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]global.User, error)
+	FindByUsername(ctx context.Context, username string) (global.User, error)
+	FindByEmail(ctx context.Context, email string) (global.User, error)
+	// FindByLogin matches either the username or the email
+	FindByLogin(ctx context.Context, login string) (global.User, error)
+	// List returns a page of matching users ordered by id, and the number of all matches
+	List(ctx context.Context, filter UserFilter, skip, limit int64) ([]global.User, int64, error)
+
+	// AddRole and RemoveRole first persist the default role for accounts
+	// created before roles existed
+	AddRole(ctx context.Context, id primitive.ObjectID, role policy.Role) (global.User, error)
+	RemoveRole(ctx context.Context, id primitive.ObjectID, role policy.Role) (global.User, error)
+	Suspend(ctx context.Context, id primitive.ObjectID, reason string) (global.User, error)
+	Unsuspend(ctx context.Context, id primitive.ObjectID) (global.User, error)
+	// RevokeTokens bumps the token version, invalidating every token issued before
+	RevokeTokens(ctx context.Context, id primitive.ObjectID) (global.User, error)
+	// SetPassword stores a new password hash and revokes existing tokens
+	SetPassword(ctx context.Context, id primitive.ObjectID, hash string) (global.User, error)
+	// SetProfile stores the display name, bio and avatar of profile, leaving
+	// the privacy settings as they are
+	SetProfile(ctx context.Context, id primitive.ObjectID, profile global.Profile) (global.User, error)
+	SetPrivacy(ctx context.Context, id primitive.ObjectID, privacy global.Privacy) (global.User, error)
+}
+
+// RoleChange is one entry of the role audit trail
+type RoleChange struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	ActorID   primitive.ObjectID `bson:"actor_id"`
+	TargetID  primitive.ObjectID `bson:"target_id"`
+	Role      policy.Role        `bson:"role"`
+	Action    string             `bson:"action"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// RoleAudit stores the role audit trail
+type RoleAudit interface {
+	Insert(ctx context.Context, change RoleChange) error
+	// List returns the newest changes first, a zero targetID matches every user
+	List(ctx context.Context, targetID primitive.ObjectID, limit int64) ([]RoleChange, error)
+}
+
+// PostState is a step of the lifecycle of a post
+type PostState string
+
+const (
+	PostDraft     PostState = "draft"
+	PostScheduled PostState = "scheduled"
+	PostPublished PostState = "published"
+	// unlisted posts are published, but only reachable by their link
+	PostUnlisted PostState = "unlisted"
+	PostArchived PostState = "archived"
+)
+
+// Post is a blog post, stored with its rendering so that reads never render
+type Post struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	AuthorID primitive.ObjectID `bson:"author_id"`
+	Title    string             `bson:"title"`
+	// Body is the Markdown source, BodyHTML and TOC its rendering
+	Body      string           `bson:"body"`
+	BodyHTML  string           `bson:"body_html"`
+	TOC       []render.Heading `bson:"toc"`
+	CreatedAt time.Time        `bson:"created_at"`
+	UpdatedAt time.Time        `bson:"updated_at"`
+
+	State PostState `bson:"state"`
+	// PublishAt is when a scheduled post goes out, PublishedAt when the post
+	// was first published
+	PublishAt   time.Time `bson:"publish_at,omitempty"`
+	PublishedAt time.Time `bson:"published_at,omitempty"`
+
+	// Revision numbers the saved versions of the content, from 1
+	Revision int `bson:"revision"`
+
+	// Tags holds the slugs of the tags of the post, Category the slug of its category
+	Tags     []string `bson:"tags,omitempty"`
+	Category string   `bson:"category,omitempty"`
+
+	// Likes, Bookmarks and Claps count the reactions of users to the post
+	Likes     int64 `bson:"likes,omitempty"`
+	Bookmarks int64 `bson:"bookmarks,omitempty"`
+	Claps     int64 `bson:"claps,omitempty"`
+}
+
+// GetState returns the state of the post, posts stored before the lifecycle
+// existed were published
+func (p Post) GetState() PostState {
+	if p.State == "" {
+		return PostPublished
+	}
+	return p.State
+}
+
+// PostFilter selects published posts, zero values match everything
+type PostFilter struct {
+	// Authors matches posts by any of the authors
+	Authors []primitive.ObjectID
+	Tag     string
+	// Categories matches posts in any of the categories
+	Categories []string
+	// After, when set, lists the posts after the one with this key, for
+	// paging. It leaves the total as is.
+	After *PostKey
+}
+
+// PostKey is the position of a post in listings, latest published first
+type PostKey struct {
+	PublishedAt time.Time
+	ID          primitive.ObjectID
+}
+
+// Key returns the position of the post in listings
+func (p Post) Key() PostKey {
+	return PostKey{PublishedAt: p.PublishedAt, ID: p.ID}
+}
+
+// Before reports whether k comes before other in listings
+func (k PostKey) Before(other PostKey) bool {
+	if !k.PublishedAt.Equal(other.PublishedAt) {
+		return k.PublishedAt.After(other.PublishedAt)
+	}
+	return k.ID.Hex() > other.ID.Hex()
+}
+
+// Posts stores blog posts
+type Posts interface {
+	Insert(ctx context.Context, post Post) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (Post, error)
+	// FindByIDs returns the posts with the given ids in no particular order,
+	// leaving out missing ones
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Post, error)
+	// List returns a page of matching published posts, latest published
+	// first, and the number of all matches
+	List(ctx context.Context, filter PostFilter, skip, limit int64) ([]Post, int64, error)
+	// Count returns the number of matching published posts
+	Count(ctx context.Context, filter PostFilter) (int64, error)
+	// UpdateContent stores the title, body, rendering, tags, category and
+	// UpdatedAt of post, bumps its revision and returns the updated record
+	UpdateContent(ctx context.Context, post Post) (Post, error)
+	// SetState stores the state, PublishAt, PublishedAt and UpdatedAt of
+	// post, unless the stored state is no longer from (ErrConflict)
+	SetState(ctx context.Context, from PostState, post Post) (Post, error)
+	// PublishDue publishes the scheduled post due the longest by now and
+	// returns it, or ErrNotFound when none is due. Concurrent calls never
+	// publish the same post twice.
+	PublishDue(ctx context.Context, now time.Time) (Post, error)
+	// ReplaceTag swaps the tag from for the tag to on every post holding it.
+	// Calling it again after a failure finishes the job.
+	ReplaceTag(ctx context.Context, from, to string) error
+	// CountReaction adds delta to the count of reactions of kind on the post,
+	// atomically, and returns the updated record
+	CountReaction(ctx context.Context, id primitive.ObjectID, kind ReactionKind, delta int64) (Post, error)
+	// Delete returns the deleted post, or ErrNotFound
+	Delete(ctx context.Context, id primitive.ObjectID) (Post, error)
+}
+
+// Revision is a saved version of the content of a post
+type Revision struct {
+	ID     primitive.ObjectID `bson:"_id"`
+	PostID primitive.ObjectID `bson:"post_id"`
+	Number int                `bson:"number"`
+	// AuthorID saved this version, not necessarily the author of the post
+	AuthorID  primitive.ObjectID `bson:"author_id"`
+	Title     string             `bson:"title"`
+	Body      string             `bson:"body"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// Revisions stores the history of posts
+type Revisions interface {
+	// Insert stores revision, then drops the oldest revisions of its post
+	// beyond the retention limit
+	Insert(ctx context.Context, revision Revision) error
+	Find(ctx context.Context, postID primitive.ObjectID, number int) (Revision, error)
+	// List returns the revisions of a post newest first, without their body
+	List(ctx context.Context, postID primitive.ObjectID) ([]Revision, error)
+	// DeletePost drops every revision of a post
+	DeletePost(ctx context.Context, postID primitive.ObjectID) error
+}
+
+// Tag labels posts, tags are created the first time a post uses them
+type Tag struct {
+	Slug string `bson:"_id"`
+	// Name is the tag as first written
+	Name string `bson:"name"`
+	// PostCount counts the published posts with the tag
+	PostCount int64 `bson:"post_count"`
+}
+
+// Tags stores the tags of posts
+type Tags interface {
+	// Ensure inserts tag unless a tag with its slug exists
+	Ensure(ctx context.Context, tag Tag) error
+	Find(ctx context.Context, slug string) (Tag, error)
+	// List returns the tags used by published posts, most used first
+	List(ctx context.Context) ([]Tag, error)
+	Rename(ctx context.Context, slug, name string) error
+	SetCount(ctx context.Context, slug string, count int64) error
+	Delete(ctx context.Context, slug string) error
+}
+
+// Category files posts in a hierarchy
+type Category struct {
+	Slug string `bson:"_id"`
+	Name string `bson:"name"`
+	// Parent is the slug of the parent category, empty at the top
+	Parent string `bson:"parent,omitempty"`
+	// PostCount counts the published posts in the category and below
+	PostCount int64 `bson:"post_count"`
+}
+
+// Categories stores the categories of posts
+type Categories interface {
+	// Insert returns ErrConflict when the slug is taken
+	Insert(ctx context.Context, category Category) error
+	Find(ctx context.Context, slug string) (Category, error)
+	// List returns every category ordered by slug
+	List(ctx context.Context) ([]Category, error)
+	SetCount(ctx context.Context, slug string, count int64) error
+}
+
+// Follow is a user following another one
+type Follow struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	FollowerID primitive.ObjectID `bson:"follower_id"`
+	FolloweeID primitive.ObjectID `bson:"followee_id"`
+	CreatedAt  time.Time          `bson:"created_at"`
+}
+
+// FollowFilter selects follows, zero values match everything
+type FollowFilter struct {
+	FollowerID primitive.ObjectID
+	FolloweeID primitive.ObjectID
+	// After, when set, lists the follows after the one with this id, for
+	// paging. It leaves the total as is.
+	After primitive.ObjectID
+}
+
+// Follows stores who follows whom
+type Follows interface {
+	// Insert returns ErrConflict when the follower already follows the followee
+	Insert(ctx context.Context, follow Follow) error
+	// Delete returns the deleted follow, or ErrNotFound
+	Delete(ctx context.Context, followerID, followeeID primitive.ObjectID) (Follow, error)
+	// List returns a page of matching follows, newest first, and the number of all matches
+	List(ctx context.Context, filter FollowFilter, skip, limit int64) ([]Follow, int64, error)
+}
+
+// TimelineEntry puts a post on the home feed of a user, for feeds built
+// when posts are published
+type TimelineEntry struct {
+	UserID      primitive.ObjectID `bson:"user_id"`
+	PostID      primitive.ObjectID `bson:"post_id"`
+	AuthorID    primitive.ObjectID `bson:"author_id"`
+	PublishedAt time.Time          `bson:"published_at"`
+}
+
+// Key returns the position of the post of the entry in listings
+func (e TimelineEntry) Key() PostKey {
+	return PostKey{PublishedAt: e.PublishedAt, ID: e.PostID}
+}
+
+// Timelines stores the home feeds of users
+type Timelines interface {
+	// Add stores entries, replacing those for the same user and post
+	Add(ctx context.Context, entries []TimelineEntry) error
+	// RemovePost takes a post off every timeline
+	RemovePost(ctx context.Context, postID primitive.ObjectID) error
+	// RemoveAuthor takes the posts of an author off the timeline of a user
+	RemoveAuthor(ctx context.Context, userID, authorID primitive.ObjectID) error
+	// List returns a page of the timeline of a user, latest published first,
+	// and its length. after, when set, lists the entries after it.
+	List(ctx context.Context, userID primitive.ObjectID, after *PostKey, limit int64) ([]TimelineEntry, int64, error)
+}
+
+// ReactionKind is how a user reacts to a post
+type ReactionKind string
+
+// Reaction kinds
+const (
+	ReactionLike     ReactionKind = "like"
+	ReactionBookmark ReactionKind = "bookmark"
+	ReactionClap     ReactionKind = "clap"
+)
+
+// Reaction is a user liking, bookmarking or clapping for a post, once per kind
+type Reaction struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Kind      ReactionKind       `bson:"kind"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	PostID    primitive.ObjectID `bson:"post_id"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// ReactionFilter selects reactions, zero values match everything
+type ReactionFilter struct {
+	Kind   ReactionKind
+	UserID primitive.ObjectID
+	PostID primitive.ObjectID
+	// After, when set, lists the reactions after the one with this id, for
+	// paging. It leaves the total as is.
+	After primitive.ObjectID
+}
+
+// Reactions stores the likes, bookmarks and claps of users
+type Reactions interface {
+	// Insert returns ErrConflict when the user reacted to the post with the
+	// same kind already, concurrent inserts of the same reaction included
+	Insert(ctx context.Context, reaction Reaction) error
+	// Delete returns the deleted reaction, or ErrNotFound
+	Delete(ctx context.Context, kind ReactionKind, userID, postID primitive.ObjectID) (Reaction, error)
+	// List returns a page of matching reactions, newest first, and the number of all matches
+	List(ctx context.Context, filter ReactionFilter, skip, limit int64) ([]Reaction, int64, error)
+	// DeletePost drops every reaction to a post
+	DeletePost(ctx context.Context, postID primitive.ObjectID) error
+}
+
+// Notification tells a user about something another user did, like
+// following them or liking one of their posts
+type Notification struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	UserID        primitive.ObjectID `bson:"user_id"`
+	Kind          string             `bson:"kind"`
+	ActorID       primitive.ObjectID `bson:"actor_id"`
+	ActorUsername string             `bson:"actor_username"`
+	// PostID and PostTitle are set for notifications about a post, the
+	// title as it was then
+	PostID    primitive.ObjectID `bson:"post_id,omitempty"`
+	PostTitle string             `bson:"post_title,omitempty"`
+	CreatedAt time.Time          `bson:"created_at"`
+	// ReadAt is zero until the user reads the notification
+	ReadAt time.Time `bson:"read_at,omitempty"`
+	// DigestedAt is zero until a digest email tells the user about it
+	DigestedAt time.Time `bson:"digested_at,omitempty"`
+}
+
+// NotificationFilter selects notifications, zero values match everything
+type NotificationFilter struct {
+	UserID primitive.ObjectID
+	Unread bool
+	// Undigested selects the notifications no digest told about yet
+	Undigested bool
+	// After, when set, lists the notifications after the one with this id,
+	// for paging. It leaves the total as is.
+	After primitive.ObjectID
+}
+
+// Notifications stores the inboxes of users
+type Notifications interface {
+	Insert(ctx context.Context, notification Notification) error
+	// List returns a page of matching notifications, newest first, and the number of all matches
+	List(ctx context.Context, filter NotificationFilter, skip, limit int64) ([]Notification, int64, error)
+	// MarkRead marks the unread notifications of the user with ids read at,
+	// all of them when ids is empty, and returns how many it marked
+	MarkRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID, at time.Time) (int64, error)
+	// MarkDigested records that a digest told the user at about their
+	// notifications up to the one with id upTo, those after are left for the
+	// next digest
+	MarkDigested(ctx context.Context, userID, upTo primitive.ObjectID, at time.Time) error
+}
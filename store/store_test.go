@@ -0,0 +1,817 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/policy"
+	"github.com/HiteshRepo/blog-application/render"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testUsers checks the behaviour every Users implementation shares
+func testUsers(t *testing.T, users Users) {
+	ctx := context.Background()
+
+	alpha := global.User{ID: primitive.NewObjectID(), Username: "store-alpha", Email: "store-alpha@gmail.com", Roles: []policy.Role{policy.RoleEditor}}
+	beta := global.User{ID: primitive.NewObjectID(), Username: "store-beta", Email: "store-beta@gmail.com", Suspended: true}
+	gamma := global.User{ID: primitive.NewObjectID(), Username: "store-gamma", Email: "store-gamma@gmail.com"}
+	for _, u := range []global.User{alpha, beta, gamma} {
+		if err := users.Insert(ctx, u); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+
+	for _, find := range []func() (global.User, error){
+		func() (global.User, error) { return users.FindByID(ctx, alpha.ID) },
+		func() (global.User, error) { return users.FindByUsername(ctx, "store-alpha") },
+		func() (global.User, error) { return users.FindByEmail(ctx, "store-alpha@gmail.com") },
+		func() (global.User, error) { return users.FindByLogin(ctx, "store-alpha") },
+		func() (global.User, error) { return users.FindByLogin(ctx, "store-alpha@gmail.com") },
+	} {
+		user, err := find()
+		assert.NoError(t, err)
+		assert.Equal(t, alpha.ID, user.ID)
+	}
+
+	_, err := users.FindByLogin(ctx, "store-unknown")
+	assert.Equal(t, ErrNotFound, err)
+
+	found, err := users.FindByIDs(ctx, []primitive.ObjectID{gamma.ID, primitive.NewObjectID(), alpha.ID})
+	assert.NoError(t, err)
+	var usernames []string
+	for _, u := range found {
+		usernames = append(usernames, u.Username)
+	}
+	assert.ElementsMatch(t, []string{"store-alpha", "store-gamma"}, usernames)
+
+	suspended, active := true, false
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"filter": UserFilter{Query: "STORE-"},
+			"users":  []string{"store-alpha", "store-beta", "store-gamma"},
+		},
+		map[string]interface{}{
+			"filter": UserFilter{Query: "store-", Role: policy.RoleEditor},
+			"users":  []string{"store-alpha"},
+		},
+		map[string]interface{}{
+			"filter": UserFilter{Query: "store-", Suspended: &suspended},
+			"users":  []string{"store-beta"},
+		},
+		map[string]interface{}{
+			"filter": UserFilter{Query: "store-", Suspended: &active},
+			"users":  []string{"store-alpha", "store-gamma"},
+		},
+		map[string]interface{}{
+			"filter": UserFilter{Query: "store-", Suspended: &active},
+			"skip":   int64(1),
+			"users":  []string{"store-gamma"},
+			"total":  int64(2),
+		},
+		map[string]interface{}{
+			"filter": UserFilter{Query: "store-", After: alpha.ID},
+			"users":  []string{"store-beta", "store-gamma"},
+			"total":  int64(3),
+		},
+		map[string]interface{}{
+			// skipping past the users after the key lists none
+			"filter": UserFilter{Query: "store-", After: alpha.ID},
+			"skip":   int64(3),
+			"users":  []string(nil),
+			"total":  int64(3),
+		},
+	}
+
+	for _, tcase := range testCases {
+		skip, _ := tcase["skip"].(int64)
+		list, total, err := users.List(ctx, tcase["filter"].(UserFilter), skip, 10)
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		var usernames []string
+		for _, u := range list {
+			usernames = append(usernames, u.Username)
+		}
+		assert.Equalf(t, tcase["users"], usernames, "case: %v", tcase)
+		if want, ok := tcase["total"]; ok {
+			assert.Equalf(t, want, total, "case: %v", tcase)
+		}
+	}
+
+	// role changes keep the implicit default role of users without stored roles
+	user, err := users.AddRole(ctx, gamma.ID, policy.RoleEditor)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []policy.Role{policy.RoleAuthor, policy.RoleEditor}, user.Roles)
+	user, err = users.RemoveRole(ctx, gamma.ID, policy.RoleAuthor)
+	assert.NoError(t, err)
+	assert.Equal(t, []policy.Role{policy.RoleEditor}, user.Roles)
+
+	user, err = users.Suspend(ctx, gamma.ID, "spam")
+	assert.NoError(t, err)
+	assert.True(t, user.Suspended)
+	assert.Equal(t, "spam", user.SuspendReason)
+	user, err = users.Unsuspend(ctx, gamma.ID)
+	assert.NoError(t, err)
+	assert.False(t, user.Suspended)
+	assert.Empty(t, user.SuspendReason)
+
+	user, err = users.RevokeTokens(ctx, gamma.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.TokenVersion)
+	user, err = users.SetPassword(ctx, gamma.ID, "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, "hash", user.Password)
+	assert.Equal(t, 2, user.TokenVersion)
+
+	user, err = users.SetPrivacy(ctx, gamma.ID, global.Privacy{HideBio: true})
+	assert.NoError(t, err)
+	assert.Equal(t, global.Profile{Privacy: global.Privacy{HideBio: true}}, user.Profile)
+	user, err = users.SetProfile(ctx, gamma.ID, global.Profile{DisplayName: "Gamma", Bio: "Hello", AvatarURL: "https://example.com/g.png"})
+	assert.NoError(t, err)
+	assert.Equal(t, global.Profile{DisplayName: "Gamma", Bio: "Hello", AvatarURL: "https://example.com/g.png", Privacy: global.Privacy{HideBio: true}}, user.Profile)
+	user, err = users.FindByID(ctx, gamma.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Gamma", user.Profile.DisplayName)
+
+	_, err = users.RevokeTokens(ctx, primitive.NewObjectID())
+	assert.Equal(t, ErrNotFound, err)
+	_, err = users.AddRole(ctx, primitive.NewObjectID(), policy.RoleEditor)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+// testRoleAudit checks the behaviour every RoleAudit implementation shares
+func testRoleAudit(t *testing.T, roleAudit RoleAudit) {
+	ctx := context.Background()
+
+	alpha, beta := primitive.NewObjectID(), primitive.NewObjectID()
+	for _, c := range []RoleChange{
+		RoleChange{ID: primitive.NewObjectID(), TargetID: alpha, Role: policy.RoleEditor, Action: "grant"},
+		RoleChange{ID: primitive.NewObjectID(), TargetID: beta, Role: policy.RoleEditor, Action: "grant"},
+		RoleChange{ID: primitive.NewObjectID(), TargetID: alpha, Role: policy.RoleEditor, Action: "revoke"},
+	} {
+		if err := roleAudit.Insert(ctx, c); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+
+	changes, err := roleAudit.List(ctx, alpha, 10)
+	if assert.NoError(t, err) && assert.Len(t, changes, 2) {
+		assert.Equal(t, "revoke", changes[0].Action)
+	}
+	changes, err = roleAudit.List(ctx, primitive.ObjectID{}, 2)
+	if assert.NoError(t, err) && assert.Len(t, changes, 2) {
+		assert.Equal(t, beta, changes[1].TargetID)
+	}
+}
+
+// testPosts checks the behaviour every Posts implementation shares
+func testPosts(t *testing.T, posts Posts) {
+	ctx := context.Background()
+
+	// mongo keeps milliseconds
+	created := time.Now().UTC().Truncate(time.Millisecond)
+	post := Post{
+		ID:        primitive.NewObjectID(),
+		AuthorID:  primitive.NewObjectID(),
+		Title:     "Store post",
+		Body:      "# Store",
+		BodyHTML:  `<h1 id="store">Store</h1>`,
+		TOC:       []render.Heading{render.Heading{Level: 1, ID: "store", Text: "Store"}},
+		CreatedAt: created,
+		UpdatedAt: created,
+		Revision:  1,
+	}
+	if err := posts.Insert(ctx, post); !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	found, err := posts.FindByID(ctx, post.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, post, found)
+	_, err = posts.FindByID(ctx, primitive.NewObjectID())
+	assert.Equal(t, ErrNotFound, err)
+	listed, err := posts.FindByIDs(ctx, []primitive.ObjectID{primitive.NewObjectID(), post.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []Post{post}, listed)
+
+	// only the content changes, whatever else the update carries
+	updated, err := posts.UpdateContent(ctx, Post{
+		ID:        post.ID,
+		AuthorID:  primitive.NewObjectID(),
+		Title:     "Updated post",
+		Body:      "Updated",
+		BodyHTML:  "<p>Updated</p>",
+		TOC:       []render.Heading{},
+		UpdatedAt: created.Add(time.Minute),
+		Tags:      []string{"go", "grpc"},
+		Category:  "backend",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, post.AuthorID, updated.AuthorID)
+	assert.Equal(t, created, updated.CreatedAt)
+	assert.Equal(t, "Updated post", updated.Title)
+	assert.Equal(t, "<p>Updated</p>", updated.BodyHTML)
+	assert.Empty(t, updated.TOC)
+	assert.Equal(t, created.Add(time.Minute), updated.UpdatedAt)
+	assert.Equal(t, 2, updated.Revision)
+	assert.Equal(t, []string{"go", "grpc"}, updated.Tags)
+	assert.Equal(t, "backend", updated.Category)
+
+	_, err = posts.UpdateContent(ctx, Post{ID: primitive.NewObjectID()})
+	assert.Equal(t, ErrNotFound, err)
+
+	// state changes apply only from the state the caller saw
+	publishAt := created.Add(time.Hour)
+	scheduled, err := posts.SetState(ctx, "", Post{ID: post.ID, State: PostScheduled, PublishAt: publishAt, UpdatedAt: created})
+	assert.NoError(t, err)
+	assert.Equal(t, PostScheduled, scheduled.State)
+	assert.Equal(t, publishAt, scheduled.PublishAt)
+	assert.Equal(t, "Updated post", scheduled.Title)
+	_, err = posts.SetState(ctx, PostDraft, Post{ID: post.ID, State: PostPublished})
+	assert.Equal(t, ErrConflict, err)
+	_, err = posts.SetState(ctx, PostDraft, Post{ID: primitive.NewObjectID(), State: PostPublished})
+	assert.Equal(t, ErrNotFound, err)
+
+	// reaction counts add up under concurrent updates
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := posts.CountReaction(ctx, post.ID, ReactionLike, 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	_, err = posts.CountReaction(ctx, post.ID, ReactionLike, -1)
+	assert.NoError(t, err)
+	counted, err := posts.CountReaction(ctx, post.ID, ReactionBookmark, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), counted.Likes)
+	assert.Equal(t, int64(1), counted.Bookmarks)
+	assert.Equal(t, "Updated post", counted.Title)
+	_, err = posts.CountReaction(ctx, primitive.NewObjectID(), ReactionLike, 1)
+	assert.Equal(t, ErrNotFound, err)
+
+	deleted, err := posts.Delete(ctx, post.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, counted, deleted)
+	_, err = posts.FindByID(ctx, post.ID)
+	assert.Equal(t, ErrNotFound, err)
+	_, err = posts.Delete(ctx, post.ID)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+// testPublishDue checks the scheduling every Posts implementation shares
+func testPublishDue(t *testing.T, posts Posts) {
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	first := Post{ID: primitive.NewObjectID(), State: PostScheduled, PublishAt: now.Add(-2 * time.Minute)}
+	// republished posts keep their first publication date
+	second := Post{ID: primitive.NewObjectID(), State: PostScheduled, PublishAt: now.Add(-time.Minute), PublishedAt: now.Add(-time.Hour)}
+	later := Post{ID: primitive.NewObjectID(), State: PostScheduled, PublishAt: now.Add(time.Minute)}
+	draft := Post{ID: primitive.NewObjectID(), State: PostDraft}
+	for _, p := range []Post{later, second, draft, first} {
+		if err := posts.Insert(ctx, p); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+
+	published, err := posts.PublishDue(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, published.ID)
+	assert.Equal(t, PostPublished, published.State)
+	assert.Equal(t, first.PublishAt, published.PublishedAt)
+	assert.True(t, published.PublishAt.IsZero())
+	assert.Equal(t, now, published.UpdatedAt)
+
+	published, err = posts.PublishDue(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, second.ID, published.ID)
+	assert.Equal(t, second.PublishedAt, published.PublishedAt)
+
+	_, err = posts.PublishDue(ctx, now)
+	assert.Equal(t, ErrNotFound, err)
+
+	found, _ := posts.FindByID(ctx, later.ID)
+	assert.Equal(t, PostScheduled, found.State)
+}
+
+// testPublishDueConcurrently checks that every due post is published exactly once
+func testPublishDueConcurrently(t *testing.T, posts Posts) {
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	for i := 0; i < 20; i++ {
+		posts.Insert(ctx, Post{ID: primitive.NewObjectID(), State: PostScheduled, PublishAt: now.Add(-time.Minute)})
+	}
+
+	var mu sync.Mutex
+	seen := map[primitive.ObjectID]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				post, err := posts.PublishDue(ctx, now)
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				seen[post.ID]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, 20)
+	for id, count := range seen {
+		assert.Equalf(t, 1, count, "post %s", id.Hex())
+	}
+}
+
+func Test_memoryUsers(t *testing.T) {
+	testUsers(t, NewMemoryUsers())
+
+	// stored records are not shared with callers
+	users := NewMemoryUsers()
+	user := global.User{ID: primitive.NewObjectID(), Roles: []policy.Role{policy.RoleEditor}}
+	users.Insert(context.Background(), user)
+	user.Roles[0] = policy.RoleAdmin
+	found, _ := users.FindByID(context.Background(), user.ID)
+	assert.Equal(t, []policy.Role{policy.RoleEditor}, found.Roles)
+
+	assert.Error(t, users.Insert(context.Background(), user))
+}
+
+func Test_memoryRoleAudit(t *testing.T) {
+	testRoleAudit(t, NewMemoryRoleAudit())
+}
+
+// testPostList checks the listing and tagging every Posts implementation shares
+func testPostList(t *testing.T, posts Posts) {
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	author := primitive.NewObjectID()
+	older := Post{ID: primitive.NewObjectID(), AuthorID: author, State: PostPublished, PublishedAt: now.Add(-time.Hour), Tags: []string{"go", "db"}, Category: "backend"}
+	newer := Post{ID: primitive.NewObjectID(), State: PostPublished, PublishedAt: now, Tags: []string{"go"}, Category: "frontend"}
+	draft := Post{ID: primitive.NewObjectID(), AuthorID: author, State: PostDraft, Tags: []string{"go"}, Category: "backend"}
+	lastID := primitive.ObjectID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	for _, p := range []Post{older, draft, newer} {
+		if err := posts.Insert(ctx, p); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"filter": PostFilter{}, "ids": []primitive.ObjectID{newer.ID, older.ID}, "total": int64(2)},
+		map[string]interface{}{"filter": PostFilter{Tag: "go"}, "ids": []primitive.ObjectID{newer.ID, older.ID}, "total": int64(2)},
+		map[string]interface{}{"filter": PostFilter{Tag: "go"}, "skip": int64(1), "ids": []primitive.ObjectID{older.ID}, "total": int64(2)},
+		map[string]interface{}{"filter": PostFilter{Tag: "go"}, "limit": int64(1), "ids": []primitive.ObjectID{newer.ID}, "total": int64(2)},
+		map[string]interface{}{"filter": PostFilter{Tag: "db"}, "ids": []primitive.ObjectID{older.ID}, "total": int64(1)},
+		map[string]interface{}{"filter": PostFilter{Tag: "rust"}, "ids": []primitive.ObjectID{}, "total": int64(0)},
+		map[string]interface{}{"filter": PostFilter{Categories: []string{"backend"}}, "ids": []primitive.ObjectID{older.ID}, "total": int64(1)},
+		map[string]interface{}{"filter": PostFilter{Categories: []string{"backend", "frontend"}}, "ids": []primitive.ObjectID{newer.ID, older.ID}, "total": int64(2)},
+		map[string]interface{}{"filter": PostFilter{Categories: []string{}}, "ids": []primitive.ObjectID{}, "total": int64(0)},
+		map[string]interface{}{"filter": PostFilter{Authors: []primitive.ObjectID{author}}, "ids": []primitive.ObjectID{older.ID}, "total": int64(1)},
+		map[string]interface{}{"filter": PostFilter{Authors: []primitive.ObjectID{author, newer.AuthorID}}, "ids": []primitive.ObjectID{newer.ID, older.ID}, "total": int64(2)},
+		map[string]interface{}{"filter": PostFilter{Authors: []primitive.ObjectID{}}, "ids": []primitive.ObjectID{}, "total": int64(0)},
+		map[string]interface{}{"filter": PostFilter{Tag: "go", After: &PostKey{PublishedAt: newer.PublishedAt, ID: newer.ID}}, "ids": []primitive.ObjectID{older.ID}, "total": int64(2)},
+		map[string]interface{}{"filter": PostFilter{After: &PostKey{PublishedAt: older.PublishedAt, ID: older.ID}}, "ids": []primitive.ObjectID{}, "total": int64(2)},
+		// posts published at the same time are ordered by id
+		map[string]interface{}{"filter": PostFilter{After: &PostKey{PublishedAt: now, ID: lastID}}, "ids": []primitive.ObjectID{newer.ID, older.ID}, "total": int64(2)},
+		map[string]interface{}{"filter": PostFilter{After: &PostKey{PublishedAt: now}}, "ids": []primitive.ObjectID{older.ID}, "total": int64(2)},
+		map[string]interface{}{"filter": PostFilter{After: &PostKey{PublishedAt: now}}, "skip": int64(2), "ids": []primitive.ObjectID{}, "total": int64(2)},
+	}
+
+	for _, tcase := range testCases {
+		filter := tcase["filter"].(PostFilter)
+		skip, _ := tcase["skip"].(int64)
+		limit, _ := tcase["limit"].(int64)
+		found, total, err := posts.List(ctx, filter, skip, limit)
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		ids := []primitive.ObjectID{}
+		for _, p := range found {
+			ids = append(ids, p.ID)
+		}
+		assert.Equalf(t, tcase["ids"], ids, "case: %v", tcase)
+		assert.Equalf(t, tcase["total"], total, "case: %v", tcase)
+
+		count, err := posts.Count(ctx, filter)
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		assert.Equalf(t, tcase["total"], count, "case: %v", tcase)
+	}
+
+	// merging into a tag the post already holds keeps one of it
+	assert.NoError(t, posts.ReplaceTag(ctx, "go", "golang"))
+	assert.NoError(t, posts.ReplaceTag(ctx, "db", "golang"))
+	stored, _ := posts.FindByID(ctx, older.ID)
+	assert.Equal(t, []string{"golang"}, stored.Tags)
+	stored, _ = posts.FindByID(ctx, draft.ID)
+	assert.Equal(t, []string{"golang"}, stored.Tags)
+	count, _ := posts.Count(ctx, PostFilter{Tag: "go"})
+	assert.Equal(t, int64(0), count)
+
+	// posts stored before the lifecycle existed are published
+	legacy := Post{ID: primitive.NewObjectID(), PublishedAt: now, Tags: []string{"legacy"}}
+	assert.NoError(t, posts.Insert(ctx, legacy))
+	found, total, err := posts.List(ctx, PostFilter{Tag: "legacy"}, 0, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, legacy.ID, found[0].ID)
+	}
+	assert.Equal(t, int64(1), total)
+}
+
+// testTags checks the behaviour every Tags implementation shares
+func testTags(t *testing.T, tags Tags) {
+	ctx := context.Background()
+
+	assert.NoError(t, tags.Ensure(ctx, Tag{Slug: "go", Name: "Go"}))
+	// the first spelling stays
+	assert.NoError(t, tags.Ensure(ctx, Tag{Slug: "go", Name: "GO"}))
+	assert.NoError(t, tags.Ensure(ctx, Tag{Slug: "grpc", Name: "gRPC"}))
+	assert.NoError(t, tags.Ensure(ctx, Tag{Slug: "unused", Name: "Unused"}))
+
+	found, err := tags.Find(ctx, "go")
+	assert.NoError(t, err)
+	assert.Equal(t, Tag{Slug: "go", Name: "Go"}, found)
+	_, err = tags.Find(ctx, "rust")
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.NoError(t, tags.SetCount(ctx, "go", 2))
+	assert.NoError(t, tags.SetCount(ctx, "grpc", 5))
+	assert.Equal(t, ErrNotFound, tags.SetCount(ctx, "rust", 1))
+	assert.NoError(t, tags.Rename(ctx, "grpc", "GRPC"))
+	assert.Equal(t, ErrNotFound, tags.Rename(ctx, "rust", "Rust"))
+
+	// unused tags are left out
+	listed, err := tags.List(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{Tag{Slug: "grpc", Name: "GRPC", PostCount: 5}, Tag{Slug: "go", Name: "Go", PostCount: 2}}, listed)
+
+	assert.NoError(t, tags.Delete(ctx, "go"))
+	assert.Equal(t, ErrNotFound, tags.Delete(ctx, "go"))
+	_, err = tags.Find(ctx, "go")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+// testCategories checks the behaviour every Categories implementation shares
+func testCategories(t *testing.T, categories Categories) {
+	ctx := context.Background()
+
+	backend := Category{Slug: "backend", Name: "Backend"}
+	golang := Category{Slug: "go", Name: "Go", Parent: "backend"}
+	assert.NoError(t, categories.Insert(ctx, golang))
+	assert.NoError(t, categories.Insert(ctx, backend))
+	assert.Equal(t, ErrConflict, categories.Insert(ctx, Category{Slug: "go", Name: "Golang"}))
+
+	found, err := categories.Find(ctx, "go")
+	assert.NoError(t, err)
+	assert.Equal(t, golang, found)
+	_, err = categories.Find(ctx, "rust")
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.NoError(t, categories.SetCount(ctx, "backend", 3))
+	assert.Equal(t, ErrNotFound, categories.SetCount(ctx, "rust", 1))
+	backend.PostCount = 3
+
+	listed, err := categories.List(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []Category{backend, golang}, listed)
+}
+
+func Test_memoryTags(t *testing.T) {
+	testTags(t, NewMemoryTags())
+}
+
+func Test_memoryCategories(t *testing.T) {
+	testCategories(t, NewMemoryCategories())
+}
+
+// testRevisions checks the behaviour every Revisions implementation shares,
+// revisions must keep the last 3 revisions of a post
+func testRevisions(t *testing.T, revisions Revisions) {
+	ctx := context.Background()
+
+	created := time.Now().UTC().Truncate(time.Millisecond)
+	postID, otherID := primitive.NewObjectID(), primitive.NewObjectID()
+	for number := 1; number <= 5; number++ {
+		revision := Revision{
+			ID:        primitive.NewObjectID(),
+			PostID:    postID,
+			Number:    number,
+			AuthorID:  primitive.NewObjectID(),
+			Title:     fmt.Sprintf("Revision %d", number),
+			Body:      fmt.Sprintf("Body %d", number),
+			CreatedAt: created.Add(time.Duration(number) * time.Minute),
+		}
+		if err := revisions.Insert(ctx, revision); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+	other := Revision{ID: primitive.NewObjectID(), PostID: otherID, Number: 1, Title: "Other", Body: "Other", CreatedAt: created}
+	assert.NoError(t, revisions.Insert(ctx, other))
+
+	// newest first, the oldest beyond the limit are gone
+	listed, err := revisions.List(ctx, postID)
+	assert.NoError(t, err)
+	var numbers []int
+	for _, r := range listed {
+		numbers = append(numbers, r.Number)
+		assert.Empty(t, r.Body)
+	}
+	assert.Equal(t, []int{5, 4, 3}, numbers)
+
+	found, err := revisions.Find(ctx, postID, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "Body 4", found.Body)
+	assert.Equal(t, created.Add(4*time.Minute), found.CreatedAt)
+	_, err = revisions.Find(ctx, postID, 2)
+	assert.Equal(t, ErrNotFound, err)
+
+	found, err = revisions.Find(ctx, otherID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, other, found)
+
+	listed, err = revisions.List(ctx, primitive.NewObjectID())
+	assert.NoError(t, err)
+	assert.Empty(t, listed)
+
+	// deleting the history of a post leaves the others alone
+	assert.NoError(t, revisions.DeletePost(ctx, postID))
+	listed, err = revisions.List(ctx, postID)
+	assert.NoError(t, err)
+	assert.Empty(t, listed)
+	_, err = revisions.Find(ctx, otherID, 1)
+	assert.NoError(t, err)
+}
+
+func Test_memoryRevisions(t *testing.T) {
+	testRevisions(t, NewMemoryRevisions(3))
+}
+
+func Test_memoryPosts(t *testing.T) {
+	testPosts(t, NewMemoryPosts())
+	testPostList(t, NewMemoryPosts())
+	testPublishDue(t, NewMemoryPosts())
+	testPublishDueConcurrently(t, NewMemoryPosts())
+}
+
+// testFollows checks the behaviour every Follows implementation shares
+func testFollows(t *testing.T, follows Follows) {
+	ctx := context.Background()
+
+	alice, bob, carol := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	created := time.Now().UTC().Truncate(time.Millisecond)
+	all := []Follow{
+		Follow{ID: primitive.NewObjectID(), FollowerID: alice, FolloweeID: carol, CreatedAt: created},
+		Follow{ID: primitive.NewObjectID(), FollowerID: bob, FolloweeID: carol, CreatedAt: created},
+		Follow{ID: primitive.NewObjectID(), FollowerID: alice, FolloweeID: bob, CreatedAt: created},
+	}
+	for _, f := range all {
+		if err := follows.Insert(ctx, f); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+	assert.Equal(t, ErrConflict, follows.Insert(ctx, Follow{ID: primitive.NewObjectID(), FollowerID: alice, FolloweeID: carol, CreatedAt: created}))
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"filter": FollowFilter{FolloweeID: carol}, "follows": []Follow{all[1], all[0]}, "total": int64(2)},
+		map[string]interface{}{"filter": FollowFilter{FollowerID: alice}, "follows": []Follow{all[2], all[0]}, "total": int64(2)},
+		map[string]interface{}{"filter": FollowFilter{FollowerID: alice, FolloweeID: bob}, "follows": []Follow{all[2]}, "total": int64(1)},
+		map[string]interface{}{"filter": FollowFilter{FollowerID: carol}, "follows": []Follow{}, "total": int64(0)},
+		map[string]interface{}{"filter": FollowFilter{FollowerID: alice}, "limit": int64(1), "follows": []Follow{all[2]}, "total": int64(2)},
+		map[string]interface{}{"filter": FollowFilter{FollowerID: alice, After: all[2].ID}, "follows": []Follow{all[0]}, "total": int64(2)},
+		map[string]interface{}{"filter": FollowFilter{FollowerID: alice, After: all[2].ID}, "skip": int64(2), "follows": []Follow{}, "total": int64(2)},
+	}
+
+	for _, tcase := range testCases {
+		skip, _ := tcase["skip"].(int64)
+		limit, _ := tcase["limit"].(int64)
+		listed, total, err := follows.List(ctx, tcase["filter"].(FollowFilter), skip, limit)
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		assert.Equalf(t, tcase["follows"], listed, "case: %v", tcase)
+		assert.Equalf(t, tcase["total"], total, "case: %v", tcase)
+	}
+
+	deleted, err := follows.Delete(ctx, alice, carol)
+	assert.NoError(t, err)
+	assert.Equal(t, all[0], deleted)
+	_, err = follows.Delete(ctx, alice, carol)
+	assert.Equal(t, ErrNotFound, err)
+	_, total, _ := follows.List(ctx, FollowFilter{FolloweeID: carol}, 0, 0)
+	assert.Equal(t, int64(1), total)
+
+	// following again after unfollowing is allowed
+	assert.NoError(t, follows.Insert(ctx, Follow{ID: primitive.NewObjectID(), FollowerID: alice, FolloweeID: carol, CreatedAt: created}))
+}
+
+// testTimelines checks the behaviour every Timelines implementation shares
+func testTimelines(t *testing.T, timelines Timelines) {
+	ctx := context.Background()
+
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+	author, other := primitive.NewObjectID(), primitive.NewObjectID()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	older := TimelineEntry{UserID: alice, PostID: primitive.NewObjectID(), AuthorID: author, PublishedAt: now.Add(-time.Hour)}
+	newer := TimelineEntry{UserID: alice, PostID: primitive.NewObjectID(), AuthorID: other, PublishedAt: now}
+	tied := TimelineEntry{UserID: alice, PostID: primitive.NewObjectID(), AuthorID: author, PublishedAt: now}
+	bobs := newer
+	bobs.UserID = bob
+	if err := timelines.Add(ctx, []TimelineEntry{older, newer, bobs}); !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	// adding again replaces the entry
+	if err := timelines.Add(ctx, []TimelineEntry{tied, older}); !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, timelines.Add(ctx, nil))
+
+	list := func(user primitive.ObjectID, after *PostKey, limit int64) ([]TimelineEntry, int64) {
+		entries, total, err := timelines.List(ctx, user, after, limit)
+		assert.NoError(t, err)
+		return entries, total
+	}
+
+	entries, total := list(alice, nil, 0)
+	assert.Equal(t, []TimelineEntry{tied, newer, older}, entries)
+	assert.Equal(t, int64(3), total)
+	entries, total = list(alice, nil, 2)
+	assert.Equal(t, []TimelineEntry{tied, newer}, entries)
+	assert.Equal(t, int64(3), total)
+	last := newer.Key()
+	entries, _ = list(alice, &last, 2)
+	assert.Equal(t, []TimelineEntry{older}, entries)
+	entries, total = list(primitive.NewObjectID(), nil, 0)
+	assert.Empty(t, entries)
+	assert.Equal(t, int64(0), total)
+
+	assert.NoError(t, timelines.RemovePost(ctx, newer.PostID))
+	entries, _ = list(alice, nil, 0)
+	assert.Equal(t, []TimelineEntry{tied, older}, entries)
+	entries, _ = list(bob, nil, 0)
+	assert.Empty(t, entries)
+
+	assert.NoError(t, timelines.RemoveAuthor(ctx, alice, author))
+	entries, total = list(alice, nil, 0)
+	assert.Empty(t, entries)
+	assert.Equal(t, int64(0), total)
+}
+
+func Test_memoryFollows(t *testing.T) {
+	testFollows(t, NewMemoryFollows())
+}
+
+func Test_memoryTimelines(t *testing.T) {
+	testTimelines(t, NewMemoryTimelines())
+}
+
+// testReactions checks the behaviour every Reactions implementation shares
+func testReactions(t *testing.T, reactions Reactions) {
+	ctx := context.Background()
+
+	alice, bob, post, other := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	created := time.Now().UTC().Truncate(time.Millisecond)
+	all := []Reaction{
+		Reaction{ID: primitive.NewObjectID(), Kind: ReactionLike, UserID: alice, PostID: post, CreatedAt: created},
+		Reaction{ID: primitive.NewObjectID(), Kind: ReactionBookmark, UserID: alice, PostID: post, CreatedAt: created},
+		Reaction{ID: primitive.NewObjectID(), Kind: ReactionLike, UserID: bob, PostID: post, CreatedAt: created},
+		Reaction{ID: primitive.NewObjectID(), Kind: ReactionBookmark, UserID: alice, PostID: other, CreatedAt: created},
+	}
+	for _, r := range all {
+		if err := reactions.Insert(ctx, r); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+	assert.Equal(t, ErrConflict, reactions.Insert(ctx, Reaction{ID: primitive.NewObjectID(), Kind: ReactionLike, UserID: alice, PostID: post, CreatedAt: created}))
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"filter": ReactionFilter{Kind: ReactionLike, PostID: post}, "reactions": []Reaction{all[2], all[0]}, "total": int64(2)},
+		map[string]interface{}{"filter": ReactionFilter{Kind: ReactionBookmark, UserID: alice}, "reactions": []Reaction{all[3], all[1]}, "total": int64(2)},
+		map[string]interface{}{"filter": ReactionFilter{UserID: alice}, "limit": int64(2), "reactions": []Reaction{all[3], all[1]}, "total": int64(3)},
+		map[string]interface{}{"filter": ReactionFilter{UserID: alice, After: all[1].ID}, "reactions": []Reaction{all[0]}, "total": int64(3)},
+		map[string]interface{}{"filter": ReactionFilter{Kind: ReactionBookmark, UserID: bob}, "reactions": []Reaction{}, "total": int64(0)},
+	}
+
+	for _, tcase := range testCases {
+		limit, _ := tcase["limit"].(int64)
+		listed, total, err := reactions.List(ctx, tcase["filter"].(ReactionFilter), 0, limit)
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		assert.Equalf(t, tcase["reactions"], listed, "case: %v", tcase)
+		assert.Equalf(t, tcase["total"], total, "case: %v", tcase)
+	}
+
+	deleted, err := reactions.Delete(ctx, ReactionLike, alice, post)
+	assert.NoError(t, err)
+	assert.Equal(t, all[0], deleted)
+	_, err = reactions.Delete(ctx, ReactionLike, alice, post)
+	assert.Equal(t, ErrNotFound, err)
+
+	// of concurrent inserts of the same reaction, one goes through
+	var mu sync.Mutex
+	inserted := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := reactions.Insert(ctx, Reaction{ID: primitive.NewObjectID(), Kind: ReactionLike, UserID: alice, PostID: post, CreatedAt: created})
+			if err == ErrConflict {
+				return
+			}
+			assert.NoError(t, err)
+			mu.Lock()
+			inserted++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, inserted)
+
+	// deleting the reactions to a post leaves the others alone
+	assert.NoError(t, reactions.DeletePost(ctx, post))
+	listed, total, err := reactions.List(ctx, ReactionFilter{UserID: alice}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []Reaction{all[3]}, listed)
+	assert.Equal(t, int64(1), total)
+}
+
+func Test_memoryReactions(t *testing.T) {
+	testReactions(t, NewMemoryReactions())
+}
+
+// testNotifications checks the behaviour every Notifications implementation shares
+func testNotifications(t *testing.T, notifications Notifications) {
+	ctx := context.Background()
+
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+	created := time.Now().UTC().Truncate(time.Millisecond)
+	all := []Notification{
+		Notification{ID: primitive.NewObjectID(), UserID: alice, Kind: "follow", ActorID: bob, ActorUsername: "bob", CreatedAt: created},
+		Notification{ID: primitive.NewObjectID(), UserID: alice, Kind: "like", ActorID: bob, ActorUsername: "bob", PostID: primitive.NewObjectID(), PostTitle: "Hello", CreatedAt: created},
+		Notification{ID: primitive.NewObjectID(), UserID: bob, Kind: "follow", ActorID: alice, ActorUsername: "alice", CreatedAt: created},
+		Notification{ID: primitive.NewObjectID(), UserID: alice, Kind: "follow", ActorID: primitive.NewObjectID(), ActorUsername: "carol", CreatedAt: created},
+	}
+	for _, n := range all {
+		if err := notifications.Insert(ctx, n); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+	assert.Equal(t, ErrConflict, notifications.Insert(ctx, all[0]))
+
+	// reading all of them but the latest one of alice
+	read := created.Add(time.Minute)
+	marked, err := notifications.MarkRead(ctx, alice, []primitive.ObjectID{all[0].ID, all[1].ID, all[2].ID}, read)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), marked, "the notifications of others are left alone")
+	marked, err = notifications.MarkRead(ctx, alice, []primitive.ObjectID{all[0].ID}, read.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), marked, "read notifications stay read when they were")
+	all[0].ReadAt, all[1].ReadAt = read, read
+	assert.NoError(t, notifications.MarkDigested(ctx, bob, all[2].ID, read))
+	all[2].DigestedAt = read
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{"filter": NotificationFilter{UserID: alice}, "notifications": []Notification{all[3], all[1], all[0]}, "total": int64(3)},
+		map[string]interface{}{"filter": NotificationFilter{UserID: alice, Unread: true}, "notifications": []Notification{all[3]}, "total": int64(1)},
+		map[string]interface{}{"filter": NotificationFilter{UserID: alice}, "limit": int64(2), "notifications": []Notification{all[3], all[1]}, "total": int64(3)},
+		map[string]interface{}{"filter": NotificationFilter{UserID: alice, After: all[1].ID}, "notifications": []Notification{all[0]}, "total": int64(3)},
+		map[string]interface{}{"filter": NotificationFilter{Unread: true, Undigested: true}, "notifications": []Notification{all[3]}, "total": int64(1)},
+		map[string]interface{}{"filter": NotificationFilter{Unread: true}, "notifications": []Notification{all[3], all[2]}, "total": int64(2)},
+	}
+
+	for _, tcase := range testCases {
+		limit, _ := tcase["limit"].(int64)
+		listed, total, err := notifications.List(ctx, tcase["filter"].(NotificationFilter), 0, limit)
+		assert.NoErrorf(t, err, "case: %v", tcase)
+		assert.Equalf(t, tcase["notifications"], listed, "case: %v", tcase)
+		assert.Equalf(t, tcase["total"], total, "case: %v", tcase)
+	}
+
+	// digests tell about the notifications up to the latest they listed
+	assert.NoError(t, notifications.MarkDigested(ctx, alice, all[1].ID, read))
+	undigested, _, err := notifications.List(ctx, NotificationFilter{UserID: alice, Undigested: true}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []Notification{all[3]}, undigested)
+
+	// no ids marks everything
+	marked, err = notifications.MarkRead(ctx, bob, nil, read)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), marked)
+	_, total, err := notifications.List(ctx, NotificationFilter{Unread: true}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
+
+func Test_memoryNotifications(t *testing.T) {
+	testNotifications(t, NewMemoryNotifications())
+}
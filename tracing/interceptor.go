@@ -0,0 +1,128 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadataCarrier adapts grpc metadata to the propagation API.
+// grpc-web requests arrive with their HTTP headers, traceparent included, as metadata.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// UnaryServerInterceptor continues the caller's trace, if any, and wraps every call in a server span
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md.Copy()))
+
+		ctx, span := tracer().Start(ctx, strings.TrimPrefix(info.FullMethod, "/"),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(rpcAttributes(info.FullMethod)...),
+		)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		endRPCSpan(span, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor does for streams what UnaryServerInterceptor does
+// for unary calls, the span lasts as long as the stream
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		ctx := otel.GetTextMapPropagator().Extract(ss.Context(), metadataCarrier(md.Copy()))
+
+		ctx, span := tracer().Start(ctx, strings.TrimPrefix(info.FullMethod, "/"),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(rpcAttributes(info.FullMethod)...),
+		)
+		defer span.End()
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		endRPCSpan(span, err)
+		return err
+	}
+}
+
+// serverStream is a stream with the context of the span
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// UnaryClientInterceptor wraps outgoing calls in a client span and propagates
+// the trace to the callee, so service to service calls share one trace
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := tracer().Start(ctx, strings.TrimPrefix(method, "/"),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(rpcAttributes(method)...),
+		)
+		defer span.End()
+
+		md, ok := metadata.FromOutgoingContext(ctx)
+		if ok {
+			md = md.Copy()
+		} else {
+			md = metadata.MD{}
+		}
+		otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+
+		err := invoker(metadata.NewOutgoingContext(ctx, md), method, req, reply, cc, opts...)
+		endRPCSpan(span, err)
+		return err
+	}
+}
+
+// rpcAttributes splits "/proto.AuthService/Login" into service and method attributes
+func rpcAttributes(fullMethod string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.RPCSystemGRPC}
+	parts := strings.SplitN(strings.TrimPrefix(fullMethod, "/"), "/", 2)
+	if len(parts) == 2 {
+		attrs = append(attrs, semconv.RPCServiceKey.String(parts[0]), semconv.RPCMethodKey.String(parts[1]))
+	}
+	return attrs
+}
+
+func endRPCSpan(span trace.Span, err error) {
+	s, _ := status.FromError(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(s.Code())))
+	if err != nil {
+		span.SetStatus(codes.Error, s.Message())
+	}
+}
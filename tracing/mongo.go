@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/HiteshRepo/blog-application/database"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MongoMonitor returns a command monitor that records a client span for every
+// mongo command, as a child of the span found in the operation's context
+func MongoMonitor() *event.CommandMonitor {
+	var spans sync.Map
+
+	end := func(requestID int64, failure string) {
+		value, ok := spans.LoadAndDelete(requestID)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		if failure != "" {
+			span.SetStatus(codes.Error, failure)
+		}
+		span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			collection := database.CommandCollection(e.CommandName, e.Command)
+			_, span := tracer().Start(ctx, "mongo."+e.CommandName,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					semconv.DBSystemMongoDB,
+					semconv.DBNameKey.String(e.DatabaseName),
+					semconv.DBOperationKey.String(e.CommandName),
+					semconv.DBMongoDBCollectionKey.String(collection),
+				),
+			)
+			spans.Store(e.RequestID, span)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			end(e.RequestID, "")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			end(e.RequestID, e.Failure)
+		},
+	}
+}
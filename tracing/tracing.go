@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/HiteshRepo/blog-application/tracing"
+
+// Exporters accepted by Setup
+const (
+	ExporterNone   = ""
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Setup installs the global tracer provider and the W3C trace context propagator.
+// The otlp exporter sends spans over HTTP to OTEL_EXPORTER_OTLP_ENDPOINT (localhost:4318 by default),
+// the stdout exporter prints them for local use and no exporter leaves tracing disabled.
+// The returned function flushes pending spans and must be called before exiting.
+func Setup(ctx context.Context, serviceName, exporter string) (func(context.Context) error, error) {
+	// propagate even when not exporting, so that traces started by clients survive this hop
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if os.Getenv("OTEL_EXPORTER_OTLP_INSECURE") == "true" {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		spanExporter, err = otlptracehttp.New(ctx, opts...)
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, errors.New("Unknown trace exporter : " + exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
@@ -0,0 +1,131 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	_, err := Setup(context.Background(), "test", ExporterNone)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return recorder
+}
+
+func Test_UnaryServerInterceptor(t *testing.T) {
+
+	recorder := setupRecorder(t)
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.AuthService/Login"}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"traceparent": traceparent,
+			"err":         nil,
+		},
+		map[string]interface{}{
+			"traceparent": "",
+			"err":         errors.New("Invalid login credentials provided"),
+		},
+	}
+
+	for _, tcase := range testCases {
+
+		ctx := context.Background()
+		if tp := tcase["traceparent"].(string); tp != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("traceparent", tp))
+		}
+
+		var handlerSpan trace.SpanContext
+		err, _ := tcase["err"].(error)
+		interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			handlerSpan = trace.SpanContextFromContext(ctx)
+			return nil, err
+		})
+
+		spans := recorder.Ended()
+		span := spans[len(spans)-1]
+		assert.Equalf(t, "proto.AuthService/Login", span.Name(), "case: %v", tcase)
+		assert.Equalf(t, trace.SpanKindServer, span.SpanKind(), "case: %v", tcase)
+		assert.Equalf(t, span.SpanContext().SpanID(), handlerSpan.SpanID(), "case: %v", tcase)
+
+		if tcase["traceparent"] != "" {
+			assert.Equalf(t, traceID, span.SpanContext().TraceID().String(), "case: %v", tcase)
+			assert.Truef(t, span.Parent().IsRemote(), "case: %v", tcase)
+		}
+		if err != nil {
+			assert.Equalf(t, codes.Error, span.Status().Code, "case: %v", tcase)
+		}
+	}
+}
+
+func Test_UnaryClientInterceptor(t *testing.T) {
+
+	recorder := setupRecorder(t)
+	parent, parentSpan := otel.Tracer("test").Start(context.Background(), "parent")
+
+	var sent metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	err := UnaryClientInterceptor()(parent, "/proto.BlogService/GetPost", nil, nil, nil, invoker)
+	parentSpan.End()
+
+	assert.NoError(t, err)
+	if assert.Len(t, sent.Get("traceparent"), 1) {
+		assert.Contains(t, sent.Get("traceparent")[0], parentSpan.SpanContext().TraceID().String())
+	}
+	assert.Len(t, recorder.Ended(), 2)
+}
+
+func Test_MongoMonitor(t *testing.T) {
+
+	recorder := setupRecorder(t)
+	monitor := MongoMonitor()
+
+	ctx, rpc := otel.Tracer("test").Start(context.Background(), "rpc")
+	find, _ := bson.Marshal(bson.D{{Key: "find", Value: "user"}})
+
+	monitor.Started(ctx, &event.CommandStartedEvent{Command: find, CommandName: "find", DatabaseName: "blog-application", RequestID: 7})
+	monitor.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 7}, Failure: "timeout"})
+	rpc.End()
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "mongo.find", spans[0].Name())
+		assert.Equal(t, rpc.SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	}
+}
+
+func Test_Setup(t *testing.T) {
+
+	_, err := Setup(context.Background(), "test", "zipkin")
+	assert.Error(t, err)
+
+	shutdown, err := Setup(context.Background(), "test", ExporterStdout)
+	if assert.NoError(t, err) {
+		assert.NoError(t, shutdown(context.Background()))
+	}
+}
@@ -0,0 +1,207 @@
+// Package validate checks requests against the FieldRules declared on
+// their fields in the .proto definitions.
+package validate
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/HiteshRepo/blog-application/global"
+	"github.com/HiteshRepo/blog-application/proto"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var emailRegex = regexp.MustCompile(global.EmailRegex)
+
+// formats are the values FieldRules.Format may take
+var formats = map[string]struct {
+	valid       func(string) bool
+	description string
+}{
+	"email":     {emailRegex.MatchString, "a valid email"},
+	"object_id": {func(s string) bool { _, err := primitive.ObjectIDFromHex(s); return err == nil }, "a valid id"},
+	"url":       {validURL, "a valid http or https url"},
+}
+
+// validURL accepts absolute http and https urls
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Violation is a field breaking one of its rules
+type Violation struct {
+	Field       string
+	Description string
+}
+
+func (v Violation) String() string {
+	return v.Field + " " + v.Description
+}
+
+// Check returns the violations of msg, in field order
+func Check(msg protobuf.Message) []Violation {
+	return check(msg.ProtoReflect(), "")
+}
+
+func check(msg protoreflect.Message, prefix string) []Violation {
+	var violations []Violation
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		name := prefix + string(field.Name())
+
+		if field.Kind() == protoreflect.MessageKind && !field.IsMap() {
+			if field.IsList() {
+				list := msg.Get(field).List()
+				for j := 0; j < list.Len(); j++ {
+					violations = append(violations, check(list.Get(j).Message(), fmt.Sprintf("%s[%d].", name, j))...)
+				}
+			} else if msg.Has(field) {
+				violations = append(violations, check(msg.Get(field).Message(), name+".")...)
+			}
+		}
+
+		rules := Rules(field)
+		if rules == nil {
+			continue
+		}
+		if !msg.Has(field) {
+			if rules.GetRequired() {
+				violations = append(violations, Violation{name, "is required"})
+			}
+			continue
+		}
+		if field.Kind() != protoreflect.StringKind {
+			continue
+		}
+		values := []string{}
+		if field.IsList() {
+			list := msg.Get(field).List()
+			for j := 0; j < list.Len(); j++ {
+				values = append(values, list.Get(j).String())
+			}
+		} else {
+			values = append(values, msg.Get(field).String())
+		}
+		for _, value := range values {
+			if description := checkString(rules, value); description != "" {
+				violations = append(violations, Violation{name, description})
+				break
+			}
+		}
+	}
+	return violations
+}
+
+// checkString describes the first rule value breaks, if any
+func checkString(rules *proto.FieldRules, value string) string {
+	length := uint32(utf8.RuneCountInString(value))
+	min, max := rules.GetMinLen(), rules.GetMaxLen()
+	switch {
+	case min > 0 && max > 0 && (length < min || length > max):
+		return fmt.Sprintf("should be between %d and %d characters", min, max)
+	case min > 0 && length < min:
+		return fmt.Sprintf("should be at least %d characters", min)
+	case max > 0 && length > max:
+		return fmt.Sprintf("should be at most %d characters", max)
+	}
+	if format, ok := formats[rules.GetFormat()]; ok && !format.valid(value) {
+		return "should be " + format.description
+	}
+	if in := rules.GetIn(); len(in) > 0 && !contains(in, value) {
+		return "should be one of " + strings.Join(in, ", ")
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// rulesCache holds the rules of every field seen, nil when it has none
+var rulesCache sync.Map
+
+// Rules returns the rules declared on field, nil when it has none
+func Rules(field protoreflect.FieldDescriptor) *proto.FieldRules {
+	if rules, ok := rulesCache.Load(field.FullName()); ok {
+		return rules.(*proto.FieldRules)
+	}
+	var rules *proto.FieldRules
+	if options := field.Options(); options != nil && protobuf.HasExtension(options, proto.E_Rules) {
+		rules = protobuf.GetExtension(options, proto.E_Rules).(*proto.FieldRules)
+	}
+	rulesCache.Store(field.FullName(), rules)
+	return rules
+}
+
+// Error returns an InvalidArgument status listing the violations of msg,
+// with a BadRequest detail per field, or nil when msg is valid
+func Error(msg protobuf.Message) error {
+	violations := Check(msg)
+	if len(violations) == 0 {
+		return nil
+	}
+	messages := make([]string, len(violations))
+	details := &errdetails.BadRequest{}
+	for i, v := range violations {
+		messages[i] = v.String()
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: v.Field, Description: v.Description})
+	}
+	s := status.New(codes.InvalidArgument, "Validation failed : "+strings.Join(messages, "; "))
+	if withDetails, err := s.WithDetails(details); err == nil {
+		s = withDetails
+	}
+	return s.Err()
+}
+
+// UnaryServerInterceptor rejects requests breaking their rules before they reach the handler
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if msg, ok := req.(protobuf.Message); ok {
+			if err := Error(msg); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects the requests of streams breaking their rules
+// as the handler receives them
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ss})
+	}
+}
+
+// serverStream validates every message it receives
+type serverStream struct {
+	grpc.ServerStream
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if msg, ok := m.(protobuf.Message); ok {
+		return Error(msg)
+	}
+	return nil
+}
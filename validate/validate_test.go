@@ -0,0 +1,202 @@
+package validate
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/HiteshRepo/blog-application/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func Test_Check(t *testing.T) {
+
+	validSignup := func() *proto.SignupRequest {
+		return &proto.SignupRequest{Username: "someone", Email: "someone@gmail.com", Password: "a-password"}
+	}
+	withSignup := func(update func(*proto.SignupRequest)) *proto.SignupRequest {
+		in := validSignup()
+		update(in)
+		return in
+	}
+
+	testCases := []map[string]interface{}{
+		map[string]interface{}{
+			"msg":        validSignup(),
+			"violations": []string{},
+		},
+		map[string]interface{}{
+			"msg":        &proto.SignupRequest{},
+			"violations": []string{"Username is required", "Email is required", "Password is required"},
+		},
+		map[string]interface{}{
+			"msg":        withSignup(func(in *proto.SignupRequest) { in.Username = "abc" }),
+			"violations": []string{"Username should be between 4 and 20 characters"},
+		},
+		map[string]interface{}{
+			// lengths count characters, not bytes
+			"msg":        withSignup(func(in *proto.SignupRequest) { in.Username = strings.Repeat("é", 20) }),
+			"violations": []string{},
+		},
+		map[string]interface{}{
+			"msg":        withSignup(func(in *proto.SignupRequest) { in.Username = strings.Repeat("a", 21) }),
+			"violations": []string{"Username should be between 4 and 20 characters"},
+		},
+		map[string]interface{}{
+			"msg":        withSignup(func(in *proto.SignupRequest) { in.Email = "not-an-email" }),
+			"violations": []string{"Email should be a valid email"},
+		},
+		map[string]interface{}{
+			"msg":        withSignup(func(in *proto.SignupRequest) { in.Password = "short" }),
+			"violations": []string{"Password should be between 8 and 120 characters"},
+		},
+		map[string]interface{}{
+			"msg":        &proto.LoginRequest{Password: "a-password"},
+			"violations": []string{"Login is required"},
+		},
+		map[string]interface{}{
+			"msg":        &proto.LoginRequest{Login: strings.Repeat("a", 36), Password: "a-password"},
+			"violations": []string{"Login should be at most 35 characters"},
+		},
+		map[string]interface{}{
+			"msg":        &proto.UsernameUsedRequest{},
+			"violations": []string{"Username is required"},
+		},
+		map[string]interface{}{
+			"msg":        &proto.EmailUsedRequest{},
+			"violations": []string{"Email is required"},
+		},
+		map[string]interface{}{
+			// the token may come as metadata instead
+			"msg":        &proto.AuthUserRequest{},
+			"violations": []string{},
+		},
+		map[string]interface{}{
+			"msg":        &proto.UserRequest{UserID: "not-an-id"},
+			"violations": []string{"UserID should be a valid id"},
+		},
+		map[string]interface{}{
+			"msg":        &proto.UserRequest{UserID: "62a000000000000000000001"},
+			"violations": []string{},
+		},
+		map[string]interface{}{
+			"msg":        &proto.RoleAuditRequest{},
+			"violations": []string{},
+		},
+		map[string]interface{}{
+			"msg":        &proto.ListUsersRequest{Status: "deleted"},
+			"violations": []string{"Status should be one of active, suspended"},
+		},
+		map[string]interface{}{
+			"msg":        &proto.ListUsersRequest{Status: "suspended"},
+			"violations": []string{},
+		},
+		map[string]interface{}{
+			// a temporary password is generated when none is given
+			"msg":        &proto.ResetUserPasswordRequest{UserID: "62a000000000000000000001"},
+			"violations": []string{},
+		},
+		map[string]interface{}{
+			"msg":        &proto.ResetUserPasswordRequest{UserID: "62a000000000000000000001", NewPassword: "short"},
+			"violations": []string{"NewPassword should be between 8 and 120 characters"},
+		},
+		map[string]interface{}{
+			"msg":        &proto.SuspendUserRequest{UserID: "62a000000000000000000001"},
+			"violations": []string{"Reason is required"},
+		},
+		map[string]interface{}{
+			"msg":        &proto.UpdateProfileRequest{AvatarURL: "javascript:alert(1)"},
+			"violations": []string{"AvatarURL should be a valid http or https url"},
+		},
+		map[string]interface{}{
+			// every field may be cleared
+			"msg":        &proto.UpdateProfileRequest{},
+			"violations": []string{},
+		},
+		map[string]interface{}{
+			"msg":        &proto.UpdateProfileRequest{DisplayName: "Someone", AvatarURL: "https://example.com/avatar.png"},
+			"violations": []string{},
+		},
+		map[string]interface{}{
+			// messages without rules
+			"msg":        &proto.AuthResponse{},
+			"violations": []string{},
+		},
+	}
+
+	for _, tcase := range testCases {
+		violations := []string{}
+		for _, v := range Check(tcase["msg"].(protobuf.Message)) {
+			violations = append(violations, v.String())
+		}
+		assert.Equalf(t, tcase["violations"], violations, "case: %v", tcase)
+	}
+}
+
+func Test_Error(t *testing.T) {
+
+	assert.NoError(t, Error(&proto.LoginRequest{Login: "someone", Password: "a-password"}))
+
+	err := Error(&proto.LoginRequest{})
+	s := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, s.Code())
+	assert.Equal(t, "Validation failed : Login is required; Password is required", s.Message())
+
+	// clients can tell the fields apart from the details
+	if assert.Len(t, s.Details(), 1) {
+		details := s.Details()[0].(*errdetails.BadRequest)
+		assert.Equal(t, "Login", details.GetFieldViolations()[0].GetField())
+		assert.Equal(t, "is required", details.GetFieldViolations()[0].GetDescription())
+		assert.Equal(t, "Password", details.GetFieldViolations()[1].GetField())
+	}
+}
+
+func Test_UnaryServerInterceptor(t *testing.T) {
+
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.AuthService/Login"}
+	called := false
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return &proto.AuthResponse{}, nil
+	}
+
+	_, err := interceptor(context.Background(), &proto.LoginRequest{}, info, handler)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.False(t, called)
+
+	_, err = interceptor(context.Background(), &proto.LoginRequest{Login: "someone", Password: "a-password"}, info, handler)
+	assert.NoError(t, err)
+	assert.True(t, called)
+}
+
+// testStream is a server stream receiving one message
+type testStream struct {
+	grpc.ServerStream
+	msg *proto.LoginRequest
+}
+
+func (s *testStream) RecvMsg(m interface{}) error {
+	protobuf.Merge(m.(protobuf.Message), s.msg)
+	return nil
+}
+
+func Test_StreamServerInterceptor(t *testing.T) {
+
+	interceptor := StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/proto.AuthService/Login"}
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		return ss.RecvMsg(&proto.LoginRequest{})
+	}
+
+	err := interceptor(nil, &testStream{msg: &proto.LoginRequest{}}, info, handler)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	err = interceptor(nil, &testStream{msg: &proto.LoginRequest{Login: "someone", Password: "a-password"}}, info, handler)
+	assert.NoError(t, err)
+}